@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"log"
 	"net/http"
 
@@ -52,28 +53,88 @@ func main() {
 	projectRepo := postgres.NewProjectRepository(db)
 	problemRepo := postgres.NewProblemRepository(db)
 	resultRepo := postgres.NewResultRepository(db)
+	loginAttemptRepo := postgres.NewLoginAttemptRepository(db)
+	adminRepo := postgres.NewAdminRepository(db)
+	inviteRepo := postgres.NewInviteRepository(db)
+	joinRequestRepo := postgres.NewJoinRequestRepository(db)
+	notificationRepo := postgres.NewNotificationRepository(db)
+	ownershipTransferRepo := postgres.NewOwnershipTransferRepository(db)
+	groupRepo := postgres.NewGroupRepository(db)
+	searchRepo := postgres.NewSearchRepository(db)
+	problemTemplateRepo := postgres.NewProblemTemplateRepository(db)
+	milestoneRepo := postgres.NewMilestoneRepository(db)
+	extensionRepo := postgres.NewExtensionRepository(db)
+	submissionRepo := postgres.NewSubmissionRepository(db)
+	rubricRepo := postgres.NewRubricRepository(db)
+	gradeRepo := postgres.NewGradeRepository(db)
+	peerReviewRepo := postgres.NewPeerReviewRepository(db)
 	tokenRepo := redisrepo.NewTokenRepository(redisClient)
+	rateLimitRepo := redisrepo.NewRateLimitRepository(redisClient)
 
-	authService := services.NewAuthService(userRepo, tokenRepo, cfg.JWT)
-	subjectService := services.NewSubjectService(subjectRepo, roleRepo, userRepo)
-	roleService := services.NewRoleService(roleRepo)
-	taskService := services.NewTaskService(taskRepo, roleRepo, subjectRepo)
-	projectService := services.NewProjectService(projectRepo, taskRepo, roleRepo, problemRepo)
-	resultService := services.NewResultService(resultRepo, problemRepo, projectRepo)
-	problemService := services.NewProblemService(problemRepo, projectRepo)
+	loginThrottleService := services.NewLoginThrottleService(rateLimitRepo, loginAttemptRepo, cfg.RateLimit)
+	authService := services.NewAuthService(userRepo, tokenRepo, loginThrottleService, cfg.JWT, cfg.Admin)
+	if err := authService.BootstrapSuperAdmins(context.Background()); err != nil {
+		log.Printf("Failed to bootstrap super-admins: %v", err)
+	}
+	authorizer := services.NewAuthorizer(userRepo, roleRepo, taskRepo, projectRepo, subjectRepo)
+	userService := services.NewUserService(userRepo, roleRepo, authService)
+	adminService := services.NewAdminService(adminRepo, userRepo, subjectRepo, roleRepo, authService, userService, loginThrottleService)
+	notificationService := services.NewNotificationService(notificationRepo)
+	groupService := services.NewGroupService(groupRepo, roleRepo, authorizer)
+	problemTemplateService := services.NewProblemTemplateService(problemTemplateRepo, taskRepo, projectRepo, problemRepo, authorizer)
+	problemService := services.NewProblemService(problemRepo, projectRepo, milestoneRepo, extensionRepo, authorizer)
+	teamService := services.NewTeamService(projectRepo, taskRepo, roleRepo, problemService, groupService, problemTemplateService, authorizer)
+	inviteService := services.NewInviteService(inviteRepo, projectRepo, userRepo, groupRepo, authorizer)
+	joinRequestService := services.NewJoinRequestService(joinRequestRepo, subjectRepo, projectRepo, roleRepo, teamService, notificationService, authorizer)
+	rosterService := services.NewRosterService(roleRepo, userRepo, groupService, inviteService, authorizer)
+	subjectService := services.NewSubjectService(subjectRepo, roleRepo, userRepo, taskRepo, problemTemplateService, groupService, inviteService, joinRequestService, ownershipTransferRepo, notificationService, authorizer)
+	roleService := services.NewRoleService(roleRepo, userRepo, groupService, authorizer)
+	projectService := services.NewProjectService(projectRepo, taskRepo, roleRepo, groupService, teamService, inviteService, joinRequestService, authorizer)
+	extensionService := services.NewExtensionService(extensionRepo, projectRepo, taskRepo, problemRepo, groupService, problemService, authorizer)
+	resultService := services.NewResultService(resultRepo, problemRepo, projectRepo, extensionService, authorizer)
+	submissionService := services.NewSubmissionService(submissionRepo, projectRepo, taskRepo, problemRepo, resultRepo, extensionService, authorizer)
+	peerReviewService := services.NewPeerReviewService(peerReviewRepo, gradeRepo, taskRepo, projectRepo, authorizer)
+	dashboardService := services.NewDashboardService(taskRepo, projectRepo, problemRepo, resultRepo, extensionService, authorizer)
+	analyticsService := services.NewAnalyticsService(projectRepo, problemRepo, resultRepo, authorizer)
+	gradingService := services.NewGradingService(rubricRepo, gradeRepo, taskRepo, projectRepo, submissionRepo, roleRepo, peerReviewService, authorizer)
+	taskService := services.NewTaskService(taskRepo, roleRepo, subjectRepo, groupService, problemService, authorizer)
+	milestoneService := services.NewMilestoneService(milestoneRepo, taskRepo, projectRepo, authorizer)
+	searchService := services.NewSearchService(searchRepo, userRepo, roleRepo)
 
 	authHandler := handlers.NewAuthHandler(authService)
+	userHandler := handlers.NewUserHandler(userService)
+	adminHandler := handlers.NewAdminHandler(adminService)
 	subjectHandler := handlers.NewSubjectHandler(subjectService)
 	roleHandler := handlers.NewRoleHandler(roleService)
+	inviteHandler := handlers.NewInviteHandler(inviteService)
+	joinRequestHandler := handlers.NewJoinRequestHandler(joinRequestService)
+	rosterHandler := handlers.NewRosterHandler(rosterService)
+	groupHandler := handlers.NewGroupHandler(groupService)
+	searchHandler := handlers.NewSearchHandler(searchService)
+	notificationHandler := handlers.NewNotificationHandler(notificationService)
 	taskHandler := handlers.NewTaskHandler(taskService)
+	problemTemplateHandler := handlers.NewProblemTemplateHandler(problemTemplateService)
+	teamHandler := handlers.NewTeamHandler(teamService)
+	milestoneHandler := handlers.NewMilestoneHandler(milestoneService)
+	extensionHandler := handlers.NewExtensionHandler(extensionService)
+	submissionHandler := handlers.NewSubmissionHandler(submissionService)
+	gradingHandler := handlers.NewGradingHandler(gradingService)
+	peerReviewHandler := handlers.NewPeerReviewHandler(peerReviewService)
+	analyticsHandler := handlers.NewAnalyticsHandler(analyticsService)
+	dashboardHandler := handlers.NewDashboardHandler(dashboardService)
 	projectHandler := handlers.NewProjectHandler(projectService)
 	problemHandler := handlers.NewProblemHandler(problemService, resultService)
 	resultHandler := handlers.NewResultHandler(resultService)
 
 	authMiddleware := middleware.NewAuthMiddleware(authService)
+	rateLimiter := middleware.NewRateLimitMiddleware(rateLimitRepo, cfg.RateLimit.TrustedProxies)
+	// общий бюджет по IP работает до аутентификации, бюджет пользователя вешается на защищенные маршруты
+	userLimit := rateLimiter.Limit("api-user", cfg.RateLimit.APIPerUser, cfg.RateLimit.APIWindow)
 
 	r := mux.NewRouter()
 	r.Use(CORSMiddleware)
+	r.Use(rateLimiter.ResolveClientIP)
+	r.Use(rateLimiter.Limit("api", cfg.RateLimit.APIPerIP, cfg.RateLimit.APIWindow))
 
 	// Глобальный обработчик для всех OPTIONS запросов
 	r.Methods("OPTIONS").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -81,44 +142,168 @@ func main() {
 	})
 
 	// auth
-	r.HandleFunc("/api/auth/register", authHandler.Register).Methods("POST", "OPTIONS")
-	r.HandleFunc("/api/auth/login", authHandler.Login).Methods("POST", "OPTIONS")
-	r.HandleFunc("/api/auth/refresh", authHandler.Refresh).Methods("POST", "OPTIONS")
+	registerLimit := rateLimiter.Limit("register", cfg.RateLimit.RegisterPerIP, cfg.RateLimit.Window)
+	loginLimit := rateLimiter.Limit("login", cfg.RateLimit.LoginPerIP, cfg.RateLimit.Window)
+	refreshLimit := rateLimiter.Limit("refresh", cfg.RateLimit.LoginPerIP, cfg.RateLimit.Window)
+	joinLimit := rateLimiter.Limit("join", cfg.RateLimit.JoinPerUser, cfg.RateLimit.Window)
+	reauthLimit := rateLimiter.Limit("reauth", cfg.RateLimit.LoginPerEmail, cfg.RateLimit.Window)
+
+	r.Handle("/api/auth/register", registerLimit(http.HandlerFunc(authHandler.Register))).Methods("POST", "OPTIONS")
+	r.Handle("/api/auth/login", loginLimit(http.HandlerFunc(authHandler.Login))).Methods("POST", "OPTIONS")
+	r.Handle("/api/auth/refresh", refreshLimit(http.HandlerFunc(authHandler.Refresh))).Methods("POST", "OPTIONS")
 
 	protected := r.PathPrefix("/api/auth").Subrouter()
 	protected.Use(authMiddleware.Authenticate)
+	protected.Use(userLimit)
 	protected.HandleFunc("/logout", authHandler.Logout).Methods("POST", "OPTIONS")
 
-	// предмет паблик
-	r.HandleFunc("/api/subjects", subjectHandler.GetAllSubjects).Methods("GET", "OPTIONS")
-	r.HandleFunc("/api/subjects/{id}", subjectHandler.GetSubject).Methods("GET", "OPTIONS")
-	r.HandleFunc("/api/subjects/{id}/tasks", taskHandler.GetSubjectTasks).Methods("GET", "OPTIONS")
-	r.HandleFunc("/api/subjects/{id}/members", roleHandler.GetSubjectMembers).Methods("GET", "OPTIONS")
-	r.HandleFunc("/api/tasks/{taskId}", taskHandler.GetTask).Methods("GET", "OPTIONS")
+	// предмет паблик: ответ зависит от видимости предмета и от того, кто смотрит
+	optional := authMiddleware.OptionalAuth
+	r.Handle("/api/subjects", optional(http.HandlerFunc(subjectHandler.GetAllSubjects))).Methods("GET", "OPTIONS")
+	r.Handle("/api/subjects/{id}", optional(http.HandlerFunc(subjectHandler.GetSubject))).Methods("GET", "OPTIONS")
+	r.Handle("/api/subjects/{id}/tasks", optional(http.HandlerFunc(taskHandler.GetSubjectTasks))).Methods("GET", "OPTIONS")
+	r.Handle("/api/subjects/{id}/members", optional(http.HandlerFunc(roleHandler.GetSubjectMembers))).Methods("GET", "OPTIONS")
+	r.Handle("/api/subjects/{id}/groups", optional(http.HandlerFunc(groupHandler.GetSubjectGroups))).Methods("GET", "OPTIONS")
+	r.Handle("/api/groups/{groupId}", optional(http.HandlerFunc(groupHandler.GetGroup))).Methods("GET", "OPTIONS")
+	r.Handle("/api/tasks/{taskId}", optional(http.HandlerFunc(taskHandler.GetTask))).Methods("GET", "OPTIONS")
+	r.Handle("/api/tasks/{taskId}/templates", optional(http.HandlerFunc(problemTemplateHandler.GetTemplates))).Methods("GET", "OPTIONS")
+	r.Handle("/api/tasks/{taskId}/milestones", optional(http.HandlerFunc(milestoneHandler.GetTaskMilestones))).Methods("GET", "OPTIONS")
+	r.Handle("/api/tasks/{taskId}/rubric", optional(http.HandlerFunc(gradingHandler.GetRubric))).Methods("GET", "OPTIONS")
+	r.Handle("/api/tasks/{taskId}/peer-review", optional(http.HandlerFunc(peerReviewHandler.GetRound))).Methods("GET", "OPTIONS")
+	r.Handle("/api/search", optional(http.HandlerFunc(searchHandler.Search))).Methods("GET", "OPTIONS")
 
 	// защищенные
 	protectedRouter := r.PathPrefix("/api").Subrouter()
 	protectedRouter.Use(authMiddleware.Authenticate)
+	protectedRouter.Use(userLimit)
+
+	// пользователи
+	protectedRouter.HandleFunc("/users/me", userHandler.GetMe).Methods("GET", "OPTIONS")
+	protectedRouter.HandleFunc("/users/me", userHandler.UpdateMe).Methods("PUT", "OPTIONS")
+	protectedRouter.Handle("/users/me/password", authMiddleware.ForbidImpersonation(reauthLimit(http.HandlerFunc(userHandler.ChangePassword)))).Methods("POST", "OPTIONS")
+	protectedRouter.Handle("/users/me/email", authMiddleware.ForbidImpersonation(reauthLimit(http.HandlerFunc(userHandler.ChangeEmail)))).Methods("POST", "OPTIONS")
+	protectedRouter.Handle("/users/me/deactivate", authMiddleware.ForbidImpersonation(reauthLimit(http.HandlerFunc(userHandler.DeactivateMe)))).Methods("POST", "OPTIONS")
+	protectedRouter.HandleFunc("/users/{userId}", userHandler.GetUser).Methods("GET", "OPTIONS")
 
 	// предметы
 	protectedRouter.HandleFunc("/subjects/get/my", subjectHandler.GetMySubjects).Methods("GET", "OPTIONS")
-	protectedRouter.HandleFunc("/subjects/join", subjectHandler.JoinSubject).Methods("POST", "OPTIONS")
+	protectedRouter.Handle("/subjects/join", joinLimit(http.HandlerFunc(subjectHandler.JoinSubject))).Methods("POST", "OPTIONS")
 	protectedRouter.HandleFunc("/subjects", subjectHandler.CreateSubject).Methods("POST", "OPTIONS")
 	protectedRouter.HandleFunc("/subjects/{id}", subjectHandler.UpdateSubject).Methods("PUT", "OPTIONS")
 	protectedRouter.HandleFunc("/subjects/{id}", subjectHandler.DeleteSubject).Methods("DELETE", "OPTIONS")
+	protectedRouter.HandleFunc("/subjects/{id}/leave", roleHandler.LeaveSubject).Methods("POST", "OPTIONS")
+	protectedRouter.HandleFunc("/subjects/{id}/archive", subjectHandler.ArchiveSubject).Methods("POST", "OPTIONS")
+	protectedRouter.HandleFunc("/subjects/{id}/unarchive", subjectHandler.UnarchiveSubject).Methods("POST", "OPTIONS")
+	protectedRouter.HandleFunc("/subjects/{id}/clone", subjectHandler.CloneSubject).Methods("POST", "OPTIONS")
+
+	// владение предметом
+	protectedRouter.HandleFunc("/subjects/{id}/transfer-ownership", subjectHandler.TransferOwnership).Methods("POST", "OPTIONS")
+	protectedRouter.HandleFunc("/ownership-transfers/my", subjectHandler.GetMyOwnershipTransfers).Methods("GET", "OPTIONS")
+	protectedRouter.HandleFunc("/ownership-transfers/{transferId}/accept", subjectHandler.AcceptOwnershipTransfer).Methods("POST", "OPTIONS")
+	protectedRouter.HandleFunc("/ownership-transfers/{transferId}/decline", subjectHandler.DeclineOwnershipTransfer).Methods("POST", "OPTIONS")
+	protectedRouter.HandleFunc("/ownership-transfers/{transferId}", subjectHandler.CancelOwnershipTransfer).Methods("DELETE", "OPTIONS")
 
 	// роли
+	protectedRouter.HandleFunc("/permissions", roleHandler.GetPermissionCatalogue).Methods("GET", "OPTIONS")
+	protectedRouter.HandleFunc("/subjects/{id}/permissions/my", roleHandler.GetMyPermissions).Methods("GET", "OPTIONS")
+	protectedRouter.HandleFunc("/subjects/{id}/roles", roleHandler.AddMember).Methods("POST", "OPTIONS")
+	protectedRouter.HandleFunc("/subjects/{id}/roles/{roleId}", roleHandler.UpdateRole).Methods("PUT", "OPTIONS")
 	protectedRouter.HandleFunc("/subjects/{id}/roles/{roleId}/change", roleHandler.ChangeRole).Methods("POST", "OPTIONS")
 	protectedRouter.HandleFunc("/subjects/{id}/roles/{roleId}", roleHandler.RemoveFromSubject).Methods("DELETE", "OPTIONS")
 
+	// список участников
+	protectedRouter.HandleFunc("/subjects/{id}/roster/import", rosterHandler.ImportRoster).Methods("POST", "OPTIONS")
+	protectedRouter.HandleFunc("/subjects/{id}/roster/export", rosterHandler.ExportRoster).Methods("GET", "OPTIONS")
+
+	// группы
+	protectedRouter.HandleFunc("/subjects/{id}/groups", groupHandler.CreateGroup).Methods("POST", "OPTIONS")
+	protectedRouter.HandleFunc("/subjects/{id}/groups/my", groupHandler.GetMyGroups).Methods("GET", "OPTIONS")
+	protectedRouter.HandleFunc("/subjects/{id}/groups/statistics", groupHandler.GetGroupStatistics).Methods("GET", "OPTIONS")
+	protectedRouter.HandleFunc("/groups/{groupId}", groupHandler.UpdateGroup).Methods("PUT", "OPTIONS")
+	protectedRouter.HandleFunc("/groups/{groupId}", groupHandler.DeleteGroup).Methods("DELETE", "OPTIONS")
+	protectedRouter.HandleFunc("/groups/{groupId}/members", groupHandler.AddGroupMembers).Methods("POST", "OPTIONS")
+	protectedRouter.HandleFunc("/groups/{groupId}/members/{userId}", groupHandler.RemoveGroupMember).Methods("DELETE", "OPTIONS")
+
+	// приглашения
+	protectedRouter.HandleFunc("/invites/my", inviteHandler.GetMyInvites).Methods("GET", "OPTIONS")
+	protectedRouter.HandleFunc("/invites/{inviteId}", inviteHandler.RevokeInvite).Methods("DELETE", "OPTIONS")
+	protectedRouter.HandleFunc("/invites/{inviteId}/regenerate", inviteHandler.RegenerateInvite).Methods("POST", "OPTIONS")
+	protectedRouter.HandleFunc("/invites/{inviteId}/decline", inviteHandler.DeclineInvite).Methods("POST", "OPTIONS")
+	protectedRouter.HandleFunc("/subjects/{id}/invites", inviteHandler.GetSubjectInvites).Methods("GET", "OPTIONS")
+	protectedRouter.HandleFunc("/subjects/{id}/invites", inviteHandler.CreateSubjectInvite).Methods("POST", "OPTIONS")
+	protectedRouter.HandleFunc("/projects/{projectId}/invites", inviteHandler.GetProjectInvites).Methods("GET", "OPTIONS")
+	protectedRouter.HandleFunc("/projects/{projectId}/invites", inviteHandler.CreateProjectInvite).Methods("POST", "OPTIONS")
+
+	// заявки на вступление
+	protectedRouter.HandleFunc("/join-requests/my", joinRequestHandler.GetMyRequests).Methods("GET", "OPTIONS")
+	protectedRouter.HandleFunc("/join-requests/decide", joinRequestHandler.DecideBulk).Methods("POST", "OPTIONS")
+	protectedRouter.HandleFunc("/join-requests/{requestId}/approve", joinRequestHandler.ApproveRequest).Methods("POST", "OPTIONS")
+	protectedRouter.HandleFunc("/join-requests/{requestId}/reject", joinRequestHandler.RejectRequest).Methods("POST", "OPTIONS")
+	protectedRouter.HandleFunc("/join-requests/{requestId}", joinRequestHandler.CancelRequest).Methods("DELETE", "OPTIONS")
+	protectedRouter.HandleFunc("/subjects/{id}/join-requests", joinRequestHandler.GetSubjectRequests).Methods("GET", "OPTIONS")
+	protectedRouter.HandleFunc("/projects/{projectId}/join-requests", joinRequestHandler.GetProjectRequests).Methods("GET", "OPTIONS")
+	protectedRouter.HandleFunc("/projects/{projectId}/join-approval", projectHandler.SetJoinApproval).Methods("PUT", "OPTIONS")
+
+	// уведомления
+	protectedRouter.HandleFunc("/notifications", notificationHandler.GetNotifications).Methods("GET", "OPTIONS")
+	protectedRouter.HandleFunc("/notifications/read-all", notificationHandler.MarkAllRead).Methods("POST", "OPTIONS")
+	protectedRouter.HandleFunc("/notifications/{notificationId}/read", notificationHandler.MarkRead).Methods("POST", "OPTIONS")
+
 	// задания
 	protectedRouter.HandleFunc("/subjects/{id}/tasks", taskHandler.CreateTask).Methods("POST", "OPTIONS")
 	protectedRouter.HandleFunc("/tasks/{taskId}", taskHandler.UpdateTask).Methods("PUT", "OPTIONS")
+	protectedRouter.HandleFunc("/tasks/{taskId}/templates", problemTemplateHandler.SetTemplates).Methods("PUT", "OPTIONS")
+	protectedRouter.HandleFunc("/tasks/{taskId}/templates/sync", problemTemplateHandler.SyncTemplates).Methods("POST", "OPTIONS")
+	protectedRouter.HandleFunc("/tasks/{taskId}/teams", teamHandler.CreateTeam).Methods("POST", "OPTIONS")
+	protectedRouter.HandleFunc("/tasks/{taskId}/teams/move", teamHandler.MoveMember).Methods("POST", "OPTIONS")
+	protectedRouter.HandleFunc("/tasks/{taskId}/teams/auto", teamHandler.AutoForm).Methods("POST", "OPTIONS")
+	protectedRouter.HandleFunc("/tasks/{taskId}/teams/report", teamHandler.GetReport).Methods("GET", "OPTIONS")
+	protectedRouter.HandleFunc("/tasks/{taskId}/schedule-impact", taskHandler.GetScheduleImpact).Methods("GET", "OPTIONS")
+	protectedRouter.HandleFunc("/tasks/{taskId}/publish", taskHandler.PublishTask).Methods("POST", "OPTIONS")
+	protectedRouter.HandleFunc("/tasks/{taskId}/unpublish", taskHandler.UnpublishTask).Methods("POST", "OPTIONS")
+	protectedRouter.HandleFunc("/tasks/{taskId}/milestones", milestoneHandler.CreateMilestone).Methods("POST", "OPTIONS")
+	protectedRouter.HandleFunc("/tasks/{taskId}/milestones/report", milestoneHandler.GetTaskMilestoneReport).Methods("GET", "OPTIONS")
+	protectedRouter.HandleFunc("/milestones/{milestoneId}", milestoneHandler.UpdateMilestone).Methods("PUT", "OPTIONS")
+	protectedRouter.HandleFunc("/milestones/{milestoneId}", milestoneHandler.DeleteMilestone).Methods("DELETE", "OPTIONS")
+	protectedRouter.HandleFunc("/projects/{projectId}/milestones", milestoneHandler.GetProjectMilestones).Methods("GET", "OPTIONS")
+	protectedRouter.HandleFunc("/tasks/{taskId}/extensions", extensionHandler.GetTaskExtensions).Methods("GET", "OPTIONS")
+	protectedRouter.HandleFunc("/projects/{projectId}/extensions", extensionHandler.GrantExtension).Methods("POST", "OPTIONS")
+	protectedRouter.HandleFunc("/projects/{projectId}/deadline", extensionHandler.GetProjectDeadline).Methods("GET", "OPTIONS")
+	protectedRouter.HandleFunc("/extensions/{extensionId}", extensionHandler.RevokeExtension).Methods("DELETE", "OPTIONS")
+	protectedRouter.HandleFunc("/projects/{projectId}/submit", submissionHandler.SubmitProject).Methods("POST", "OPTIONS")
+	protectedRouter.HandleFunc("/projects/{projectId}/review", submissionHandler.StartReview).Methods("POST", "OPTIONS")
+	protectedRouter.HandleFunc("/projects/{projectId}/return", submissionHandler.ReturnProject).Methods("POST", "OPTIONS")
+	protectedRouter.HandleFunc("/projects/{projectId}/submissions", submissionHandler.GetProjectSubmissions).Methods("GET", "OPTIONS")
+	protectedRouter.HandleFunc("/submissions/{submissionId}", submissionHandler.GetSubmission).Methods("GET", "OPTIONS")
+	protectedRouter.HandleFunc("/tasks/{taskId}/rubric", gradingHandler.SetRubric).Methods("PUT", "OPTIONS")
+	protectedRouter.HandleFunc("/tasks/{taskId}/grades/release", gradingHandler.ReleaseGrades).Methods("POST", "OPTIONS")
+	protectedRouter.HandleFunc("/projects/{projectId}/grade", gradingHandler.GradeProject).Methods("PUT", "OPTIONS")
+	protectedRouter.HandleFunc("/projects/{projectId}/grade", gradingHandler.GetProjectGrade).Methods("GET", "OPTIONS")
+	protectedRouter.HandleFunc("/projects/{projectId}/grade/members/{userId}", gradingHandler.AdjustMemberGrade).Methods("PUT", "OPTIONS")
+	protectedRouter.HandleFunc("/subjects/{id}/gradebook", gradingHandler.GetGradebook).Methods("GET", "OPTIONS")
+	protectedRouter.HandleFunc("/tasks/{taskId}/peer-review", peerReviewHandler.SetRound).Methods("PUT", "OPTIONS")
+	protectedRouter.HandleFunc("/tasks/{taskId}/peer-review/summary", peerReviewHandler.GetTaskSummary).Methods("GET", "OPTIONS")
+	protectedRouter.HandleFunc("/tasks/{taskId}/peer-review/apply", peerReviewHandler.ApplyAdjustments).Methods("POST", "OPTIONS")
+	protectedRouter.HandleFunc("/projects/{projectId}/peer-reviews", peerReviewHandler.SubmitReviews).Methods("PUT", "OPTIONS")
+	protectedRouter.HandleFunc("/projects/{projectId}/peer-reviews/mine", peerReviewHandler.GetMyReviews).Methods("GET", "OPTIONS")
+	protectedRouter.HandleFunc("/projects/{projectId}/peer-review/summary", peerReviewHandler.GetProjectSummary).Methods("GET", "OPTIONS")
+	protectedRouter.HandleFunc("/projects/{projectId}/analytics", analyticsHandler.GetProjectAnalytics).Methods("GET", "OPTIONS")
+	protectedRouter.HandleFunc("/subjects/{id}/analytics", analyticsHandler.GetSubjectAnalytics).Methods("GET", "OPTIONS")
+	protectedRouter.HandleFunc("/tasks/{taskId}/dashboard", dashboardHandler.GetTaskDashboard).Methods("GET", "OPTIONS")
+	protectedRouter.HandleFunc("/projects/{projectId}/tree", dashboardHandler.GetProjectTree).Methods("GET", "OPTIONS")
 
 	// проекты
 	protectedRouter.HandleFunc("/projects/my", projectHandler.GetMyProjects).Methods("GET", "OPTIONS")
-	protectedRouter.HandleFunc("/projects/join", projectHandler.JoinProject).Methods("POST", "OPTIONS")
+	protectedRouter.Handle("/projects/join", joinLimit(http.HandlerFunc(projectHandler.JoinProject))).Methods("POST", "OPTIONS")
 	protectedRouter.HandleFunc("/projects/{projectId}/users", projectHandler.GetProjectUsers).Methods("GET", "OPTIONS")
+	protectedRouter.HandleFunc("/projects/{projectId}", projectHandler.UpdateProject).Methods("PUT", "OPTIONS")
+	protectedRouter.HandleFunc("/projects/{projectId}", projectHandler.DeleteProject).Methods("DELETE", "OPTIONS")
+	protectedRouter.HandleFunc("/projects/{projectId}/leave", projectHandler.LeaveProject).Methods("POST", "OPTIONS")
+	protectedRouter.HandleFunc("/projects/{projectId}/members/{userId}", projectHandler.RemoveMember).Methods("DELETE", "OPTIONS")
+	protectedRouter.HandleFunc("/projects/{projectId}/creator", projectHandler.TransferCreator).Methods("PUT", "OPTIONS")
+	protectedRouter.HandleFunc("/projects/{projectId}/code", projectHandler.RegenerateCode).Methods("POST", "OPTIONS")
+	protectedRouter.HandleFunc("/projects/{projectId}/archive", projectHandler.ArchiveProject).Methods("POST", "OPTIONS")
+	protectedRouter.HandleFunc("/projects/{projectId}/unarchive", projectHandler.UnarchiveProject).Methods("POST", "OPTIONS")
 	protectedRouter.HandleFunc("/tasks/{taskId}/projects", projectHandler.GetTaskProjects).Methods("GET", "OPTIONS")
 	protectedRouter.HandleFunc("/tasks/{taskId}/projects", projectHandler.CreateProject).Methods("POST", "OPTIONS")
 
@@ -135,6 +320,22 @@ func main() {
 	protectedRouter.HandleFunc("/problems/{problemId}/result", resultHandler.GetResult).Methods("GET", "OPTIONS")
 	protectedRouter.HandleFunc("/problems/{problemId}/result", resultHandler.CreateResult).Methods("POST", "OPTIONS")
 
+	// администрирование платформы
+	adminRouter := protectedRouter.PathPrefix("/admin").Subrouter()
+	adminRouter.Use(authMiddleware.RequireSuperAdmin)
+	adminRouter.HandleFunc("/users", adminHandler.ListUsers).Methods("GET", "OPTIONS")
+	adminRouter.HandleFunc("/users/{userId}", adminHandler.GetUser).Methods("GET", "OPTIONS")
+	adminRouter.HandleFunc("/users/{userId}/deactivate", adminHandler.DeactivateUser).Methods("POST", "OPTIONS")
+	adminRouter.HandleFunc("/users/{userId}/activate", adminHandler.ActivateUser).Methods("POST", "OPTIONS")
+	adminRouter.HandleFunc("/users/{userId}/logout", adminHandler.ForceLogout).Methods("POST", "OPTIONS")
+	adminRouter.HandleFunc("/users/{userId}/unlock", adminHandler.UnlockLogin).Methods("POST", "OPTIONS")
+	adminRouter.HandleFunc("/users/{userId}/super-admin", adminHandler.SetSuperAdmin).Methods("POST", "OPTIONS")
+	adminRouter.HandleFunc("/users/{userId}/impersonate", adminHandler.Impersonate).Methods("POST", "OPTIONS")
+	adminRouter.HandleFunc("/subjects/orphaned", adminHandler.GetOrphanedSubjects).Methods("GET", "OPTIONS")
+	adminRouter.HandleFunc("/subjects/{id}/admin", adminHandler.ReassignSubjectAdmin).Methods("POST", "OPTIONS")
+	adminRouter.HandleFunc("/statistics", adminHandler.GetStatistics).Methods("GET", "OPTIONS")
+	adminRouter.HandleFunc("/audit", adminHandler.GetAuditLogs).Methods("GET", "OPTIONS")
+
 	log.Printf("Server starting on port %s", cfg.Server.Port)
 	log.Fatal(http.ListenAndServe(":"+cfg.Server.Port, r))
 }
@@ -3,16 +3,19 @@ package config
 import (
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
 )
 
 type Config struct {
-	Server   ServerConfig
-	Database DatabaseConfig
-	Redis    RedisConfig
-	JWT      JWTConfig
+	Server    ServerConfig
+	Database  DatabaseConfig
+	Redis     RedisConfig
+	JWT       JWTConfig
+	RateLimit RateLimitConfig
+	Admin     AdminConfig
 }
 
 type ServerConfig struct {
@@ -42,6 +45,39 @@ type JWTConfig struct {
 	RefreshTokenExpiry time.Duration
 }
 
+type RateLimitConfig struct {
+	LoginPerIP       int
+	LoginPerEmail    int
+	RegisterPerIP    int
+	RegisterPerEmail int
+	Window           time.Duration
+
+	// после DelayAfter неудачных попыток каждая следующая откладывается на BaseDelay*2^n (не больше MaxDelay)
+	DelayAfter int
+	BaseDelay  time.Duration
+	MaxDelay   time.Duration
+
+	LockoutThreshold int
+	LockoutWindow    time.Duration
+	LockoutDuration  time.Duration
+
+	JoinPerUser int
+	APIPerIP    int
+	// APIPerUser бюджет аутентифицированного пользователя; считается после проверки токена
+	APIPerUser int
+	APIWindow  time.Duration
+
+	// TrustedProxies адреса или подсети прокси, которым доверяются X-Forwarded-For и X-Real-IP
+	TrustedProxies []string
+}
+
+type AdminConfig struct {
+	// уже зарегистрированные пользователи с этими email получают права супер-администратора при старте,
+	// если на платформе еще нет ни одного супер-администратора
+	SuperAdminEmails    []string
+	ImpersonationExpiry time.Duration
+}
+
 func Load() *Config {
 	_ = godotenv.Load()
 
@@ -69,6 +105,28 @@ func Load() *Config {
 			AccessTokenExpiry:  getEnvAsDuration("JWT_ACCESS_EXPIRY", 15*time.Minute),
 			RefreshTokenExpiry: getEnvAsDuration("JWT_REFRESH_EXPIRY", 7*24*time.Hour),
 		},
+		RateLimit: RateLimitConfig{
+			LoginPerIP:       getEnvAsInt("RATE_LIMIT_LOGIN_PER_IP", 20),
+			LoginPerEmail:    getEnvAsInt("RATE_LIMIT_LOGIN_PER_EMAIL", 10),
+			RegisterPerIP:    getEnvAsInt("RATE_LIMIT_REGISTER_PER_IP", 5),
+			RegisterPerEmail: getEnvAsInt("RATE_LIMIT_REGISTER_PER_EMAIL", 3),
+			Window:           getEnvAsDuration("RATE_LIMIT_WINDOW", 15*time.Minute),
+			DelayAfter:       getEnvAsInt("LOGIN_DELAY_AFTER", 3),
+			BaseDelay:        getEnvAsDuration("LOGIN_BASE_DELAY", time.Second),
+			MaxDelay:         getEnvAsDuration("LOGIN_MAX_DELAY", 30*time.Second),
+			LockoutThreshold: getEnvAsInt("LOGIN_LOCKOUT_THRESHOLD", 10),
+			LockoutWindow:    getEnvAsDuration("LOGIN_LOCKOUT_WINDOW", time.Hour),
+			LockoutDuration:  getEnvAsDuration("LOGIN_LOCKOUT_DURATION", 30*time.Minute),
+			JoinPerUser:      getEnvAsInt("RATE_LIMIT_JOIN_PER_USER", 10),
+			APIPerIP:         getEnvAsInt("RATE_LIMIT_API_PER_IP", 300),
+			APIPerUser:       getEnvAsInt("RATE_LIMIT_API_PER_USER", 120),
+			APIWindow:        getEnvAsDuration("RATE_LIMIT_API_WINDOW", time.Minute),
+			TrustedProxies:   getEnvAsSlice("TRUSTED_PROXIES", nil),
+		},
+		Admin: AdminConfig{
+			SuperAdminEmails:    getEnvAsSlice("SUPER_ADMIN_EMAILS", nil),
+			ImpersonationExpiry: getEnvAsDuration("IMPERSONATION_EXPIRY", 15*time.Minute),
+		},
 	}
 }
 
@@ -88,6 +146,14 @@ func (c *Config) GetJWTConfig() JWTConfig {
 	return c.JWT
 }
 
+func (c *Config) GetRateLimitConfig() RateLimitConfig {
+	return c.RateLimit
+}
+
+func (c *Config) GetAdminConfig() AdminConfig {
+	return c.Admin
+}
+
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
@@ -112,3 +178,18 @@ func getEnvAsDuration(key string, defaultValue time.Duration) time.Duration {
 	}
 	return defaultValue
 }
+
+func getEnvAsSlice(key string, defaultValue []string) []string {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
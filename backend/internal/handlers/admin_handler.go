@@ -0,0 +1,329 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/wozhdeleniye/redclass-app/internal/models"
+	"github.com/wozhdeleniye/redclass-app/internal/services"
+)
+
+type AdminHandler struct {
+	adminService *services.AdminService
+	validate     *validator.Validate
+}
+
+func NewAdminHandler(adminService *services.AdminService) *AdminHandler {
+	return &AdminHandler{
+		adminService: adminService,
+		validate:     validator.New(),
+	}
+}
+
+// ListUsers ищет пользователей (GET /api/admin/users?q=&active=&super_admin=&limit=20&offset=0)
+func (h *AdminHandler) ListUsers(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	filter := models.UserSearchFilter{
+		Query:  q.Get("q"),
+		Limit:  20,
+		Offset: 0,
+	}
+
+	if l := q.Get("limit"); l != "" {
+		if parsedLimit, err := strconv.Atoi(l); err == nil {
+			filter.Limit = parsedLimit
+		}
+	}
+	if o := q.Get("offset"); o != "" {
+		if parsedOffset, err := strconv.Atoi(o); err == nil {
+			filter.Offset = parsedOffset
+		}
+	}
+	if v := q.Get("active"); v != "" {
+		if active, err := strconv.ParseBool(v); err == nil {
+			filter.IsActive = &active
+		}
+	}
+	if v := q.Get("super_admin"); v != "" {
+		if superAdmin, err := strconv.ParseBool(v); err == nil {
+			filter.IsSuperAdmin = &superAdmin
+		}
+	}
+
+	users, total, err := h.adminService.SearchUsers(r.Context(), filter)
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	response := map[string]interface{}{
+		"data":   users,
+		"total":  total,
+		"limit":  filter.Limit,
+		"offset": filter.Offset,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetUser возвращает пользователя с ролями (GET /api/admin/users/{userId})
+func (h *AdminHandler) GetUser(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(mux.Vars(r)["userId"])
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.adminService.GetUser(r.Context(), userID)
+	if err != nil {
+		writeError(w, err, http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(user)
+}
+
+// DeactivateUser блокирует вход и обновление токенов (POST /api/admin/users/{userId}/deactivate)
+func (h *AdminHandler) DeactivateUser(w http.ResponseWriter, r *http.Request) {
+	h.setUserActive(w, r, false)
+}
+
+// ActivateUser снова разрешает вход (POST /api/admin/users/{userId}/activate)
+func (h *AdminHandler) ActivateUser(w http.ResponseWriter, r *http.Request) {
+	h.setUserActive(w, r, true)
+}
+
+func (h *AdminHandler) setUserActive(w http.ResponseWriter, r *http.Request, active bool) {
+	adminID, ok := r.Context().Value("user_id").(uuid.UUID)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	userID, err := uuid.Parse(mux.Vars(r)["userId"])
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.adminService.SetUserActive(r.Context(), adminID, userID, active, clientInfo(r)); err != nil {
+		writeError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ForceLogout отзывает все сессии пользователя (POST /api/admin/users/{userId}/logout)
+func (h *AdminHandler) ForceLogout(w http.ResponseWriter, r *http.Request) {
+	adminID, ok := r.Context().Value("user_id").(uuid.UUID)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	userID, err := uuid.Parse(mux.Vars(r)["userId"])
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.adminService.ForceLogout(r.Context(), adminID, userID, clientInfo(r)); err != nil {
+		writeError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// UnlockLogin снимает блокировку входа (POST /api/admin/users/{userId}/unlock)
+func (h *AdminHandler) UnlockLogin(w http.ResponseWriter, r *http.Request) {
+	adminID, ok := r.Context().Value("user_id").(uuid.UUID)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	userID, err := uuid.Parse(mux.Vars(r)["userId"])
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.adminService.UnlockLogin(r.Context(), adminID, userID, clientInfo(r)); err != nil {
+		writeError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// SetSuperAdmin выдает или отзывает права супер-администратора (POST /api/admin/users/{userId}/super-admin)
+func (h *AdminHandler) SetSuperAdmin(w http.ResponseWriter, r *http.Request) {
+	adminID, ok := r.Context().Value("user_id").(uuid.UUID)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	userID, err := uuid.Parse(mux.Vars(r)["userId"])
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.SetSuperAdminRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.adminService.SetSuperAdmin(r.Context(), adminID, userID, req.IsSuperAdmin, clientInfo(r)); err != nil {
+		writeError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Impersonate выдает токен для входа под пользователем (POST /api/admin/users/{userId}/impersonate)
+func (h *AdminHandler) Impersonate(w http.ResponseWriter, r *http.Request) {
+	adminID, ok := r.Context().Value("user_id").(uuid.UUID)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	userID, err := uuid.Parse(mux.Vars(r)["userId"])
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.ImpersonateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := h.validate.Struct(req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	response, err := h.adminService.Impersonate(r.Context(), adminID, userID, req.Reason, clientInfo(r))
+	if err != nil {
+		writeError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// ReassignSubjectAdmin назначает администратора предмета (POST /api/admin/subjects/{id}/admin)
+func (h *AdminHandler) ReassignSubjectAdmin(w http.ResponseWriter, r *http.Request) {
+	adminID, ok := r.Context().Value("user_id").(uuid.UUID)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	subjectID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid subject ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.ReassignSubjectAdminRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := h.validate.Struct(req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	role, err := h.adminService.ReassignSubjectAdmin(r.Context(), adminID, subjectID, &req, clientInfo(r))
+	if err != nil {
+		writeError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(role)
+}
+
+// GetOrphanedSubjects возвращает предметы без активного администратора (GET /api/admin/subjects/orphaned)
+func (h *AdminHandler) GetOrphanedSubjects(w http.ResponseWriter, r *http.Request) {
+	subjects, err := h.adminService.GetOrphanedSubjects(r.Context())
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(subjects)
+}
+
+// GetStatistics возвращает статистику платформы (GET /api/admin/statistics)
+func (h *AdminHandler) GetStatistics(w http.ResponseWriter, r *http.Request) {
+	stats, err := h.adminService.GetStatistics(r.Context())
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stats)
+}
+
+// GetAuditLogs возвращает журнал действий администраторов (GET /api/admin/audit?target_id=&limit=50&offset=0)
+func (h *AdminHandler) GetAuditLogs(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	limit := 50
+	offset := 0
+
+	if l := q.Get("limit"); l != "" {
+		if parsedLimit, err := strconv.Atoi(l); err == nil {
+			limit = parsedLimit
+		}
+	}
+	if o := q.Get("offset"); o != "" {
+		if parsedOffset, err := strconv.Atoi(o); err == nil {
+			offset = parsedOffset
+		}
+	}
+
+	var targetID *uuid.UUID
+	if t := q.Get("target_id"); t != "" {
+		parsed, err := uuid.Parse(t)
+		if err != nil {
+			http.Error(w, "Invalid target ID", http.StatusBadRequest)
+			return
+		}
+		targetID = &parsed
+	}
+
+	logs, total, err := h.adminService.GetAuditLogs(r.Context(), targetID, limit, offset)
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	response := map[string]interface{}{
+		"data":   logs,
+		"total":  total,
+		"limit":  limit,
+		"offset": offset,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
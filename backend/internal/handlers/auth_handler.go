@@ -2,9 +2,11 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strings"
 
+	"github.com/wozhdeleniye/redclass-app/internal/middleware"
 	"github.com/wozhdeleniye/redclass-app/internal/models"
 	service "github.com/wozhdeleniye/redclass-app/internal/services"
 
@@ -36,9 +38,12 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	response, err := h.authService.Register(r.Context(), &req)
+	response, err := h.authService.Register(r.Context(), &req, clientInfo(r))
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		if writeRetryAfter(w, err) {
+			return
+		}
+		writeError(w, err, http.StatusBadRequest)
 		return
 	}
 
@@ -59,9 +64,16 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	response, err := h.authService.Login(r.Context(), &req)
+	response, err := h.authService.Login(r.Context(), &req, clientInfo(r))
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusUnauthorized)
+		if writeRetryAfter(w, err) {
+			return
+		}
+		if errors.Is(err, service.ErrAccountDeactivated) {
+			writeError(w, err, http.StatusForbidden)
+			return
+		}
+		writeError(w, err, http.StatusUnauthorized)
 		return
 	}
 
@@ -81,7 +93,11 @@ func (h *AuthHandler) Refresh(w http.ResponseWriter, r *http.Request) {
 
 	tokens, err := h.authService.RefreshTokens(r.Context(), req.RefreshToken)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusUnauthorized)
+		if errors.Is(err, service.ErrAccountDeactivated) {
+			writeError(w, err, http.StatusForbidden)
+			return
+		}
+		writeError(w, err, http.StatusUnauthorized)
 		return
 	}
 
@@ -114,10 +130,28 @@ func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := h.authService.Logout(r.Context(), accessToken, req.RefreshToken, userID); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, err, http.StatusInternalServerError)
 		return
 	}
 
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]string{"message": "Successfully logged out"})
 }
+
+func clientInfo(r *http.Request) models.ClientInfo {
+	return models.ClientInfo{
+		IP:        middleware.ClientIP(r),
+		UserAgent: r.UserAgent(),
+	}
+}
+
+// writeRetryAfter отвечает 429 с заголовком Retry-After, если err вызван ограничением частоты
+func writeRetryAfter(w http.ResponseWriter, err error) bool {
+	var retryErr *service.RetryAfterError
+	if !errors.As(err, &retryErr) {
+		return false
+	}
+	w.Header().Set("Retry-After", service.RetryAfterSeconds(retryErr.RetryAfter))
+	http.Error(w, retryErr.Error(), http.StatusTooManyRequests)
+	return true
+}
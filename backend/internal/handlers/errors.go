@@ -0,0 +1,23 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/wozhdeleniye/redclass-app/internal/policy"
+	"github.com/wozhdeleniye/redclass-app/internal/services"
+)
+
+// writeError отвечает 403 на любой отказ политики доступа, 404 на скрытый предмет и 409 на изменение
+// архивного предмета, остальные ошибки отдает со статусом status
+func writeError(w http.ResponseWriter, err error, status int) {
+	switch {
+	case errors.Is(err, policy.ErrForbidden):
+		status = http.StatusForbidden
+	case errors.Is(err, services.ErrSubjectNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, services.ErrSubjectArchived):
+		status = http.StatusConflict
+	}
+	http.Error(w, err.Error(), status)
+}
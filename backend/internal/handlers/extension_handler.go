@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/wozhdeleniye/redclass-app/internal/models"
+	"github.com/wozhdeleniye/redclass-app/internal/services"
+)
+
+type ExtensionHandler struct {
+	extensionService *services.ExtensionService
+	validate         *validator.Validate
+}
+
+func NewExtensionHandler(extensionService *services.ExtensionService) *ExtensionHandler {
+	return &ExtensionHandler{
+		extensionService: extensionService,
+		validate:         validator.New(),
+	}
+}
+
+// GrantExtension продлевает срок команде или ее участнику (POST /api/projects/{projectId}/extensions)
+func (h *ExtensionHandler) GrantExtension(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(uuid.UUID)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	projectID, err := uuid.Parse(mux.Vars(r)["projectId"])
+	if err != nil {
+		http.Error(w, "Invalid project ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.GrantExtensionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := h.validate.Struct(req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ext, err := h.extensionService.GrantExtension(r.Context(), userID, projectID, &req)
+	if err != nil {
+		writeError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(ext)
+}
+
+// RevokeExtension отзывает продление (DELETE /api/extensions/{extensionId})
+func (h *ExtensionHandler) RevokeExtension(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(uuid.UUID)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	extensionID, err := uuid.Parse(mux.Vars(r)["extensionId"])
+	if err != nil {
+		http.Error(w, "Invalid extension ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.extensionService.RevokeExtension(r.Context(), userID, extensionID); err != nil {
+		writeError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetTaskExtensions продления по всем проектам задания (GET /api/tasks/{taskId}/extensions)
+func (h *ExtensionHandler) GetTaskExtensions(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(uuid.UUID)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	taskID, err := uuid.Parse(mux.Vars(r)["taskId"])
+	if err != nil {
+		http.Error(w, "Invalid task ID", http.StatusBadRequest)
+		return
+	}
+
+	extensions, err := h.extensionService.GetTaskExtensions(r.Context(), userID, taskID)
+	if err != nil {
+		writeError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(extensions)
+}
+
+// GetProjectDeadline действующие сроки проекта и его участников (GET /api/projects/{projectId}/deadline)
+func (h *ExtensionHandler) GetProjectDeadline(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(uuid.UUID)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	projectID, err := uuid.Parse(mux.Vars(r)["projectId"])
+	if err != nil {
+		http.Error(w, "Invalid project ID", http.StatusBadRequest)
+		return
+	}
+
+	deadline, err := h.extensionService.GetProjectDeadline(r.Context(), userID, projectID)
+	if err != nil {
+		writeError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(deadline)
+}
@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/wozhdeleniye/redclass-app/internal/models"
+	"github.com/wozhdeleniye/redclass-app/internal/services"
+)
+
+type JoinRequestHandler struct {
+	joinRequestService *services.JoinRequestService
+	validate           *validator.Validate
+}
+
+func NewJoinRequestHandler(joinRequestService *services.JoinRequestService) *JoinRequestHandler {
+	return &JoinRequestHandler{
+		joinRequestService: joinRequestService,
+		validate:           validator.New(),
+	}
+}
+
+// GetSubjectRequests возвращает заявки на вступление в предмет (GET /api/subjects/{id}/join-requests?status=pending)
+func (h *JoinRequestHandler) GetSubjectRequests(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(uuid.UUID)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	subjectID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid subject ID", http.StatusBadRequest)
+		return
+	}
+
+	status := models.JoinRequestStatus(r.URL.Query().Get("status"))
+	requests, err := h.joinRequestService.GetSubjectRequests(r.Context(), userID, subjectID, status)
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(requests)
+}
+
+// GetProjectRequests возвращает заявки на вступление в проект (GET /api/projects/{projectId}/join-requests?status=pending)
+func (h *JoinRequestHandler) GetProjectRequests(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(uuid.UUID)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	projectID, err := uuid.Parse(mux.Vars(r)["projectId"])
+	if err != nil {
+		http.Error(w, "Invalid project ID", http.StatusBadRequest)
+		return
+	}
+
+	status := models.JoinRequestStatus(r.URL.Query().Get("status"))
+	requests, err := h.joinRequestService.GetProjectRequests(r.Context(), userID, projectID, status)
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(requests)
+}
+
+// GetMyRequests возвращает заявки текущего пользователя (GET /api/join-requests/my)
+func (h *JoinRequestHandler) GetMyRequests(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(uuid.UUID)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	requests, err := h.joinRequestService.GetMyRequests(r.Context(), userID)
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(requests)
+}
+
+// ApproveRequest одобряет заявку (POST /api/join-requests/{requestId}/approve)
+func (h *JoinRequestHandler) ApproveRequest(w http.ResponseWriter, r *http.Request) {
+	h.decide(w, r, true)
+}
+
+// RejectRequest отклоняет заявку (POST /api/join-requests/{requestId}/reject)
+func (h *JoinRequestHandler) RejectRequest(w http.ResponseWriter, r *http.Request) {
+	h.decide(w, r, false)
+}
+
+func (h *JoinRequestHandler) decide(w http.ResponseWriter, r *http.Request, approve bool) {
+	userID, ok := r.Context().Value("user_id").(uuid.UUID)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	requestID, err := uuid.Parse(mux.Vars(r)["requestId"])
+	if err != nil {
+		http.Error(w, "Invalid request ID", http.StatusBadRequest)
+		return
+	}
+
+	request, err := h.joinRequestService.Decide(r.Context(), userID, requestID, approve)
+	if err != nil {
+		writeError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(request)
+}
+
+// DecideBulk одобряет или отклоняет несколько заявок (POST /api/join-requests/decide)
+func (h *JoinRequestHandler) DecideBulk(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(uuid.UUID)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req models.DecideJoinRequestsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := h.validate.Struct(req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	decisions := h.joinRequestService.DecideBulk(r.Context(), userID, &req)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(decisions)
+}
+
+// CancelRequest отменяет собственную заявку (DELETE /api/join-requests/{requestId})
+func (h *JoinRequestHandler) CancelRequest(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(uuid.UUID)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	requestID, err := uuid.Parse(mux.Vars(r)["requestId"])
+	if err != nil {
+		http.Error(w, "Invalid request ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.joinRequestService.CancelRequest(r.Context(), userID, requestID); err != nil {
+		writeError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
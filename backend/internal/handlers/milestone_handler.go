@@ -0,0 +1,182 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/wozhdeleniye/redclass-app/internal/models"
+	"github.com/wozhdeleniye/redclass-app/internal/services"
+)
+
+type MilestoneHandler struct {
+	milestoneService *services.MilestoneService
+	validate         *validator.Validate
+}
+
+func NewMilestoneHandler(milestoneService *services.MilestoneService) *MilestoneHandler {
+	return &MilestoneHandler{
+		milestoneService: milestoneService,
+		validate:         validator.New(),
+	}
+}
+
+// CreateMilestone добавляет контрольную точку в задание (POST /api/tasks/{taskId}/milestones)
+func (h *MilestoneHandler) CreateMilestone(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(uuid.UUID)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	taskID, err := uuid.Parse(mux.Vars(r)["taskId"])
+	if err != nil {
+		http.Error(w, "Invalid task ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.CreateMilestoneRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := h.validate.Struct(req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	milestone, err := h.milestoneService.CreateMilestone(r.Context(), userID, taskID, &req)
+	if err != nil {
+		writeError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(milestone)
+}
+
+// GetTaskMilestones контрольные точки задания (GET /api/tasks/{taskId}/milestones)
+func (h *MilestoneHandler) GetTaskMilestones(w http.ResponseWriter, r *http.Request) {
+	taskID, err := uuid.Parse(mux.Vars(r)["taskId"])
+	if err != nil {
+		http.Error(w, "Invalid task ID", http.StatusBadRequest)
+		return
+	}
+
+	viewerID := viewerFromContext(r)
+	milestones, err := h.milestoneService.GetTaskMilestones(r.Context(), viewerID, taskID)
+	if err != nil {
+		writeError(w, err, http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(milestones)
+}
+
+// GetTaskMilestoneReport пройденные контрольные точки по каждому проекту задания (GET /api/tasks/{taskId}/milestones/report)
+func (h *MilestoneHandler) GetTaskMilestoneReport(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(uuid.UUID)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	taskID, err := uuid.Parse(mux.Vars(r)["taskId"])
+	if err != nil {
+		http.Error(w, "Invalid task ID", http.StatusBadRequest)
+		return
+	}
+
+	report, err := h.milestoneService.GetTaskReport(r.Context(), userID, taskID)
+	if err != nil {
+		writeError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// UpdateMilestone изменяет контрольную точку (PUT /api/milestones/{milestoneId})
+func (h *MilestoneHandler) UpdateMilestone(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(uuid.UUID)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	milestoneID, err := uuid.Parse(mux.Vars(r)["milestoneId"])
+	if err != nil {
+		http.Error(w, "Invalid milestone ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.UpdateMilestoneRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := h.validate.Struct(req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	milestone, err := h.milestoneService.UpdateMilestone(r.Context(), userID, milestoneID, &req)
+	if err != nil {
+		writeError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(milestone)
+}
+
+// DeleteMilestone удаляет контрольную точку (DELETE /api/milestones/{milestoneId})
+func (h *MilestoneHandler) DeleteMilestone(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(uuid.UUID)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	milestoneID, err := uuid.Parse(mux.Vars(r)["milestoneId"])
+	if err != nil {
+		http.Error(w, "Invalid milestone ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.milestoneService.DeleteMilestone(r.Context(), userID, milestoneID); err != nil {
+		writeError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetProjectMilestones контрольные точки в расписании проекта и их выполнение (GET /api/projects/{projectId}/milestones)
+func (h *MilestoneHandler) GetProjectMilestones(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(uuid.UUID)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	projectID, err := uuid.Parse(mux.Vars(r)["projectId"])
+	if err != nil {
+		http.Error(w, "Invalid project ID", http.StatusBadRequest)
+		return
+	}
+
+	progress, err := h.milestoneService.GetProjectMilestones(r.Context(), userID, projectID)
+	if err != nil {
+		writeError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(progress)
+}
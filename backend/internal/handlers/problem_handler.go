@@ -49,7 +49,7 @@ func (h *ProblemHandler) CreateProblem(w http.ResponseWriter, r *http.Request) {
 
 	problem, err := h.problemService.CreateProblem(r.Context(), userID, projectID, nil, &req)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeError(w, err, http.StatusBadRequest)
 		return
 	}
 
@@ -87,13 +87,13 @@ func (h *ProblemHandler) CreateSubproblem(w http.ResponseWriter, r *http.Request
 	// Получаем родительскую проблему чтобы узнать projectID
 	parentProblem, err := h.problemService.GetProblemByIDDirect(r.Context(), parentID)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeError(w, err, http.StatusBadRequest)
 		return
 	}
 
 	problem, err := h.problemService.CreateProblem(r.Context(), userID, parentProblem.ProjectID, &parentID, &req)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeError(w, err, http.StatusBadRequest)
 		return
 	}
 
@@ -126,7 +126,7 @@ func (h *ProblemHandler) UpdateProblem(w http.ResponseWriter, r *http.Request) {
 
 	problem, err := h.problemService.UpdateProblem(r.Context(), userID, problemID, &req)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeError(w, err, http.StatusBadRequest)
 		return
 	}
 
@@ -151,7 +151,7 @@ func (h *ProblemHandler) DeleteProblem(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := h.problemService.DeleteProblem(r.Context(), userID, problemID); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeError(w, err, http.StatusBadRequest)
 		return
 	}
 
@@ -176,7 +176,7 @@ func (h *ProblemHandler) GetProblem(w http.ResponseWriter, r *http.Request) {
 
 	problem, err := h.problemService.GetProblem(r.Context(), userID, problemID)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeError(w, err, http.StatusBadRequest)
 		return
 	}
 	var result *models.Result
@@ -231,7 +231,7 @@ func (h *ProblemHandler) GetProjectProblems(w http.ResponseWriter, r *http.Reque
 
 	problems, err := h.problemService.GetProjectProblems(r.Context(), userID, projectID, assignedOnly)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeError(w, err, http.StatusBadRequest)
 		return
 	}
 
@@ -257,7 +257,7 @@ func (h *ProblemHandler) GetMainProblem(w http.ResponseWriter, r *http.Request)
 
 	problem, err := h.problemService.GetMainProblem(r.Context(), userID, projectID)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeError(w, err, http.StatusBadRequest)
 		return
 	}
 
@@ -283,7 +283,7 @@ func (h *ProblemHandler) GetSubproblems(w http.ResponseWriter, r *http.Request)
 
 	problems, err := h.problemService.GetSubproblems(r.Context(), userID, parentID)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeError(w, err, http.StatusBadRequest)
 		return
 	}
 
@@ -309,7 +309,7 @@ func (h *ProblemHandler) GetProjectStatistics(w http.ResponseWriter, r *http.Req
 
 	stats, err := h.problemService.GetProjectStatistics(r.Context(), userID, projectID)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeError(w, err, http.StatusBadRequest)
 		return
 	}
 
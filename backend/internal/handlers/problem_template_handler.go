@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/wozhdeleniye/redclass-app/internal/models"
+	"github.com/wozhdeleniye/redclass-app/internal/services"
+)
+
+type ProblemTemplateHandler struct {
+	templateService *services.ProblemTemplateService
+	validate        *validator.Validate
+}
+
+func NewProblemTemplateHandler(templateService *services.ProblemTemplateService) *ProblemTemplateHandler {
+	return &ProblemTemplateHandler{
+		templateService: templateService,
+		validate:        validator.New(),
+	}
+}
+
+// GetTemplates возвращает шаблон этапов задания деревом (GET /api/tasks/{taskId}/templates)
+func (h *ProblemTemplateHandler) GetTemplates(w http.ResponseWriter, r *http.Request) {
+	taskID, err := uuid.Parse(mux.Vars(r)["taskId"])
+	if err != nil {
+		http.Error(w, "Invalid task ID", http.StatusBadRequest)
+		return
+	}
+
+	viewerID := viewerFromContext(r)
+	templates, err := h.templateService.GetTemplates(r.Context(), viewerID, taskID)
+	if err != nil {
+		writeError(w, err, http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(templates)
+}
+
+// SetTemplates заменяет шаблон этапов задания (PUT /api/tasks/{taskId}/templates)
+func (h *ProblemTemplateHandler) SetTemplates(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(uuid.UUID)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	taskID, err := uuid.Parse(mux.Vars(r)["taskId"])
+	if err != nil {
+		http.Error(w, "Invalid task ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.SetProblemTemplatesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := h.validate.Struct(req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	templates, err := h.templateService.SetTemplates(r.Context(), userID, taskID, &req)
+	if err != nil {
+		writeError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(templates)
+}
+
+// SyncTemplates переносит шаблон в существующие проекты задания (POST /api/tasks/{taskId}/templates/sync)
+func (h *ProblemTemplateHandler) SyncTemplates(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(uuid.UUID)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	taskID, err := uuid.Parse(mux.Vars(r)["taskId"])
+	if err != nil {
+		http.Error(w, "Invalid task ID", http.StatusBadRequest)
+		return
+	}
+
+	report, err := h.templateService.SyncProjects(r.Context(), userID, taskID)
+	if err != nil {
+		writeError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
@@ -48,7 +48,7 @@ func (h *ResultHandler) CreateResult(w http.ResponseWriter, r *http.Request) {
 
 	res, err := h.resultService.CreateResult(r.Context(), userID, problemID, &req)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeError(w, err, http.StatusBadRequest)
 		return
 	}
 
@@ -75,7 +75,7 @@ func (h *ResultHandler) GetResult(w http.ResponseWriter, r *http.Request) {
 
 	res, err := h.resultService.GetResult(r.Context(), userID, problemID)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeError(w, err, http.StatusBadRequest)
 		return
 	}
 
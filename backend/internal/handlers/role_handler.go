@@ -54,7 +54,7 @@ func (h *RoleHandler) ChangeRole(w http.ResponseWriter, r *http.Request) {
 
 	role, err := h.roleService.ChangeRole(r.Context(), userID, roleID, req.RoleType)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusForbidden)
+		writeError(w, err, http.StatusForbidden)
 		return
 	}
 
@@ -79,7 +79,7 @@ func (h *RoleHandler) RemoveFromSubject(w http.ResponseWriter, r *http.Request)
 	}
 
 	if err := h.roleService.RemoveFromSubject(r.Context(), userID, roleID); err != nil {
-		http.Error(w, err.Error(), http.StatusForbidden)
+		writeError(w, err, http.StatusForbidden)
 		return
 	}
 
@@ -96,12 +96,143 @@ func (h *RoleHandler) GetSubjectMembers(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	roles, err := h.roleService.GetSubjectRoles(r.Context(), subjectID)
+	groupID, err := queryGroupID(r)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, "Invalid group ID", http.StatusBadRequest)
+		return
+	}
+
+	viewerID := viewerFromContext(r)
+	roles, err := h.roleService.GetSubjectRoles(r.Context(), viewerID, subjectID, groupID)
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(roles)
 }
+
+// AddMember добавляет пользователя в предмет (POST /api/subjects/{id}/roles)
+func (h *RoleHandler) AddMember(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(uuid.UUID)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	subjectID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid subject ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.CreateRoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	req.SubjectID = subjectID
+
+	if err := h.validate.Struct(req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	role, err := h.roleService.AddMember(r.Context(), userID, &req)
+	if err != nil {
+		writeError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(role)
+}
+
+// UpdateRole меняет роль и индивидуальные права участника (PUT /api/subjects/{id}/roles/{roleId})
+func (h *RoleHandler) UpdateRole(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(uuid.UUID)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	roleID, err := uuid.Parse(mux.Vars(r)["roleId"])
+	if err != nil {
+		http.Error(w, "Invalid role ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.UpdateRoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	role, err := h.roleService.UpdateRole(r.Context(), userID, roleID, &req)
+	if err != nil {
+		writeError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(role)
+}
+
+// GetMyPermissions возвращает права текущего пользователя в предмете (GET /api/subjects/{id}/permissions/my)
+func (h *RoleHandler) GetMyPermissions(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(uuid.UUID)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	subjectID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid subject ID", http.StatusBadRequest)
+		return
+	}
+
+	permissions, err := h.roleService.GetMyPermissions(r.Context(), userID, subjectID)
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(permissions)
+}
+
+// GetPermissionCatalogue возвращает список прав и наборы по умолчанию для ролей (GET /api/permissions)
+func (h *RoleHandler) GetPermissionCatalogue(w http.ResponseWriter, r *http.Request) {
+	response := map[string]interface{}{
+		"permissions": models.PermissionCatalogue,
+		"defaults":    models.DefaultPermissions,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// LeaveSubject выход текущего пользователя из предмета (POST /api/subjects/{id}/leave)
+func (h *RoleHandler) LeaveSubject(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(uuid.UUID)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	subjectID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid subject ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.roleService.LeaveSubject(r.Context(), userID, subjectID); err != nil {
+		writeError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
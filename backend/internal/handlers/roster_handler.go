@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/wozhdeleniye/redclass-app/internal/models"
+	"github.com/wozhdeleniye/redclass-app/internal/roster"
+	"github.com/wozhdeleniye/redclass-app/internal/services"
+)
+
+// maxRosterFileSize ограничение размера загружаемого списка
+const maxRosterFileSize = 5 << 20
+
+type RosterHandler struct {
+	rosterService *services.RosterService
+}
+
+func NewRosterHandler(rosterService *services.RosterService) *RosterHandler {
+	return &RosterHandler{rosterService: rosterService}
+}
+
+// ImportRoster зачисляет участников из CSV/XLSX (POST /api/subjects/{id}/roster/import?dry_run=true&default_role=student).
+// Файл передается в поле формы "file" или телом запроса.
+func (h *RosterHandler) ImportRoster(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(uuid.UUID)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	subjectID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid subject ID", http.StatusBadRequest)
+		return
+	}
+
+	q := r.URL.Query()
+	dryRun := false
+	if d := q.Get("dry_run"); d != "" {
+		if parsedDryRun, err := strconv.ParseBool(d); err == nil {
+			dryRun = parsedDryRun
+		}
+	}
+
+	data, filename, err := readRosterFile(w, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// строка заголовка не входит в лимит сервиса
+	table, err := roster.Read(data, roster.DetectFormat(filename, data), services.MaxRosterRows+1)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	report, err := h.rosterService.ImportRoster(r.Context(), userID, subjectID, table, models.RoleType(q.Get("default_role")), dryRun)
+	if err != nil {
+		writeError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// ExportRoster выгружает участников предмета (GET /api/subjects/{id}/roster/export?format=csv|xlsx|json)
+func (h *RosterHandler) ExportRoster(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(uuid.UUID)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	subjectID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid subject ID", http.StatusBadRequest)
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = string(roster.FormatCSV)
+	}
+	if format != "json" && format != string(roster.FormatCSV) && format != string(roster.FormatXLSX) {
+		http.Error(w, roster.ErrUnsupportedFormat.Error(), http.StatusBadRequest)
+		return
+	}
+
+	entries, err := h.rosterService.ExportRoster(r.Context(), userID, subjectID)
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	if format == "json" {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(entries)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := roster.Write(&buf, roster.Format(format), services.RosterTable(entries)); err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", roster.ContentType(roster.Format(format)))
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"roster-%s.%s\"", subjectID, format))
+	w.Write(buf.Bytes())
+}
+
+func readRosterFile(w http.ResponseWriter, r *http.Request) ([]byte, string, error) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRosterFileSize)
+
+	if err := r.ParseMultipartForm(maxRosterFileSize); err == nil {
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			return nil, "", errors.New("file field is required")
+		}
+		defer file.Close()
+		data, err := io.ReadAll(file)
+		return data, header.Filename, err
+	} else if err != http.ErrNotMultipart {
+		return nil, "", fmt.Errorf("invalid multipart form: %w", err)
+	}
+
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read roster: %w", err)
+	}
+	if len(data) == 0 {
+		return nil, "", errors.New("roster file is empty")
+	}
+	return data, "", nil
+}
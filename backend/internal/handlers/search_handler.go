@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/wozhdeleniye/redclass-app/internal/models"
+	"github.com/wozhdeleniye/redclass-app/internal/services"
+)
+
+type SearchHandler struct {
+	searchService *services.SearchService
+}
+
+func NewSearchHandler(searchService *services.SearchService) *SearchHandler {
+	return &SearchHandler{searchService: searchService}
+}
+
+// Search полнотекстовый поиск (GET /api/search?q=&types=subject,task,project,problem&subject_id=&limit=20&offset=0)
+func (h *SearchHandler) Search(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := models.SearchFilter{Query: query.Get("q"), Limit: 20}
+
+	if types := query.Get("types"); types != "" {
+		for _, t := range strings.Split(types, ",") {
+			if t = strings.TrimSpace(t); t != "" {
+				filter.Types = append(filter.Types, models.SearchResultType(t))
+			}
+		}
+	}
+	if raw := query.Get("subject_id"); raw != "" {
+		subjectID, err := uuid.Parse(raw)
+		if err != nil {
+			http.Error(w, "Invalid subject ID", http.StatusBadRequest)
+			return
+		}
+		filter.SubjectID = &subjectID
+	}
+	if l := query.Get("limit"); l != "" {
+		if parsedLimit, err := strconv.Atoi(l); err == nil {
+			filter.Limit = parsedLimit
+		}
+	}
+	if o := query.Get("offset"); o != "" {
+		if parsedOffset, err := strconv.Atoi(o); err == nil {
+			filter.Offset = parsedOffset
+		}
+	}
+
+	results, err := h.searchService.Search(r.Context(), viewerFromContext(r), filter)
+	if err != nil {
+		writeError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	response := map[string]interface{}{
+		"query":  filter.Query,
+		"data":   results,
+		"limit":  filter.Limit,
+		"offset": filter.Offset,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
@@ -24,6 +24,12 @@ func NewSubjectHandler(subjectService *services.SubjectService) *SubjectHandler
 	}
 }
 
+// viewerFromContext пользователь на маршруте с необязательной авторизацией; uuid.Nil для анонима
+func viewerFromContext(r *http.Request) uuid.UUID {
+	viewerID, _ := r.Context().Value("user_id").(uuid.UUID)
+	return viewerID
+}
+
 func (h *SubjectHandler) CreateSubject(w http.ResponseWriter, r *http.Request) {
 	userID, ok := r.Context().Value("user_id").(uuid.UUID)
 	if !ok {
@@ -44,7 +50,7 @@ func (h *SubjectHandler) CreateSubject(w http.ResponseWriter, r *http.Request) {
 
 	subject, err := h.subjectService.CreateSubject(r.Context(), userID, &req)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeError(w, err, http.StatusBadRequest)
 		return
 	}
 
@@ -63,9 +69,10 @@ func (h *SubjectHandler) GetSubject(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	subject, err := h.subjectService.GetSubjectByID(r.Context(), id)
+	viewerID := viewerFromContext(r)
+	subject, err := h.subjectService.GetSubjectByID(r.Context(), viewerID, id)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusNotFound)
+		writeError(w, err, http.StatusNotFound)
 		return
 	}
 
@@ -89,9 +96,12 @@ func (h *SubjectHandler) GetAllSubjects(w http.ResponseWriter, r *http.Request)
 		}
 	}
 
-	subjects, total, err := h.subjectService.GetAllSubjects(r.Context(), limit, offset)
+	includeArchived := r.URL.Query().Get("archived") == "true"
+
+	viewerID := viewerFromContext(r)
+	subjects, total, err := h.subjectService.GetAllSubjects(r.Context(), viewerID, limit, offset, includeArchived)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, err, http.StatusInternalServerError)
 		return
 	}
 
@@ -107,6 +117,12 @@ func (h *SubjectHandler) GetAllSubjects(w http.ResponseWriter, r *http.Request)
 }
 
 func (h *SubjectHandler) UpdateSubject(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(uuid.UUID)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
 	vars := mux.Vars(r)
 	idStr := vars["id"]
 
@@ -122,9 +138,14 @@ func (h *SubjectHandler) UpdateSubject(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	subject, err := h.subjectService.UpdateSubject(r.Context(), id, &req)
+	if err := h.validate.Struct(req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	subject, err := h.subjectService.UpdateSubject(r.Context(), userID, id, &req)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, err, http.StatusInternalServerError)
 		return
 	}
 
@@ -133,6 +154,12 @@ func (h *SubjectHandler) UpdateSubject(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *SubjectHandler) DeleteSubject(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(uuid.UUID)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
 	vars := mux.Vars(r)
 	idStr := vars["id"]
 
@@ -142,14 +169,83 @@ func (h *SubjectHandler) DeleteSubject(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.subjectService.DeleteSubject(r.Context(), id); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if err := h.subjectService.DeleteSubject(r.Context(), userID, id); err != nil {
+		writeError(w, err, http.StatusInternalServerError)
 		return
 	}
 
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// ArchiveSubject переводит предмет в архив (POST /api/subjects/{id}/archive)
+func (h *SubjectHandler) ArchiveSubject(w http.ResponseWriter, r *http.Request) {
+	h.setArchived(w, r, true)
+}
+
+// UnarchiveSubject возвращает предмет из архива (POST /api/subjects/{id}/unarchive)
+func (h *SubjectHandler) UnarchiveSubject(w http.ResponseWriter, r *http.Request) {
+	h.setArchived(w, r, false)
+}
+
+func (h *SubjectHandler) setArchived(w http.ResponseWriter, r *http.Request, archived bool) {
+	userID, ok := r.Context().Value("user_id").(uuid.UUID)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid subject ID", http.StatusBadRequest)
+		return
+	}
+
+	subject, err := h.subjectService.ArchiveSubject(r.Context(), userID, id, archived)
+	if err != nil {
+		writeError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(subject)
+}
+
+// CloneSubject создает копию предмета для нового семестра (POST /api/subjects/{id}/clone)
+func (h *SubjectHandler) CloneSubject(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(uuid.UUID)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid subject ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.CloneSubjectRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	subject, err := h.subjectService.CloneSubject(r.Context(), userID, id, &req)
+	if err != nil {
+		writeError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(subject)
+}
+
 func (h *SubjectHandler) JoinSubject(w http.ResponseWriter, r *http.Request) {
 	userID, ok := r.Context().Value("user_id").(uuid.UUID)
 	if !ok {
@@ -170,13 +266,18 @@ func (h *SubjectHandler) JoinSubject(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	role, err := h.subjectService.JoinSubject(r.Context(), userID, req.Code)
+	role, request, err := h.subjectService.JoinSubject(r.Context(), userID, req.Code)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeError(w, err, http.StatusBadRequest)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
+	if request != nil {
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(request)
+		return
+	}
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(role)
 }
@@ -190,10 +291,131 @@ func (h *SubjectHandler) GetMySubjects(w http.ResponseWriter, r *http.Request) {
 
 	subjects, err := h.subjectService.GetUserSubjects(r.Context(), userID)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, err, http.StatusInternalServerError)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(subjects)
 }
+
+// TransferOwnership предлагает участнику стать владельцем предмета (POST /api/subjects/{id}/transfer-ownership)
+func (h *SubjectHandler) TransferOwnership(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(uuid.UUID)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	subjectID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid subject ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.TransferOwnershipRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := h.validate.Struct(req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	transfer, err := h.subjectService.TransferOwnership(r.Context(), userID, subjectID, &req)
+	if err != nil {
+		writeError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(transfer)
+}
+
+// GetMyOwnershipTransfers возвращает предложения владения текущему пользователю (GET /api/ownership-transfers/my)
+func (h *SubjectHandler) GetMyOwnershipTransfers(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(uuid.UUID)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	transfers, err := h.subjectService.GetMyOwnershipTransfers(r.Context(), userID)
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(transfers)
+}
+
+// AcceptOwnershipTransfer принимает владение предметом (POST /api/ownership-transfers/{transferId}/accept)
+func (h *SubjectHandler) AcceptOwnershipTransfer(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(uuid.UUID)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	transferID, err := uuid.Parse(mux.Vars(r)["transferId"])
+	if err != nil {
+		http.Error(w, "Invalid transfer ID", http.StatusBadRequest)
+		return
+	}
+
+	subject, err := h.subjectService.AcceptOwnershipTransfer(r.Context(), userID, transferID)
+	if err != nil {
+		writeError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(subject)
+}
+
+// DeclineOwnershipTransfer отказывается от владения (POST /api/ownership-transfers/{transferId}/decline)
+func (h *SubjectHandler) DeclineOwnershipTransfer(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(uuid.UUID)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	transferID, err := uuid.Parse(mux.Vars(r)["transferId"])
+	if err != nil {
+		http.Error(w, "Invalid transfer ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.subjectService.DeclineOwnershipTransfer(r.Context(), userID, transferID); err != nil {
+		writeError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// CancelOwnershipTransfer отзывает предложение владения (DELETE /api/ownership-transfers/{transferId})
+func (h *SubjectHandler) CancelOwnershipTransfer(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(uuid.UUID)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	transferID, err := uuid.Parse(mux.Vars(r)["transferId"])
+	if err != nil {
+		http.Error(w, "Invalid transfer ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.subjectService.CancelOwnershipTransfer(r.Context(), userID, transferID); err != nil {
+		writeError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
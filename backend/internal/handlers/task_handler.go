@@ -2,6 +2,8 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"io"
 	"net/http"
 	"strconv"
 
@@ -56,7 +58,7 @@ func (h *TaskHandler) CreateTask(w http.ResponseWriter, r *http.Request) {
 
 	task, err := h.taskService.CreateTask(r.Context(), userID, &req)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusForbidden)
+		writeError(w, err, http.StatusForbidden)
 		return
 	}
 
@@ -76,9 +78,10 @@ func (h *TaskHandler) GetTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	task, err := h.taskService.GetTaskByID(r.Context(), taskID)
+	viewerID := viewerFromContext(r)
+	task, err := h.taskService.GetTaskByID(r.Context(), viewerID, taskID)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusNotFound)
+		writeError(w, err, http.StatusNotFound)
 		return
 	}
 
@@ -86,7 +89,7 @@ func (h *TaskHandler) GetTask(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(task)
 }
 
-// GetSubjectTasks получает все задачи предмета (GET /api/subjects/{id}/tasks?limit=20&offset=0)
+// GetSubjectTasks получает все задачи предмета (GET /api/subjects/{id}/tasks?limit=20&offset=0&group_id=)
 func (h *TaskHandler) GetSubjectTasks(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	subjectIDStr := vars["id"]
@@ -112,9 +115,16 @@ func (h *TaskHandler) GetSubjectTasks(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	tasks, total, err := h.taskService.GetTasksBySubject(r.Context(), subjectID, limit, offset)
+	groupID, err := queryGroupID(r)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, "Invalid group ID", http.StatusBadRequest)
+		return
+	}
+
+	viewerID := viewerFromContext(r)
+	tasks, total, err := h.taskService.GetTasksBySubject(r.Context(), viewerID, subjectID, groupID, limit, offset)
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
 		return
 	}
 
@@ -151,10 +161,93 @@ func (h *TaskHandler) UpdateTask(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
+	if err := h.validate.Struct(req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
 	task, err := h.taskService.UpdateTask(r.Context(), userID, taskID, &req)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusForbidden)
+		writeError(w, err, http.StatusForbidden)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(task)
+}
+
+// GetScheduleImpact подпроблемы проектов вне окна задания (GET /api/tasks/{taskId}/schedule-impact)
+func (h *TaskHandler) GetScheduleImpact(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(uuid.UUID)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	taskID, err := uuid.Parse(mux.Vars(r)["taskId"])
+	if err != nil {
+		http.Error(w, "Invalid task ID", http.StatusBadRequest)
+		return
+	}
+
+	impact, err := h.taskService.GetScheduleImpact(r.Context(), userID, taskID)
+	if err != nil {
+		writeError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(impact)
+}
+
+// PublishTask публикует задание; тело с publish_at откладывает публикацию (POST /api/tasks/{taskId}/publish)
+func (h *TaskHandler) PublishTask(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(uuid.UUID)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	taskID, err := uuid.Parse(mux.Vars(r)["taskId"])
+	if err != nil {
+		http.Error(w, "Invalid task ID", http.StatusBadRequest)
+		return
+	}
+
+	// тело необязательно: без него задание публикуется сразу
+	var req models.PublishTaskRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	task, err := h.taskService.PublishTask(r.Context(), userID, taskID, &req)
+	if err != nil {
+		writeError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(task)
+}
+
+// UnpublishTask возвращает задание в черновики (POST /api/tasks/{taskId}/unpublish)
+func (h *TaskHandler) UnpublishTask(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(uuid.UUID)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	taskID, err := uuid.Parse(mux.Vars(r)["taskId"])
+	if err != nil {
+		http.Error(w, "Invalid task ID", http.StatusBadRequest)
+		return
+	}
+
+	task, err := h.taskService.UnpublishTask(r.Context(), userID, taskID)
+	if err != nil {
+		writeError(w, err, http.StatusBadRequest)
 		return
 	}
 
@@ -180,7 +273,7 @@ func (h *TaskHandler) DeleteTask(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := h.taskService.DeleteTask(r.Context(), userID, taskID); err != nil {
-		http.Error(w, err.Error(), http.StatusForbidden)
+		writeError(w, err, http.StatusForbidden)
 		return
 	}
 
@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"strings"
 
+	"github.com/google/uuid"
 	"github.com/wozhdeleniye/redclass-app/internal/services"
 )
 
@@ -30,7 +31,7 @@ func (m *AuthMiddleware) Authenticate(next http.Handler) http.Handler {
 			return
 		}
 
-		claims, err := m.authService.ValidateToken(token)
+		claims, err := m.authService.Authenticate(r.Context(), token)
 		if err != nil {
 			http.Error(w, "Invalid token", http.StatusUnauthorized)
 			return
@@ -38,11 +39,42 @@ func (m *AuthMiddleware) Authenticate(next http.Handler) http.Handler {
 
 		ctx := context.WithValue(r.Context(), "user_id", claims.UserID)
 		ctx = context.WithValue(ctx, "user_email", claims.Email)
+		if claims.ImpersonatorID != nil {
+			ctx = context.WithValue(ctx, "impersonator_id", *claims.ImpersonatorID)
+		}
 
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
+// RequireSuperAdmin пропускает только супер-администраторов платформы; должен идти после Authenticate
+func (m *AuthMiddleware) RequireSuperAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value("user_id").(uuid.UUID)
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		if _, impersonated := r.Context().Value("impersonator_id").(uuid.UUID); impersonated {
+			http.Error(w, "Admin API is not available in impersonated session", http.StatusForbidden)
+			return
+		}
+
+		isAdmin, err := m.authService.IsSuperAdmin(r.Context(), userID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !isAdmin {
+			http.Error(w, "Super admin access required", http.StatusForbidden)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
 func (m *AuthMiddleware) OptionalAuth(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		authHeader := r.Header.Get("Authorization")
@@ -57,13 +89,27 @@ func (m *AuthMiddleware) OptionalAuth(next http.Handler) http.Handler {
 			return
 		}
 
-		claims, err := m.authService.ValidateToken(token)
+		claims, err := m.authService.Authenticate(r.Context(), token)
 		if err == nil {
 			ctx := context.WithValue(r.Context(), "user_id", claims.UserID)
 			ctx = context.WithValue(ctx, "user_email", claims.Email)
+			if claims.ImpersonatorID != nil {
+				ctx = context.WithValue(ctx, "impersonator_id", *claims.ImpersonatorID)
+			}
 			r = r.WithContext(ctx)
 		}
 
 		next.ServeHTTP(w, r)
 	})
 }
+
+// ForbidImpersonation запрещает действия, требующие подтверждения самим пользователем, в сессии имперсонации
+func (m *AuthMiddleware) ForbidImpersonation(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, impersonated := r.Context().Value("impersonator_id").(uuid.UUID); impersonated {
+			http.Error(w, "Not allowed in impersonated session", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,146 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	redisrepo "github.com/wozhdeleniye/redclass-app/internal/repositories/redis"
+	"github.com/wozhdeleniye/redclass-app/internal/services"
+)
+
+type RateLimitMiddleware struct {
+	limitRepo      *redisrepo.RateLimitRepository
+	trustedProxies []*net.IPNet
+}
+
+// NewRateLimitMiddleware trustedProxies адреса или подсети в нотации CIDR; некорректные записи пропускаются
+func NewRateLimitMiddleware(limitRepo *redisrepo.RateLimitRepository, trustedProxies []string) *RateLimitMiddleware {
+	m := &RateLimitMiddleware{limitRepo: limitRepo}
+	for _, entry := range trustedProxies {
+		if ip := net.ParseIP(entry); ip != nil {
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			m.trustedProxies = append(m.trustedProxies, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			log.Printf("ignoring invalid trusted proxy %q: %v", entry, err)
+			continue
+		}
+		m.trustedProxies = append(m.trustedProxies, network)
+	}
+	return m
+}
+
+// ResolveClientIP определяет IP клиента с учетом доверенных прокси и кладет его в контекст;
+// должен идти раньше всех ограничителей
+func (m *RateLimitMiddleware) ResolveClientIP(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), "client_ip", m.clientIP(r))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// Limit ограничивает маршрут name бюджетом limit запросов за window.
+// Аутентифицированные запросы считаются по пользователю, остальные по IP.
+func (m *RateLimitMiddleware) Limit(name string, limit int, window time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if limit <= 0 || r.Method == http.MethodOptions {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			key := fmt.Sprintf("%s:ip:%s", name, ClientIP(r))
+			if userID, ok := r.Context().Value("user_id").(uuid.UUID); ok {
+				key = fmt.Sprintf("%s:user:%s", name, userID)
+			}
+
+			count, ttl, err := m.limitRepo.Increment(r.Context(), key, window)
+			if err != nil {
+				// при недоступности Redis не блокируем запросы
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			remaining := int64(limit) - count
+			if remaining < 0 {
+				remaining = 0
+			}
+			w.Header().Set("X-RateLimit-Limit", strconv.Itoa(limit))
+			w.Header().Set("X-RateLimit-Remaining", strconv.FormatInt(remaining, 10))
+
+			if count > int64(limit) {
+				w.Header().Set("Retry-After", services.RetryAfterSeconds(ttl))
+				http.Error(w, "Too many requests", http.StatusTooManyRequests)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// ClientIP возвращает IP клиента, определенный ResolveClientIP, а без него адрес соединения
+func ClientIP(r *http.Request) string {
+	if ip, ok := r.Context().Value("client_ip").(string); ok {
+		return ip
+	}
+	return remoteHost(r)
+}
+
+// clientIP заголовкам прокси верим, только если соединение пришло от доверенного прокси.
+// X-Forwarded-For разбирается справа налево до первого адреса не из доверенных.
+func (m *RateLimitMiddleware) clientIP(r *http.Request) string {
+	host := remoteHost(r)
+	if !m.isTrusted(net.ParseIP(host)) {
+		return host
+	}
+
+	if forwarded := r.Header.Values("X-Forwarded-For"); len(forwarded) > 0 {
+		hops := strings.Split(strings.Join(forwarded, ","), ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			ip := net.ParseIP(strings.TrimSpace(hops[i]))
+			if ip == nil {
+				break
+			}
+			if !m.isTrusted(ip) {
+				return ip.String()
+			}
+		}
+	}
+	if ip := net.ParseIP(strings.TrimSpace(r.Header.Get("X-Real-IP"))); ip != nil {
+		return ip.String()
+	}
+	return host
+}
+
+func (m *RateLimitMiddleware) isTrusted(ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+	for _, network := range m.trustedProxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func remoteHost(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -1,7 +1,10 @@
 package migrations
 
 import (
+	"fmt"
+
 	"github.com/wozhdeleniye/redclass-app/internal/models"
+	"github.com/wozhdeleniye/redclass-app/internal/repositories/postgres"
 	"gorm.io/gorm"
 )
 
@@ -27,6 +30,10 @@ func (m *GormMigrator) Migrate() error {
 		}
 	}
 
+	if err := m.backfillSubjectOwners(); err != nil {
+		return err
+	}
+
 	err = m.db.AutoMigrate(
 		&models.User{},
 		&models.Subject{},
@@ -37,6 +44,70 @@ func (m *GormMigrator) Migrate() error {
 		&models.Problem{},
 		&models.ProblemAssignee{},
 		&models.Result{},
+		&models.LoginAttempt{},
+		&models.AdminAuditLog{},
+		&models.Invite{},
+		&models.JoinRequest{},
+		&models.Notification{},
+		&models.OwnershipTransfer{},
+		&models.Group{},
+		&models.GroupMember{},
+		&models.TaskGroup{},
+		&models.ProblemTemplate{},
+		&models.Milestone{},
+		&models.DeadlineExtension{},
+		&models.ProjectSubmission{},
+		&models.RubricCriterion{},
+		&models.ProjectGrade{},
+		&models.MemberGrade{},
+		&models.PeerReviewRound{},
+		&models.PeerReview{},
 	)
-	return err
+	if err != nil {
+		return err
+	}
+
+	// индексы по выражениям AutoMigrate не создает
+	for _, statement := range postgres.SearchIndexStatements() {
+		if err := m.db.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// backfillSubjectOwners заполняет owner_id у предметов, созданных до появления владельца:
+// владельцем становится самый ранний администратор, а без администраторов самый ранний участник.
+// Выполняется до AutoMigrate, которая затем делает колонку NOT NULL.
+func (m *GormMigrator) backfillSubjectOwners() error {
+	if !m.db.Migrator().HasTable(&models.Subject{}) {
+		return nil
+	}
+	if !m.db.Migrator().HasColumn(&models.Subject{}, "OwnerID") {
+		if err := m.db.Exec("ALTER TABLE subjects ADD COLUMN owner_id uuid").Error; err != nil {
+			return err
+		}
+	}
+
+	const missing = "(s.owner_id IS NULL OR s.owner_id = '00000000-0000-0000-0000-000000000000')"
+	err := m.db.Exec(`
+		UPDATE subjects s SET owner_id = (
+			SELECT r.user_id FROM roles r
+			WHERE r.subject_id = s.id AND r.deleted_at IS NULL
+			ORDER BY (r.role_type = ?) DESC, r.created_at
+			LIMIT 1
+		)
+		WHERE `+missing, models.RoleAdmin).Error
+	if err != nil {
+		return err
+	}
+
+	var orphans int64
+	if err := m.db.Table("subjects s").Where(missing).Count(&orphans).Error; err != nil {
+		return err
+	}
+	if orphans > 0 {
+		return fmt.Errorf("%d subjects have no members to become their owner", orphans)
+	}
+	return nil
 }
@@ -0,0 +1,75 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type AdminAction string
+
+const (
+	AdminActionDeactivateUser       AdminAction = "deactivate_user"
+	AdminActionActivateUser         AdminAction = "activate_user"
+	AdminActionForceLogout          AdminAction = "force_logout"
+	AdminActionImpersonate          AdminAction = "impersonate"
+	AdminActionSetSuperAdmin        AdminAction = "set_super_admin"
+	AdminActionReassignSubjectAdmin AdminAction = "reassign_subject_admin"
+	AdminActionUnlockLogin          AdminAction = "unlock_login"
+)
+
+// AdminAuditLog журнал действий супер-администраторов платформы
+type AdminAuditLog struct {
+	ID         uuid.UUID   `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	ActorID    uuid.UUID   `json:"actor_id" gorm:"type:uuid;not null;index"`
+	Action     AdminAction `json:"action" gorm:"type:varchar(40);not null;index"`
+	TargetType string      `json:"target_type" gorm:"type:varchar(20);not null"`
+	TargetID   uuid.UUID   `json:"target_id" gorm:"type:uuid;not null;index"`
+	Details    string      `json:"details"`
+	IP         string      `json:"ip" gorm:"type:varchar(64)"`
+	CreatedAt  time.Time   `json:"created_at" gorm:"index"`
+
+	Actor *User `json:"actor,omitempty" gorm:"foreignKey:ActorID;constraint:OnDelete:RESTRICT"`
+}
+
+type PlatformStatistics struct {
+	Users             int64 `json:"users"`
+	ActiveUsers       int64 `json:"active_users"`
+	SuperAdmins       int64 `json:"super_admins"`
+	NewUsersLast7Days int64 `json:"new_users_last_7_days"`
+	Subjects          int64 `json:"subjects"`
+	OrphanedSubjects  int64 `json:"orphaned_subjects"`
+	Tasks             int64 `json:"tasks"`
+	Projects          int64 `json:"projects"`
+	Problems          int64 `json:"problems"`
+	SolvedProblems    int64 `json:"solved_problems"`
+	Results           int64 `json:"results"`
+	FailedLoginsToday int64 `json:"failed_logins_today"`
+}
+
+type UserSearchFilter struct {
+	Query        string
+	IsActive     *bool
+	IsSuperAdmin *bool
+	Limit        int
+	Offset       int
+}
+
+type SetSuperAdminRequest struct {
+	IsSuperAdmin bool `json:"is_super_admin"`
+}
+
+type ImpersonateRequest struct {
+	Reason string `json:"reason" validate:"required"`
+}
+
+type ImpersonationResponse struct {
+	User        *User     `json:"user"`
+	AccessToken string    `json:"access_token"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+type ReassignSubjectAdminRequest struct {
+	UserID         uuid.UUID `json:"user_id" validate:"required"`
+	DemoteExisting bool      `json:"demote_existing"`
+}
@@ -0,0 +1,50 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MemberContribution вклад участника по данным дерева проблем. Главная проблема проекта
+// не учитывается: она описывает проект целиком.
+type MemberContribution struct {
+	UserID   uuid.UUID `json:"user_id"`
+	Nickname string    `json:"nickname"`
+	// Projects число проектов, по которым собраны данные; в отчете по проекту всегда 1
+	Projects         int `json:"projects"`
+	ProblemsCreated  int `json:"problems_created"`
+	ProblemsAssigned int `json:"problems_assigned"`
+	ProblemsSolved   int `json:"problems_solved"`
+	ResultsPosted    int `json:"results_posted"`
+	// SolvedOnTime и SolvedLate назначенные проблемы, решенные до и после конца их окна
+	SolvedOnTime int `json:"solved_on_time"`
+	SolvedLate   int `json:"solved_late"`
+	// Overdue назначенные нерешенные проблемы, окно которых уже закончилось
+	Overdue int `json:"overdue"`
+	// Workload суммарная длительность назначенных проблем в днях, поделенная между исполнителями
+	Workload float64 `json:"workload"`
+	// WorkloadShare доля участника в общей нагрузке команды в процентах
+	WorkloadShare float64           `json:"workload_share"`
+	Activity      []*ActivityBucket `json:"activity"`
+}
+
+// ActivityBucket активность участника за неделю, начинающуюся в понедельник WeekStart (UTC)
+type ActivityBucket struct {
+	WeekStart       time.Time `json:"week_start"`
+	ProblemsCreated int       `json:"problems_created"`
+	ResultsPosted   int       `json:"results_posted"`
+}
+
+// ProjectAnalytics вклад участников одного проекта
+type ProjectAnalytics struct {
+	ProjectID uuid.UUID             `json:"project_id"`
+	Title     string                `json:"title"`
+	Members   []*MemberContribution `json:"members"`
+}
+
+// SubjectAnalytics вклад студентов, сведенный по всем проектам предмета
+type SubjectAnalytics struct {
+	SubjectID uuid.UUID             `json:"subject_id"`
+	Members   []*MemberContribution `json:"members"`
+}
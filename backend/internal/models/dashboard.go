@@ -0,0 +1,73 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ProjectRisk признак проекта, которому нужно внимание преподавателя
+type ProjectRisk string
+
+const (
+	// RiskOverdue срок команды прошел, а проект не решен и не сдан
+	RiskOverdue ProjectRisk = "overdue"
+	// RiskOverdueProblems у проекта есть нерешенные проблемы с истекшим окном
+	RiskOverdueProblems ProjectRisk = "overdue_problems"
+	// RiskBehindSchedule доля решенных проблем заметно отстает от прошедшей доли срока
+	RiskBehindSchedule ProjectRisk = "behind_schedule"
+	// RiskInactive в проекте давно ничего не происходило
+	RiskInactive ProjectRisk = "inactive"
+	// RiskUnassigned есть нерешенные проблемы без исполнителей
+	RiskUnassigned ProjectRisk = "unassigned_work"
+	// RiskUnderstaffed в команде меньше участников, чем требует задание
+	RiskUnderstaffed ProjectRisk = "understaffed"
+)
+
+type DashboardMember struct {
+	UserID   uuid.UUID   `json:"user_id"`
+	Nickname string      `json:"nickname"`
+	Role     ProjectRole `json:"role"`
+}
+
+// DashboardProject строка обзора задания по одному проекту
+type DashboardProject struct {
+	ProjectID   uuid.UUID          `json:"project_id"`
+	Title       string             `json:"title"`
+	Status      ProjectStatus      `json:"status"`
+	SubmittedAt *time.Time         `json:"submitted_at,omitempty"`
+	ArchivedAt  *time.Time         `json:"archived_at,omitempty"`
+	Members     []*DashboardMember `json:"members"`
+	Statistics  *ProblemStatistics `json:"statistics"`
+	// DueDate срок команды с учетом продления
+	DueDate      *time.Time    `json:"due_date"`
+	Overdue      int           `json:"overdue"`
+	LastActivity time.Time     `json:"last_activity"`
+	Risks        []ProjectRisk `json:"risks"`
+}
+
+// TaskDashboard обзор всех проектов задания для преподавателя
+type TaskDashboard struct {
+	TaskID          uuid.UUID           `json:"task_id"`
+	Title           string              `json:"title"`
+	DueDate         *time.Time          `json:"due_date"`
+	Submitted       int                 `json:"submitted"`
+	AtRisk          int                 `json:"at_risk"`
+	AverageProgress int                 `json:"average_progress"`
+	Projects        []*DashboardProject `json:"projects"`
+}
+
+// ProblemNode проблема проекта с результатом и подпроблемами
+type ProblemNode struct {
+	*Problem
+	Result   *ResultResponse `json:"result"`
+	Children []*ProblemNode  `json:"children"`
+}
+
+// ProjectTree дерево проблем проекта для просмотра без вступления в команду
+type ProjectTree struct {
+	ProjectID uuid.UUID      `json:"project_id"`
+	Title     string         `json:"title"`
+	Status    ProjectStatus  `json:"status"`
+	Problems  []*ProblemNode `json:"problems"`
+}
@@ -0,0 +1,84 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// DeadlineExtension продление срока сдачи для одного проекта или одного студента проекта.
+// На проект и на каждого его участника действует не больше одного продления.
+type DeadlineExtension struct {
+	ID        uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	TaskID    uuid.UUID `json:"task_id" gorm:"type:uuid;not null;index"`
+	ProjectID uuid.UUID `json:"project_id" gorm:"type:uuid;not null;index"`
+	// UserID nil означает продление для всей команды
+	UserID      *uuid.UUID     `json:"user_id,omitempty" gorm:"type:uuid;index"`
+	DueDate     time.Time      `json:"due_date" gorm:"not null"`
+	Reason      string         `json:"reason" gorm:"not null"`
+	GrantedByID uuid.UUID      `json:"granted_by_id" gorm:"type:uuid;not null"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `json:"-" gorm:"index"`
+
+	Project   *Project `json:"-" gorm:"foreignKey:ProjectID;constraint:OnDelete:CASCADE"`
+	User      *User    `json:"user,omitempty" gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+	GrantedBy *User    `json:"granted_by,omitempty" gorm:"foreignKey:GrantedByID;constraint:OnDelete:RESTRICT"`
+}
+
+// IsPersonal продление выдано одному студенту, а не всей команде
+func (e *DeadlineExtension) IsPersonal() bool {
+	return e.UserID != nil
+}
+
+type GrantExtensionRequest struct {
+	// UserID участник проекта; без него продление получает вся команда
+	UserID  *uuid.UUID `json:"user_id"`
+	DueDate time.Time  `json:"due_date" validate:"required"`
+	Reason  string     `json:"reason" validate:"required,max=500"`
+}
+
+// EffectiveDueDate действующий срок: самый поздний из общего срока и продлений. Продление
+// никогда не сокращает срок, а без общего срока продлевать нечего.
+func EffectiveDueDate(base *time.Time, extensions ...*DeadlineExtension) *time.Time {
+	if base == nil {
+		return nil
+	}
+	due := *base
+	for _, ext := range extensions {
+		if ext != nil && ext.DueDate.After(due) {
+			due = ext.DueDate
+		}
+	}
+	return &due
+}
+
+// LateStatus опоздание результата относительно действующего срока по политике задания
+type LateStatus struct {
+	Late     bool    `json:"late"`
+	DaysLate int     `json:"days_late"`
+	Penalty  float64 `json:"penalty"`
+	// Closed срок жесткого закрытия прошел, результат не принимается
+	Closed bool `json:"closed"`
+}
+
+// MemberDeadline действующий срок одного участника проекта
+type MemberDeadline struct {
+	UserID   uuid.UUID  `json:"user_id"`
+	Nickname string     `json:"nickname"`
+	DueDate  *time.Time `json:"due_date"`
+	Extended bool       `json:"extended"`
+	Overdue  bool       `json:"overdue"`
+}
+
+// ProjectDeadline сроки проекта: общий срок задания для его группы, срок с учетом
+// продления команды и личные сроки участников
+type ProjectDeadline struct {
+	ProjectID   uuid.UUID            `json:"project_id"`
+	TaskDueDate *time.Time           `json:"task_due_date"`
+	DueDate     *time.Time           `json:"due_date"`
+	Overdue     bool                 `json:"overdue"`
+	Members     []*MemberDeadline    `json:"members"`
+	Extensions  []*DeadlineExtension `json:"extensions"`
+}
@@ -0,0 +1,122 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func intPtr(v int) *int { return &v }
+
+func TestTaskLateness(t *testing.T) {
+	due := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	cases := []struct {
+		name string
+		task Task
+		due  *time.Time
+		at   time.Time
+		want LateStatus
+	}{
+		{
+			name: "no due date",
+			task: Task{LatePenaltyPerDay: 10},
+			at:   due.AddDate(0, 1, 0),
+		},
+		{
+			name: "exactly on time",
+			task: Task{LatePenaltyPerDay: 10},
+			due:  &due,
+			at:   due,
+		},
+		{
+			name: "a minute late is a whole day",
+			task: Task{LatePenaltyPerDay: 10},
+			due:  &due,
+			at:   due.Add(time.Minute),
+			want: LateStatus{Late: true, DaysLate: 1, Penalty: 10},
+		},
+		{
+			name: "inside grace period",
+			task: Task{LateGraceHours: 24, LatePenaltyPerDay: 10},
+			due:  &due,
+			at:   due.Add(24 * time.Hour),
+		},
+		{
+			// льготный период не сдвигает отсчет дней опоздания
+			name: "past grace period counts from due date",
+			task: Task{LateGraceHours: 24, LatePenaltyPerDay: 10},
+			due:  &due,
+			at:   due.Add(25 * time.Hour),
+			want: LateStatus{Late: true, DaysLate: 2, Penalty: 20},
+		},
+		{
+			name: "penalty capped at 100",
+			task: Task{LatePenaltyPerDay: 30},
+			due:  &due,
+			at:   due.AddDate(0, 0, 5),
+			want: LateStatus{Late: true, DaysLate: 5, Penalty: 100},
+		},
+		{
+			name: "no penalty configured",
+			task: Task{},
+			due:  &due,
+			at:   due.Add(50 * time.Hour),
+			want: LateStatus{Late: true, DaysLate: 3},
+		},
+		{
+			name: "on the cutoff day",
+			task: Task{LatePenaltyPerDay: 5, LateCutoffDays: intPtr(3)},
+			due:  &due,
+			at:   due.AddDate(0, 0, 3),
+			want: LateStatus{Late: true, DaysLate: 3, Penalty: 15},
+		},
+		{
+			name: "past cutoff",
+			task: Task{LatePenaltyPerDay: 5, LateCutoffDays: intPtr(3)},
+			due:  &due,
+			at:   due.AddDate(0, 0, 3).Add(time.Hour),
+			want: LateStatus{Late: true, DaysLate: 4, Penalty: 20, Closed: true},
+		},
+		{
+			name: "cutoff before grace ends",
+			task: Task{LateGraceHours: 48, LateCutoffDays: intPtr(0)},
+			due:  &due,
+			at:   due.Add(time.Hour),
+			want: LateStatus{Closed: true},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.task.Lateness(tc.due, tc.at); got != tc.want {
+				t.Errorf("got %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestEffectiveDueDate(t *testing.T) {
+	base := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	earlier := &DeadlineExtension{DueDate: base.AddDate(0, 0, -2)}
+	later := &DeadlineExtension{DueDate: base.AddDate(0, 0, 2)}
+	latest := &DeadlineExtension{DueDate: base.AddDate(0, 0, 5)}
+
+	cases := []struct {
+		name       string
+		base       *time.Time
+		extensions []*DeadlineExtension
+		want       *time.Time
+	}{
+		{"no base date", nil, []*DeadlineExtension{later}, nil},
+		{"no extensions", &base, nil, &base},
+		{"extension moves due date", &base, []*DeadlineExtension{later}, &later.DueDate},
+		{"earlier extension is ignored", &base, []*DeadlineExtension{earlier}, &base},
+		{"latest of several", &base, []*DeadlineExtension{later, nil, latest, earlier}, &latest.DueDate},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := EffectiveDueDate(tc.base, tc.extensions...)
+			if (got == nil) != (tc.want == nil) || got != nil && !got.Equal(*tc.want) {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,175 @@
+package models
+
+import (
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// RubricCriterion критерий оценивания задания: вес в итоговой оценке и уровни выполнения
+type RubricCriterion struct {
+	ID          uuid.UUID      `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	TaskID      uuid.UUID      `json:"task_id" gorm:"type:uuid;not null;index"`
+	Position    int            `json:"position" gorm:"not null;default:0"`
+	Title       string         `json:"title" gorm:"not null"`
+	Description string         `json:"description"`
+	Weight      float64        `json:"weight" gorm:"not null"`
+	Levels      []*RubricLevel `json:"levels" gorm:"serializer:json;type:jsonb"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+
+	Task *Task `json:"-" gorm:"foreignKey:TaskID;constraint:OnDelete:CASCADE"`
+}
+
+// RubricLevel описание уровня выполнения критерия и баллы за него
+type RubricLevel struct {
+	Title       string  `json:"title" validate:"required,max=255"`
+	Description string  `json:"description"`
+	Points      float64 `json:"points" validate:"min=0"`
+}
+
+// MaxPoints баллы за лучший уровень критерия
+func (c *RubricCriterion) MaxPoints() float64 {
+	max := 0.0
+	for _, l := range c.Levels {
+		max = math.Max(max, l.Points)
+	}
+	return max
+}
+
+type RubricCriterionInput struct {
+	Title       string         `json:"title" validate:"required,max=255"`
+	Description string         `json:"description"`
+	Weight      float64        `json:"weight" validate:"gt=0"`
+	Levels      []*RubricLevel `json:"levels" validate:"required,min=1,dive"`
+}
+
+type SetRubricRequest struct {
+	Criteria []*RubricCriterionInput `json:"criteria" validate:"dive"`
+}
+
+// ProjectGrade оценка проекта по критериям задания. Студенты видят ее только после публикации.
+type ProjectGrade struct {
+	ID           uuid.UUID         `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	ProjectID    uuid.UUID         `json:"project_id" gorm:"type:uuid;not null;uniqueIndex"`
+	TaskID       uuid.UUID         `json:"task_id" gorm:"type:uuid;not null;index"`
+	SubmissionID *uuid.UUID        `json:"submission_id,omitempty" gorm:"type:uuid"`
+	GradedByID   uuid.UUID         `json:"graded_by_id" gorm:"type:uuid;not null"`
+	Comment      string            `json:"comment"`
+	Scores       []*CriterionScore `json:"scores" gorm:"serializer:json;type:jsonb"`
+	// Score взвешенная оценка по критериям в процентах, FinalScore после штрафа за опоздание
+	Score      float64        `json:"score" gorm:"not null"`
+	Penalty    float64        `json:"penalty" gorm:"not null;default:0"`
+	FinalScore float64        `json:"final_score" gorm:"not null"`
+	Released   bool           `json:"released" gorm:"not null;default:false"`
+	ReleasedAt *time.Time     `json:"released_at,omitempty"`
+	CreatedAt  time.Time      `json:"created_at"`
+	UpdatedAt  time.Time      `json:"updated_at"`
+	DeletedAt  gorm.DeletedAt `json:"-" gorm:"index"`
+
+	Project *Project       `json:"-" gorm:"foreignKey:ProjectID;constraint:OnDelete:CASCADE"`
+	Members []*MemberGrade `json:"members" gorm:"foreignKey:GradeID;constraint:OnDelete:CASCADE"`
+}
+
+// CriterionScore баллы проекта по одному критерию
+type CriterionScore struct {
+	CriterionID uuid.UUID `json:"criterion_id" validate:"required"`
+	Points      float64   `json:"points" validate:"min=0"`
+	Comment     string    `json:"comment"`
+}
+
+// MemberGrade индивидуальная оценка участника: оценка проекта с поправками преподавателя
+// и взаимной оценки
+type MemberGrade struct {
+	ID      uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	GradeID uuid.UUID `json:"grade_id" gorm:"type:uuid;not null;index:idx_member_grade,unique"`
+	UserID  uuid.UUID `json:"user_id" gorm:"type:uuid;not null;index:idx_member_grade,unique"`
+	// Adjustment поправка в процентных пунктах к оценке проекта
+	Adjustment float64 `json:"adjustment" gorm:"not null;default:0"`
+	// PeerAdjustment поправка по итогам взаимной оценки команды
+	PeerAdjustment float64   `json:"peer_adjustment" gorm:"not null;default:0"`
+	Comment        string    `json:"comment"`
+	Score          float64   `json:"score" gorm:"not null"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+
+	User *User `json:"user,omitempty" gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+}
+
+// Recalculate пересчитывает оценку участника от итоговой оценки проекта с обеими поправками
+func (m *MemberGrade) Recalculate(projectScore float64) {
+	m.Score = math.Max(0, math.Min(100, projectScore+m.Adjustment+m.PeerAdjustment))
+}
+
+// WeightedScore взвешенная оценка в процентах; ok == false, если не все критерии оценены
+// или баллы выходят за уровни критерия
+func WeightedScore(criteria []*RubricCriterion, scores []*CriterionScore) (score float64, ok bool) {
+	points := make(map[uuid.UUID]float64, len(scores))
+	for _, s := range scores {
+		points[s.CriterionID] = s.Points
+	}
+
+	var total, weights float64
+	for _, c := range criteria {
+		p, scored := points[c.ID]
+		max := c.MaxPoints()
+		if !scored || p > max {
+			return 0, false
+		}
+		if max > 0 {
+			total += c.Weight * p / max
+		}
+		weights += c.Weight
+	}
+	if weights == 0 {
+		return 0, false
+	}
+	return math.Round(total/weights*10000) / 100, true
+}
+
+type GradeProjectRequest struct {
+	Scores  []*CriterionScore `json:"scores" validate:"required,min=1,dive"`
+	Comment string            `json:"comment"`
+}
+
+type AdjustMemberGradeRequest struct {
+	Adjustment float64 `json:"adjustment" validate:"min=-100,max=100"`
+	Comment    string  `json:"comment"`
+}
+
+// ReleaseGradesRequest публикует оценки; пустой список означает все оцененные проекты задания
+type ReleaseGradesRequest struct {
+	ProjectIDs []uuid.UUID `json:"project_ids"`
+}
+
+// GradebookEntry оценка студента по заданию, как ее хранит журнал
+type GradebookEntry struct {
+	TaskID    uuid.UUID `json:"task_id"`
+	ProjectID uuid.UUID `json:"project_id"`
+	UserID    uuid.UUID `json:"-"`
+	Score     float64   `json:"score"`
+	Released  bool      `json:"released"`
+}
+
+type GradebookTask struct {
+	ID      uuid.UUID  `json:"id"`
+	Title   string     `json:"title"`
+	DueDate *time.Time `json:"due_date"`
+}
+
+// GradebookRow оценки одного студента по заданиям предмета; Average по оцененным заданиям
+type GradebookRow struct {
+	UserID   uuid.UUID         `json:"user_id"`
+	Nickname string            `json:"nickname"`
+	Grades   []*GradebookEntry `json:"grades"`
+	Average  *float64          `json:"average"`
+}
+
+// Gradebook журнал оценок предмета
+type Gradebook struct {
+	SubjectID uuid.UUID        `json:"subject_id"`
+	Tasks     []*GradebookTask `json:"tasks"`
+	Rows      []*GradebookRow  `json:"rows"`
+}
@@ -0,0 +1,104 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func criterion(weight float64, points ...float64) *RubricCriterion {
+	c := &RubricCriterion{ID: uuid.New(), Weight: weight}
+	for _, p := range points {
+		c.Levels = append(c.Levels, &RubricLevel{Points: p})
+	}
+	return c
+}
+
+func TestWeightedScore(t *testing.T) {
+	a := criterion(2, 0, 5, 10)
+	b := criterion(1, 5, 0)
+	empty := criterion(1, 0)
+
+	cases := []struct {
+		name     string
+		criteria []*RubricCriterion
+		scores   []*CriterionScore
+		want     float64
+		ok       bool
+	}{
+		{
+			name:     "weighted and rounded",
+			criteria: []*RubricCriterion{a, b},
+			scores:   []*CriterionScore{{CriterionID: a.ID, Points: 5}, {CriterionID: b.ID, Points: 5}},
+			want:     66.67,
+			ok:       true,
+		},
+		{
+			name:     "full marks",
+			criteria: []*RubricCriterion{a, b},
+			scores:   []*CriterionScore{{CriterionID: a.ID, Points: 10}, {CriterionID: b.ID, Points: 5}},
+			want:     100,
+			ok:       true,
+		},
+		{
+			name:     "unknown criterion is ignored",
+			criteria: []*RubricCriterion{a},
+			scores:   []*CriterionScore{{CriterionID: a.ID, Points: 10}, {CriterionID: uuid.New(), Points: 3}},
+			want:     100,
+			ok:       true,
+		},
+		{
+			// критерий без баллов учитывается в весах, но ничего не добавляет
+			name:     "criterion without points",
+			criteria: []*RubricCriterion{a, empty},
+			scores:   []*CriterionScore{{CriterionID: a.ID, Points: 10}, {CriterionID: empty.ID}},
+			want:     66.67,
+			ok:       true,
+		},
+		{
+			name:     "missing criterion",
+			criteria: []*RubricCriterion{a, b},
+			scores:   []*CriterionScore{{CriterionID: a.ID, Points: 10}},
+		},
+		{
+			name:     "points above best level",
+			criteria: []*RubricCriterion{a},
+			scores:   []*CriterionScore{{CriterionID: a.ID, Points: 11}},
+		},
+		{
+			name: "no criteria",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, ok := WeightedScore(tc.criteria, tc.scores)
+			if got != tc.want || ok != tc.ok {
+				t.Errorf("got %v, %v; want %v, %v", got, ok, tc.want, tc.ok)
+			}
+		})
+	}
+}
+
+func TestMemberGradeRecalculate(t *testing.T) {
+	cases := []struct {
+		name         string
+		projectScore float64
+		adjustment   float64
+		peer         float64
+		want         float64
+	}{
+		{"no adjustments", 72.5, 0, 0, 72.5},
+		{"both adjustments add up", 80, 5, -3, 82},
+		{"capped at 100", 95, 10, 2, 100},
+		{"never below zero", 10, -15, -5, 0},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			m := &MemberGrade{Adjustment: tc.adjustment, PeerAdjustment: tc.peer}
+			m.Recalculate(tc.projectScore)
+			if m.Score != tc.want {
+				t.Errorf("got %v, want %v", m.Score, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Group учебная группа (секция) внутри предмета. Студент состоит не более чем в одной группе
+// предмета, преподаватель может вести несколько.
+type Group struct {
+	ID          uuid.UUID      `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	SubjectID   uuid.UUID      `json:"subject_id" gorm:"type:uuid;not null;index:idx_subject_group_name,unique"`
+	Name        string         `json:"name" gorm:"not null;index:idx_subject_group_name,unique"`
+	Description string         `json:"description"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `json:"-" gorm:"index"`
+
+	Subject *Subject       `json:"-" gorm:"foreignKey:SubjectID;constraint:OnDelete:CASCADE"`
+	Members []*GroupMember `json:"members,omitempty" gorm:"foreignKey:GroupID;constraint:OnDelete:CASCADE"`
+}
+
+type GroupMember struct {
+	ID        uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	GroupID   uuid.UUID `json:"group_id" gorm:"type:uuid;not null;index:idx_group_user,unique"`
+	UserID    uuid.UUID `json:"user_id" gorm:"type:uuid;not null;index:idx_group_user,unique;index"`
+	CreatedAt time.Time `json:"created_at"`
+
+	User  *User  `json:"user,omitempty" gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+	Group *Group `json:"-" gorm:"foreignKey:GroupID;constraint:OnDelete:CASCADE"`
+}
+
+// TaskGroup назначение задания группе со своим сроком сдачи; nil означает срок задания
+type TaskGroup struct {
+	TaskID    uuid.UUID  `json:"task_id" gorm:"type:uuid;primaryKey"`
+	GroupID   uuid.UUID  `json:"group_id" gorm:"type:uuid;primaryKey;index"`
+	DueDate   *time.Time `json:"due_date"`
+	CreatedAt time.Time  `json:"created_at"`
+
+	Task  *Task  `json:"-" gorm:"foreignKey:TaskID;constraint:OnDelete:CASCADE"`
+	Group *Group `json:"group,omitempty" gorm:"foreignKey:GroupID;constraint:OnDelete:CASCADE"`
+}
+
+type CreateGroupRequest struct {
+	Name        string `json:"name" validate:"required"`
+	Description string `json:"description"`
+}
+
+type UpdateGroupRequest struct {
+	Name        *string `json:"name"`
+	Description *string `json:"description"`
+}
+
+type GroupMembersRequest struct {
+	UserIDs []uuid.UUID `json:"user_ids" validate:"required,min=1"`
+}
+
+// TaskGroupTarget группа, которой назначено задание, и ее срок сдачи
+type TaskGroupTarget struct {
+	GroupID uuid.UUID  `json:"group_id" validate:"required"`
+	DueDate *time.Time `json:"due_date"`
+}
+
+// GroupStatistics сводка по группе: участники, проекты и задачи проектов ее студентов
+type GroupStatistics struct {
+	GroupID  uuid.UUID         `json:"group_id"`
+	Name     string            `json:"name"`
+	Students int               `json:"students"`
+	Teachers int               `json:"teachers"`
+	Projects int               `json:"projects"`
+	Problems ProblemStatistics `json:"problems"`
+}
@@ -0,0 +1,78 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type InviteTarget string
+
+const (
+	InviteTargetSubject InviteTarget = "subject"
+	InviteTargetProject InviteTarget = "project"
+)
+
+// Invite код приглашения в предмет или проект. Код может ограничиваться сроком
+// действия, числом использований и конкретным email получателя.
+type Invite struct {
+	ID          uuid.UUID      `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	TargetType  InviteTarget   `json:"target_type" gorm:"type:varchar(20);not null"`
+	SubjectID   *uuid.UUID     `json:"subject_id,omitempty" gorm:"type:uuid;index"`
+	ProjectID   *uuid.UUID     `json:"project_id,omitempty" gorm:"type:uuid;index"`
+	GroupID     *uuid.UUID     `json:"group_id,omitempty" gorm:"type:uuid"`
+	Code        string         `json:"code" gorm:"uniqueIndex;not null"`
+	RoleType    RoleType       `json:"role_type,omitempty" gorm:"type:varchar(20)"`
+	Email       string         `json:"email,omitempty" gorm:"index"`
+	MaxUses     *int           `json:"max_uses,omitempty"`
+	Uses        int            `json:"uses" gorm:"not null;default:0"`
+	ExpiresAt   *time.Time     `json:"expires_at,omitempty"`
+	RevokedAt   *time.Time     `json:"revoked_at,omitempty"`
+	CreatedByID uuid.UUID      `json:"created_by_id" gorm:"type:uuid;not null"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `json:"-" gorm:"index"`
+
+	Subject   *Subject `json:"subject,omitempty" gorm:"foreignKey:SubjectID;constraint:OnDelete:CASCADE"`
+	Project   *Project `json:"project,omitempty" gorm:"foreignKey:ProjectID;constraint:OnDelete:CASCADE"`
+	CreatedBy *User    `json:"created_by,omitempty" gorm:"foreignKey:CreatedByID;constraint:OnDelete:CASCADE"`
+}
+
+func (i *Invite) TableName() string {
+	return "invites"
+}
+
+func (i *Invite) IsRevoked() bool {
+	return i.RevokedAt != nil
+}
+
+func (i *Invite) IsExpired(now time.Time) bool {
+	return i.ExpiresAt != nil && !now.Before(*i.ExpiresAt)
+}
+
+func (i *Invite) IsExhausted() bool {
+	return i.MaxUses != nil && i.Uses >= *i.MaxUses
+}
+
+// IsActive приглашение еще можно использовать
+func (i *Invite) IsActive(now time.Time) bool {
+	return !i.IsRevoked() && !i.IsExpired(now) && !i.IsExhausted()
+}
+
+// RegenerateInviteRequest новые ограничения при смене кода; без них меняется только код.
+// Истекшее или исчерпанное приглашение открывается заново только явным новым сроком или лимитом.
+type RegenerateInviteRequest struct {
+	MaxUses        *int `json:"max_uses" validate:"omitempty,min=1"`
+	ExpiresInHours *int `json:"expires_in_hours" validate:"omitempty,min=1"`
+}
+
+type CreateInviteRequest struct {
+	// RoleType роль в предмете, которую получит вступивший; для проектов не используется
+	RoleType       RoleType `json:"role_type"`
+	Email          string   `json:"email" validate:"omitempty,email"`
+	MaxUses        *int     `json:"max_uses" validate:"omitempty,min=1"`
+	ExpiresInHours *int     `json:"expires_in_hours" validate:"omitempty,min=1"`
+	// GroupID группа предмета, в которую попадет вступивший
+	GroupID *uuid.UUID `json:"group_id"`
+}
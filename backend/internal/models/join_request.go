@@ -0,0 +1,54 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type JoinRequestStatus string
+
+const (
+	JoinRequestPending   JoinRequestStatus = "pending"
+	JoinRequestApproved  JoinRequestStatus = "approved"
+	JoinRequestRejected  JoinRequestStatus = "rejected"
+	JoinRequestCancelled JoinRequestStatus = "cancelled"
+)
+
+// JoinRequest заявка на вступление в предмет или проект, для которых включено подтверждение
+type JoinRequest struct {
+	ID          uuid.UUID         `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	TargetType  InviteTarget      `json:"target_type" gorm:"type:varchar(20);not null"`
+	SubjectID   *uuid.UUID        `json:"subject_id,omitempty" gorm:"type:uuid;index"`
+	ProjectID   *uuid.UUID        `json:"project_id,omitempty" gorm:"type:uuid;index"`
+	UserID      uuid.UUID         `json:"user_id" gorm:"type:uuid;not null;index"`
+	Status      JoinRequestStatus `json:"status" gorm:"type:varchar(20);not null;index"`
+	DecidedByID *uuid.UUID        `json:"decided_by_id,omitempty" gorm:"type:uuid"`
+	DecidedAt   *time.Time        `json:"decided_at,omitempty"`
+	CreatedAt   time.Time         `json:"created_at"`
+	UpdatedAt   time.Time         `json:"updated_at"`
+
+	User    *User    `json:"user,omitempty" gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+	Subject *Subject `json:"subject,omitempty" gorm:"foreignKey:SubjectID;constraint:OnDelete:CASCADE"`
+	Project *Project `json:"project,omitempty" gorm:"foreignKey:ProjectID;constraint:OnDelete:CASCADE"`
+}
+
+func (j *JoinRequest) IsPending() bool {
+	return j.Status == JoinRequestPending
+}
+
+type DecideJoinRequestsRequest struct {
+	IDs     []uuid.UUID `json:"ids" validate:"required,min=1,max=200"`
+	Approve bool        `json:"approve"`
+}
+
+// JoinRequestDecision результат решения по одной заявке при массовой обработке
+type JoinRequestDecision struct {
+	ID     uuid.UUID         `json:"id"`
+	Status JoinRequestStatus `json:"status,omitempty"`
+	Error  string            `json:"error,omitempty"`
+}
+
+type SetJoinApprovalRequest struct {
+	RequiresApproval bool `json:"requires_approval"`
+}
@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type LoginAttemptType string
+
+const (
+	LoginAttemptLogin    LoginAttemptType = "login"
+	LoginAttemptRegister LoginAttemptType = "register"
+)
+
+type LoginAttempt struct {
+	ID        uuid.UUID        `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	Type      LoginAttemptType `json:"type" gorm:"type:varchar(20);not null"`
+	Email     string           `json:"email" gorm:"not null;index"`
+	IP        string           `json:"ip" gorm:"type:varchar(64);index"`
+	UserAgent string           `json:"user_agent"`
+	Success   bool             `json:"success" gorm:"not null;default:false"`
+	Reason    string           `json:"reason"`
+	CreatedAt time.Time        `json:"created_at" gorm:"index"`
+}
+
+// ClientInfo описывает источник запроса для аудита и ограничения частоты
+type ClientInfo struct {
+	IP        string
+	UserAgent string
+}
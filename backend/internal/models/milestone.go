@@ -0,0 +1,110 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Milestone промежуточная контрольная точка задания (заявка, прототип, черновик отчета).
+// Проблемы проектов привязываются к ней, и по их решению видно, пройдена ли точка.
+type Milestone struct {
+	ID          uuid.UUID      `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	TaskID      uuid.UUID      `json:"task_id" gorm:"type:uuid;not null;index"`
+	Title       string         `json:"title" gorm:"not null"`
+	Description string         `json:"description"`
+	DueDate     time.Time      `json:"due_date" gorm:"not null"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `json:"-" gorm:"index"`
+
+	Task *Task `json:"-" gorm:"foreignKey:TaskID;constraint:OnDelete:CASCADE"`
+}
+
+func (m *Milestone) TableName() string {
+	return "milestones"
+}
+
+type CreateMilestoneRequest struct {
+	Title       string    `json:"title" validate:"required,max=255"`
+	Description string    `json:"description"`
+	DueDate     time.Time `json:"due_date" validate:"required"`
+}
+
+type UpdateMilestoneRequest struct {
+	Title       *string    `json:"title" validate:"omitempty,min=1,max=255"`
+	Description *string    `json:"description"`
+	DueDate     *time.Time `json:"due_date"`
+}
+
+type MilestoneState string
+
+const (
+	// MilestoneUnplanned к точке не привязано ни одной проблемы
+	MilestoneUnplanned MilestoneState = "unplanned"
+	MilestonePending   MilestoneState = "pending"
+	MilestoneMet       MilestoneState = "met"
+	// MilestoneLate все проблемы решены, но часть после срока
+	MilestoneLate   MilestoneState = "late"
+	MilestoneMissed MilestoneState = "missed"
+)
+
+// MilestoneProblemState решение проблемы, привязанной к контрольной точке
+type MilestoneProblemState struct {
+	MilestoneID uuid.UUID  `json:"-"`
+	ProblemID   uuid.UUID  `json:"problem_id"`
+	Number      int        `json:"number"`
+	Title       string     `json:"title"`
+	Solved      bool       `json:"solved"`
+	SolvedAt    *time.Time `json:"solved_at,omitempty"`
+}
+
+// MilestoneProgress контрольная точка в расписании одного проекта
+type MilestoneProgress struct {
+	Milestone *Milestone               `json:"milestone"`
+	State     MilestoneState           `json:"state"`
+	Solved    int                      `json:"solved"`
+	Total     int                      `json:"total"`
+	Problems  []*MilestoneProblemState `json:"problems"`
+}
+
+// NewMilestoneProgress оценивает точку по привязанным проблемам на момент now
+func NewMilestoneProgress(m *Milestone, problems []*MilestoneProblemState, now time.Time) *MilestoneProgress {
+	progress := &MilestoneProgress{Milestone: m, Total: len(problems), Problems: problems}
+	if progress.Problems == nil {
+		progress.Problems = []*MilestoneProblemState{}
+	}
+
+	late := false
+	for _, p := range problems {
+		if !p.Solved {
+			continue
+		}
+		progress.Solved++
+		if p.SolvedAt != nil && p.SolvedAt.After(m.DueDate) {
+			late = true
+		}
+	}
+
+	switch {
+	case progress.Total == 0:
+		progress.State = MilestoneUnplanned
+	case progress.Solved == progress.Total && late:
+		progress.State = MilestoneLate
+	case progress.Solved == progress.Total:
+		progress.State = MilestoneMet
+	case now.After(m.DueDate):
+		progress.State = MilestoneMissed
+	default:
+		progress.State = MilestonePending
+	}
+	return progress
+}
+
+// ProjectMilestoneReport контрольные точки задания по одному проекту
+type ProjectMilestoneReport struct {
+	ProjectID  uuid.UUID            `json:"project_id"`
+	Title      string               `json:"title"`
+	Milestones []*MilestoneProgress `json:"milestones"`
+}
@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type NotificationType string
+
+const (
+	NotificationJoinRequestApproved NotificationType = "join_request_approved"
+	NotificationJoinRequestRejected NotificationType = "join_request_rejected"
+	NotificationOwnershipOffered    NotificationType = "ownership_offered"
+	NotificationOwnershipAccepted   NotificationType = "ownership_accepted"
+	NotificationOwnershipDeclined   NotificationType = "ownership_declined"
+)
+
+// Notification уведомление пользователю внутри приложения
+type Notification struct {
+	ID     uuid.UUID        `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	UserID uuid.UUID        `json:"user_id" gorm:"type:uuid;not null;index"`
+	Type   NotificationType `json:"type" gorm:"type:varchar(40);not null"`
+	Title  string           `json:"title" gorm:"not null"`
+	Body   string           `json:"body"`
+	// TargetID ресурс, к которому относится уведомление (предмет, проект, задание)
+	TargetID  *uuid.UUID `json:"target_id,omitempty" gorm:"type:uuid"`
+	ReadAt    *time.Time `json:"read_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at" gorm:"index"`
+
+	User *User `json:"-" gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+}
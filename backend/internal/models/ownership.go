@@ -0,0 +1,44 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type OwnershipTransferStatus string
+
+const (
+	OwnershipTransferPending   OwnershipTransferStatus = "pending"
+	OwnershipTransferAccepted  OwnershipTransferStatus = "accepted"
+	OwnershipTransferDeclined  OwnershipTransferStatus = "declined"
+	OwnershipTransferCancelled OwnershipTransferStatus = "cancelled"
+)
+
+// OwnershipTransfer предложение передать владение предметом; вступает в силу после подтверждения получателем
+type OwnershipTransfer struct {
+	ID         uuid.UUID               `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	SubjectID  uuid.UUID               `json:"subject_id" gorm:"type:uuid;not null;index"`
+	FromUserID uuid.UUID               `json:"from_user_id" gorm:"type:uuid;not null"`
+	ToUserID   uuid.UUID               `json:"to_user_id" gorm:"type:uuid;not null;index"`
+	Status     OwnershipTransferStatus `json:"status" gorm:"type:varchar(20);not null"`
+	// DemoteFrom после передачи прежний владелец становится преподавателем, иначе остается администратором
+	DemoteFrom bool       `json:"demote_from" gorm:"not null;default:false"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	DecidedAt  *time.Time `json:"decided_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+
+	Subject  *Subject `json:"subject,omitempty" gorm:"foreignKey:SubjectID;constraint:OnDelete:CASCADE"`
+	FromUser *User    `json:"from_user,omitempty" gorm:"foreignKey:FromUserID;constraint:OnDelete:CASCADE"`
+	ToUser   *User    `json:"to_user,omitempty" gorm:"foreignKey:ToUserID;constraint:OnDelete:CASCADE"`
+}
+
+func (t *OwnershipTransfer) IsPending(now time.Time) bool {
+	return t.Status == OwnershipTransferPending && now.Before(t.ExpiresAt)
+}
+
+type TransferOwnershipRequest struct {
+	UserID     uuid.UUID `json:"user_id" validate:"required"`
+	DemoteSelf bool      `json:"demote_self"`
+}
@@ -0,0 +1,180 @@
+package models
+
+import (
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PeerReviewRound раунд взаимной оценки по заданию: участники проектов оценивают вклад
+// товарищей по команде по критериям раунда
+type PeerReviewRound struct {
+	ID       uuid.UUID        `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	TaskID   uuid.UUID        `json:"task_id" gorm:"type:uuid;not null;uniqueIndex"`
+	Criteria []*PeerCriterion `json:"criteria" gorm:"serializer:json;type:jsonb"`
+	OpensAt  *time.Time       `json:"opens_at"`
+	ClosesAt *time.Time       `json:"closes_at"`
+	// AutoAdjust после закрытия раунда оценки участников сдвигаются по взаимной оценке,
+	// но не больше чем на MaxAdjustment процентных пунктов
+	AutoAdjust    bool      `json:"auto_adjust" gorm:"not null;default:false"`
+	MaxAdjustment float64   `json:"max_adjustment" gorm:"not null;default:0"`
+	CreatedByID   uuid.UUID `json:"created_by_id" gorm:"type:uuid;not null"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+
+	Task *Task `json:"-" gorm:"foreignKey:TaskID;constraint:OnDelete:CASCADE"`
+}
+
+// PeerCriterion критерий взаимной оценки со шкалой от 0 до MaxScore
+type PeerCriterion struct {
+	ID          uuid.UUID `json:"id"`
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	MaxScore    int       `json:"max_score"`
+}
+
+// IsOpen принимает ли раунд оценки в момент now
+func (r *PeerReviewRound) IsOpen(now time.Time) bool {
+	if r.OpensAt != nil && now.Before(*r.OpensAt) {
+		return false
+	}
+	return r.ClosesAt == nil || now.Before(*r.ClosesAt)
+}
+
+// IsClosed раунд завершен и его итоги можно применять к оценкам
+func (r *PeerReviewRound) IsClosed(now time.Time) bool {
+	return r.ClosesAt != nil && !now.Before(*r.ClosesAt)
+}
+
+// Percent средний балл отзыва по критериям раунда в процентах; ok == false, если оценены
+// не все критерии или балл вне шкалы
+func (r *PeerReviewRound) Percent(scores []*PeerScore) (percent float64, ok bool) {
+	given := make(map[uuid.UUID]int, len(scores))
+	for _, s := range scores {
+		given[s.CriterionID] = s.Score
+	}
+	if len(r.Criteria) == 0 {
+		return 0, false
+	}
+	total := 0.0
+	for _, c := range r.Criteria {
+		score, scored := given[c.ID]
+		if !scored || score > c.MaxScore {
+			return 0, false
+		}
+		total += float64(score) / float64(c.MaxScore)
+	}
+	return math.Round(total/float64(len(r.Criteria))*10000) / 100, true
+}
+
+// PeerReview отзыв одного участника проекта о другом
+type PeerReview struct {
+	ID         uuid.UUID    `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	RoundID    uuid.UUID    `json:"round_id" gorm:"type:uuid;not null;index:idx_peer_review,unique"`
+	ProjectID  uuid.UUID    `json:"project_id" gorm:"type:uuid;not null;index"`
+	ReviewerID uuid.UUID    `json:"reviewer_id" gorm:"type:uuid;not null;index:idx_peer_review,unique"`
+	RevieweeID uuid.UUID    `json:"reviewee_id" gorm:"type:uuid;not null;index:idx_peer_review,unique"`
+	Scores     []*PeerScore `json:"scores" gorm:"serializer:json;type:jsonb"`
+	Comment    string       `json:"comment"`
+	CreatedAt  time.Time    `json:"created_at"`
+	UpdatedAt  time.Time    `json:"updated_at"`
+
+	Round   *PeerReviewRound `json:"-" gorm:"foreignKey:RoundID;constraint:OnDelete:CASCADE"`
+	Project *Project         `json:"-" gorm:"foreignKey:ProjectID;constraint:OnDelete:CASCADE"`
+}
+
+type PeerScore struct {
+	CriterionID uuid.UUID `json:"criterion_id" validate:"required"`
+	Score       int       `json:"score" validate:"min=0"`
+}
+
+type PeerCriterionInput struct {
+	Title       string `json:"title" validate:"required,max=255"`
+	Description string `json:"description"`
+	MaxScore    int    `json:"max_score" validate:"min=1,max=100"`
+}
+
+// SetPeerReviewRoundRequest настройки раунда; пустой список критериев при изменении
+// существующего раунда оставляет прежние критерии
+type SetPeerReviewRoundRequest struct {
+	Criteria      []*PeerCriterionInput `json:"criteria" validate:"dive"`
+	OpensAt       *time.Time            `json:"opens_at"`
+	ClosesAt      *time.Time            `json:"closes_at"`
+	AutoAdjust    bool                  `json:"auto_adjust"`
+	MaxAdjustment float64               `json:"max_adjustment" validate:"min=0,max=100"`
+}
+
+type PeerReviewInput struct {
+	RevieweeID uuid.UUID    `json:"reviewee_id" validate:"required"`
+	Scores     []*PeerScore `json:"scores" validate:"required,min=1,dive"`
+	Comment    string       `json:"comment" validate:"max=2000"`
+}
+
+// SubmitPeerReviewsRequest отзывы участника о товарищах; повторная отправка заменяет прежние отзывы
+type SubmitPeerReviewsRequest struct {
+	Reviews []*PeerReviewInput `json:"reviews" validate:"required,min=1,dive"`
+}
+
+// Отклонения относительной взаимной оценки участника от среднего по команде
+const (
+	PeerOutlierLow  = "low"
+	PeerOutlierHigh = "high"
+)
+
+// PeerOutlierDeviation насколько относительная взаимная оценка участника может отличаться
+// от среднего по команде, прежде чем участник будет отмечен
+const PeerOutlierDeviation = 0.2
+
+// PeerMemberSummary обезличенные итоги взаимной оценки участника: средние баллы и комментарии
+// без указания авторов
+type PeerMemberSummary struct {
+	UserID           uuid.UUID             `json:"user_id"`
+	Nickname         string                `json:"nickname"`
+	ReviewsReceived  int                   `json:"reviews_received"`
+	ReviewsGiven     int                   `json:"reviews_given"`
+	Average          *float64              `json:"average"`
+	CriterionAverage map[uuid.UUID]float64 `json:"criterion_average"`
+	// Relative средняя оценка участника относительно среднего по команде, 1 означает вровень с командой
+	Relative   *float64 `json:"relative"`
+	Outlier    string   `json:"outlier,omitempty"`
+	Adjustment float64  `json:"adjustment"`
+	Comments   []string `json:"comments"`
+}
+
+// Compare сопоставляет среднюю оценку участника со средним по команде: заполняет относительную
+// оценку, отметку об отклонении и поправку, ограниченную maxAdjustment процентными пунктами
+func (m *PeerMemberSummary) Compare(teamAverage, maxAdjustment float64) {
+	if m.Average == nil || teamAverage == 0 {
+		return
+	}
+	relative := math.Round(*m.Average/teamAverage*100) / 100
+	m.Relative = &relative
+	switch {
+	case relative < 1-PeerOutlierDeviation:
+		m.Outlier = PeerOutlierLow
+	case relative > 1+PeerOutlierDeviation:
+		m.Outlier = PeerOutlierHigh
+	}
+	if maxAdjustment > 0 {
+		adj := math.Max(-maxAdjustment, math.Min(maxAdjustment, (relative-1)*100))
+		m.Adjustment = math.Round(adj*100) / 100
+	}
+}
+
+// PeerProjectSummary итоги взаимной оценки в одном проекте
+type PeerProjectSummary struct {
+	ProjectID uuid.UUID            `json:"project_id"`
+	Title     string               `json:"title"`
+	Complete  bool                 `json:"complete"`
+	Members   []*PeerMemberSummary `json:"members"`
+}
+
+// Adjustments поправки к оценкам участников, рассчитанные по взаимной оценке
+func (s *PeerProjectSummary) Adjustments() map[uuid.UUID]float64 {
+	adjustments := make(map[uuid.UUID]float64, len(s.Members))
+	for _, m := range s.Members {
+		adjustments[m.UserID] = m.Adjustment
+	}
+	return adjustments
+}
@@ -0,0 +1,74 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestPeerReviewRoundPercent(t *testing.T) {
+	c1 := &PeerCriterion{ID: uuid.New(), MaxScore: 5}
+	c2 := &PeerCriterion{ID: uuid.New(), MaxScore: 10}
+	round := &PeerReviewRound{Criteria: []*PeerCriterion{c1, c2}}
+
+	cases := []struct {
+		name   string
+		round  *PeerReviewRound
+		scores []*PeerScore
+		want   float64
+		ok     bool
+	}{
+		{"average of criteria", round, []*PeerScore{{CriterionID: c1.ID, Score: 4}, {CriterionID: c2.ID, Score: 5}}, 65, true},
+		{"rounded to hundredths", &PeerReviewRound{Criteria: []*PeerCriterion{{ID: c1.ID, MaxScore: 3}}}, []*PeerScore{{CriterionID: c1.ID, Score: 2}}, 66.67, true},
+		{"zero scores", round, []*PeerScore{{CriterionID: c1.ID}, {CriterionID: c2.ID}}, 0, true},
+		{"missing criterion", round, []*PeerScore{{CriterionID: c1.ID, Score: 4}}, 0, false},
+		{"score above scale", round, []*PeerScore{{CriterionID: c1.ID, Score: 6}, {CriterionID: c2.ID, Score: 5}}, 0, false},
+		{"round without criteria", &PeerReviewRound{}, []*PeerScore{{CriterionID: c1.ID, Score: 4}}, 0, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, ok := tc.round.Percent(tc.scores)
+			if got != tc.want || ok != tc.ok {
+				t.Errorf("got %v, %v; want %v, %v", got, ok, tc.want, tc.ok)
+			}
+		})
+	}
+}
+
+func TestPeerMemberSummaryCompare(t *testing.T) {
+	avg := func(v float64) *float64 { return &v }
+	cases := []struct {
+		name          string
+		average       *float64
+		teamAverage   float64
+		maxAdjustment float64
+		relative      *float64
+		outlier       string
+		adjustment    float64
+	}{
+		{"level with team", avg(80), 80, 10, avg(1), "", 0},
+		{"small deviation is not an outlier", avg(88), 80, 30, avg(1.1), "", 10},
+		{"on the low boundary", avg(64), 80, 30, avg(0.8), "", -20},
+		{"low outlier capped", avg(60), 80, 10, avg(0.75), PeerOutlierLow, -10},
+		{"high outlier", avg(100), 80, 30, avg(1.25), PeerOutlierHigh, 25},
+		{"adjustment disabled", avg(100), 80, 0, avg(1.25), PeerOutlierHigh, 0},
+		{"relative rounded first", avg(70), 90, 50, avg(0.78), PeerOutlierLow, -22},
+		{"no reviews received", nil, 80, 10, nil, "", 0},
+		{"team without scores", avg(0), 0, 10, nil, "", 0},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			m := &PeerMemberSummary{Average: tc.average}
+			m.Compare(tc.teamAverage, tc.maxAdjustment)
+			if (m.Relative == nil) != (tc.relative == nil) || m.Relative != nil && *m.Relative != *tc.relative {
+				t.Errorf("relative = %v, want %v", m.Relative, tc.relative)
+			}
+			if m.Outlier != tc.outlier {
+				t.Errorf("outlier = %q, want %q", m.Outlier, tc.outlier)
+			}
+			if m.Adjustment != tc.adjustment {
+				t.Errorf("adjustment = %v, want %v", m.Adjustment, tc.adjustment)
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package models
+
+// Permission отдельное право участника внутри предмета
+type Permission string
+
+const (
+	PermSubjectUpdate   Permission = "subject.update"
+	PermSubjectDelete   Permission = "subject.delete"
+	PermMembersView     Permission = "members.view"
+	PermMembersManage   Permission = "members.manage"
+	PermMembersApprove  Permission = "members.approve"
+	PermTasksCreate     Permission = "tasks.create"
+	PermTasksManage     Permission = "tasks.manage"
+	PermProjectsCreate  Permission = "projects.create"
+	PermProjectsViewAll Permission = "projects.view_all"
+	PermGrade           Permission = "grades.manage"
+)
+
+type PermissionInfo struct {
+	Key         Permission `json:"key"`
+	Description string     `json:"description"`
+}
+
+// PermissionCatalogue полный список прав в порядке отображения
+var PermissionCatalogue = []PermissionInfo{
+	{PermSubjectUpdate, "Edit subject name and description"},
+	{PermSubjectDelete, "Delete the subject"},
+	{PermMembersView, "See the list of subject members"},
+	{PermMembersManage, "Add, remove members and change their roles and permissions"},
+	{PermMembersApprove, "Approve or reject join requests"},
+	{PermTasksCreate, "Create tasks"},
+	{PermTasksManage, "Edit and delete any task, not only own"},
+	{PermProjectsCreate, "Create projects for tasks"},
+	{PermProjectsViewAll, "View all projects of the subject, including ones the member has not joined"},
+	{PermGrade, "Grade projects and students"},
+}
+
+// DefaultPermissions набор прав по умолчанию для каждой роли предмета
+var DefaultPermissions = map[RoleType][]Permission{
+	RoleStudent: {
+		PermMembersView,
+		PermProjectsCreate,
+	},
+	RoleTeacher: {
+		PermMembersView,
+		PermMembersApprove,
+		PermTasksCreate,
+		PermProjectsCreate,
+		PermProjectsViewAll,
+		PermGrade,
+	},
+	RoleAdmin: {
+		PermSubjectUpdate,
+		PermSubjectDelete,
+		PermMembersView,
+		PermMembersManage,
+		PermMembersApprove,
+		PermTasksCreate,
+		PermTasksManage,
+		PermProjectsCreate,
+		PermProjectsViewAll,
+		PermGrade,
+	},
+}
+
+func IsKnownPermission(p Permission) bool {
+	for _, info := range PermissionCatalogue {
+		if info.Key == p {
+			return true
+		}
+	}
+	return false
+}
@@ -8,19 +8,25 @@ import (
 )
 
 type Problem struct {
-	ID          uuid.UUID      `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
-	ProjectID   uuid.UUID      `json:"project_id" gorm:"type:uuid;not null;index"`
-	ParentID    *uuid.UUID     `json:"parent_id" gorm:"type:uuid;index"`
-	CreatorID   uuid.UUID      `json:"creator_id" gorm:"type:uuid;not null;index"`
-	Number      int            `json:"number" gorm:"not null"`
-	Title       string         `json:"title" gorm:"not null"`
-	Description string         `json:"description"`
-	StartTime   time.Time      `json:"start_time" gorm:"not null"`
-	EndTime     time.Time      `json:"end_time" gorm:"not null"`
-	Solved      bool           `json:"solved" gorm:"not null;default:false"`
-	CreatedAt   time.Time      `json:"created_at"`
-	UpdatedAt   time.Time      `json:"updated_at"`
-	DeletedAt   gorm.DeletedAt `json:"-" gorm:"index"`
+	ID          uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	ProjectID   uuid.UUID  `json:"project_id" gorm:"type:uuid;not null;index"`
+	ParentID    *uuid.UUID `json:"parent_id" gorm:"type:uuid;index"`
+	CreatorID   uuid.UUID  `json:"creator_id" gorm:"type:uuid;not null;index"`
+	Number      int        `json:"number" gorm:"not null"`
+	Title       string     `json:"title" gorm:"not null"`
+	Description string     `json:"description"`
+	StartTime   time.Time  `json:"start_time" gorm:"not null"`
+	EndTime     time.Time  `json:"end_time" gorm:"not null"`
+	Solved      bool       `json:"solved" gorm:"not null;default:false"`
+	// TemplateID узел шаблона задания, по которому создана проблема
+	TemplateID *uuid.UUID `json:"template_id,omitempty" gorm:"type:uuid;index"`
+	// MilestoneID контрольная точка задания, к которой относится проблема
+	MilestoneID *uuid.UUID `json:"milestone_id,omitempty" gorm:"type:uuid;index"`
+	// Required этап из шаблона, который студенты не могут удалить
+	Required  bool           `json:"required" gorm:"not null;default:false"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
 
 	Project   *Project           `json:"project" gorm:"foreignKey:ProjectID;constraint:OnDelete:CASCADE"`
 	Parent    *Problem           `json:"parent" gorm:"foreignKey:ParentID;constraint:OnDelete:SET NULL"`
@@ -47,6 +53,7 @@ type CreateProblemRequest struct {
 	StartTime   *time.Time  `json:"start_time"`
 	EndTime     *time.Time  `json:"end_time"`
 	AssigneeIDs []uuid.UUID `json:"assignee_ids"`
+	MilestoneID *uuid.UUID  `json:"milestone_id"`
 }
 
 type UpdateProblemRequest struct {
@@ -55,6 +62,8 @@ type UpdateProblemRequest struct {
 	StartTime   *time.Time   `json:"start_time"`
 	EndTime     *time.Time   `json:"end_time"`
 	AssigneeIDs *[]uuid.UUID `json:"assignee_ids"`
+	// MilestoneID uuid.Nil отвязывает проблему от контрольной точки
+	MilestoneID *uuid.UUID `json:"milestone_id"`
 }
 
 type ProblemStatistics struct {
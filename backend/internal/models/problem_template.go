@@ -0,0 +1,80 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ProblemTemplate узел шаблона разбиения задания на этапы. При создании проекта дерево
+// шаблона разворачивается в проблемы под главной проблемой проекта.
+type ProblemTemplate struct {
+	ID          uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	TaskID      uuid.UUID  `json:"task_id" gorm:"type:uuid;not null;index"`
+	ParentID    *uuid.UUID `json:"parent_id,omitempty" gorm:"type:uuid;index"`
+	Position    int        `json:"position" gorm:"not null;default:0"`
+	Title       string     `json:"title" gorm:"not null"`
+	Description string     `json:"description"`
+	// EndOffsetDays за сколько дней до срока сдачи задания этап должен быть завершен
+	EndOffsetDays int `json:"end_offset_days" gorm:"not null;default:0"`
+	// DurationDays длительность этапа; 0 означает, что этап начинается вместе с проектом
+	DurationDays int `json:"duration_days" gorm:"not null;default:0"`
+	// Required студенты не могут удалить созданную по узлу проблему
+	Required  bool           `json:"required" gorm:"not null;default:false"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+
+	Task     *Task              `json:"-" gorm:"foreignKey:TaskID;constraint:OnDelete:CASCADE"`
+	Children []*ProblemTemplate `json:"children" gorm:"foreignKey:ParentID;constraint:OnDelete:CASCADE"`
+}
+
+func (t *ProblemTemplate) TableName() string {
+	return "problem_templates"
+}
+
+// Schedule сроки этапа внутри главной проблемы проекта
+func (t *ProblemTemplate) Schedule(projectStart, projectEnd time.Time) (time.Time, time.Time) {
+	const day = 24 * time.Hour
+
+	end := projectEnd.Add(-time.Duration(t.EndOffsetDays) * day)
+	if end.Before(projectStart) {
+		end = projectStart
+	}
+	start := projectStart
+	if t.DurationDays > 0 {
+		start = end.Add(-time.Duration(t.DurationDays) * day)
+		if start.Before(projectStart) {
+			start = projectStart
+		}
+	}
+	return start, end
+}
+
+// ProblemTemplateNode узел дерева в запросе; ID указывается для уже существующих узлов,
+// чтобы проблемы проектов, созданные по ним, остались связаны с шаблоном
+type ProblemTemplateNode struct {
+	ID            *uuid.UUID            `json:"id"`
+	Title         string                `json:"title" validate:"required,max=255"`
+	Description   string                `json:"description"`
+	EndOffsetDays int                   `json:"end_offset_days" validate:"min=0"`
+	DurationDays  int                   `json:"duration_days" validate:"min=0"`
+	Required      bool                  `json:"required"`
+	Children      []ProblemTemplateNode `json:"children" validate:"dive"`
+}
+
+// SetProblemTemplatesRequest заменяет шаблон задания целиком
+type SetProblemTemplatesRequest struct {
+	Problems []ProblemTemplateNode `json:"problems" validate:"dive"`
+}
+
+// TemplateSyncReport итог переноса изменений шаблона в существующие проекты
+type TemplateSyncReport struct {
+	Projects int `json:"projects"`
+	Created  int `json:"created"`
+	Updated  int `json:"updated"`
+	Detached int `json:"detached"`
+	// Locked сданные и архивные проекты, которые не менялись
+	Locked int `json:"locked"`
+}
@@ -15,21 +15,30 @@ const (
 )
 
 type Project struct {
-	ID          uuid.UUID      `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
-	TaskID      uuid.UUID      `json:"task_id" gorm:"type:uuid;not null;index"`
-	CreatorID   uuid.UUID      `json:"creator_id" gorm:"type:uuid;not null;index"`
-	Title       string         `json:"title" gorm:"not null"`
-	Description string         `json:"description"`
-	Code        string         `json:"code" gorm:"uniqueIndex;not null"`
-	CreatedAt   time.Time      `json:"created_at"`
-	UpdatedAt   time.Time      `json:"updated_at"`
-	DeletedAt   gorm.DeletedAt `json:"-" gorm:"index"`
+	ID               uuid.UUID      `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	TaskID           uuid.UUID      `json:"task_id" gorm:"type:uuid;not null;index"`
+	CreatorID        uuid.UUID      `json:"creator_id" gorm:"type:uuid;not null;index"`
+	Title            string         `json:"title" gorm:"not null"`
+	Description      string         `json:"description"`
+	Code             string         `json:"code" gorm:"uniqueIndex;not null"`
+	RequiresApproval bool           `json:"requires_approval" gorm:"not null;default:false"`
+	Status           ProjectStatus  `json:"status" gorm:"type:varchar(20);not null;default:'active'"`
+	SubmittedAt      *time.Time     `json:"submitted_at,omitempty"`
+	ArchivedAt       *time.Time     `json:"archived_at,omitempty" gorm:"index"`
+	CreatedAt        time.Time      `json:"created_at"`
+	UpdatedAt        time.Time      `json:"updated_at"`
+	DeletedAt        gorm.DeletedAt `json:"-" gorm:"index"`
 
 	Task    *Task            `json:"task" gorm:"foreignKey:TaskID;constraint:OnDelete:CASCADE"`
 	Creator *User            `json:"creator" gorm:"foreignKey:CreatorID;constraint:OnDelete:RESTRICT"`
 	Members []*ProjectMember `json:"members" gorm:"foreignKey:ProjectID;constraint:OnDelete:CASCADE"`
 }
 
+// IsArchived проект в архиве: команда закрыта, проблемы и результаты доступны только для чтения
+func (p *Project) IsArchived() bool {
+	return p.ArchivedAt != nil
+}
+
 type ProjectMember struct {
 	ID        uuid.UUID      `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
 	ProjectID uuid.UUID      `json:"project_id" gorm:"type:uuid;not null;index:idx_project_user,unique"`
@@ -44,10 +53,20 @@ type ProjectMember struct {
 }
 
 type CreateProjectRequest struct {
-	Title       string `json:"title" validate:"required"`
-	Description string `json:"description"`
+	Title            string `json:"title" validate:"required"`
+	Description      string `json:"description"`
+	RequiresApproval bool   `json:"requires_approval"`
 }
 
 type JoinProjectRequest struct {
 	Code string `json:"code" validate:"required"`
 }
+
+type UpdateProjectRequest struct {
+	Title       *string `json:"title" validate:"omitempty,min=1,max=255"`
+	Description *string `json:"description"`
+}
+
+type TransferProjectRequest struct {
+	UserID uuid.UUID `json:"user_id" validate:"required"`
+}
@@ -8,11 +8,15 @@ import (
 )
 
 type Result struct {
-	ID        uuid.UUID      `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
-	ProblemID uuid.UUID      `json:"problem_id" gorm:"type:uuid;not null;uniqueIndex"`
-	CreatorID uuid.UUID      `json:"creator_id" gorm:"type:uuid;not null;index"`
-	Done      bool           `json:"done" gorm:"not null;default:true"`
-	Comment   string         `json:"comment"`
+	ID        uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	ProblemID uuid.UUID `json:"problem_id" gorm:"type:uuid;not null;uniqueIndex"`
+	CreatorID uuid.UUID `json:"creator_id" gorm:"type:uuid;not null;index"`
+	Done      bool      `json:"done" gorm:"not null;default:true"`
+	Comment   string    `json:"comment"`
+	// Late, DaysLate и Penalty фиксируют опоздание по политике задания на момент публикации
+	Late      bool           `json:"late" gorm:"not null;default:false"`
+	DaysLate  int            `json:"days_late" gorm:"not null;default:0"`
+	Penalty   float64        `json:"penalty" gorm:"not null;default:0"`
 	CreatedAt time.Time      `json:"created_at"`
 	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
 
@@ -30,5 +34,22 @@ type ResultResponse struct {
 	CreatorID uuid.UUID `json:"creator_id"`
 	Done      bool      `json:"done"`
 	Comment   string    `json:"comment"`
+	Late      bool      `json:"late"`
+	DaysLate  int       `json:"days_late"`
+	Penalty   float64   `json:"penalty"`
 	CreatedAt time.Time `json:"created_at"`
 }
+
+func NewResultResponse(r *Result) *ResultResponse {
+	return &ResultResponse{
+		ID:        r.ID,
+		ProblemID: r.ProblemID,
+		CreatorID: r.CreatorID,
+		Done:      r.Done,
+		Comment:   r.Comment,
+		Late:      r.Late,
+		DaysLate:  r.DaysLate,
+		Penalty:   r.Penalty,
+		CreatedAt: r.CreatedAt,
+	}
+}
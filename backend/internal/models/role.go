@@ -24,6 +24,12 @@ type Role struct {
 	UpdatedAt time.Time      `json:"updated_at"`
 	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
 
+	// индивидуальные отличия от прав роли по умолчанию
+	GrantedPermissions []Permission `json:"granted_permissions" gorm:"serializer:json;type:jsonb"`
+	RevokedPermissions []Permission `json:"revoked_permissions" gorm:"serializer:json;type:jsonb"`
+	// Permissions итоговый набор прав, вычисляется после загрузки
+	Permissions []Permission `json:"permissions" gorm:"-"`
+
 	User    *User    `json:"user" gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
 	Subject *Subject `json:"subject" gorm:"foreignKey:SubjectID;constraint:OnDelete:CASCADE"`
 }
@@ -44,14 +50,81 @@ func (r *Role) IsAdmin() bool {
 	return r.RoleType == RoleAdmin
 }
 
+func (r *Role) AfterFind(tx *gorm.DB) error {
+	r.Permissions = r.EffectivePermissions()
+	return nil
+}
+
+// EffectivePermissions права роли по умолчанию с учетом индивидуальных выдач и отзывов
+func (r *Role) EffectivePermissions() []Permission {
+	set := make(map[Permission]bool)
+	for _, p := range DefaultPermissions[r.RoleType] {
+		set[p] = true
+	}
+	for _, p := range r.GrantedPermissions {
+		set[p] = true
+	}
+	for _, p := range r.RevokedPermissions {
+		delete(set, p)
+	}
+
+	permissions := make([]Permission, 0, len(set))
+	for _, info := range PermissionCatalogue {
+		if set[info.Key] {
+			permissions = append(permissions, info.Key)
+		}
+	}
+	return permissions
+}
+
+func (r *Role) HasPermission(permission Permission) bool {
+	for _, p := range r.EffectivePermissions() {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// SetPermissions задает итоговый набор прав, сохраняя только отличия от прав роли по умолчанию
+func (r *Role) SetPermissions(permissions []Permission) {
+	wanted := make(map[Permission]bool, len(permissions))
+	for _, p := range permissions {
+		wanted[p] = true
+	}
+	defaults := make(map[Permission]bool)
+	for _, p := range DefaultPermissions[r.RoleType] {
+		defaults[p] = true
+	}
+
+	r.GrantedPermissions = nil
+	r.RevokedPermissions = nil
+	for _, info := range PermissionCatalogue {
+		switch {
+		case wanted[info.Key] && !defaults[info.Key]:
+			r.GrantedPermissions = append(r.GrantedPermissions, info.Key)
+		case !wanted[info.Key] && defaults[info.Key]:
+			r.RevokedPermissions = append(r.RevokedPermissions, info.Key)
+		}
+	}
+	r.Permissions = r.EffectivePermissions()
+}
+
+// ResetPermissions сбрасывает индивидуальные права к набору роли по умолчанию
+func (r *Role) ResetPermissions() {
+	r.GrantedPermissions = nil
+	r.RevokedPermissions = nil
+	r.Permissions = r.EffectivePermissions()
+}
+
 type CreateRoleRequest struct {
-	UserID      uuid.UUID `json:"user_id" validate:"required"`
-	SubjectID   uuid.UUID `json:"subject_id" validate:"required"`
-	RoleType    RoleType  `json:"role_type" validate:"required"`
-	Permissions []string  `json:"permissions"`
+	UserID      uuid.UUID    `json:"user_id" validate:"required"`
+	SubjectID   uuid.UUID    `json:"subject_id" validate:"required"`
+	RoleType    RoleType     `json:"role_type" validate:"required"`
+	Permissions []Permission `json:"permissions"`
 }
 
 type UpdateRoleRequest struct {
-	RoleType    *RoleType `json:"role_type"`
-	Permissions *[]string `json:"permissions"`
+	RoleType    *RoleType     `json:"role_type"`
+	Permissions *[]Permission `json:"permissions"`
 }
@@ -0,0 +1,55 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type RosterRowStatus string
+
+const (
+	RosterRowAdded         RosterRowStatus = "added"
+	RosterRowInvited       RosterRowStatus = "invited"
+	RosterRowAlreadyMember RosterRowStatus = "already_member"
+	RosterRowError         RosterRowStatus = "error"
+)
+
+// RosterRowResult итог обработки одной строки импортируемого списка
+type RosterRowResult struct {
+	Line     int             `json:"line"`
+	Email    string          `json:"email"`
+	RoleType RoleType        `json:"role_type,omitempty"`
+	Group    string          `json:"group,omitempty"`
+	Status   RosterRowStatus `json:"status"`
+	UserID   *uuid.UUID      `json:"user_id,omitempty"`
+	InviteID *uuid.UUID      `json:"invite_id,omitempty"`
+	Error    string          `json:"error,omitempty"`
+}
+
+// RosterImportReport отчет об импорте; при DryRun изменения не сохраняются
+type RosterImportReport struct {
+	DryRun         bool               `json:"dry_run"`
+	Total          int                `json:"total"`
+	Added          int                `json:"added"`
+	Invited        int                `json:"invited"`
+	AlreadyMembers int                `json:"already_members"`
+	Errors         int                `json:"errors"`
+	Rows           []*RosterRowResult `json:"rows"`
+}
+
+// RosterEntry строка выгрузки списка участников предмета
+type RosterEntry struct {
+	UserID   uuid.UUID `json:"user_id"`
+	Email    string    `json:"email"`
+	Nickname string    `json:"nickname"`
+	RoleType RoleType  `json:"role_type"`
+	Groups   []string  `json:"groups"`
+	IsActive bool      `json:"is_active"`
+	JoinedAt time.Time `json:"joined_at"`
+}
+
+func (r *RosterRowResult) Fail(message string) {
+	r.Status = RosterRowError
+	r.Error = message
+}
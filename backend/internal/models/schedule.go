@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// OutOfWindowProblem проблема, которая после переноса срока вышла за окно главной проблемы проекта
+type OutOfWindowProblem struct {
+	ProjectID    uuid.UUID `json:"project_id"`
+	ProjectTitle string    `json:"project_title"`
+	ProblemID    uuid.UUID `json:"problem_id"`
+	Number       int       `json:"number"`
+	Title        string    `json:"title"`
+	StartTime    time.Time `json:"start_time"`
+	EndTime      time.Time `json:"end_time"`
+	WindowStart  time.Time `json:"window_start"`
+	WindowEnd    time.Time `json:"window_end"`
+}
+
+// ScheduleImpact последствия изменения срока задания для уже созданных проектов
+type ScheduleImpact struct {
+	// Rescheduled число проектов, у которых сдвинулось окончание главной проблемы
+	Rescheduled int                   `json:"rescheduled"`
+	OutOfWindow []*OutOfWindowProblem `json:"out_of_window"`
+}
@@ -0,0 +1,54 @@
+package models
+
+import "github.com/google/uuid"
+
+type SearchResultType string
+
+const (
+	SearchSubject SearchResultType = "subject"
+	SearchTask    SearchResultType = "task"
+	SearchProject SearchResultType = "project"
+	SearchProblem SearchResultType = "problem"
+)
+
+// SearchResult найденный объект; Snippet содержит фрагмент текста с совпадениями в <mark>
+type SearchResult struct {
+	Type      SearchResultType `json:"type"`
+	ID        uuid.UUID        `json:"id"`
+	SubjectID uuid.UUID        `json:"subject_id"`
+	TaskID    *uuid.UUID       `json:"task_id,omitempty"`
+	ProjectID *uuid.UUID       `json:"project_id,omitempty"`
+	Title     string           `json:"title"`
+	Snippet   string           `json:"snippet"`
+	Rank      float64          `json:"rank"`
+}
+
+// SearchFilter параметры поиска вместе с тем, что вызывающему разрешено видеть
+type SearchFilter struct {
+	Query     string
+	Types     []SearchResultType
+	SubjectID *uuid.UUID
+	// ViewerID nil для анонима: доступны только публичные предметы и их задания
+	ViewerID *uuid.UUID
+	// All супер-администратор видит все
+	All bool
+	// ProjectSubjectIDs предметы, где вызывающий видит все проекты (projects.view_all)
+	ProjectSubjectIDs []uuid.UUID
+	// TaskSubjectIDs предметы, где вызывающий ведет задания и видит неопубликованные
+	TaskSubjectIDs []uuid.UUID
+	Limit          int
+	Offset         int
+}
+
+// HasType пустой список типов означает поиск по всем
+func (f *SearchFilter) HasType(t SearchResultType) bool {
+	if len(f.Types) == 0 {
+		return true
+	}
+	for _, ft := range f.Types {
+		if ft == t {
+			return true
+		}
+	}
+	return false
+}
@@ -7,26 +7,80 @@ import (
 	"gorm.io/gorm"
 )
 
+// SubjectVisibility кому виден предмет вне круга его участников
+type SubjectVisibility string
+
+const (
+	// VisibilityPublic предмет в общем каталоге, задания и участники видны всем
+	VisibilityPublic SubjectVisibility = "public"
+	// VisibilityUnlisted карточка предмета открывается по ссылке, содержимое только участникам
+	VisibilityUnlisted SubjectVisibility = "unlisted"
+	// VisibilityPrivate предмет виден только участникам
+	VisibilityPrivate SubjectVisibility = "private"
+)
+
 type Subject struct {
-	ID          uuid.UUID      `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
-	Name        string         `json:"name" gorm:"not null;index"`
-	Description string         `json:"description"`
-	Code        string         `json:"code" gorm:"uniqueIndex;not null"`
-	CreatedAt   time.Time      `json:"created_at"`
-	UpdatedAt   time.Time      `json:"updated_at"`
-	DeletedAt   gorm.DeletedAt `json:"-" gorm:"index"`
+	ID               uuid.UUID         `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	Name             string            `json:"name" gorm:"not null;index"`
+	Description      string            `json:"description"`
+	Code             string            `json:"code" gorm:"uniqueIndex;not null"`
+	OwnerID          uuid.UUID         `json:"owner_id" gorm:"type:uuid;not null;index"`
+	RequiresApproval bool              `json:"requires_approval" gorm:"not null;default:false"`
+	Visibility       SubjectVisibility `json:"visibility" gorm:"type:varchar(20);not null;default:private;index"`
+	TermName         string            `json:"term_name"`
+	TermStart        *time.Time        `json:"term_start"`
+	TermEnd          *time.Time        `json:"term_end"`
+	ArchivedAt       *time.Time        `json:"archived_at,omitempty" gorm:"index"`
+	CreatedAt        time.Time         `json:"created_at"`
+	UpdatedAt        time.Time         `json:"updated_at"`
+	DeletedAt        gorm.DeletedAt    `json:"-" gorm:"index"`
 
 	Roles []*Role `json:"roles" gorm:"foreignKey:SubjectID;constraint:OnDelete:CASCADE"`
 	Tasks []*Task `json:"tasks" gorm:"foreignKey:SubjectID;constraint:OnDelete:CASCADE"`
 }
 
+// IsArchived архивный предмет доступен только для чтения
+func (s *Subject) IsArchived() bool {
+	return s.ArchivedAt != nil
+}
+
 type CreateSubjectRequest struct {
-	Name        string `json:"name" validate:"required"`
-	Description string `json:"description"`
-	Code        string `json:"code" validate:"required"`
+	Name             string            `json:"name" validate:"required"`
+	Description      string            `json:"description"`
+	Code             string            `json:"code" validate:"required"`
+	RequiresApproval bool              `json:"requires_approval"`
+	Visibility       SubjectVisibility `json:"visibility" validate:"omitempty,oneof=public unlisted private"`
+	TermName         string            `json:"term_name"`
+	TermStart        *time.Time        `json:"term_start"`
+	TermEnd          *time.Time        `json:"term_end"`
 }
 
 type UpdateSubjectRequest struct {
-	Name        *string `json:"name"`
-	Description *string `json:"description"`
+	Name             *string            `json:"name"`
+	Description      *string            `json:"description"`
+	RequiresApproval *bool              `json:"requires_approval"`
+	Visibility       *SubjectVisibility `json:"visibility" validate:"omitempty,oneof=public unlisted private"`
+	TermName         *string            `json:"term_name"`
+	TermStart        *time.Time         `json:"term_start"`
+	TermEnd          *time.Time         `json:"term_end"`
+}
+
+// SubjectListFilter фильтр каталога предметов. Без All в выдачу попадают публичные предметы
+// и предметы, в которых состоит ViewerID.
+type SubjectListFilter struct {
+	ViewerID        *uuid.UUID
+	All             bool
+	IncludeArchived bool
+	Limit           int
+	Offset          int
+}
+
+// CloneSubjectRequest копия предмета в новый семестр: описание и задания без участников и проектов
+type CloneSubjectRequest struct {
+	Name        string     `json:"name" validate:"required"`
+	Code        string     `json:"code" validate:"required"`
+	Description *string    `json:"description"`
+	TermName    string     `json:"term_name"`
+	TermStart   *time.Time `json:"term_start"`
+	TermEnd     *time.Time `json:"term_end"`
 }
@@ -0,0 +1,122 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ProjectStatus этап жизненного цикла проекта
+type ProjectStatus string
+
+const (
+	ProjectActive    ProjectStatus = "active"
+	ProjectSubmitted ProjectStatus = "submitted"
+	// ProjectUnderReview преподаватель взял сданный проект на проверку
+	ProjectUnderReview ProjectStatus = "under_review"
+	ProjectGraded      ProjectStatus = "graded"
+	// ProjectReopened преподаватель вернул проект на доработку
+	ProjectReopened ProjectStatus = "reopened"
+)
+
+// LockedProjectStatuses статусы сданного проекта, см. IsLocked
+var LockedProjectStatuses = []ProjectStatus{ProjectSubmitted, ProjectUnderReview, ProjectGraded}
+
+// IsLocked сданный проект доступен участникам только для чтения, пока его не вернут на доработку
+func (s ProjectStatus) IsLocked() bool {
+	for _, locked := range LockedProjectStatuses {
+		if s == locked {
+			return true
+		}
+	}
+	return false
+}
+
+// ProjectSubmission сдача проекта: снимок дерева проблем и результатов на момент сдачи.
+// Каждая повторная сдача после возврата на доработку создает новую попытку.
+type ProjectSubmission struct {
+	ID            uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	ProjectID     uuid.UUID `json:"project_id" gorm:"type:uuid;not null;uniqueIndex:idx_submission_project_attempt"`
+	Attempt       int       `json:"attempt" gorm:"not null;uniqueIndex:idx_submission_project_attempt"`
+	SubmittedByID uuid.UUID `json:"submitted_by_id" gorm:"type:uuid;not null"`
+	Comment       string    `json:"comment"`
+	// Late, DaysLate и Penalty опоздание сдачи по политике задания относительно срока команды
+	Late     bool               `json:"late" gorm:"not null;default:false"`
+	DaysLate int                `json:"days_late" gorm:"not null;default:0"`
+	Penalty  float64            `json:"penalty" gorm:"not null;default:0"`
+	Problems []*SnapshotProblem `json:"problems" gorm:"serializer:json;type:jsonb"`
+	// ReturnedAt заполняется, когда преподаватель возвращает эту попытку на доработку
+	ReturnedAt    *time.Time `json:"returned_at,omitempty"`
+	ReturnedByID  *uuid.UUID `json:"returned_by_id,omitempty" gorm:"type:uuid"`
+	ReturnComment string     `json:"return_comment,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+
+	Project     *Project `json:"-" gorm:"foreignKey:ProjectID;constraint:OnDelete:CASCADE"`
+	SubmittedBy *User    `json:"submitted_by,omitempty" gorm:"foreignKey:SubmittedByID;constraint:OnDelete:RESTRICT"`
+}
+
+// SnapshotProblem проблема в снимке сдачи; дерево восстанавливается по ParentID
+type SnapshotProblem struct {
+	ID          uuid.UUID       `json:"id"`
+	ParentID    *uuid.UUID      `json:"parent_id,omitempty"`
+	Number      int             `json:"number"`
+	Title       string          `json:"title"`
+	Description string          `json:"description"`
+	StartTime   time.Time       `json:"start_time"`
+	EndTime     time.Time       `json:"end_time"`
+	Solved      bool            `json:"solved"`
+	Required    bool            `json:"required"`
+	MilestoneID *uuid.UUID      `json:"milestone_id,omitempty"`
+	CreatorID   uuid.UUID       `json:"creator_id"`
+	AssigneeIDs []uuid.UUID     `json:"assignee_ids"`
+	Result      *SnapshotResult `json:"result,omitempty"`
+}
+
+type SnapshotResult struct {
+	CreatorID uuid.UUID `json:"creator_id"`
+	Comment   string    `json:"comment"`
+	Late      bool      `json:"late"`
+	DaysLate  int       `json:"days_late"`
+	Penalty   float64   `json:"penalty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// NewSnapshotProblem копирует проблему вместе с исполнителями и результатом
+func NewSnapshotProblem(p *Problem, result *Result) *SnapshotProblem {
+	snapshot := &SnapshotProblem{
+		ID:          p.ID,
+		ParentID:    p.ParentID,
+		Number:      p.Number,
+		Title:       p.Title,
+		Description: p.Description,
+		StartTime:   p.StartTime,
+		EndTime:     p.EndTime,
+		Solved:      p.Solved,
+		Required:    p.Required,
+		MilestoneID: p.MilestoneID,
+		CreatorID:   p.CreatorID,
+		AssigneeIDs: []uuid.UUID{},
+	}
+	for _, a := range p.Assignees {
+		snapshot.AssigneeIDs = append(snapshot.AssigneeIDs, a.UserID)
+	}
+	if result != nil {
+		snapshot.Result = &SnapshotResult{
+			CreatorID: result.CreatorID,
+			Comment:   result.Comment,
+			Late:      result.Late,
+			DaysLate:  result.DaysLate,
+			Penalty:   result.Penalty,
+			CreatedAt: result.CreatedAt,
+		}
+	}
+	return snapshot
+}
+
+type SubmitProjectRequest struct {
+	Comment string `json:"comment" validate:"max=2000"`
+}
+
+type ReturnProjectRequest struct {
+	Comment string `json:"comment" validate:"required,max=2000"`
+}
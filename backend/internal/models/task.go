@@ -1,25 +1,149 @@
 package models
 
 import (
+	"math"
 	"time"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
+// TaskStatus черновик видят только преподаватели; опубликованное задание видно студентам
+// с момента PublishAt, если он задан
+type TaskStatus string
+
+const (
+	TaskDraft     TaskStatus = "draft"
+	TaskPublished TaskStatus = "published"
+)
+
+// TeamFormation кто составляет команды по заданию
+type TeamFormation string
+
+const (
+	TeamFormationSelf    TeamFormation = "self"
+	TeamFormationTeacher TeamFormation = "teacher"
+)
+
 type Task struct {
-	ID          uuid.UUID      `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
-	SubjectID   uuid.UUID      `json:"subject_id" gorm:"type:uuid;not null;index"`
-	CreatedByID uuid.UUID      `json:"created_by_id" gorm:"type:uuid;not null;index"`
-	Title       string         `json:"title" gorm:"not null"`
-	Description string         `json:"description"`
-	DueDate     *time.Time     `json:"due_date"`
-	CreatedAt   time.Time      `json:"created_at"`
-	UpdatedAt   time.Time      `json:"updated_at"`
-	DeletedAt   gorm.DeletedAt `json:"-" gorm:"index"`
+	ID          uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	SubjectID   uuid.UUID  `json:"subject_id" gorm:"type:uuid;not null;index"`
+	CreatedByID uuid.UUID  `json:"created_by_id" gorm:"type:uuid;not null;index"`
+	Title       string     `json:"title" gorm:"not null"`
+	Description string     `json:"description"`
+	DueDate     *time.Time `json:"due_date"`
+	Status      TaskStatus `json:"status" gorm:"type:varchar(20);not null;default:'published'"`
+	PublishAt   *time.Time `json:"publish_at,omitempty"`
+	// StartDate официальное начало работы; раньше него проекты по заданию не начинаются
+	StartDate *time.Time `json:"start_date,omitempty"`
+	// TeamMinSize и TeamMaxSize ограничения размера команды; nil означает без ограничения
+	TeamMinSize *int `json:"team_min_size,omitempty"`
+	TeamMaxSize *int `json:"team_max_size,omitempty"`
+	// TeamDeadline после этой даты студенты не могут создавать команды и вступать в них
+	TeamDeadline  *time.Time    `json:"team_deadline,omitempty"`
+	TeamFormation TeamFormation `json:"team_formation" gorm:"type:varchar(20);not null;default:'self'"`
+	// LateGraceHours сколько часов после срока результат еще считается сданным вовремя
+	LateGraceHours int `json:"late_grace_hours" gorm:"not null;default:0"`
+	// LatePenaltyPerDay штраф в процентах за каждый начатый день опоздания
+	LatePenaltyPerDay float64 `json:"late_penalty_per_day" gorm:"not null;default:0"`
+	// LateCutoffDays через сколько дней после срока результаты перестают приниматься; nil без ограничения
+	LateCutoffDays *int           `json:"late_cutoff_days,omitempty"`
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+	DeletedAt      gorm.DeletedAt `json:"-" gorm:"index"`
 
 	Subject   *Subject `json:"subject" gorm:"foreignKey:SubjectID;constraint:OnDelete:CASCADE"`
 	CreatedBy *User    `json:"created_by" gorm:"foreignKey:CreatedByID;constraint:OnDelete:RESTRICT"`
+	// Groups группы, которым назначено задание; пустой список означает весь предмет
+	Groups []*TaskGroup `json:"groups" gorm:"foreignKey:TaskID;constraint:OnDelete:CASCADE"`
+
+	// ScheduleImpact заполняется только в ответе на изменение срока сдачи
+	ScheduleImpact *ScheduleImpact `json:"schedule_impact,omitempty" gorm:"-"`
+}
+
+// IsPublished задание опубликовано и время публикации уже наступило
+func (t *Task) IsPublished(now time.Time) bool {
+	return t.Status == TaskPublished && (t.PublishAt == nil || !now.Before(*t.PublishAt))
+}
+
+// TeamsFormedByTeacher студенты не могут сами создавать команды и вступать в них
+func (t *Task) TeamsFormedByTeacher() bool {
+	return t.TeamFormation == TeamFormationTeacher
+}
+
+// TeamRegistrationClosed срок регистрации команд истек
+func (t *Task) TeamRegistrationClosed(now time.Time) bool {
+	return t.TeamDeadline != nil && now.After(*t.TeamDeadline)
+}
+
+// TeamIsFull в команде уже максимальное число участников
+func (t *Task) TeamIsFull(size int) bool {
+	return t.TeamMaxSize != nil && size >= *t.TeamMaxSize
+}
+
+// Lateness применяет политику опозданий задания к результату, опубликованному в at при
+// действующем сроке due. Дни опоздания считаются от срока, льготный период лишь освобождает от них.
+func (t *Task) Lateness(due *time.Time, at time.Time) LateStatus {
+	var status LateStatus
+	if due == nil || !at.After(*due) {
+		return status
+	}
+	if t.LateCutoffDays != nil && at.After(due.AddDate(0, 0, *t.LateCutoffDays)) {
+		status.Closed = true
+	}
+	if !at.After(due.Add(time.Duration(t.LateGraceHours) * time.Hour)) {
+		return status
+	}
+
+	status.Late = true
+	status.DaysLate = int(math.Ceil(at.Sub(*due).Hours() / 24))
+	status.Penalty = math.Min(100, float64(status.DaysLate)*t.LatePenaltyPerDay)
+	return status
+}
+
+// IsTargeted задание назначено только части групп предмета
+func (t *Task) IsTargeted() bool {
+	return len(t.Groups) > 0
+}
+
+// TargetsAny проверяет, назначено ли задание хотя бы одной из групп; задание для всего предмета назначено всем
+func (t *Task) TargetsAny(groupIDs []uuid.UUID) bool {
+	if !t.IsTargeted() {
+		return true
+	}
+	for _, tg := range t.Groups {
+		for _, id := range groupIDs {
+			if tg.GroupID == id {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// GroupFor группа задания, в которой состоит пользователь; nil для задания всего предмета
+func (t *Task) GroupFor(userGroupIDs []uuid.UUID) *uuid.UUID {
+	for _, tg := range t.Groups {
+		for _, id := range userGroupIDs {
+			if tg.GroupID == id {
+				groupID := id
+				return &groupID
+			}
+		}
+	}
+	return nil
+}
+
+// DueDateFor срок сдачи для группы: собственный срок группы или общий срок задания
+func (t *Task) DueDateFor(groupID *uuid.UUID) *time.Time {
+	if groupID != nil {
+		for _, tg := range t.Groups {
+			if tg.GroupID == *groupID && tg.DueDate != nil {
+				return tg.DueDate
+			}
+		}
+	}
+	return t.DueDate
 }
 
 type CreateTaskRequest struct {
@@ -27,10 +151,46 @@ type CreateTaskRequest struct {
 	Title       string     `json:"title" validate:"required"`
 	Description string     `json:"description"`
 	DueDate     *time.Time `json:"due_date"`
+	StartDate   *time.Time `json:"start_date"`
+	// Draft создать черновик; иначе задание публикуется сразу или в PublishAt
+	Draft     bool              `json:"draft"`
+	PublishAt *time.Time        `json:"publish_at"`
+	Groups    []TaskGroupTarget `json:"groups" validate:"dive"`
+	TeamSettings
+	LatePolicy
 }
 
 type UpdateTaskRequest struct {
 	Title       *string    `json:"title"`
 	Description *string    `json:"description"`
 	DueDate     *time.Time `json:"due_date"`
+	// ClearDueDate снимает срок сдачи; проекты получают окно до конца семестра
+	ClearDueDate bool       `json:"clear_due_date"`
+	StartDate    *time.Time `json:"start_date"`
+	// Groups заменяет список групп; пустой список снимает ограничение
+	Groups *[]TaskGroupTarget `json:"groups"`
+	TeamSettings
+	LatePolicy
+	// ClearLateCutoff снова принимает результаты без ограничения по времени
+	ClearLateCutoff bool `json:"clear_late_cutoff"`
+}
+
+// PublishTaskRequest публикует задание сейчас или в назначенное время
+type PublishTaskRequest struct {
+	PublishAt *time.Time `json:"publish_at"`
+}
+
+// TeamSettings правила формирования команд; в запросе на изменение нулевой размер снимает ограничение
+type TeamSettings struct {
+	TeamMinSize   *int          `json:"team_min_size" validate:"omitempty,min=0"`
+	TeamMaxSize   *int          `json:"team_max_size" validate:"omitempty,min=0"`
+	TeamDeadline  *time.Time    `json:"team_deadline"`
+	TeamFormation TeamFormation `json:"team_formation" validate:"omitempty,oneof=self teacher"`
+}
+
+// LatePolicy политика опозданий: льготный период, штраф за день и жесткое закрытие приема
+type LatePolicy struct {
+	LateGraceHours    *int     `json:"late_grace_hours" validate:"omitempty,min=0"`
+	LatePenaltyPerDay *float64 `json:"late_penalty_per_day" validate:"omitempty,min=0,max=100"`
+	LateCutoffDays    *int     `json:"late_cutoff_days" validate:"omitempty,min=0"`
 }
@@ -0,0 +1,49 @@
+package models
+
+import "github.com/google/uuid"
+
+// TeamFormationMode способ автоматического распределения студентов по командам
+type TeamFormationMode string
+
+const (
+	// TeamFormationRandom студенты без команды распределяются по новым командам случайно
+	TeamFormationRandom TeamFormationMode = "random"
+	// TeamFormationBalanced сначала дополняются неполные команды, затем создаются новые
+	// так, чтобы размеры команд различались не больше чем на одного человека
+	TeamFormationBalanced TeamFormationMode = "balanced"
+)
+
+// CreateTeamRequest команда, которую составляет преподаватель; первый участник становится ее создателем
+type CreateTeamRequest struct {
+	Title       string      `json:"title" validate:"required"`
+	Description string      `json:"description"`
+	MemberIDs   []uuid.UUID `json:"member_ids" validate:"required,min=1"`
+}
+
+type MoveTeamMemberRequest struct {
+	UserID uuid.UUID `json:"user_id" validate:"required"`
+	// ProjectID команда назначения; без нее студент просто исключается из своей команды
+	ProjectID *uuid.UUID `json:"project_id"`
+}
+
+type AutoFormTeamsRequest struct {
+	Mode TeamFormationMode `json:"mode" validate:"required,oneof=random balanced"`
+	// TeamSize желаемый размер команды; по умолчанию максимальный размер из настроек задания
+	TeamSize int `json:"team_size" validate:"omitempty,min=1"`
+}
+
+type AutoFormTeamsResult struct {
+	Created  []*Project `json:"created"`
+	Assigned int        `json:"assigned"`
+}
+
+type UnassignedStudent struct {
+	User   *User    `json:"user"`
+	Groups []string `json:"groups"`
+}
+
+// TeamReport состояние команд по заданию: кто остался без команды и какие команды меньше минимума
+type TeamReport struct {
+	Unassigned []*UnassignedStudent `json:"unassigned"`
+	Undersized []*Project           `json:"undersized"`
+}
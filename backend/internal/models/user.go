@@ -10,15 +10,25 @@ import (
 
 type User struct {
 	ID           uuid.UUID      `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
-	Email        string         `json:"email" gorm:"uniqueIndex;not null"`
+	Email        string         `json:"email,omitempty" gorm:"uniqueIndex;not null"`
 	PasswordHash string         `json:"-" gorm:"not null"`
 	Nickname     string         `json:"nickname" gorm:"not null"`
 	IsActive     bool           `json:"is_active" gorm:"default:true"`
+	IsSuperAdmin bool           `json:"is_super_admin" gorm:"not null;default:false"`
 	CreatedAt    time.Time      `json:"created_at"`
 	UpdatedAt    time.Time      `json:"updated_at"`
 	DeletedAt    gorm.DeletedAt `json:"-" gorm:"index"`
 
-	Roles []*Role `json:"roles" gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+	Roles []*Role `json:"roles,omitempty" gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+}
+
+// UserCard публичная карточка пользователя без ролей; email виден только участникам общих предметов
+type UserCard struct {
+	ID        uuid.UUID `json:"id"`
+	Nickname  string    `json:"nickname"`
+	Email     string    `json:"email,omitempty"`
+	IsActive  bool      `json:"is_active"`
+	CreatedAt time.Time `json:"created_at"`
 }
 
 type CreateUserRequest struct {
@@ -32,6 +42,24 @@ type LoginRequest struct {
 	Password string `json:"password" validate:"required"`
 }
 
+type UpdateProfileRequest struct {
+	Nickname *string `json:"nickname" validate:"omitempty,min=1,max=64"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" validate:"required"`
+	NewPassword     string `json:"new_password" validate:"required,min=6"`
+}
+
+type ChangeEmailRequest struct {
+	Password string `json:"password" validate:"required"`
+	NewEmail string `json:"new_email" validate:"required,email"`
+}
+
+type DeactivateAccountRequest struct {
+	Password string `json:"password" validate:"required"`
+}
+
 type AuthResponse struct {
 	User         *User  `json:"user"`
 	AccessToken  string `json:"access_token"`
@@ -70,3 +98,16 @@ func (u *User) CheckPassword(password string) bool {
 	err := bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte(password))
 	return err == nil
 }
+
+func (u *User) Card(withEmail bool) *UserCard {
+	card := &UserCard{
+		ID:        u.ID,
+		Nickname:  u.Nickname,
+		IsActive:  u.IsActive,
+		CreatedAt: u.CreatedAt,
+	}
+	if withEmail {
+		card.Email = u.Email
+	}
+	return card
+}
@@ -0,0 +1,239 @@
+package policy
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/wozhdeleniye/redclass-app/internal/models"
+)
+
+var ErrForbidden = errors.New("forbidden")
+
+// ForbiddenError отказ политики доступа; errors.Is(err, ErrForbidden) == true
+type ForbiddenError struct {
+	Resource Resource
+	Action   Action
+	Reason   string
+}
+
+func (e *ForbiddenError) Error() string {
+	if e.Reason != "" {
+		return fmt.Sprintf("forbidden: not allowed to %s %s: %s", e.Action, e.Resource, e.Reason)
+	}
+	return fmt.Sprintf("forbidden: not allowed to %s %s", e.Action, e.Resource)
+}
+
+func (e *ForbiddenError) Unwrap() error {
+	return ErrForbidden
+}
+
+type Resource string
+
+const (
+	ResourceSubject Resource = "subject"
+	ResourceMember  Resource = "member"
+	ResourceTask    Resource = "task"
+	ResourceProject Resource = "project"
+	ResourceProblem Resource = "problem"
+	ResourceResult  Resource = "result"
+	// ResourceGrade оценки проектов и участников; неопубликованные видят только оценивающие
+	ResourceGrade Resource = "grade"
+	// ResourceJoinRequest заявки на вступление в предмет; заявки в проект решает его создатель
+	ResourceJoinRequest Resource = "join_request"
+)
+
+type Action string
+
+const (
+	ActionView   Action = "view"
+	ActionCreate Action = "create"
+	ActionUpdate Action = "update"
+	ActionDelete Action = "delete"
+)
+
+// Role роль субъекта доступа по отношению к конкретному ресурсу
+type Role string
+
+const (
+	RoleSuperAdmin     Role = "super_admin"
+	RoleSubjectAdmin   Role = "subject_admin"
+	RoleTeacher        Role = "teacher"
+	RoleStudent        Role = "student"
+	RoleProjectCreator Role = "project_creator"
+	RoleProjectMember  Role = "project_member"
+	// RoleOwner автор конкретного ресурса (задания, проблемы)
+	RoleOwner Role = "owner"
+	// RoleAssignee исполнитель проблемы
+	RoleAssignee Role = "assignee"
+)
+
+// Principal пользователь вместе с ролями и правами относительно проверяемого ресурса
+type Principal struct {
+	UserID      uuid.UUID
+	Roles       map[Role]bool
+	Permissions map[models.Permission]bool
+	// ReadOnly предмет в архиве: изменять учебные ресурсы нельзя никому
+	ReadOnly bool
+	// Locked проект сдан: его проблемы и результаты заморожены до возврата на доработку
+	Locked bool
+	// Archived проект в архиве: проблемы и результаты не меняются, пока его не вернут из архива
+	Archived bool
+}
+
+func NewPrincipal(userID uuid.UUID, roles ...Role) *Principal {
+	p := &Principal{
+		UserID:      userID,
+		Roles:       make(map[Role]bool, len(roles)),
+		Permissions: make(map[models.Permission]bool),
+	}
+	for _, r := range roles {
+		p.Grant(r)
+	}
+	return p
+}
+
+func (p *Principal) Grant(role Role) {
+	p.Roles[role] = true
+}
+
+func (p *Principal) Has(role Role) bool {
+	return p.Roles[role]
+}
+
+func (p *Principal) GrantPermissions(permissions ...models.Permission) {
+	for _, perm := range permissions {
+		p.Permissions[perm] = true
+	}
+}
+
+func (p *Principal) HasPermission(permission models.Permission) bool {
+	return p.Permissions[permission]
+}
+
+// rule действие разрешено, если у субъекта есть хотя бы одна из ролей или хотя бы одно из прав
+type rule struct {
+	roles       []Role
+	permissions []models.Permission
+}
+
+func allow(roles ...Role) rule {
+	return rule{roles: roles}
+}
+
+func (r rule) or(permissions ...models.Permission) rule {
+	r.permissions = append(r.permissions, permissions...)
+	return r
+}
+
+// rules таблица политик. Права внутри предмета задаются через models.Permission,
+// роли в проекте и авторство ресурса через Role. Супер-администратору разрешено все.
+var rules = map[Resource]map[Action]rule{
+	ResourceSubject: {
+		ActionUpdate: allow().or(models.PermSubjectUpdate),
+		ActionDelete: allow().or(models.PermSubjectDelete),
+	},
+	ResourceMember: {
+		ActionView:   allow().or(models.PermMembersView, models.PermMembersManage),
+		ActionCreate: allow().or(models.PermMembersManage),
+		ActionUpdate: allow().or(models.PermMembersManage),
+		ActionDelete: allow().or(models.PermMembersManage),
+	},
+	ResourceJoinRequest: {
+		ActionView:   allow().or(models.PermMembersApprove, models.PermMembersManage),
+		ActionUpdate: allow().or(models.PermMembersApprove, models.PermMembersManage),
+	},
+	ResourceTask: {
+		ActionCreate: allow().or(models.PermTasksCreate),
+		ActionUpdate: allow(RoleOwner).or(models.PermTasksManage),
+		ActionDelete: allow(RoleOwner).or(models.PermTasksManage),
+	},
+	ResourceProject: {
+		ActionView:   allow(RoleProjectMember, RoleProjectCreator).or(models.PermProjectsViewAll),
+		ActionCreate: allow().or(models.PermProjectsCreate),
+		ActionUpdate: allow(RoleProjectCreator),
+		ActionDelete: allow(RoleProjectCreator),
+	},
+	ResourceProblem: {
+		ActionView:   allow(RoleProjectMember, RoleProjectCreator).or(models.PermProjectsViewAll),
+		ActionCreate: allow(RoleProjectMember, RoleProjectCreator),
+		ActionUpdate: allow(RoleOwner, RoleProjectCreator, RoleAssignee),
+		ActionDelete: allow(RoleOwner, RoleProjectCreator),
+	},
+	ResourceResult: {
+		ActionView:   allow(RoleProjectMember, RoleProjectCreator).or(models.PermProjectsViewAll),
+		ActionCreate: allow(RoleProjectMember, RoleProjectCreator),
+	},
+	ResourceGrade: {
+		ActionView:   allow().or(models.PermGrade),
+		ActionCreate: allow().or(models.PermGrade),
+		ActionUpdate: allow().or(models.PermGrade),
+	},
+}
+
+// frozen ресурсы, которые в архивном предмете доступны только для чтения
+var frozen = map[Resource]bool{
+	ResourceTask:    true,
+	ResourceProject: true,
+	ResourceProblem: true,
+	ResourceResult:  true,
+	ResourceGrade:   true,
+}
+
+// locked ресурсы сданного или архивного проекта, которые нельзя менять
+var locked = map[Resource]bool{
+	ResourceProblem: true,
+	ResourceResult:  true,
+}
+
+// Can проверяет, разрешено ли principal выполнить action над resource
+func Can(p *Principal, resource Resource, action Action) bool {
+	if p == nil {
+		return false
+	}
+	if isFrozen(p, resource, action) || isLocked(p, resource, action) {
+		return false
+	}
+	if p.Has(RoleSuperAdmin) {
+		return true
+	}
+
+	r := rules[resource][action]
+	for _, role := range r.roles {
+		if p.Has(role) {
+			return true
+		}
+	}
+	for _, perm := range r.permissions {
+		if p.HasPermission(perm) {
+			return true
+		}
+	}
+	return false
+}
+
+// Authorize возвращает *ForbiddenError, если действие запрещено
+func Authorize(p *Principal, resource Resource, action Action) error {
+	if isFrozen(p, resource, action) {
+		return &ForbiddenError{Resource: resource, Action: action, Reason: "subject is archived"}
+	}
+	if isLocked(p, resource, action) {
+		reason := "project is submitted"
+		if p.Archived {
+			reason = "project is archived"
+		}
+		return &ForbiddenError{Resource: resource, Action: action, Reason: reason}
+	}
+	if !Can(p, resource, action) {
+		return &ForbiddenError{Resource: resource, Action: action}
+	}
+	return nil
+}
+
+func isFrozen(p *Principal, resource Resource, action Action) bool {
+	return p != nil && p.ReadOnly && action != ActionView && frozen[resource]
+}
+
+func isLocked(p *Principal, resource Resource, action Action) bool {
+	return p != nil && (p.Locked || p.Archived) && action != ActionView && locked[resource]
+}
@@ -0,0 +1,237 @@
+package policy
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/wozhdeleniye/redclass-app/internal/models"
+)
+
+var (
+	allResources = []Resource{
+		ResourceSubject, ResourceMember, ResourceTask, ResourceProject,
+		ResourceProblem, ResourceResult, ResourceGrade, ResourceJoinRequest,
+	}
+	allActions = []Action{ActionView, ActionCreate, ActionUpdate, ActionDelete}
+	// allRoles роли, которые проверяются по таблице; супер-администратор проверяется отдельно
+	allRoles = []Role{
+		RoleSubjectAdmin, RoleTeacher, RoleStudent, RoleProjectCreator,
+		RoleProjectMember, RoleOwner, RoleAssignee,
+	}
+)
+
+type grant struct {
+	roles       []Role
+	permissions []models.Permission
+}
+
+// expected ожидаемая таблица политик; пара ресурс-действие без записи запрещена всем,
+// кроме супер-администратора
+var expected = map[Resource]map[Action]grant{
+	ResourceSubject: {
+		ActionUpdate: {permissions: []models.Permission{models.PermSubjectUpdate}},
+		ActionDelete: {permissions: []models.Permission{models.PermSubjectDelete}},
+	},
+	ResourceMember: {
+		ActionView:   {permissions: []models.Permission{models.PermMembersView, models.PermMembersManage}},
+		ActionCreate: {permissions: []models.Permission{models.PermMembersManage}},
+		ActionUpdate: {permissions: []models.Permission{models.PermMembersManage}},
+		ActionDelete: {permissions: []models.Permission{models.PermMembersManage}},
+	},
+	ResourceJoinRequest: {
+		ActionView:   {permissions: []models.Permission{models.PermMembersApprove, models.PermMembersManage}},
+		ActionUpdate: {permissions: []models.Permission{models.PermMembersApprove, models.PermMembersManage}},
+	},
+	ResourceTask: {
+		ActionCreate: {permissions: []models.Permission{models.PermTasksCreate}},
+		ActionUpdate: {roles: []Role{RoleOwner}, permissions: []models.Permission{models.PermTasksManage}},
+		ActionDelete: {roles: []Role{RoleOwner}, permissions: []models.Permission{models.PermTasksManage}},
+	},
+	ResourceProject: {
+		ActionView:   {roles: []Role{RoleProjectMember, RoleProjectCreator}, permissions: []models.Permission{models.PermProjectsViewAll}},
+		ActionCreate: {permissions: []models.Permission{models.PermProjectsCreate}},
+		ActionUpdate: {roles: []Role{RoleProjectCreator}},
+		ActionDelete: {roles: []Role{RoleProjectCreator}},
+	},
+	ResourceProblem: {
+		ActionView:   {roles: []Role{RoleProjectMember, RoleProjectCreator}, permissions: []models.Permission{models.PermProjectsViewAll}},
+		ActionCreate: {roles: []Role{RoleProjectMember, RoleProjectCreator}},
+		ActionUpdate: {roles: []Role{RoleOwner, RoleProjectCreator, RoleAssignee}},
+		ActionDelete: {roles: []Role{RoleOwner, RoleProjectCreator}},
+	},
+	ResourceResult: {
+		ActionView:   {roles: []Role{RoleProjectMember, RoleProjectCreator}, permissions: []models.Permission{models.PermProjectsViewAll}},
+		ActionCreate: {roles: []Role{RoleProjectMember, RoleProjectCreator}},
+	},
+	ResourceGrade: {
+		ActionView:   {permissions: []models.Permission{models.PermGrade}},
+		ActionCreate: {permissions: []models.Permission{models.PermGrade}},
+		ActionUpdate: {permissions: []models.Permission{models.PermGrade}},
+	},
+}
+
+func hasRole(roles []Role, r Role) bool {
+	for _, x := range roles {
+		if x == r {
+			return true
+		}
+	}
+	return false
+}
+
+func hasPermission(perms []models.Permission, p models.Permission) bool {
+	for _, x := range perms {
+		if x == p {
+			return true
+		}
+	}
+	return false
+}
+
+func TestRulesMatchExpectedTable(t *testing.T) {
+	for resource, actions := range rules {
+		for action := range actions {
+			if _, ok := expected[resource][action]; !ok {
+				t.Errorf("rules has %s/%s, which the expected table does not cover", resource, action)
+			}
+		}
+	}
+}
+
+func TestRolesPerResourceAction(t *testing.T) {
+	for _, resource := range allResources {
+		for _, action := range allActions {
+			want := expected[resource][action]
+			for _, role := range allRoles {
+				p := NewPrincipal(uuid.New(), role)
+				if got := Can(p, resource, action); got != hasRole(want.roles, role) {
+					t.Errorf("role %s: Can(%s, %s) = %v, want %v", role, resource, action, got, !got)
+				}
+			}
+		}
+	}
+}
+
+func TestPermissionsPerResourceAction(t *testing.T) {
+	for _, resource := range allResources {
+		for _, action := range allActions {
+			want := expected[resource][action]
+			for _, info := range models.PermissionCatalogue {
+				p := NewPrincipal(uuid.New())
+				p.GrantPermissions(info.Key)
+				if got := Can(p, resource, action); got != hasPermission(want.permissions, info.Key) {
+					t.Errorf("permission %s: Can(%s, %s) = %v, want %v", info.Key, resource, action, got, !got)
+				}
+			}
+		}
+	}
+}
+
+func TestNoRolesNoPermissionsDenied(t *testing.T) {
+	p := NewPrincipal(uuid.New())
+	for _, resource := range allResources {
+		for _, action := range allActions {
+			if Can(p, resource, action) {
+				t.Errorf("empty principal allowed to %s %s", action, resource)
+			}
+		}
+	}
+	if Can(nil, ResourceProject, ActionView) {
+		t.Error("nil principal allowed")
+	}
+}
+
+// everything субъект со всеми ролями и правами, чтобы отказ мог дать только заморозка
+func everything() *Principal {
+	p := NewPrincipal(uuid.New(), append([]Role{RoleSuperAdmin}, allRoles...)...)
+	for _, info := range models.PermissionCatalogue {
+		p.GrantPermissions(info.Key)
+	}
+	return p
+}
+
+func TestSuperAdminBypass(t *testing.T) {
+	p := NewPrincipal(uuid.New(), RoleSuperAdmin)
+	for _, resource := range allResources {
+		for _, action := range allActions {
+			if err := Authorize(p, resource, action); err != nil {
+				t.Errorf("super-admin denied %s %s: %v", action, resource, err)
+			}
+		}
+	}
+}
+
+func TestFrozenSubject(t *testing.T) {
+	for _, resource := range allResources {
+		for _, action := range allActions {
+			p := everything()
+			p.ReadOnly = true
+			err := Authorize(p, resource, action)
+
+			if frozen[resource] && action != ActionView {
+				if !errors.Is(err, ErrForbidden) || !strings.Contains(err.Error(), "subject is archived") {
+					t.Errorf("archived %s %s: got %v, want archived refusal", action, resource, err)
+				}
+				continue
+			}
+			if err != nil {
+				t.Errorf("archived %s %s: unexpected refusal %v", action, resource, err)
+			}
+		}
+	}
+}
+
+func TestLockedProject(t *testing.T) {
+	for _, archived := range []bool{false, true} {
+		testLockedProject(t, archived)
+	}
+}
+
+func testLockedProject(t *testing.T, archived bool) {
+	reason := "project is submitted"
+	if archived {
+		reason = "project is archived"
+	}
+	cases := []struct {
+		resource Resource
+		action   Action
+		denied   bool
+	}{
+		{ResourceProblem, ActionView, false},
+		{ResourceProblem, ActionCreate, true},
+		{ResourceProblem, ActionUpdate, true},
+		{ResourceProblem, ActionDelete, true},
+		{ResourceResult, ActionView, false},
+		{ResourceResult, ActionCreate, true},
+		{ResourceProject, ActionView, false},
+		{ResourceProject, ActionUpdate, false},
+		{ResourceGrade, ActionCreate, false},
+		{ResourceTask, ActionUpdate, false},
+	}
+	for _, tc := range cases {
+		p := everything()
+		p.Locked = !archived
+		p.Archived = archived
+		err := Authorize(p, tc.resource, tc.action)
+		if tc.denied {
+			if !errors.Is(err, ErrForbidden) || !strings.Contains(err.Error(), reason) {
+				t.Errorf("locked %s %s: got %v, want %q refusal", tc.action, tc.resource, err, reason)
+			}
+		} else if err != nil {
+			t.Errorf("locked %s %s: unexpected refusal %v", tc.action, tc.resource, err)
+		}
+	}
+}
+
+func TestForbiddenErrorUnwraps(t *testing.T) {
+	err := Authorize(NewPrincipal(uuid.New(), RoleStudent), ResourceTask, ActionCreate)
+	var fe *ForbiddenError
+	if !errors.As(err, &fe) || fe.Resource != ResourceTask || fe.Action != ActionCreate {
+		t.Fatalf("got %v, want *ForbiddenError for create task", err)
+	}
+	if !errors.Is(err, ErrForbidden) {
+		t.Error("ForbiddenError must unwrap to ErrForbidden")
+	}
+}
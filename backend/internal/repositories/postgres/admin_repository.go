@@ -0,0 +1,94 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/wozhdeleniye/redclass-app/internal/models"
+	"gorm.io/gorm"
+)
+
+type AdminRepository struct {
+	db *gorm.DB
+}
+
+func NewAdminRepository(db *gorm.DB) *AdminRepository {
+	return &AdminRepository{db: db}
+}
+
+func (r *AdminRepository) CreateAuditLog(ctx context.Context, entry *models.AdminAuditLog) error {
+	if entry.ID == uuid.Nil {
+		entry.ID = uuid.New()
+	}
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = time.Now()
+	}
+	return r.db.WithContext(ctx).Create(entry).Error
+}
+
+// GetAuditLogs получает журнал действий администраторов, опционально по целевому объекту
+func (r *AdminRepository) GetAuditLogs(ctx context.Context, targetID *uuid.UUID, limit, offset int) ([]*models.AdminAuditLog, int64, error) {
+	var logs []*models.AdminAuditLog
+	var total int64
+
+	query := r.db.WithContext(ctx).Model(&models.AdminAuditLog{})
+	if targetID != nil {
+		query = query.Where("target_id = ?", *targetID)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := query.
+		Preload("Actor", publicUserColumns).
+		Order("created_at DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&logs).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return logs, total, nil
+}
+
+// GetPlatformStatistics собирает сводную статистику использования платформы
+func (r *AdminRepository) GetPlatformStatistics(ctx context.Context) (*models.PlatformStatistics, error) {
+	stats := &models.PlatformStatistics{}
+	db := r.db.WithContext(ctx)
+	now := time.Now()
+	startOfDay := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	counts := []struct {
+		dest  *int64
+		query *gorm.DB
+	}{
+		{&stats.Users, db.Model(&models.User{})},
+		{&stats.ActiveUsers, db.Model(&models.User{}).Where("is_active = ?", true)},
+		{&stats.SuperAdmins, db.Model(&models.User{}).Where("is_super_admin = ?", true)},
+		{&stats.NewUsersLast7Days, db.Model(&models.User{}).Where("created_at >= ?", now.AddDate(0, 0, -7))},
+		{&stats.Subjects, db.Model(&models.Subject{})},
+		{&stats.OrphanedSubjects, db.Model(&models.Subject{}).
+			Where("NOT EXISTS (?)", db.Model(&models.Role{}).
+				Select("1").
+				Joins("JOIN users ON users.id = roles.user_id AND users.is_active = ?", true).
+				Where("roles.subject_id = subjects.id AND roles.role_type = ?", models.RoleAdmin))},
+		{&stats.Tasks, db.Model(&models.Task{})},
+		{&stats.Projects, db.Model(&models.Project{})},
+		{&stats.Problems, db.Model(&models.Problem{})},
+		{&stats.SolvedProblems, db.Model(&models.Problem{}).Where("solved = ?", true)},
+		{&stats.Results, db.Model(&models.Result{})},
+		{&stats.FailedLoginsToday, db.Model(&models.LoginAttempt{}).
+			Where("type = ? AND success = ? AND created_at >= ?", models.LoginAttemptLogin, false, startOfDay)},
+	}
+
+	for _, c := range counts {
+		if err := c.query.Count(c.dest).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	return stats, nil
+}
@@ -0,0 +1,105 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/wozhdeleniye/redclass-app/internal/models"
+	"gorm.io/gorm"
+)
+
+type ExtensionRepository struct {
+	db *gorm.DB
+}
+
+func NewExtensionRepository(db *gorm.DB) *ExtensionRepository {
+	return &ExtensionRepository{db: db}
+}
+
+func (r *ExtensionRepository) Create(ctx context.Context, ext *models.DeadlineExtension) error {
+	if ext.ID == uuid.Nil {
+		ext.ID = uuid.New()
+	}
+	return r.db.WithContext(ctx).Create(ext).Error
+}
+
+func (r *ExtensionRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.DeadlineExtension, error) {
+	var ext models.DeadlineExtension
+	err := r.db.WithContext(ctx).First(&ext, "id = ?", id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("extension not found")
+		}
+		return nil, err
+	}
+	return &ext, nil
+}
+
+// GetFor продление команды (userID nil) или личное продление участника проекта
+func (r *ExtensionRepository) GetFor(ctx context.Context, projectID uuid.UUID, userID *uuid.UUID) (*models.DeadlineExtension, error) {
+	query := r.db.WithContext(ctx).Where("project_id = ?", projectID)
+	if userID == nil {
+		query = query.Where("user_id IS NULL")
+	} else {
+		query = query.Where("user_id = ?", *userID)
+	}
+
+	var ext models.DeadlineExtension
+	if err := query.First(&ext).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &ext, nil
+}
+
+// GetByProject продления проекта вместе с получателями и выдавшими их преподавателями
+func (r *ExtensionRepository) GetByProject(ctx context.Context, projectID uuid.UUID) ([]*models.DeadlineExtension, error) {
+	extensions := []*models.DeadlineExtension{}
+	err := r.db.WithContext(ctx).
+		Preload("User", publicUserColumns).
+		Preload("GrantedBy", publicUserColumns).
+		Where("project_id = ?", projectID).
+		Order("created_at ASC").
+		Find(&extensions).Error
+	return extensions, err
+}
+
+// GetByTask все продления по проектам задания
+func (r *ExtensionRepository) GetByTask(ctx context.Context, taskID uuid.UUID) ([]*models.DeadlineExtension, error) {
+	extensions := []*models.DeadlineExtension{}
+	err := r.db.WithContext(ctx).
+		Preload("User", publicUserColumns).
+		Preload("GrantedBy", publicUserColumns).
+		Where("task_id = ?", taskID).
+		Order("project_id, created_at ASC").
+		Find(&extensions).Error
+	return extensions, err
+}
+
+// GetTeamExtensions продления команд по проектам задания, ключ ID проекта
+func (r *ExtensionRepository) GetTeamExtensions(ctx context.Context, taskID uuid.UUID) (map[uuid.UUID]*models.DeadlineExtension, error) {
+	var extensions []*models.DeadlineExtension
+	err := r.db.WithContext(ctx).
+		Where("task_id = ? AND user_id IS NULL", taskID).
+		Find(&extensions).Error
+	if err != nil {
+		return nil, err
+	}
+
+	byProject := make(map[uuid.UUID]*models.DeadlineExtension, len(extensions))
+	for _, ext := range extensions {
+		byProject[ext.ProjectID] = ext
+	}
+	return byProject, nil
+}
+
+func (r *ExtensionRepository) Update(ctx context.Context, ext *models.DeadlineExtension) error {
+	return r.db.WithContext(ctx).Omit("Project", "User", "GrantedBy").Save(ext).Error
+}
+
+func (r *ExtensionRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	return r.db.WithContext(ctx).Delete(&models.DeadlineExtension{}, "id = ?", id).Error
+}
@@ -0,0 +1,103 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/wozhdeleniye/redclass-app/internal/models"
+	"gorm.io/gorm"
+)
+
+type GradeRepository struct {
+	db *gorm.DB
+}
+
+func NewGradeRepository(db *gorm.DB) *GradeRepository {
+	return &GradeRepository{db: db}
+}
+
+// GetByProject оценка проекта вместе с оценками участников; nil, если проект не оценен
+func (r *GradeRepository) GetByProject(ctx context.Context, projectID uuid.UUID) (*models.ProjectGrade, error) {
+	var grade models.ProjectGrade
+	err := r.db.WithContext(ctx).
+		Preload("Members", func(db *gorm.DB) *gorm.DB { return db.Order("created_at ASC") }).
+		Preload("Members.User", publicUserColumns).
+		First(&grade, "project_id = ?", projectID).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &grade, nil
+}
+
+// Save создает или обновляет оценку проекта и оценки всех ее участников
+func (r *GradeRepository) Save(ctx context.Context, grade *models.ProjectGrade) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if grade.ID == uuid.Nil {
+			grade.ID = uuid.New()
+			if err := tx.Omit("Project", "Members").Create(grade).Error; err != nil {
+				return err
+			}
+		} else if err := tx.Omit("Project", "Members").Save(grade).Error; err != nil {
+			return err
+		}
+
+		for _, member := range grade.Members {
+			member.GradeID = grade.ID
+			if member.ID == uuid.Nil {
+				member.ID = uuid.New()
+				if err := tx.Omit("User").Create(member).Error; err != nil {
+					return err
+				}
+			} else if err := tx.Omit("User").Save(member).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// SaveMember сохраняет оценку одного участника
+func (r *GradeRepository) SaveMember(ctx context.Context, member *models.MemberGrade) error {
+	return r.db.WithContext(ctx).Omit("User").Save(member).Error
+}
+
+// CountByTask число оцененных проектов задания
+func (r *GradeRepository) CountByTask(ctx context.Context, taskID uuid.UUID) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.ProjectGrade{}).
+		Where("task_id = ?", taskID).
+		Count(&count).Error
+	return count, err
+}
+
+// Release публикует оценки проектов задания; пустой projectIDs означает все оценки задания
+func (r *GradeRepository) Release(ctx context.Context, taskID uuid.UUID, projectIDs []uuid.UUID) (int64, error) {
+	query := r.db.WithContext(ctx).Model(&models.ProjectGrade{}).
+		Where("task_id = ? AND released = ?", taskID, false)
+	if len(projectIDs) > 0 {
+		query = query.Where("project_id IN ?", projectIDs)
+	}
+	res := query.Updates(map[string]interface{}{"released": true, "released_at": time.Now()})
+	return res.RowsAffected, res.Error
+}
+
+// GetSubjectEntries индивидуальные оценки по всем заданиям предмета; при releasedOnly только опубликованные
+func (r *GradeRepository) GetSubjectEntries(ctx context.Context, subjectID uuid.UUID, releasedOnly bool) ([]*models.GradebookEntry, error) {
+	entries := []*models.GradebookEntry{}
+	query := r.db.WithContext(ctx).
+		Table("member_grades AS mg").
+		Select("g.task_id, g.project_id, mg.user_id, mg.score, g.released").
+		Joins("JOIN project_grades g ON g.id = mg.grade_id AND g.deleted_at IS NULL").
+		Joins("JOIN tasks t ON t.id = g.task_id AND t.deleted_at IS NULL").
+		Where("t.subject_id = ?", subjectID)
+	if releasedOnly {
+		query = query.Where("g.released = ?", true)
+	}
+	err := query.Scan(&entries).Error
+	return entries, err
+}
@@ -0,0 +1,226 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/wozhdeleniye/redclass-app/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type GroupRepository struct {
+	db *gorm.DB
+}
+
+func NewGroupRepository(db *gorm.DB) *GroupRepository {
+	return &GroupRepository{db: db}
+}
+
+func (r *GroupRepository) Create(ctx context.Context, group *models.Group) error {
+	if group.ID == uuid.Nil {
+		group.ID = uuid.New()
+	}
+	return r.db.WithContext(ctx).Create(group).Error
+}
+
+func (r *GroupRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Group, error) {
+	var group models.Group
+	err := r.db.WithContext(ctx).
+		Preload("Members.User", publicUserColumns).
+		First(&group, "id = ?", id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("group not found")
+		}
+		return nil, err
+	}
+	return &group, nil
+}
+
+// GetByName получает группу предмета по названию без учета регистра
+func (r *GroupRepository) GetByName(ctx context.Context, subjectID uuid.UUID, name string) (*models.Group, error) {
+	var group models.Group
+	err := r.db.WithContext(ctx).
+		Where("subject_id = ? AND LOWER(name) = LOWER(?)", subjectID, name).
+		First(&group).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &group, nil
+}
+
+func (r *GroupRepository) GetBySubject(ctx context.Context, subjectID uuid.UUID) ([]*models.Group, error) {
+	var groups []*models.Group
+	err := r.db.WithContext(ctx).
+		Preload("Members.User", publicUserColumns).
+		Where("subject_id = ?", subjectID).
+		Order("name ASC").
+		Find(&groups).Error
+	return groups, err
+}
+
+func (r *GroupRepository) Update(ctx context.Context, group *models.Group) error {
+	return r.db.WithContext(ctx).
+		Model(group).
+		Select("name", "description", "updated_at").
+		Updates(group).Error
+}
+
+func (r *GroupRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("group_id = ?", id).Delete(&models.GroupMember{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("group_id = ?", id).Delete(&models.TaskGroup{}).Error; err != nil {
+			return err
+		}
+		// удаляем без следа, чтобы название освободилось для новой группы
+		return tx.Unscoped().Delete(&models.Group{}, "id = ?", id).Error
+	})
+}
+
+// AddMembers добавляет пользователей в группу, уже состоящие пропускаются
+func (r *GroupRepository) AddMembers(ctx context.Context, groupID uuid.UUID, userIDs []uuid.UUID) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+	members := make([]*models.GroupMember, 0, len(userIDs))
+	for _, id := range userIDs {
+		members = append(members, &models.GroupMember{
+			ID:        uuid.New(),
+			GroupID:   groupID,
+			UserID:    id,
+			CreatedAt: time.Now(),
+		})
+	}
+	return r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&members).Error
+}
+
+func (r *GroupRepository) RemoveMember(ctx context.Context, groupID, userID uuid.UUID) error {
+	return r.db.WithContext(ctx).
+		Where("group_id = ? AND user_id = ?", groupID, userID).
+		Delete(&models.GroupMember{}).Error
+}
+
+// RemoveUserFromSubject исключает пользователя из всех групп предмета
+func (r *GroupRepository) RemoveUserFromSubject(ctx context.Context, subjectID, userID uuid.UUID) error {
+	return r.db.WithContext(ctx).
+		Where("user_id = ? AND group_id IN (?)", userID,
+			r.db.Model(&models.Group{}).Select("id").Where("subject_id = ?", subjectID)).
+		Delete(&models.GroupMember{}).Error
+}
+
+// GetUserGroups получает группы предмета, в которых состоит пользователь
+func (r *GroupRepository) GetUserGroups(ctx context.Context, subjectID, userID uuid.UUID) ([]*models.Group, error) {
+	var groups []*models.Group
+	err := r.db.WithContext(ctx).
+		Joins("JOIN group_members ON group_members.group_id = groups.id").
+		Where("groups.subject_id = ? AND group_members.user_id = ?", subjectID, userID).
+		Order("groups.name ASC").
+		Find(&groups).Error
+	return groups, err
+}
+
+// GetSubjectMemberships получает все членства в группах предмета вместе с группами
+func (r *GroupRepository) GetSubjectMemberships(ctx context.Context, subjectID uuid.UUID) ([]*models.GroupMember, error) {
+	var members []*models.GroupMember
+	err := r.db.WithContext(ctx).
+		Preload("Group").
+		Joins("JOIN groups ON groups.id = group_members.group_id AND groups.deleted_at IS NULL").
+		Where("groups.subject_id = ?", subjectID).
+		Order("groups.name ASC").
+		Find(&members).Error
+	return members, err
+}
+
+// GetMemberIDs получает ID участников группы
+func (r *GroupRepository) GetMemberIDs(ctx context.Context, groupID uuid.UUID) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	err := r.db.WithContext(ctx).
+		Model(&models.GroupMember{}).
+		Where("group_id = ?", groupID).
+		Pluck("user_id", &ids).Error
+	return ids, err
+}
+
+// SetTaskGroups заменяет список групп, которым назначено задание
+func (r *GroupRepository) SetTaskGroups(ctx context.Context, taskID uuid.UUID, targets []*models.TaskGroup) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("task_id = ?", taskID).Delete(&models.TaskGroup{}).Error; err != nil {
+			return err
+		}
+		if len(targets) == 0 {
+			return nil
+		}
+		return tx.Create(&targets).Error
+	})
+}
+
+// GetStatistics сводка по группе. Проект относится к группе, если его создатель состоит в ней.
+func (r *GroupRepository) GetStatistics(ctx context.Context, group *models.Group) (*models.GroupStatistics, error) {
+	stats := &models.GroupStatistics{GroupID: group.ID, Name: group.Name}
+
+	var roleCounts []struct {
+		RoleType models.RoleType
+		Count    int
+	}
+	err := r.db.WithContext(ctx).
+		Table("group_members").
+		Select("roles.role_type, COUNT(*) AS count").
+		Joins("JOIN roles ON roles.user_id = group_members.user_id AND roles.subject_id = ? AND roles.deleted_at IS NULL", group.SubjectID).
+		Where("group_members.group_id = ?", group.ID).
+		Group("roles.role_type").
+		Scan(&roleCounts).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, rc := range roleCounts {
+		if rc.RoleType == models.RoleStudent {
+			stats.Students += rc.Count
+		} else {
+			stats.Teachers += rc.Count
+		}
+	}
+
+	projects := r.db.Model(&models.Project{}).
+		Select("projects.id").
+		Joins("JOIN tasks ON tasks.id = projects.task_id AND tasks.deleted_at IS NULL").
+		Joins("JOIN group_members ON group_members.user_id = projects.creator_id AND group_members.group_id = ?", group.ID).
+		Where("tasks.subject_id = ?", group.SubjectID)
+
+	var projectCount int64
+	if err := r.db.WithContext(ctx).Table("(?) AS p", projects).Count(&projectCount).Error; err != nil {
+		return nil, err
+	}
+	stats.Projects = int(projectCount)
+
+	var total, completed int64
+	if err := r.db.WithContext(ctx).Model(&models.Problem{}).
+		Where("project_id IN (?)", projects).
+		Count(&total).Error; err != nil {
+		return nil, err
+	}
+	if err := r.db.WithContext(ctx).Model(&models.Problem{}).
+		Where("project_id IN (?) AND solved = true", projects).
+		Count(&completed).Error; err != nil {
+		return nil, err
+	}
+	stats.Problems = models.ProblemStatistics{
+		Completed:  int(completed),
+		Incomplete: int(total - completed),
+		Total:      int(total),
+	}
+	if total > 0 {
+		stats.Problems.Percentage = int((completed * 100) / total)
+	}
+
+	return stats, nil
+}
@@ -0,0 +1,149 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/wozhdeleniye/redclass-app/internal/models"
+	"gorm.io/gorm"
+)
+
+type InviteRepository struct {
+	db *gorm.DB
+}
+
+func NewInviteRepository(db *gorm.DB) *InviteRepository {
+	return &InviteRepository{db: db}
+}
+
+func (r *InviteRepository) Create(ctx context.Context, invite *models.Invite) error {
+	if invite.ID == uuid.Nil {
+		invite.ID = uuid.New()
+	}
+	return r.db.WithContext(ctx).Create(invite).Error
+}
+
+func (r *InviteRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Invite, error) {
+	var invite models.Invite
+	err := r.db.WithContext(ctx).First(&invite, "id = ?", id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("invite not found")
+		}
+		return nil, err
+	}
+	return &invite, nil
+}
+
+func (r *InviteRepository) GetByCode(ctx context.Context, code string) (*models.Invite, error) {
+	var invite models.Invite
+	err := r.db.WithContext(ctx).First(&invite, "code = ?", code).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &invite, nil
+}
+
+func (r *InviteRepository) GetBySubject(ctx context.Context, subjectID uuid.UUID) ([]*models.Invite, error) {
+	var invites []*models.Invite
+	err := r.db.WithContext(ctx).
+		Where("subject_id = ?", subjectID).
+		Order("created_at DESC").
+		Find(&invites).Error
+	return invites, err
+}
+
+func (r *InviteRepository) GetByProject(ctx context.Context, projectID uuid.UUID) ([]*models.Invite, error) {
+	var invites []*models.Invite
+	err := r.db.WithContext(ctx).
+		Where("project_id = ?", projectID).
+		Order("created_at DESC").
+		Find(&invites).Error
+	return invites, err
+}
+
+// GetPendingByEmail получает действующие именные приглашения на email
+func (r *InviteRepository) GetPendingByEmail(ctx context.Context, email string, now time.Time) ([]*models.Invite, error) {
+	var invites []*models.Invite
+	err := r.db.WithContext(ctx).
+		Preload("Subject").
+		Preload("Project").
+		Preload("CreatedBy", publicUserColumns).
+		Where("LOWER(email) = LOWER(?) AND revoked_at IS NULL", email).
+		Where("expires_at IS NULL OR expires_at > ?", now).
+		Where("max_uses IS NULL OR uses < max_uses").
+		Order("created_at DESC").
+		Find(&invites).Error
+	return invites, err
+}
+
+// errInviteUnavailable откатывает транзакцию, в которой приглашение не удалось использовать
+var errInviteUnavailable = errors.New("invite is unavailable")
+
+// consumeInvite увеличивает счетчик использований только у действующего приглашения: не отозванного,
+// не исчерпанного и не истекшего к моменту обновления
+func consumeInvite(db *gorm.DB, id uuid.UUID) (bool, error) {
+	now := time.Now()
+	result := db.
+		Model(&models.Invite{}).
+		Where("id = ? AND revoked_at IS NULL AND (max_uses IS NULL OR uses < max_uses)", id).
+		Where("(expires_at IS NULL OR expires_at > ?)", now).
+		Updates(map[string]interface{}{
+			"uses":       gorm.Expr("uses + 1"),
+			"updated_at": now,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// Rotate меняет код, срок и лимит действующего приглашения, сохраняя число использований;
+// false, если приглашение уже отозвано
+func (r *InviteRepository) Rotate(ctx context.Context, invite *models.Invite) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Model(&models.Invite{}).
+		Where("id = ? AND revoked_at IS NULL", invite.ID).
+		Updates(map[string]interface{}{
+			"code":       invite.Code,
+			"max_uses":   invite.MaxUses,
+			"expires_at": invite.ExpiresAt,
+			"updated_at": invite.UpdatedAt,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+func (r *InviteRepository) Revoke(ctx context.Context, id uuid.UUID, at time.Time) error {
+	return r.db.WithContext(ctx).
+		Model(&models.Invite{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Updates(map[string]interface{}{
+			"revoked_at": at,
+			"updated_at": at,
+		}).Error
+}
+
+// GetActiveForEmail получает действующее именное приглашение в предмет
+func (r *InviteRepository) GetActiveForEmail(ctx context.Context, subjectID uuid.UUID, email string, now time.Time) (*models.Invite, error) {
+	var invite models.Invite
+	err := r.db.WithContext(ctx).
+		Where("subject_id = ? AND LOWER(email) = LOWER(?) AND revoked_at IS NULL", subjectID, email).
+		Where("expires_at IS NULL OR expires_at > ?", now).
+		Where("max_uses IS NULL OR uses < max_uses").
+		First(&invite).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &invite, nil
+}
@@ -0,0 +1,135 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/wozhdeleniye/redclass-app/internal/models"
+	"gorm.io/gorm"
+)
+
+type JoinRequestRepository struct {
+	db *gorm.DB
+}
+
+func NewJoinRequestRepository(db *gorm.DB) *JoinRequestRepository {
+	return &JoinRequestRepository{db: db}
+}
+
+func (r *JoinRequestRepository) Create(ctx context.Context, request *models.JoinRequest) error {
+	if request.ID == uuid.Nil {
+		request.ID = uuid.New()
+	}
+	return r.db.WithContext(ctx).Create(request).Error
+}
+
+func (r *JoinRequestRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.JoinRequest, error) {
+	var request models.JoinRequest
+	err := r.db.WithContext(ctx).First(&request, "id = ?", id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("join request not found")
+		}
+		return nil, err
+	}
+	return &request, nil
+}
+
+// GetPending получает ожидающую заявку пользователя в предмет или проект
+func (r *JoinRequestRepository) GetPending(ctx context.Context, userID uuid.UUID, target models.InviteTarget, targetID uuid.UUID) (*models.JoinRequest, error) {
+	var request models.JoinRequest
+	err := r.db.WithContext(ctx).
+		Where("user_id = ? AND status = ?", userID, models.JoinRequestPending).
+		Where(targetColumn(target)+" = ?", targetID).
+		First(&request).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &request, nil
+}
+
+// GetByTarget получает заявки предмета или проекта; пустой status означает все заявки
+func (r *JoinRequestRepository) GetByTarget(ctx context.Context, target models.InviteTarget, targetID uuid.UUID, status models.JoinRequestStatus) ([]*models.JoinRequest, error) {
+	var requests []*models.JoinRequest
+	query := r.db.WithContext(ctx).
+		Preload("User", publicUserColumns).
+		Where(targetColumn(target)+" = ?", targetID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	err := query.Order("created_at ASC").Find(&requests).Error
+	return requests, err
+}
+
+func (r *JoinRequestRepository) GetByUser(ctx context.Context, userID uuid.UUID) ([]*models.JoinRequest, error) {
+	var requests []*models.JoinRequest
+	err := r.db.WithContext(ctx).
+		Preload("Subject").
+		Preload("Project").
+		Where("user_id = ?", userID).
+		Order("created_at DESC").
+		Find(&requests).Error
+	return requests, err
+}
+
+// UpdateStatus переводит заявку из ожидания в новый статус; false, если заявка уже обработана
+func (r *JoinRequestRepository) UpdateStatus(ctx context.Context, request *models.JoinRequest) (bool, error) {
+	return updateJoinRequestStatus(r.db.WithContext(ctx), request)
+}
+
+// errJoinRequestProcessed откатывает одобрение, если заявку уже обработали
+var errJoinRequestProcessed = errors.New("join request is already processed")
+
+// Approve одобряет заявку и в той же транзакции создает роль в предмете или участника проекта
+// (nil, если пользователь уже состоит). false, если заявка уже обработана; тогда ничего не сохраняется.
+func (r *JoinRequestRepository) Approve(ctx context.Context, request *models.JoinRequest, role *models.Role, member *models.ProjectMember) (bool, error) {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		ok, err := updateJoinRequestStatus(tx, request)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return errJoinRequestProcessed
+		}
+		if role != nil {
+			if err := tx.Create(role).Error; err != nil {
+				return err
+			}
+		}
+		if member != nil {
+			return tx.Create(member).Error
+		}
+		return nil
+	})
+	if errors.Is(err, errJoinRequestProcessed) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func updateJoinRequestStatus(db *gorm.DB, request *models.JoinRequest) (bool, error) {
+	result := db.
+		Model(&models.JoinRequest{}).
+		Where("id = ? AND status = ?", request.ID, models.JoinRequestPending).
+		Updates(map[string]interface{}{
+			"status":        request.Status,
+			"decided_by_id": request.DecidedByID,
+			"decided_at":    request.DecidedAt,
+			"updated_at":    request.UpdatedAt,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+func targetColumn(target models.InviteTarget) string {
+	if target == models.InviteTargetProject {
+		return "project_id"
+	}
+	return "subject_id"
+}
@@ -0,0 +1,38 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/wozhdeleniye/redclass-app/internal/models"
+	"gorm.io/gorm"
+)
+
+type LoginAttemptRepository struct {
+	db *gorm.DB
+}
+
+func NewLoginAttemptRepository(db *gorm.DB) *LoginAttemptRepository {
+	return &LoginAttemptRepository{db: db}
+}
+
+func (r *LoginAttemptRepository) Create(ctx context.Context, attempt *models.LoginAttempt) error {
+	if attempt.ID == uuid.Nil {
+		attempt.ID = uuid.New()
+	}
+	if attempt.CreatedAt.IsZero() {
+		attempt.CreatedAt = time.Now()
+	}
+	return r.db.WithContext(ctx).Create(attempt).Error
+}
+
+// GetFailedByEmail получает неудачные попытки входа по email начиная с момента since
+func (r *LoginAttemptRepository) GetFailedByEmail(ctx context.Context, email string, since time.Time) ([]*models.LoginAttempt, error) {
+	var attempts []*models.LoginAttempt
+	err := r.db.WithContext(ctx).
+		Where("email = ? AND success = ? AND created_at >= ?", email, false, since).
+		Order("created_at DESC").
+		Find(&attempts).Error
+	return attempts, err
+}
@@ -0,0 +1,86 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/wozhdeleniye/redclass-app/internal/models"
+	"gorm.io/gorm"
+)
+
+type MilestoneRepository struct {
+	db *gorm.DB
+}
+
+func NewMilestoneRepository(db *gorm.DB) *MilestoneRepository {
+	return &MilestoneRepository{db: db}
+}
+
+func (r *MilestoneRepository) Create(ctx context.Context, milestone *models.Milestone) error {
+	if milestone.ID == uuid.Nil {
+		milestone.ID = uuid.New()
+	}
+	return r.db.WithContext(ctx).Create(milestone).Error
+}
+
+func (r *MilestoneRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Milestone, error) {
+	var milestone models.Milestone
+	err := r.db.WithContext(ctx).First(&milestone, "id = ?", id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("milestone not found")
+		}
+		return nil, err
+	}
+	return &milestone, nil
+}
+
+// GetByTask контрольные точки задания в хронологическом порядке
+func (r *MilestoneRepository) GetByTask(ctx context.Context, taskID uuid.UUID) ([]*models.Milestone, error) {
+	var milestones []*models.Milestone
+	err := r.db.WithContext(ctx).
+		Where("task_id = ?", taskID).
+		Order("due_date ASC").
+		Find(&milestones).Error
+	return milestones, err
+}
+
+func (r *MilestoneRepository) Update(ctx context.Context, milestone *models.Milestone) error {
+	return r.db.WithContext(ctx).Save(milestone).Error
+}
+
+// Delete удаляет точку и отвязывает от нее проблемы проектов
+func (r *MilestoneRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Problem{}).Where("milestone_id = ?", id).Update("milestone_id", nil).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.Milestone{}, "id = ?", id).Error
+	})
+}
+
+// GetProblemStates проблемы проектов задания, привязанные к контрольным точкам, с датой решения
+func (r *MilestoneRepository) GetProblemStates(ctx context.Context, projectIDs []uuid.UUID) (map[uuid.UUID][]*models.MilestoneProblemState, error) {
+	var rows []struct {
+		models.MilestoneProblemState
+		ProjectID uuid.UUID
+	}
+	err := r.db.WithContext(ctx).
+		Table("problems").
+		Select("problems.project_id, problems.milestone_id, problems.id AS problem_id, problems.number, problems.title, problems.solved, results.created_at AS solved_at").
+		Joins("LEFT JOIN results ON results.problem_id = problems.id AND results.deleted_at IS NULL").
+		Where("problems.project_id IN ? AND problems.milestone_id IS NOT NULL AND problems.deleted_at IS NULL", projectIDs).
+		Order("problems.number ASC").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	states := make(map[uuid.UUID][]*models.MilestoneProblemState)
+	for i := range rows {
+		state := rows[i].MilestoneProblemState
+		states[rows[i].ProjectID] = append(states[rows[i].ProjectID], &state)
+	}
+	return states, nil
+}
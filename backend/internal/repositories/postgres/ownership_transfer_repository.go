@@ -0,0 +1,80 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/wozhdeleniye/redclass-app/internal/models"
+	"gorm.io/gorm"
+)
+
+type OwnershipTransferRepository struct {
+	db *gorm.DB
+}
+
+func NewOwnershipTransferRepository(db *gorm.DB) *OwnershipTransferRepository {
+	return &OwnershipTransferRepository{db: db}
+}
+
+func (r *OwnershipTransferRepository) Create(ctx context.Context, transfer *models.OwnershipTransfer) error {
+	if transfer.ID == uuid.Nil {
+		transfer.ID = uuid.New()
+	}
+	return r.db.WithContext(ctx).Create(transfer).Error
+}
+
+func (r *OwnershipTransferRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.OwnershipTransfer, error) {
+	var transfer models.OwnershipTransfer
+	err := r.db.WithContext(ctx).First(&transfer, "id = ?", id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("ownership transfer not found")
+		}
+		return nil, err
+	}
+	return &transfer, nil
+}
+
+// GetPendingForUser получает действующие предложения, адресованные пользователю
+func (r *OwnershipTransferRepository) GetPendingForUser(ctx context.Context, userID uuid.UUID, now time.Time) ([]*models.OwnershipTransfer, error) {
+	var transfers []*models.OwnershipTransfer
+	err := r.db.WithContext(ctx).
+		Preload("Subject").
+		Preload("FromUser", publicUserColumns).
+		Where("to_user_id = ? AND status = ? AND expires_at > ?", userID, models.OwnershipTransferPending, now).
+		Order("created_at DESC").
+		Find(&transfers).Error
+	return transfers, err
+}
+
+// CancelPending отменяет все ожидающие предложения по предмету
+func (r *OwnershipTransferRepository) CancelPending(ctx context.Context, subjectID uuid.UUID) error {
+	now := time.Now()
+	return r.db.WithContext(ctx).
+		Model(&models.OwnershipTransfer{}).
+		Where("subject_id = ? AND status = ?", subjectID, models.OwnershipTransferPending).
+		Updates(map[string]interface{}{
+			"status":     models.OwnershipTransferCancelled,
+			"decided_at": now,
+			"updated_at": now,
+		}).Error
+}
+
+// Decide переводит ожидающее предложение в итоговый статус; false, если оно уже обработано
+func (r *OwnershipTransferRepository) Decide(ctx context.Context, id uuid.UUID, status models.OwnershipTransferStatus) (bool, error) {
+	now := time.Now()
+	result := r.db.WithContext(ctx).
+		Model(&models.OwnershipTransfer{}).
+		Where("id = ? AND status = ?", id, models.OwnershipTransferPending).
+		Updates(map[string]interface{}{
+			"status":     status,
+			"decided_at": now,
+			"updated_at": now,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
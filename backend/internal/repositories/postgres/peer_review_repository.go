@@ -0,0 +1,81 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/wozhdeleniye/redclass-app/internal/models"
+	"gorm.io/gorm"
+)
+
+type PeerReviewRepository struct {
+	db *gorm.DB
+}
+
+func NewPeerReviewRepository(db *gorm.DB) *PeerReviewRepository {
+	return &PeerReviewRepository{db: db}
+}
+
+// GetRound раунд взаимной оценки задания; nil, если раунд не настроен
+func (r *PeerReviewRepository) GetRound(ctx context.Context, taskID uuid.UUID) (*models.PeerReviewRound, error) {
+	var round models.PeerReviewRound
+	err := r.db.WithContext(ctx).First(&round, "task_id = ?", taskID).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &round, nil
+}
+
+func (r *PeerReviewRepository) CreateRound(ctx context.Context, round *models.PeerReviewRound) error {
+	return r.db.WithContext(ctx).Omit("Task").Create(round).Error
+}
+
+func (r *PeerReviewRepository) UpdateRound(ctx context.Context, round *models.PeerReviewRound) error {
+	return r.db.WithContext(ctx).Omit("Task").Save(round).Error
+}
+
+// CountReviews число отзывов, оставленных в раунде
+func (r *PeerReviewRepository) CountReviews(ctx context.Context, roundID uuid.UUID) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.PeerReview{}).
+		Where("round_id = ?", roundID).
+		Count(&count).Error
+	return count, err
+}
+
+// ReplaceReviews заменяет все отзывы участника в раунде новыми
+func (r *PeerReviewRepository) ReplaceReviews(ctx context.Context, roundID, reviewerID uuid.UUID, reviews []*models.PeerReview) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("round_id = ? AND reviewer_id = ?", roundID, reviewerID).Delete(&models.PeerReview{}).Error; err != nil {
+			return err
+		}
+		if len(reviews) == 0 {
+			return nil
+		}
+		return tx.Omit("Round", "Project").Create(&reviews).Error
+	})
+}
+
+// GetByProject отзывы участников проекта в раунде
+func (r *PeerReviewRepository) GetByProject(ctx context.Context, roundID, projectID uuid.UUID) ([]*models.PeerReview, error) {
+	reviews := []*models.PeerReview{}
+	err := r.db.WithContext(ctx).
+		Where("round_id = ? AND project_id = ?", roundID, projectID).
+		Order("created_at ASC").
+		Find(&reviews).Error
+	return reviews, err
+}
+
+// GetByReviewer отзывы, которые участник оставил о товарищах
+func (r *PeerReviewRepository) GetByReviewer(ctx context.Context, roundID, reviewerID uuid.UUID) ([]*models.PeerReview, error) {
+	reviews := []*models.PeerReview{}
+	err := r.db.WithContext(ctx).
+		Where("round_id = ? AND reviewer_id = ?", roundID, reviewerID).
+		Order("created_at ASC").
+		Find(&reviews).Error
+	return reviews, err
+}
@@ -3,6 +3,7 @@ package postgres
 import (
 	"context"
 	"errors"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/wozhdeleniye/redclass-app/internal/models"
@@ -26,10 +27,10 @@ func (r *ProblemRepository) Create(ctx context.Context, problem *models.Problem)
 func (r *ProblemRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Problem, error) {
 	var problem models.Problem
 	err := r.db.WithContext(ctx).
-		Preload("Creator").
+		Preload("Creator", publicUserColumns).
 		Preload("Project").
 		Preload("Parent").
-		Preload("Assignees.User").
+		Preload("Assignees.User", publicUserColumns).
 		First(&problem, "id = ?", id).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
@@ -44,8 +45,8 @@ func (r *ProblemRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.
 func (r *ProblemRepository) GetByProjectID(ctx context.Context, projectID uuid.UUID) ([]*models.Problem, error) {
 	var problems []*models.Problem
 	err := r.db.WithContext(ctx).
-		Preload("Creator").
-		Preload("Assignees.User").
+		Preload("Creator", publicUserColumns).
+		Preload("Assignees.User", publicUserColumns).
 		Preload("Children").
 		Where("project_id = ? AND parent_id IS NULL", projectID).
 		Order("number ASC").
@@ -57,20 +58,34 @@ func (r *ProblemRepository) GetByProjectID(ctx context.Context, projectID uuid.U
 func (r *ProblemRepository) GetProjectProblems(ctx context.Context, projectID uuid.UUID) ([]*models.Problem, error) {
 	var problems []*models.Problem
 	err := r.db.WithContext(ctx).
-		Preload("Creator").
-		Preload("Assignees.User").
+		Preload("Creator", publicUserColumns).
+		Preload("Assignees.User", publicUserColumns).
 		Where("project_id = ?", projectID).
 		Order("number ASC").
 		Find(&problems).Error
 	return problems, err
 }
 
+// GetByProjects проблемы нескольких проектов с исполнителями, без данных пользователей
+func (r *ProblemRepository) GetByProjects(ctx context.Context, projectIDs []uuid.UUID) ([]*models.Problem, error) {
+	problems := []*models.Problem{}
+	if len(projectIDs) == 0 {
+		return problems, nil
+	}
+	err := r.db.WithContext(ctx).
+		Preload("Assignees").
+		Where("project_id IN ?", projectIDs).
+		Order("created_at ASC").
+		Find(&problems).Error
+	return problems, err
+}
+
 // GetProjectProblemsAssigned получает проблемы проекта, назначенные на конкретного пользователя
 func (r *ProblemRepository) GetProjectProblemsAssigned(ctx context.Context, projectID uuid.UUID, userID uuid.UUID) ([]*models.Problem, error) {
 	var problems []*models.Problem
 	err := r.db.WithContext(ctx).
-		Preload("Creator").
-		Preload("Assignees.User").
+		Preload("Creator", publicUserColumns).
+		Preload("Assignees.User", publicUserColumns).
 		Joins("JOIN problem_assignees ON problem_assignees.problem_id = problems.id").
 		Where("problems.project_id = ? AND problem_assignees.user_id = ?", projectID, userID).
 		Order("number ASC").
@@ -82,8 +97,8 @@ func (r *ProblemRepository) GetProjectProblemsAssigned(ctx context.Context, proj
 func (r *ProblemRepository) GetChildProblems(ctx context.Context, parentID uuid.UUID) ([]*models.Problem, error) {
 	var problems []*models.Problem
 	err := r.db.WithContext(ctx).
-		Preload("Creator").
-		Preload("Assignees.User").
+		Preload("Creator", publicUserColumns).
+		Preload("Assignees.User", publicUserColumns).
 		Where("parent_id = ?", parentID).
 		Order("number ASC").
 		Find(&problems).Error
@@ -105,6 +120,40 @@ func (r *ProblemRepository) GetNextNumber(ctx context.Context, projectID uuid.UU
 	return maxNumber + 1, nil
 }
 
+// GetTemplatedProblems проблемы проекта, созданные по шаблону задания
+func (r *ProblemRepository) GetTemplatedProblems(ctx context.Context, projectID uuid.UUID) ([]*models.Problem, error) {
+	var problems []*models.Problem
+	err := r.db.WithContext(ctx).
+		Where("project_id = ? AND template_id IS NOT NULL", projectID).
+		Order("number ASC").
+		Find(&problems).Error
+	return problems, err
+}
+
+// SetMainProblemWindow переносит начало и окончание главной проблемы проекта
+func (r *ProblemRepository) SetMainProblemWindow(ctx context.Context, projectID uuid.UUID, start, end time.Time) error {
+	return r.db.WithContext(ctx).
+		Model(&models.Problem{}).
+		Where("project_id = ? AND parent_id IS NULL", projectID).
+		Updates(map[string]interface{}{"start_time": start, "end_time": end}).Error
+}
+
+// GetOutOfWindow подпроблемы проектов задания, выходящие за окно главной проблемы своего проекта
+func (r *ProblemRepository) GetOutOfWindow(ctx context.Context, taskID uuid.UUID) ([]*models.OutOfWindowProblem, error) {
+	problems := []*models.OutOfWindowProblem{}
+	err := r.db.WithContext(ctx).
+		Table("problems AS p").
+		Select(`p.project_id, pr.title AS project_title, p.id AS problem_id, p.number, p.title,
+			p.start_time, p.end_time, m.start_time AS window_start, m.end_time AS window_end`).
+		Joins("JOIN projects pr ON pr.id = p.project_id AND pr.deleted_at IS NULL").
+		Joins("JOIN problems m ON m.project_id = p.project_id AND m.parent_id IS NULL AND m.deleted_at IS NULL").
+		Where("pr.task_id = ? AND p.parent_id IS NOT NULL AND p.deleted_at IS NULL", taskID).
+		Where("p.end_time > m.end_time OR p.start_time < m.start_time").
+		Order("pr.title ASC, p.number ASC").
+		Scan(&problems).Error
+	return problems, err
+}
+
 // Update обновляет проблему
 func (r *ProblemRepository) Update(ctx context.Context, problem *models.Problem) error {
 	return r.db.WithContext(ctx).Save(problem).Error
@@ -139,11 +188,34 @@ func (r *ProblemRepository) RemoveAssignee(ctx context.Context, problemID uuid.U
 	return r.db.WithContext(ctx).Delete(&models.ProblemAssignee{}, "problem_id = ? AND user_id = ?", problemID, userID).Error
 }
 
+// ReassignMember снимает участника со всех проблем проекта; если задан successor, его проблемы
+// переходят к нему. Назначения удаляются безвозвратно, чтобы участника можно было назначить снова.
+func (r *ProblemRepository) ReassignMember(ctx context.Context, projectID, userID uuid.UUID, successor *uuid.UUID) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		problemIDs := tx.Model(&models.Problem{}).Select("id").Where("project_id = ?", projectID)
+		if successor != nil {
+			err := tx.Exec(`
+				INSERT INTO problem_assignees (id, problem_id, user_id, created_at, updated_at)
+				SELECT gen_random_uuid(), pa.problem_id, ?, NOW(), NOW()
+				FROM problem_assignees pa
+				WHERE pa.user_id = ? AND pa.deleted_at IS NULL AND pa.problem_id IN (?)
+				ON CONFLICT (problem_id, user_id) DO UPDATE SET deleted_at = NULL, updated_at = NOW()`,
+				*successor, userID, problemIDs).Error
+			if err != nil {
+				return err
+			}
+		}
+		return tx.Unscoped().
+			Where("user_id = ? AND problem_id IN (?)", userID, problemIDs).
+			Delete(&models.ProblemAssignee{}).Error
+	})
+}
+
 // GetAssignees получает список назначенных пользователей
 func (r *ProblemRepository) GetAssignees(ctx context.Context, problemID uuid.UUID) ([]*models.ProblemAssignee, error) {
 	var assignees []*models.ProblemAssignee
 	err := r.db.WithContext(ctx).
-		Preload("User").
+		Preload("User", publicUserColumns).
 		Where("problem_id = ?", problemID).
 		Find(&assignees).Error
 	return assignees, err
@@ -163,8 +235,8 @@ func (r *ProblemRepository) IsUserAssignedToProblem(ctx context.Context, problem
 func (r *ProblemRepository) GetMainProblemByProject(ctx context.Context, projectID uuid.UUID) (*models.Problem, error) {
 	var problem models.Problem
 	err := r.db.WithContext(ctx).
-		Preload("Creator").
-		Preload("Assignees.User").
+		Preload("Creator", publicUserColumns).
+		Preload("Assignees.User", publicUserColumns).
 		Where("project_id = ? AND parent_id IS NULL", projectID).
 		First(&problem).Error
 	if err != nil {
@@ -0,0 +1,40 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/wozhdeleniye/redclass-app/internal/models"
+	"gorm.io/gorm"
+)
+
+type ProblemTemplateRepository struct {
+	db *gorm.DB
+}
+
+func NewProblemTemplateRepository(db *gorm.DB) *ProblemTemplateRepository {
+	return &ProblemTemplateRepository{db: db}
+}
+
+// GetByTask все узлы шаблона задания плоским списком: родители идут раньше детей
+func (r *ProblemTemplateRepository) GetByTask(ctx context.Context, taskID uuid.UUID) ([]*models.ProblemTemplate, error) {
+	var templates []*models.ProblemTemplate
+	err := r.db.WithContext(ctx).
+		Where("task_id = ?", taskID).
+		Order("position ASC").
+		Find(&templates).Error
+	return templates, err
+}
+
+// ReplaceForTask заменяет шаблон задания; узлы передаются в порядке обхода дерева
+func (r *ProblemTemplateRepository) ReplaceForTask(ctx context.Context, taskID uuid.UUID, templates []*models.ProblemTemplate) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("task_id = ?", taskID).Delete(&models.ProblemTemplate{}).Error; err != nil {
+			return err
+		}
+		if len(templates) == 0 {
+			return nil
+		}
+		return tx.Omit("Children").Create(&templates).Error
+	})
+}
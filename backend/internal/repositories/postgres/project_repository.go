@@ -3,6 +3,7 @@ package postgres
 import (
 	"context"
 	"errors"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/wozhdeleniye/redclass-app/internal/models"
@@ -27,7 +28,7 @@ func (r *ProjectRepository) Create(ctx context.Context, project *models.Project)
 func (r *ProjectRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Project, error) {
 	var project models.Project
 	err := r.db.WithContext(ctx).
-		Preload("Members.User").
+		Preload("Members.User", publicUserColumns).
 		First(&project, "id = ?", id).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
@@ -58,6 +59,41 @@ func (r *ProjectRepository) GetByTask(ctx context.Context, taskID uuid.UUID) ([]
 	return projects, err
 }
 
+// GetByTaskAndGroup получает проекты задания, созданные участниками группы
+func (r *ProjectRepository) GetByTaskAndGroup(ctx context.Context, taskID, groupID uuid.UUID) ([]*models.Project, error) {
+	var projects []*models.Project
+	err := r.db.WithContext(ctx).
+		Joins("JOIN group_members ON group_members.user_id = projects.creator_id AND group_members.group_id = ?", groupID).
+		Where("projects.task_id = ?", taskID).
+		Find(&projects).Error
+	return projects, err
+}
+
+// GetProjectGroups для проектов задания, назначенного группам, возвращает группу задания,
+// в которой состоит создатель проекта
+func (r *ProjectRepository) GetProjectGroups(ctx context.Context, taskID uuid.UUID) (map[uuid.UUID]uuid.UUID, error) {
+	var rows []struct {
+		ProjectID uuid.UUID
+		GroupID   uuid.UUID
+	}
+	err := r.db.WithContext(ctx).
+		Table("projects").
+		Select("projects.id AS project_id, task_groups.group_id").
+		Joins("JOIN group_members ON group_members.user_id = projects.creator_id").
+		Joins("JOIN task_groups ON task_groups.group_id = group_members.group_id AND task_groups.task_id = projects.task_id").
+		Where("projects.task_id = ? AND projects.deleted_at IS NULL", taskID).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	groups := make(map[uuid.UUID]uuid.UUID, len(rows))
+	for _, row := range rows {
+		groups[row.ProjectID] = row.GroupID
+	}
+	return groups, nil
+}
+
 func (r *ProjectRepository) GetUserProjects(ctx context.Context, userID uuid.UUID) ([]*models.Project, error) {
 	var projects []*models.Project
 	err := r.db.WithContext(ctx).
@@ -89,6 +125,31 @@ func (r *ProjectRepository) AddMember(ctx context.Context, member *models.Projec
 	return r.db.WithContext(ctx).Create(member).Error
 }
 
+// AddMemberFromInvite добавляет участника и использует приглашение в одной транзакции. Возвращает
+// false и ничего не сохраняет, если приглашение уже исчерпано, отозвано или истекло.
+func (r *ProjectRepository) AddMemberFromInvite(ctx context.Context, member *models.ProjectMember, inviteID uuid.UUID) (bool, error) {
+	if member.ID == uuid.Nil {
+		member.ID = uuid.New()
+	}
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(member).Error; err != nil {
+			return err
+		}
+		ok, err := consumeInvite(tx, inviteID)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return errInviteUnavailable
+		}
+		return nil
+	})
+	if errors.Is(err, errInviteUnavailable) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
 func (r *ProjectRepository) IsUserMember(ctx context.Context, projectID, userID uuid.UUID) (bool, error) {
 	var count int64
 	err := r.db.WithContext(ctx).Model(&models.ProjectMember{}).
@@ -97,12 +158,151 @@ func (r *ProjectRepository) IsUserMember(ctx context.Context, projectID, userID
 	return count > 0, err
 }
 
+// GetMember получает участие пользователя в проекте
+func (r *ProjectRepository) GetMember(ctx context.Context, projectID, userID uuid.UUID) (*models.ProjectMember, error) {
+	var member models.ProjectMember
+	err := r.db.WithContext(ctx).
+		First(&member, "project_id = ? AND user_id = ?", projectID, userID).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &member, nil
+}
+
 // GetMembersByProject получает участников проекта с предзагрузкой пользователей
 func (r *ProjectRepository) GetMembersByProject(ctx context.Context, projectID uuid.UUID) ([]*models.ProjectMember, error) {
 	var members []*models.ProjectMember
 	err := r.db.WithContext(ctx).
-		Preload("User").
+		Preload("User", publicUserColumns).
 		Where("project_id = ? AND deleted_at IS NULL", projectID).
 		Find(&members).Error
 	return members, err
 }
+
+// GetTeams получает проекты задания вместе с участниками
+func (r *ProjectRepository) GetTeams(ctx context.Context, taskID uuid.UUID) ([]*models.Project, error) {
+	var projects []*models.Project
+	err := r.db.WithContext(ctx).
+		Preload("Members", func(db *gorm.DB) *gorm.DB { return db.Order("created_at ASC") }).
+		Preload("Members.User", publicUserColumns).
+		Where("task_id = ?", taskID).
+		Order("created_at ASC").
+		Find(&projects).Error
+	return projects, err
+}
+
+// GetTeamsBySubject проекты всех заданий предмета вместе с участниками
+func (r *ProjectRepository) GetTeamsBySubject(ctx context.Context, subjectID uuid.UUID) ([]*models.Project, error) {
+	var projects []*models.Project
+	err := r.db.WithContext(ctx).
+		Preload("Members.User", publicUserColumns).
+		Joins("JOIN tasks ON tasks.id = projects.task_id AND tasks.deleted_at IS NULL").
+		Where("tasks.subject_id = ?", subjectID).
+		Order("projects.created_at ASC").
+		Find(&projects).Error
+	return projects, err
+}
+
+// CountMembers считает участников проекта
+func (r *ProjectRepository) CountMembers(ctx context.Context, projectID uuid.UUID) (int, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.ProjectMember{}).
+		Where("project_id = ?", projectID).
+		Count(&count).Error
+	return int(count), err
+}
+
+// RemoveMember удаляет участие без возможности восстановления, чтобы пользователь мог вернуться в проект
+func (r *ProjectRepository) RemoveMember(ctx context.Context, projectID, userID uuid.UUID) error {
+	return r.db.WithContext(ctx).Unscoped().
+		Where("project_id = ? AND user_id = ?", projectID, userID).
+		Delete(&models.ProjectMember{}).Error
+}
+
+// SetCreator передает роль создателя проекта другому участнику
+func (r *ProjectRepository) SetCreator(ctx context.Context, projectID, userID uuid.UUID) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Project{}).Where("id = ?", projectID).Update("creator_id", userID).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.ProjectMember{}).
+			Where("project_id = ? AND role = ?", projectID, models.ProjectRoleCreator).
+			Update("role", models.ProjectRoleMember).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.ProjectMember{}).
+			Where("project_id = ? AND user_id = ?", projectID, userID).
+			Update("role", models.ProjectRoleCreator).Error
+	})
+}
+
+// Delete мягко удаляет проект вместе с участниками, проблемами, их исполнителями и результатами,
+// продлениями и оценкой. История сдач остается.
+func (r *ProjectRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		problemIDs := tx.Model(&models.Problem{}).Select("id").Where("project_id = ?", id)
+		if err := tx.Where("problem_id IN (?)", problemIDs).Delete(&models.Result{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("problem_id IN (?)", problemIDs).Delete(&models.ProblemAssignee{}).Error; err != nil {
+			return err
+		}
+		for _, model := range []interface{}{
+			&models.Problem{},
+			&models.ProjectMember{},
+			&models.DeadlineExtension{},
+			&models.ProjectGrade{},
+		} {
+			if err := tx.Where("project_id = ?", id).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Delete(&models.Project{}, "id = ?", id).Error
+	})
+}
+
+// UpdateDetails сохраняет название и описание проекта
+func (r *ProjectRepository) UpdateDetails(ctx context.Context, project *models.Project) error {
+	return r.db.WithContext(ctx).
+		Model(&models.Project{}).
+		Where("id = ?", project.ID).
+		Updates(map[string]interface{}{
+			"title":       project.Title,
+			"description": project.Description,
+			"updated_at":  project.UpdatedAt,
+		}).Error
+}
+
+// SetArchivedAt переводит проект в архив или возвращает из него при archivedAt == nil
+func (r *ProjectRepository) SetArchivedAt(ctx context.Context, id uuid.UUID, archivedAt *time.Time) error {
+	return r.db.WithContext(ctx).
+		Model(&models.Project{}).
+		Where("id = ?", id).
+		Update("archived_at", archivedAt).Error
+}
+
+// SetCode заменяет постоянный код вступления в проект
+func (r *ProjectRepository) SetCode(ctx context.Context, id uuid.UUID, code string) error {
+	return r.db.WithContext(ctx).
+		Model(&models.Project{}).
+		Where("id = ?", id).
+		Update("code", code).Error
+}
+
+// SetStatus переводит проект на другой этап; submittedAt обновляется вместе со статусом
+func (r *ProjectRepository) SetStatus(ctx context.Context, id uuid.UUID, status models.ProjectStatus, submittedAt *time.Time) error {
+	return r.db.WithContext(ctx).
+		Model(&models.Project{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{"status": status, "submitted_at": submittedAt}).Error
+}
+
+func (r *ProjectRepository) SetRequiresApproval(ctx context.Context, id uuid.UUID, requiresApproval bool) error {
+	return r.db.WithContext(ctx).
+		Model(&models.Project{}).
+		Where("id = ?", id).
+		Update("requires_approval", requiresApproval).Error
+}
@@ -29,7 +29,7 @@ func (r *ResultRepository) Create(ctx context.Context, res *models.Result) error
 func (r *ResultRepository) GetByProblemID(ctx context.Context, problemID uuid.UUID) (*models.Result, error) {
 	var res models.Result
 	err := r.db.WithContext(ctx).
-		Preload("Creator").
+		Preload("Creator", publicUserColumns).
 		Preload("Problem").
 		First(&res, "problem_id = ?", problemID).Error
 	if err != nil {
@@ -41,6 +41,37 @@ func (r *ResultRepository) GetByProblemID(ctx context.Context, problemID uuid.UU
 	return &res, nil
 }
 
+// GetByProject результаты всех проблем проекта, ключ ID проблемы
+func (r *ResultRepository) GetByProject(ctx context.Context, projectID uuid.UUID) (map[uuid.UUID]*models.Result, error) {
+	var results []*models.Result
+	err := r.db.WithContext(ctx).
+		Joins("JOIN problems ON problems.id = results.problem_id AND problems.deleted_at IS NULL").
+		Where("problems.project_id = ?", projectID).
+		Find(&results).Error
+	if err != nil {
+		return nil, err
+	}
+
+	byProblem := make(map[uuid.UUID]*models.Result, len(results))
+	for _, res := range results {
+		byProblem[res.ProblemID] = res
+	}
+	return byProblem, nil
+}
+
+// GetByProjects результаты проблем нескольких проектов
+func (r *ResultRepository) GetByProjects(ctx context.Context, projectIDs []uuid.UUID) ([]*models.Result, error) {
+	results := []*models.Result{}
+	if len(projectIDs) == 0 {
+		return results, nil
+	}
+	err := r.db.WithContext(ctx).
+		Joins("JOIN problems ON problems.id = results.problem_id AND problems.deleted_at IS NULL").
+		Where("problems.project_id IN ?", projectIDs).
+		Find(&results).Error
+	return results, err
+}
+
 func (r *ResultRepository) Delete(ctx context.Context, id uuid.UUID) error {
 	return r.db.WithContext(ctx).Delete(&models.Result{}, "id = ?", id).Error
 }
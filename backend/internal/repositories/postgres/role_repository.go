@@ -24,6 +24,31 @@ func (r *RoleRepository) Create(ctx context.Context, role *models.Role) error {
 	return r.db.WithContext(ctx).Create(role).Error
 }
 
+// CreateFromInvite создает роль и использует приглашение в одной транзакции. Возвращает false
+// и ничего не сохраняет, если приглашение уже исчерпано, отозвано или истекло.
+func (r *RoleRepository) CreateFromInvite(ctx context.Context, role *models.Role, inviteID uuid.UUID) (bool, error) {
+	if role.ID == uuid.Nil {
+		role.ID = uuid.New()
+	}
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(role).Error; err != nil {
+			return err
+		}
+		ok, err := consumeInvite(tx, inviteID)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return errInviteUnavailable
+		}
+		return nil
+	})
+	if errors.Is(err, errInviteUnavailable) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
 func (r *RoleRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Role, error) {
 	var role models.Role
 	err := r.db.WithContext(ctx).
@@ -66,26 +91,47 @@ func (r *RoleRepository) GetUserRoles(ctx context.Context, userID uuid.UUID) ([]
 func (r *RoleRepository) GetSubjectRoles(ctx context.Context, subjectID uuid.UUID) ([]*models.Role, error) {
 	var roles []*models.Role
 	err := r.db.WithContext(ctx).
-		Preload("User").
+		Preload("User", publicUserColumns).
 		Where("subject_id = ?", subjectID).
 		Find(&roles).Error
 	return roles, err
 }
 
+// GetGroupRoles получает роли в предмете участников группы
+func (r *RoleRepository) GetGroupRoles(ctx context.Context, subjectID, groupID uuid.UUID) ([]*models.Role, error) {
+	var roles []*models.Role
+	err := r.db.WithContext(ctx).
+		Preload("User", publicUserColumns).
+		Joins("JOIN group_members ON group_members.user_id = roles.user_id AND group_members.group_id = ?", groupID).
+		Where("roles.subject_id = ?", subjectID).
+		Find(&roles).Error
+	return roles, err
+}
+
 func (r *RoleRepository) Update(ctx context.Context, role *models.Role) error {
 	return r.db.WithContext(ctx).
 		Model(role).
 		Updates(role).Error
 }
 
+// UpdateAccess сохраняет тип роли и индивидуальные права, включая пустые значения
+func (r *RoleRepository) UpdateAccess(ctx context.Context, role *models.Role) error {
+	return r.db.WithContext(ctx).
+		Model(role).
+		Select("role_type", "granted_permissions", "revoked_permissions", "updated_at").
+		Updates(role).Error
+}
+
+// Delete удаляет роль физически: мягко удаленная строка занимала бы уникальный индекс
+// idx_user_subject, и пользователь не смог бы вернуться в предмет
 func (r *RoleRepository) Delete(ctx context.Context, id uuid.UUID) error {
-	return r.db.WithContext(ctx).Delete(&models.Role{}, "id = ?", id).Error
+	return r.db.WithContext(ctx).Unscoped().Delete(&models.Role{}, "id = ?", id).Error
 }
 
 func (r *RoleRepository) GetSubjectAdmin(ctx context.Context, subjectID uuid.UUID) (*models.Role, error) {
 	var role models.Role
 	err := r.db.WithContext(ctx).
-		Preload("User").
+		Preload("User", publicUserColumns).
 		Where("subject_id = ? AND role_type = ?", subjectID, models.RoleAdmin).
 		First(&role).Error
 	if err != nil {
@@ -96,3 +142,34 @@ func (r *RoleRepository) GetSubjectAdmin(ctx context.Context, subjectID uuid.UUI
 	}
 	return &role, nil
 }
+
+// SharesSubject проверяет, состоят ли два пользователя хотя бы в одном общем предмете
+func (r *RoleRepository) SharesSubject(ctx context.Context, userID, otherUserID uuid.UUID) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).
+		Table("roles AS r1").
+		Joins("JOIN roles AS r2 ON r2.subject_id = r1.subject_id AND r2.deleted_at IS NULL").
+		Where("r1.user_id = ? AND r2.user_id = ? AND r1.deleted_at IS NULL", userID, otherUserID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// GetSubjectRoster получает участников предмета вместе с email для выгрузки списка
+func (r *RoleRepository) GetSubjectRoster(ctx context.Context, subjectID uuid.UUID) ([]*models.Role, error) {
+	var roles []*models.Role
+	err := r.db.WithContext(ctx).
+		Preload("User").
+		Where("subject_id = ?", subjectID).
+		Order("created_at ASC").
+		Find(&roles).Error
+	return roles, err
+}
+
+// CountAdmins считает администраторов предмета
+func (r *RoleRepository) CountAdmins(ctx context.Context, subjectID uuid.UUID) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.Role{}).
+		Where("subject_id = ? AND role_type = ?", subjectID, models.RoleAdmin).
+		Count(&count).Error
+	return count, err
+}
@@ -0,0 +1,40 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/wozhdeleniye/redclass-app/internal/models"
+	"gorm.io/gorm"
+)
+
+type RubricRepository struct {
+	db *gorm.DB
+}
+
+func NewRubricRepository(db *gorm.DB) *RubricRepository {
+	return &RubricRepository{db: db}
+}
+
+// GetByTask критерии задания в порядке отображения
+func (r *RubricRepository) GetByTask(ctx context.Context, taskID uuid.UUID) ([]*models.RubricCriterion, error) {
+	criteria := []*models.RubricCriterion{}
+	err := r.db.WithContext(ctx).
+		Where("task_id = ?", taskID).
+		Order("position ASC").
+		Find(&criteria).Error
+	return criteria, err
+}
+
+// ReplaceForTask заменяет критерии задания целиком
+func (r *RubricRepository) ReplaceForTask(ctx context.Context, taskID uuid.UUID, criteria []*models.RubricCriterion) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("task_id = ?", taskID).Delete(&models.RubricCriterion{}).Error; err != nil {
+			return err
+		}
+		if len(criteria) == 0 {
+			return nil
+		}
+		return tx.Omit("Task").Create(&criteria).Error
+	})
+}
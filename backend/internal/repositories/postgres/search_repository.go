@@ -0,0 +1,215 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/wozhdeleniye/redclass-app/internal/models"
+	"gorm.io/gorm"
+)
+
+// Контент смешанный, поэтому текст индексируется сразу в русской и английской конфигурациях,
+// а запрос строится как объединение запросов в обеих.
+const (
+	searchQuery    = "(websearch_to_tsquery('russian', @q) || websearch_to_tsquery('english', @q))"
+	headlineOption = "StartSel=<mark>, StopSel=</mark>, MaxFragments=2, MaxWords=20, MinWords=5"
+)
+
+// searchText текст, по которому ищется объект; alias пустой для выражений индекса
+func searchText(alias string, columns ...string) string {
+	parts := make([]string, 0, len(columns))
+	for _, c := range columns {
+		if alias != "" {
+			c = alias + "." + c
+		}
+		parts = append(parts, fmt.Sprintf("coalesce(%s, '')", c))
+	}
+	return strings.Join(parts, " || ' ' || ")
+}
+
+// searchVector должен совпадать с выражением индекса, иначе индекс не используется
+func searchVector(text string) string {
+	return fmt.Sprintf("(to_tsvector('russian', %s) || to_tsvector('english', %s))", text, text)
+}
+
+// weightedVector вектор для ранжирования: совпадение в заголовке весит больше, чем в описании
+func weightedVector(title, body string) string {
+	return fmt.Sprintf("(setweight(%s, 'A') || setweight(%s, 'B'))", searchVector(title), searchVector(body))
+}
+
+type searchIndex struct {
+	name, table string
+	columns     []string
+}
+
+var searchIndexes = []searchIndex{
+	{"idx_subjects_search", "subjects", []string{"name", "description"}},
+	{"idx_tasks_search", "tasks", []string{"title", "description"}},
+	{"idx_projects_search", "projects", []string{"title", "description"}},
+	{"idx_problems_search", "problems", []string{"title", "description"}},
+	{"idx_results_search", "results", []string{"comment"}},
+}
+
+// SearchIndexStatements SQL для создания GIN-индексов полнотекстового поиска
+func SearchIndexStatements() []string {
+	statements := make([]string, 0, len(searchIndexes))
+	for _, idx := range searchIndexes {
+		statements = append(statements, fmt.Sprintf(
+			"CREATE INDEX IF NOT EXISTS %s ON %s USING GIN (%s)",
+			idx.name, idx.table, searchVector(searchText("", idx.columns...)),
+		))
+	}
+	return statements
+}
+
+type SearchRepository struct {
+	db *gorm.DB
+}
+
+func NewSearchRepository(db *gorm.DB) *SearchRepository {
+	return &SearchRepository{db: db}
+}
+
+// Search ищет по всем типам объектов с учетом прав вызывающего и сортирует по релевантности
+func (r *SearchRepository) Search(ctx context.Context, filter models.SearchFilter) ([]*models.SearchResult, error) {
+	args := map[string]interface{}{
+		"q":      filter.Query,
+		"limit":  filter.Limit,
+		"offset": filter.Offset,
+	}
+	if filter.ViewerID != nil {
+		args["viewer"] = *filter.ViewerID
+	}
+	if filter.SubjectID != nil {
+		args["subject"] = *filter.SubjectID
+	}
+	if len(filter.ProjectSubjectIDs) > 0 {
+		args["view_all"] = filter.ProjectSubjectIDs
+	}
+	if len(filter.TaskSubjectIDs) > 0 {
+		args["task_managers"] = filter.TaskSubjectIDs
+	}
+
+	var parts []string
+	if filter.HasType(models.SearchSubject) {
+		parts = append(parts, subjectSearchSQL(&filter))
+	}
+	if filter.HasType(models.SearchTask) {
+		parts = append(parts, taskSearchSQL(&filter))
+	}
+	// проекты и задачи анониму недоступны
+	if filter.ViewerID != nil || filter.All {
+		if filter.HasType(models.SearchProject) {
+			parts = append(parts, projectSearchSQL(&filter))
+		}
+		if filter.HasType(models.SearchProblem) {
+			parts = append(parts, problemSearchSQL(&filter))
+		}
+	}
+
+	results := []*models.SearchResult{}
+	if len(parts) == 0 {
+		return results, nil
+	}
+
+	sql := strings.Join(parts, "\nUNION ALL\n") +
+		"\nORDER BY rank DESC, title ASC LIMIT @limit OFFSET @offset"
+	err := r.db.WithContext(ctx).Raw(sql, args).Scan(&results).Error
+	return results, err
+}
+
+// visibleSubjects условие на колонку с ID предмета: публичный предмет или предмет, где вызывающий участник
+func visibleSubjects(f *models.SearchFilter, column string) string {
+	if f.All {
+		return "TRUE"
+	}
+	cond := fmt.Sprintf("%s IN (SELECT id FROM subjects WHERE visibility = '%s' AND deleted_at IS NULL)", column, models.VisibilityPublic)
+	if f.ViewerID != nil {
+		cond += fmt.Sprintf(" OR %s IN (SELECT subject_id FROM roles WHERE user_id = @viewer AND deleted_at IS NULL)", column)
+	}
+	return "(" + cond + ")"
+}
+
+// visibleProjects условие на проект: вызывающий в нем состоит или видит все проекты предмета
+func visibleProjects(f *models.SearchFilter, projectColumn, subjectColumn string) string {
+	if f.All {
+		return "TRUE"
+	}
+	cond := fmt.Sprintf("%s IN (SELECT project_id FROM project_members WHERE user_id = @viewer AND deleted_at IS NULL)", projectColumn)
+	if len(f.ProjectSubjectIDs) > 0 {
+		cond += fmt.Sprintf(" OR %s IN @view_all", subjectColumn)
+	}
+	return "(" + cond + ")"
+}
+
+// publishedTasks условие на задание: опубликовано либо вызывающий ведет задания предмета
+func publishedTasks(f *models.SearchFilter, alias string) string {
+	if f.All {
+		return "TRUE"
+	}
+	cond := fmt.Sprintf("%[1]s.status = '%[2]s' AND (%[1]s.publish_at IS NULL OR %[1]s.publish_at <= NOW())", alias, models.TaskPublished)
+	if len(f.TaskSubjectIDs) > 0 {
+		cond = fmt.Sprintf("(%s) OR %s.subject_id IN @task_managers", cond, alias)
+	}
+	return "(" + cond + ")"
+}
+
+func subjectFilter(f *models.SearchFilter, column string) string {
+	if f.SubjectID == nil {
+		return ""
+	}
+	return fmt.Sprintf(" AND %s = @subject", column)
+}
+
+func subjectSearchSQL(f *models.SearchFilter) string {
+	text := searchText("s", "name", "description")
+	return fmt.Sprintf(`(SELECT '%s' AS type, s.id, s.id AS subject_id, NULL::uuid AS task_id, NULL::uuid AS project_id,
+	s.name AS title, ts_headline('russian', %s, %s, '%s') AS snippet, ts_rank(%s, %s) AS rank
+FROM subjects s
+WHERE s.deleted_at IS NULL AND %s @@ %s AND %s%s)`,
+		models.SearchSubject, text, searchQuery, headlineOption,
+		weightedVector(searchText("s", "name"), searchText("s", "description")), searchQuery,
+		searchVector(text), searchQuery, visibleSubjects(f, "s.id"), subjectFilter(f, "s.id"))
+}
+
+func taskSearchSQL(f *models.SearchFilter) string {
+	text := searchText("t", "title", "description")
+	return fmt.Sprintf(`(SELECT '%s' AS type, t.id, t.subject_id, t.id AS task_id, NULL::uuid AS project_id,
+	t.title, ts_headline('russian', %s, %s, '%s') AS snippet, ts_rank(%s, %s) AS rank
+FROM tasks t
+WHERE t.deleted_at IS NULL AND %s @@ %s AND %s AND %s%s)`,
+		models.SearchTask, text, searchQuery, headlineOption,
+		weightedVector(searchText("t", "title"), searchText("t", "description")), searchQuery,
+		searchVector(text), searchQuery, visibleSubjects(f, "t.subject_id"), publishedTasks(f, "t"), subjectFilter(f, "t.subject_id"))
+}
+
+func projectSearchSQL(f *models.SearchFilter) string {
+	text := searchText("pr", "title", "description")
+	return fmt.Sprintf(`(SELECT '%s' AS type, pr.id, t.subject_id, t.id AS task_id, pr.id AS project_id,
+	pr.title, ts_headline('russian', %s, %s, '%s') AS snippet, ts_rank(%s, %s) AS rank
+FROM projects pr
+JOIN tasks t ON t.id = pr.task_id AND t.deleted_at IS NULL
+WHERE pr.deleted_at IS NULL AND %s @@ %s AND %s%s)`,
+		models.SearchProject, text, searchQuery, headlineOption,
+		weightedVector(searchText("pr", "title"), searchText("pr", "description")), searchQuery,
+		searchVector(text), searchQuery, visibleProjects(f, "pr.id", "t.subject_id"), subjectFilter(f, "t.subject_id"))
+}
+
+// problemSearchSQL ищет по заголовку и описанию задачи, а также по комментарию к ее результату
+func problemSearchSQL(f *models.SearchFilter) string {
+	text := searchText("p", "title", "description")
+	comment := searchText("res", "comment")
+	full := text + " || ' ' || " + comment
+	return fmt.Sprintf(`(SELECT '%s' AS type, p.id, t.subject_id, t.id AS task_id, pr.id AS project_id,
+	p.title, ts_headline('russian', %s, %s, '%s') AS snippet, ts_rank(%s, %s) AS rank
+FROM problems p
+JOIN projects pr ON pr.id = p.project_id AND pr.deleted_at IS NULL
+JOIN tasks t ON t.id = pr.task_id AND t.deleted_at IS NULL
+LEFT JOIN results res ON res.problem_id = p.id AND res.deleted_at IS NULL
+WHERE p.deleted_at IS NULL AND (%s @@ %s OR %s @@ %s) AND %s%s)`,
+		models.SearchProblem, full, searchQuery, headlineOption,
+		weightedVector(searchText("p", "title"), searchText("p", "description")+" || ' ' || "+comment), searchQuery,
+		searchVector(text), searchQuery, searchVector(comment), searchQuery,
+		visibleProjects(f, "pr.id", "t.subject_id"), subjectFilter(f, "t.subject_id"))
+}
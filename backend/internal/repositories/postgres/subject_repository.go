@@ -3,6 +3,7 @@ package postgres
 import (
 	"context"
 	"errors"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/wozhdeleniye/redclass-app/internal/models"
@@ -24,6 +25,28 @@ func (r *SubjectRepository) Create(ctx context.Context, subject *models.Subject)
 	return r.db.WithContext(ctx).Create(subject).Error
 }
 
+// CreateWithContents создает предмет вместе с ролью владельца, заданиями и их шаблонами этапов
+// в одной транзакции. Шаблоны передаются в порядке обхода дерева.
+func (r *SubjectRepository) CreateWithContents(ctx context.Context, subject *models.Subject, owner *models.Role, tasks []*models.Task, templates []*models.ProblemTemplate) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(subject).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(owner).Error; err != nil {
+			return err
+		}
+		for _, task := range tasks {
+			if err := tx.Create(task).Error; err != nil {
+				return err
+			}
+		}
+		if len(templates) == 0 {
+			return nil
+		}
+		return tx.Omit("Children").Create(&templates).Error
+	})
+}
+
 func (r *SubjectRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Subject, error) {
 	var subject models.Subject
 	err := r.db.WithContext(ctx).
@@ -52,18 +75,45 @@ func (r *SubjectRepository) GetByCode(ctx context.Context, code string) (*models
 	return &subject, nil
 }
 
-func (r *SubjectRepository) GetAll(ctx context.Context, limit, offset int) ([]*models.Subject, int64, error) {
+// GetShallowByID получает предмет без ролей и заданий
+func (r *SubjectRepository) GetShallowByID(ctx context.Context, id uuid.UUID) (*models.Subject, error) {
+	var subject models.Subject
+	err := r.db.WithContext(ctx).First(&subject, "id = ?", id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("subject not found")
+		}
+		return nil, err
+	}
+	return &subject, nil
+}
+
+func (r *SubjectRepository) GetAll(ctx context.Context, filter models.SubjectListFilter) ([]*models.Subject, int64, error) {
 	var subjects []*models.Subject
 	var total int64
 
-	err := r.db.WithContext(ctx).Model(&models.Subject{}).Count(&total).Error
+	query := r.db.WithContext(ctx).Model(&models.Subject{})
+	if !filter.IncludeArchived {
+		query = query.Where("archived_at IS NULL")
+	}
+	if !filter.All {
+		if filter.ViewerID != nil {
+			query = query.Where("visibility = ? OR id IN (?)", models.VisibilityPublic,
+				r.db.Model(&models.Role{}).Select("subject_id").Where("user_id = ?", *filter.ViewerID))
+		} else {
+			query = query.Where("visibility = ?", models.VisibilityPublic)
+		}
+	}
+
+	err := query.Count(&total).Error
 	if err != nil {
 		return nil, 0, err
 	}
 
-	err = r.db.WithContext(ctx).
-		Limit(limit).
-		Offset(offset).
+	err = query.
+		Order("created_at DESC").
+		Limit(filter.Limit).
+		Offset(filter.Offset).
 		Find(&subjects).Error
 	if err != nil {
 		return nil, 0, err
@@ -90,3 +140,45 @@ func (r *SubjectRepository) GetUserSubjects(ctx context.Context, userID uuid.UUI
 		Find(&subjects).Error
 	return subjects, err
 }
+
+// GetOrphaned получает предметы, у которых не осталось активного администратора
+func (r *SubjectRepository) GetOrphaned(ctx context.Context) ([]*models.Subject, error) {
+	var subjects []*models.Subject
+	err := r.db.WithContext(ctx).
+		Where("NOT EXISTS (?)", r.db.Model(&models.Role{}).
+			Select("1").
+			Joins("JOIN users ON users.id = roles.user_id AND users.is_active = ?", true).
+			Where("roles.subject_id = subjects.id AND roles.role_type = ?", models.RoleAdmin)).
+		Find(&subjects).Error
+	return subjects, err
+}
+
+func (r *SubjectRepository) SetRequiresApproval(ctx context.Context, id uuid.UUID, requiresApproval bool) error {
+	return r.db.WithContext(ctx).
+		Model(&models.Subject{}).
+		Where("id = ?", id).
+		Update("requires_approval", requiresApproval).Error
+}
+
+func (r *SubjectRepository) SetOwner(ctx context.Context, id, ownerID uuid.UUID) error {
+	return r.db.WithContext(ctx).
+		Model(&models.Subject{}).
+		Where("id = ?", id).
+		Update("owner_id", ownerID).Error
+}
+
+// IsArchived проверяет, находится ли предмет в архиве; несуществующий предмет считается неархивным
+func (r *SubjectRepository) IsArchived(ctx context.Context, id uuid.UUID) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.Subject{}).
+		Where("id = ? AND archived_at IS NOT NULL", id).
+		Count(&count).Error
+	return count > 0, err
+}
+
+func (r *SubjectRepository) SetArchivedAt(ctx context.Context, id uuid.UUID, archivedAt *time.Time) error {
+	return r.db.WithContext(ctx).
+		Model(&models.Subject{}).
+		Where("id = ?", id).
+		Update("archived_at", archivedAt).Error
+}
@@ -0,0 +1,131 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/wozhdeleniye/redclass-app/internal/models"
+	"gorm.io/gorm"
+)
+
+type SubmissionRepository struct {
+	db *gorm.DB
+}
+
+func NewSubmissionRepository(db *gorm.DB) *SubmissionRepository {
+	return &SubmissionRepository{db: db}
+}
+
+func (r *SubmissionRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.ProjectSubmission, error) {
+	var submission models.ProjectSubmission
+	err := r.db.WithContext(ctx).
+		Preload("SubmittedBy", publicUserColumns).
+		First(&submission, "id = ?", id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("submission not found")
+		}
+		return nil, err
+	}
+	return &submission, nil
+}
+
+// GetByProject попытки сдачи проекта, последняя первой
+func (r *SubmissionRepository) GetByProject(ctx context.Context, projectID uuid.UUID) ([]*models.ProjectSubmission, error) {
+	submissions := []*models.ProjectSubmission{}
+	err := r.db.WithContext(ctx).
+		Preload("SubmittedBy", publicUserColumns).
+		Where("project_id = ?", projectID).
+		Order("attempt DESC").
+		Find(&submissions).Error
+	return submissions, err
+}
+
+// GetLatest последняя попытка сдачи проекта; nil, если проект еще не сдавали
+func (r *SubmissionRepository) GetLatest(ctx context.Context, projectID uuid.UUID) (*models.ProjectSubmission, error) {
+	var submission models.ProjectSubmission
+	err := r.db.WithContext(ctx).
+		Where("project_id = ?", projectID).
+		Order("attempt DESC").
+		First(&submission).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &submission, nil
+}
+
+// errProjectStatusChanged откатывает сдачу или возврат, если статус проекта уже изменили
+var errProjectStatusChanged = errors.New("project status has changed")
+
+// Submit замораживает проект и сохраняет попытку со следующим номером в одной транзакции.
+// false, если проект уже сдан; тогда попытка не создается.
+func (r *SubmissionRepository) Submit(ctx context.Context, submission *models.ProjectSubmission) (bool, error) {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// условное обновление блокирует строку проекта, поэтому параллельные сдачи идут по очереди
+		result := tx.Model(&models.Project{}).
+			Where("id = ? AND status NOT IN ?", submission.ProjectID, models.LockedProjectStatuses).
+			Updates(map[string]interface{}{"status": models.ProjectSubmitted, "submitted_at": submission.CreatedAt})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errProjectStatusChanged
+		}
+
+		var last int
+		if err := tx.Model(&models.ProjectSubmission{}).
+			Where("project_id = ?", submission.ProjectID).
+			Select("COALESCE(MAX(attempt), 0)").
+			Scan(&last).Error; err != nil {
+			return err
+		}
+		submission.Attempt = last + 1
+		if submission.ID == uuid.Nil {
+			submission.ID = uuid.New()
+		}
+		return tx.Omit("Project", "SubmittedBy").Create(submission).Error
+	})
+	if errors.Is(err, errProjectStatusChanged) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Return отмечает попытку возвращенной и открывает проект для доработки.
+// false, если проект уже не сдан или попытку вернули раньше.
+func (r *SubmissionRepository) Return(ctx context.Context, submission *models.ProjectSubmission) (bool, error) {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.Project{}).
+			Where("id = ? AND status IN ?", submission.ProjectID, models.LockedProjectStatuses).
+			Update("status", models.ProjectReopened)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errProjectStatusChanged
+		}
+
+		result = tx.Model(&models.ProjectSubmission{}).
+			Where("id = ? AND returned_at IS NULL", submission.ID).
+			Updates(map[string]interface{}{
+				"returned_at":    submission.ReturnedAt,
+				"returned_by_id": submission.ReturnedByID,
+				"return_comment": submission.ReturnComment,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errProjectStatusChanged
+		}
+		return nil
+	})
+	if errors.Is(err, errProjectStatusChanged) {
+		return false, nil
+	}
+	return err == nil, err
+}
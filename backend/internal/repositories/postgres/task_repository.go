@@ -3,10 +3,12 @@ package postgres
 import (
 	"context"
 	"errors"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/wozhdeleniye/redclass-app/internal/models"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type TaskRepository struct {
@@ -28,7 +30,8 @@ func (r *TaskRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Tas
 	var task models.Task
 	err := r.db.WithContext(ctx).
 		Preload("Subject").
-		Preload("CreatedBy").
+		Preload("CreatedBy", publicUserColumns).
+		Preload("Groups.Group").
 		First(&task, "id = ?", id).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
@@ -39,21 +42,34 @@ func (r *TaskRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Tas
 	return &task, nil
 }
 
-func (r *TaskRepository) GetBySubject(ctx context.Context, subjectID uuid.UUID, limit, offset int) ([]*models.Task, int64, error) {
+// GetBySubject получает задания предмета; при groupID только назначенные этой группе или всему предмету,
+// при publishedOnly без черновиков и заданий, время публикации которых еще не наступило
+func (r *TaskRepository) GetBySubject(ctx context.Context, subjectID uuid.UUID, groupID *uuid.UUID, publishedOnly bool, limit, offset int) ([]*models.Task, int64, error) {
 	var tasks []*models.Task
 	var total int64
 
-	err := r.db.WithContext(ctx).
+	query := r.db.WithContext(ctx).
 		Model(&models.Task{}).
-		Where("subject_id = ?", subjectID).
-		Count(&total).Error
+		Where("subject_id = ?", subjectID)
+	if publishedOnly {
+		query = query.Where("status = ? AND (publish_at IS NULL OR publish_at <= ?)", models.TaskPublished, time.Now())
+	}
+	if groupID != nil {
+		query = query.Where(
+			"NOT EXISTS (SELECT 1 FROM task_groups WHERE task_groups.task_id = tasks.id) OR "+
+				"EXISTS (SELECT 1 FROM task_groups WHERE task_groups.task_id = tasks.id AND task_groups.group_id = ?)",
+			*groupID,
+		)
+	}
+
+	err := query.Count(&total).Error
 	if err != nil {
 		return nil, 0, err
 	}
 
-	err = r.db.WithContext(ctx).
-		Where("subject_id = ?", subjectID).
-		Preload("CreatedBy").
+	err = query.
+		Preload("CreatedBy", publicUserColumns).
+		Preload("Groups.Group").
 		Limit(limit).
 		Offset(offset).
 		Find(&tasks).Error
@@ -64,12 +80,26 @@ func (r *TaskRepository) GetBySubject(ctx context.Context, subjectID uuid.UUID,
 	return tasks, total, nil
 }
 
+// Update сохраняет все поля задания, в том числе сброшенные в nil ограничения команд
 func (r *TaskRepository) Update(ctx context.Context, task *models.Task) error {
 	return r.db.WithContext(ctx).
 		Model(task).
+		Select("*").
+		Omit(clause.Associations, "created_at").
 		Updates(task).Error
 }
 
 func (r *TaskRepository) Delete(ctx context.Context, id uuid.UUID) error {
 	return r.db.WithContext(ctx).Delete(&models.Task{}, "id = ?", id).Error
 }
+
+// GetAllBySubject получает все задания предмета без пагинации
+func (r *TaskRepository) GetAllBySubject(ctx context.Context, subjectID uuid.UUID) ([]*models.Task, error) {
+	var tasks []*models.Task
+	err := r.db.WithContext(ctx).
+		Preload("Groups").
+		Where("subject_id = ?", subjectID).
+		Order("created_at ASC").
+		Find(&tasks).Error
+	return tasks, err
+}
@@ -18,6 +18,11 @@ func NewUserRepository(db *gorm.DB) *UserRepository {
 	return &UserRepository{db: db}
 }
 
+// publicUserColumns ограничивает поля пользователя при предзагрузке, чтобы не раскрывать email посторонним
+func publicUserColumns(db *gorm.DB) *gorm.DB {
+	return db.Select("id", "nickname", "is_active", "created_at", "updated_at")
+}
+
 func (r *UserRepository) CreateUser(ctx context.Context, user *models.User) error {
 	result := r.db.WithContext(ctx).Create(user)
 	return result.Error
@@ -53,6 +58,88 @@ func (r *UserRepository) GetUserByID(ctx context.Context, id uuid.UUID) (*models
 	return &user, nil
 }
 
+// GetUserByEmailIncludingInactive получает пользователя по email в том числе деактивированного
+func (r *UserRepository) GetUserByEmailIncludingInactive(ctx context.Context, email string) (*models.User, error) {
+	var user models.User
+	result := r.db.WithContext(ctx).Where("email = ?", email).First(&user)
+
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return &user, nil
+}
+
+// GetUserByIDIncludingInactive получает пользователя по ID в том числе деактивированного
+func (r *UserRepository) GetUserByIDIncludingInactive(ctx context.Context, id uuid.UUID) (*models.User, error) {
+	var user models.User
+	result := r.db.WithContext(ctx).Where("id = ?", id).First(&user)
+
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return &user, nil
+}
+
+func (r *UserRepository) SetActive(ctx context.Context, id uuid.UUID, active bool) error {
+	result := r.db.WithContext(ctx).Model(&models.User{}).Where("id = ?", id).Update("is_active", active)
+	return result.Error
+}
+
+func (r *UserRepository) SetSuperAdmin(ctx context.Context, id uuid.UUID, superAdmin bool) error {
+	result := r.db.WithContext(ctx).Model(&models.User{}).Where("id = ?", id).Update("is_super_admin", superAdmin)
+	return result.Error
+}
+
+// CountSuperAdmins число супер-администраторов, включая деактивированных
+func (r *UserRepository) CountSuperAdmins(ctx context.Context) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.User{}).Where("is_super_admin = ?", true).Count(&count).Error
+	return count, err
+}
+
+// Search ищет пользователей по email или никнейму, включая деактивированных
+func (r *UserRepository) Search(ctx context.Context, filter models.UserSearchFilter) ([]*models.User, int64, error) {
+	var users []*models.User
+	var total int64
+
+	query := r.db.WithContext(ctx).Model(&models.User{})
+	if filter.Query != "" {
+		pattern := "%" + filter.Query + "%"
+		query = query.Where("email ILIKE ? OR nickname ILIKE ?", pattern, pattern)
+	}
+	if filter.IsActive != nil {
+		query = query.Where("is_active = ?", *filter.IsActive)
+	}
+	if filter.IsSuperAdmin != nil {
+		query = query.Where("is_super_admin = ?", *filter.IsSuperAdmin)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := query.
+		Order("created_at DESC").
+		Limit(filter.Limit).
+		Offset(filter.Offset).
+		Find(&users).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return users, total, nil
+}
+
 func (r *UserRepository) UpdateUser(ctx context.Context, user *models.User) error {
 	result := r.db.WithContext(ctx).Save(user)
 	return result.Error
@@ -68,3 +155,15 @@ func (r *UserRepository) UserExists(ctx context.Context, email string) (bool, er
 	result := r.db.WithContext(ctx).Model(&models.User{}).Where("email = ? AND is_active = ?", email, true).Count(&count)
 	return count > 0, result.Error
 }
+
+// GetUsersByEmails получает пользователей по списку email без учета регистра, включая неактивных
+func (r *UserRepository) GetUsersByEmails(ctx context.Context, emails []string) ([]*models.User, error) {
+	var users []*models.User
+	if len(emails) == 0 {
+		return users, nil
+	}
+	err := r.db.WithContext(ctx).
+		Where("LOWER(email) IN ?", emails).
+		Find(&users).Error
+	return users, err
+}
@@ -0,0 +1,80 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+type RateLimitRepository struct {
+	client *redis.Client
+}
+
+func NewRateLimitRepository(client *redis.Client) *RateLimitRepository {
+	return &RateLimitRepository{client: client}
+}
+
+// Increment увеличивает счетчик в окне window и возвращает новое значение и оставшееся время жизни окна
+func (r *RateLimitRepository) Increment(ctx context.Context, key string, window time.Duration) (int64, time.Duration, error) {
+	redisKey := fmt.Sprintf("rate_limit:%s", key)
+
+	pipe := r.client.TxPipeline()
+	incr := pipe.Incr(ctx, redisKey)
+	pipe.ExpireNX(ctx, redisKey, window)
+	ttl := pipe.PTTL(ctx, redisKey)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, 0, err
+	}
+
+	remaining := ttl.Val()
+	if remaining < 0 {
+		remaining = window
+	}
+	return incr.Val(), remaining, nil
+}
+
+// GetCount возвращает текущее значение счетчика без его изменения
+func (r *RateLimitRepository) GetCount(ctx context.Context, key string) (int64, error) {
+	redisKey := fmt.Sprintf("rate_limit:%s", key)
+
+	count, err := r.client.Get(ctx, redisKey).Int64()
+	if err != nil {
+		if err == redis.Nil {
+			return 0, nil
+		}
+		return 0, err
+	}
+	return count, nil
+}
+
+func (r *RateLimitRepository) Reset(ctx context.Context, key string) error {
+	redisKey := fmt.Sprintf("rate_limit:%s", key)
+	return r.client.Del(ctx, redisKey).Err()
+}
+
+// SetBlock запрещает попытки по ключу на время duration
+func (r *RateLimitRepository) SetBlock(ctx context.Context, key string, duration time.Duration) error {
+	redisKey := fmt.Sprintf("rate_block:%s", key)
+	return r.client.Set(ctx, redisKey, "1", duration).Err()
+}
+
+// GetBlock возвращает оставшееся время блокировки (0, если блокировки нет)
+func (r *RateLimitRepository) GetBlock(ctx context.Context, key string) (time.Duration, error) {
+	redisKey := fmt.Sprintf("rate_block:%s", key)
+
+	ttl, err := r.client.PTTL(ctx, redisKey).Result()
+	if err != nil {
+		return 0, err
+	}
+	if ttl < 0 {
+		return 0, nil
+	}
+	return ttl, nil
+}
+
+func (r *RateLimitRepository) ClearBlock(ctx context.Context, key string) error {
+	redisKey := fmt.Sprintf("rate_block:%s", key)
+	return r.client.Del(ctx, redisKey).Err()
+}
@@ -73,3 +73,23 @@ func (r *TokenRepository) IsTokenBlacklisted(ctx context.Context, token string)
 
 	return exists > 0, nil
 }
+
+// StoreSessionsRevokedAt помечает все токены пользователя, выданные раньше at, как недействительные
+func (r *TokenRepository) StoreSessionsRevokedAt(ctx context.Context, userID uuid.UUID, at time.Time, expiresIn time.Duration) error {
+	key := fmt.Sprintf("sessions_revoked_at:%s", userID.String())
+	return r.client.Set(ctx, key, at.Unix(), expiresIn).Err()
+}
+
+func (r *TokenRepository) GetSessionsRevokedAt(ctx context.Context, userID uuid.UUID) (time.Time, error) {
+	key := fmt.Sprintf("sessions_revoked_at:%s", userID.String())
+
+	unix, err := r.client.Get(ctx, key).Int64()
+	if err != nil {
+		if err == redis.Nil {
+			return time.Time{}, nil
+		}
+		return time.Time{}, err
+	}
+
+	return time.Unix(unix, 0), nil
+}
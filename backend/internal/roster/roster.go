@@ -0,0 +1,372 @@
+// Package roster читает и записывает списки участников предмета в форматах CSV и XLSX.
+// Поддерживается только первый лист XLSX и только строковые и числовые ячейки,
+// чего достаточно для выгрузок из LMS и таблиц преподавателей.
+package roster
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type Format string
+
+const (
+	FormatCSV  Format = "csv"
+	FormatXLSX Format = "xlsx"
+)
+
+var ErrUnsupportedFormat = errors.New("unsupported roster format, expected csv or xlsx")
+
+var (
+	// ErrTooLarge распакованный XLSX превышает MaxUncompressedSize
+	ErrTooLarge = errors.New("roster file is too large")
+	// ErrTooManyRows в таблице больше строк, чем разрешено
+	ErrTooManyRows = errors.New("roster is too large")
+)
+
+const (
+	// MaxColumns предел числа колонок листа XLSX (XFD)
+	MaxColumns = 16384
+	// MaxUncompressedSize сколько байт можно распаковать из XLSX суммарно по всем частям
+	MaxUncompressedSize = 50 << 20
+)
+
+// DetectFormat определяет формат по имени файла, а если его нет, по сигнатуре zip-архива
+func DetectFormat(filename string, data []byte) Format {
+	switch strings.ToLower(path.Ext(filename)) {
+	case ".xlsx":
+		return FormatXLSX
+	case ".csv", ".txt":
+		return FormatCSV
+	}
+	if bytes.HasPrefix(data, []byte("PK\x03\x04")) {
+		return FormatXLSX
+	}
+	return FormatCSV
+}
+
+// Read разбирает таблицу в строки ячеек. Разбор прерывается, как только строк становится
+// больше maxRows, чтобы огромная таблица не собиралась в памяти целиком.
+func Read(data []byte, format Format, maxRows int) ([][]string, error) {
+	switch format {
+	case FormatCSV:
+		return readCSV(data, maxRows)
+	case FormatXLSX:
+		return readXLSX(data, maxRows)
+	}
+	return nil, ErrUnsupportedFormat
+}
+
+func tooManyRows(maxRows int) error {
+	return fmt.Errorf("%w, at most %d rows are allowed", ErrTooManyRows, maxRows)
+}
+
+// Write записывает таблицу в w
+func Write(w io.Writer, format Format, rows [][]string) error {
+	switch format {
+	case FormatCSV:
+		cw := csv.NewWriter(w)
+		if err := cw.WriteAll(rows); err != nil {
+			return err
+		}
+		return cw.Error()
+	case FormatXLSX:
+		return writeXLSX(w, rows)
+	}
+	return ErrUnsupportedFormat
+}
+
+func ContentType(format Format) string {
+	if format == FormatXLSX {
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+func readCSV(data []byte, maxRows int) ([][]string, error) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+
+	// Excel в русской локали сохраняет CSV с точкой с запятой
+	comma := ','
+	firstLine := data
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		firstLine = data[:i]
+	}
+	if bytes.Count(firstLine, []byte(";")) > bytes.Count(firstLine, []byte(",")) {
+		comma = ';'
+	}
+
+	r := csv.NewReader(bytes.NewReader(data))
+	r.Comma = comma
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+
+	var rows [][]string
+	for {
+		record, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(rows) == maxRows {
+			return nil, tooManyRows(maxRows)
+		}
+		rows = append(rows, record)
+	}
+}
+
+type xlsxSharedStrings struct {
+	Items []struct {
+		Text string `xml:"t"`
+		Runs []struct {
+			Text string `xml:"t"`
+		} `xml:"r"`
+	} `xml:"si"`
+}
+
+type xlsxRow struct {
+	Cells []struct {
+		Ref       string `xml:"r,attr"`
+		Type      string `xml:"t,attr"`
+		Value     string `xml:"v"`
+		InlineStr struct {
+			Text string `xml:"t"`
+		} `xml:"is"`
+	} `xml:"c"`
+}
+
+// budgetReader отдает не больше *left байт на все части архива и дальше возвращает ErrTooLarge
+type budgetReader struct {
+	r    io.Reader
+	left *int64
+}
+
+func (b *budgetReader) Read(p []byte) (int, error) {
+	if *b.left <= 0 {
+		return 0, ErrTooLarge
+	}
+	if int64(len(p)) > *b.left {
+		p = p[:*b.left]
+	}
+	n, err := b.r.Read(p)
+	*b.left -= int64(n)
+	return n, err
+}
+
+func readXLSX(data []byte, maxRows int) ([][]string, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid xlsx file: %w", err)
+	}
+
+	files := make(map[string]*zip.File, len(zr.File))
+	var sheets []string
+	for _, f := range zr.File {
+		files[f.Name] = f
+		if strings.HasPrefix(f.Name, "xl/worksheets/") && strings.HasSuffix(f.Name, ".xml") {
+			sheets = append(sheets, f.Name)
+		}
+	}
+	if len(sheets) == 0 {
+		return nil, errors.New("invalid xlsx file: no worksheets")
+	}
+	sort.Strings(sheets)
+	sheetName := sheets[0]
+	if _, ok := files["xl/worksheets/sheet1.xml"]; ok {
+		sheetName = "xl/worksheets/sheet1.xml"
+	}
+
+	budget := int64(MaxUncompressedSize)
+	var shared []string
+	if f, ok := files["xl/sharedStrings.xml"]; ok {
+		var ss xlsxSharedStrings
+		if err := decodeZipXML(f, &budget, func(d *xml.Decoder) error { return d.Decode(&ss) }); err != nil {
+			return nil, err
+		}
+		for _, item := range ss.Items {
+			text := item.Text
+			for _, run := range item.Runs {
+				text += run.Text
+			}
+			shared = append(shared, text)
+		}
+	}
+
+	// лист читается построчно, чтобы остановиться на maxRows, не разбирая остаток
+	var rows [][]string
+	err = decodeZipXML(files[sheetName], &budget, func(d *xml.Decoder) error {
+		for {
+			token, err := d.Token()
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			start, ok := token.(xml.StartElement)
+			if !ok || start.Name.Local != "row" {
+				continue
+			}
+			if len(rows) == maxRows {
+				return tooManyRows(maxRows)
+			}
+			var row xlsxRow
+			if err := d.DecodeElement(&row, &start); err != nil {
+				return err
+			}
+			cells, err := rowCells(row, shared)
+			if err != nil {
+				return err
+			}
+			rows = append(rows, cells)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+func rowCells(row xlsxRow, shared []string) ([]string, error) {
+	var cells []string
+	for i, c := range row.Cells {
+		col := i
+		if c.Ref != "" {
+			var ok bool
+			if col, ok = columnIndex(c.Ref); !ok {
+				return nil, fmt.Errorf("invalid xlsx file: bad cell reference %q", c.Ref)
+			}
+		}
+		if col >= MaxColumns {
+			return nil, fmt.Errorf("invalid xlsx file: more than %d columns", MaxColumns)
+		}
+		for len(cells) <= col {
+			cells = append(cells, "")
+		}
+
+		switch c.Type {
+		case "s":
+			idx, err := strconv.Atoi(c.Value)
+			if err != nil || idx < 0 || idx >= len(shared) {
+				return nil, fmt.Errorf("invalid xlsx file: bad shared string in %s", c.Ref)
+			}
+			cells[col] = shared[idx]
+		case "inlineStr":
+			cells[col] = c.InlineStr.Text
+		default:
+			cells[col] = c.Value
+		}
+	}
+	return cells, nil
+}
+
+// decodeZipXML распаковывает часть архива в пределах общего бюджета budget и передает ее decode
+func decodeZipXML(f *zip.File, budget *int64, decode func(*xml.Decoder) error) error {
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	if err := decode(xml.NewDecoder(&budgetReader{r: rc, left: budget})); err != nil {
+		if errors.Is(err, ErrTooLarge) || errors.Is(err, ErrTooManyRows) {
+			return err
+		}
+		return fmt.Errorf("invalid xlsx file: %w", err)
+	}
+	return nil
+}
+
+// columnIndex переводит ссылку вида "AB12" в номер колонки с нуля; false, если букв нет
+// или колонка за пределами MaxColumns
+func columnIndex(ref string) (int, bool) {
+	col := 0
+	for _, ch := range ref {
+		if ch < 'A' || ch > 'Z' {
+			break
+		}
+		col = col*26 + int(ch-'A'+1)
+		if col > MaxColumns {
+			return 0, false
+		}
+	}
+	if col == 0 {
+		return 0, false
+	}
+	return col - 1, true
+}
+
+func columnName(col int) string {
+	name := ""
+	for col++; col > 0; col = (col - 1) / 26 {
+		name = string(rune('A'+(col-1)%26)) + name
+	}
+	return name
+}
+
+const xlsxContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="xml" ContentType="application/xml"/><Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/><Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/></Types>`
+
+const xlsxRootRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/></Relationships>`
+
+const xlsxWorkbook = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="Roster" sheetId="1" r:id="rId1"/></sheets></workbook>`
+
+const xlsxWorkbookRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/></Relationships>`
+
+func writeXLSX(w io.Writer, rows [][]string) error {
+	zw := zip.NewWriter(w)
+
+	static := []struct{ name, body string }{
+		{"[Content_Types].xml", xlsxContentTypes},
+		{"_rels/.rels", xlsxRootRels},
+		{"xl/workbook.xml", xlsxWorkbook},
+		{"xl/_rels/workbook.xml.rels", xlsxWorkbookRels},
+	}
+	for _, f := range static {
+		fw, err := zw.Create(f.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(fw, f.body); err != nil {
+			return err
+		}
+	}
+
+	fw, err := zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return err
+	}
+	var sb strings.Builder
+	sb.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>`)
+	sb.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+	for i, row := range rows {
+		fmt.Fprintf(&sb, `<row r="%d">`, i+1)
+		for j, cell := range row {
+			fmt.Fprintf(&sb, `<c r="%s%d" t="inlineStr"><is><t xml:space="preserve">`, columnName(j), i+1)
+			if err := xml.EscapeText(&sb, []byte(cell)); err != nil {
+				return err
+			}
+			sb.WriteString(`</t></is></c>`)
+		}
+		sb.WriteString(`</row>`)
+	}
+	sb.WriteString(`</sheetData></worksheet>`)
+	if _, err := io.WriteString(fw, sb.String()); err != nil {
+		return err
+	}
+
+	return zw.Close()
+}
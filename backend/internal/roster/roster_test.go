@@ -0,0 +1,215 @@
+package roster
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// buildXLSX собирает архив из частей name -> содержимое
+func buildXLSX(t *testing.T, parts map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, body := range parts {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func sheet(rows string) string {
+	return `<?xml version="1.0" encoding="UTF-8"?><worksheet><sheetData>` + rows + `</sheetData></worksheet>`
+}
+
+func TestReadCSV(t *testing.T) {
+	cases := []struct {
+		name    string
+		data    string
+		maxRows int
+		want    [][]string
+		wantErr error
+	}{
+		{
+			name:    "comma",
+			data:    "email,role\na@x.ru,student\n",
+			maxRows: 10,
+			want:    [][]string{{"email", "role"}, {"a@x.ru", "student"}},
+		},
+		{
+			name:    "semicolon from excel with bom",
+			data:    "\xef\xbb\xbfemail;группа\na@x.ru;ИВТ-1\n",
+			maxRows: 10,
+			want:    [][]string{{"email", "группа"}, {"a@x.ru", "ИВТ-1"}},
+		},
+		{
+			name:    "ragged rows",
+			data:    "a@x.ru\nb@x.ru,teacher,g1\n",
+			maxRows: 10,
+			want:    [][]string{{"a@x.ru"}, {"b@x.ru", "teacher", "g1"}},
+		},
+		{
+			name:    "exactly max rows",
+			data:    "a\nb\n",
+			maxRows: 2,
+			want:    [][]string{{"a"}, {"b"}},
+		},
+		{
+			name:    "too many rows",
+			data:    "a\nb\nc\n",
+			maxRows: 2,
+			wantErr: ErrTooManyRows,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := readCSV([]byte(tc.data), tc.maxRows)
+			if tc.wantErr != nil {
+				if !errors.Is(err, tc.wantErr) {
+					t.Fatalf("got error %v, want %v", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestReadXLSX(t *testing.T) {
+	written := new(bytes.Buffer)
+	if err := writeXLSX(written, [][]string{{"email", "role"}, {"a@x.ru", "student"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	// около 60 МБ пробелов сжимаются в десятки килобайт
+	bomb := sheet(`<row r="1"><c r="A1" t="inlineStr"><is><t>a</t></is></c></row>` + strings.Repeat(" ", 60<<20))
+
+	cases := []struct {
+		name    string
+		data    []byte
+		maxRows int
+		want    [][]string
+		wantErr string
+	}{
+		{
+			name:    "round trip of written file",
+			data:    written.Bytes(),
+			maxRows: 10,
+			want:    [][]string{{"email", "role"}, {"a@x.ru", "student"}},
+		},
+		{
+			name: "shared strings and gaps",
+			data: buildXLSX(t, map[string]string{
+				"xl/sharedStrings.xml":     `<sst><si><t>email</t></si><si><r><t>a@</t></r><r><t>x.ru</t></r></si></sst>`,
+				"xl/worksheets/sheet1.xml": sheet(`<row r="1"><c r="A1" t="s"><v>0</v></c><c r="C1"><v>42</v></c></row><row r="2"><c r="B2" t="s"><v>1</v></c></row>`),
+			}),
+			maxRows: 10,
+			want:    [][]string{{"email", "", "42"}, {"", "a@x.ru"}},
+		},
+		{
+			name: "bad shared string index",
+			data: buildXLSX(t, map[string]string{
+				"xl/worksheets/sheet1.xml": sheet(`<row r="1"><c r="A1" t="s"><v>3</v></c></row>`),
+			}),
+			maxRows: 10,
+			wantErr: "bad shared string",
+		},
+		{
+			name: "huge column reference",
+			data: buildXLSX(t, map[string]string{
+				"xl/worksheets/sheet1.xml": sheet(`<row r="1"><c r="ZZZZZZZZZZ1"><v>1</v></c></row>`),
+			}),
+			maxRows: 10,
+			wantErr: "bad cell reference",
+		},
+		{
+			name: "column past the sheet limit",
+			data: buildXLSX(t, map[string]string{
+				"xl/worksheets/sheet1.xml": sheet(`<row r="1"><c r="XFE1"><v>1</v></c></row>`),
+			}),
+			maxRows: 10,
+			wantErr: "bad cell reference",
+		},
+		{
+			name: "too many rows",
+			data: buildXLSX(t, map[string]string{
+				"xl/worksheets/sheet1.xml": sheet(strings.Repeat(`<row><c><v>1</v></c></row>`, 5)),
+			}),
+			maxRows: 3,
+			wantErr: ErrTooManyRows.Error(),
+		},
+		{
+			name:    "zip bomb",
+			data:    buildXLSX(t, map[string]string{"xl/worksheets/sheet1.xml": bomb}),
+			maxRows: 10,
+			wantErr: ErrTooLarge.Error(),
+		},
+		{
+			name:    "no worksheets",
+			data:    buildXLSX(t, map[string]string{"xl/workbook.xml": "<workbook/>"}),
+			maxRows: 10,
+			wantErr: "no worksheets",
+		},
+		{
+			name:    "not a zip",
+			data:    []byte("email\n"),
+			maxRows: 10,
+			wantErr: "invalid xlsx file",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := readXLSX(tc.data, tc.maxRows)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("got error %v, want one containing %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestColumnIndex(t *testing.T) {
+	cases := []struct {
+		ref  string
+		want int
+		ok   bool
+	}{
+		{"A1", 0, true},
+		{"Z9", 25, true},
+		{"AA10", 26, true},
+		{"XFD1", MaxColumns - 1, true},
+		{"XFE1", 0, false},
+		{"ZZZZZZZZZZZZZZZZ1", 0, false},
+		{"12", 0, false},
+	}
+	for _, tc := range cases {
+		got, ok := columnIndex(tc.ref)
+		if got != tc.want || ok != tc.ok {
+			t.Errorf("columnIndex(%q) = %d, %v; want %d, %v", tc.ref, got, ok, tc.want, tc.ok)
+		}
+	}
+}
@@ -0,0 +1,270 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/wozhdeleniye/redclass-app/internal/models"
+	"github.com/wozhdeleniye/redclass-app/internal/repositories/postgres"
+)
+
+// AdminService операции супер-администратора платформы; каждое изменяющее действие пишется в журнал аудита
+type AdminService struct {
+	adminRepo   *postgres.AdminRepository
+	userRepo    *postgres.UserRepository
+	subjectRepo *postgres.SubjectRepository
+	roleRepo    *postgres.RoleRepository
+	authService *AuthService
+	userService *UserService
+	throttle    *LoginThrottleService
+}
+
+func NewAdminService(
+	adminRepo *postgres.AdminRepository,
+	userRepo *postgres.UserRepository,
+	subjectRepo *postgres.SubjectRepository,
+	roleRepo *postgres.RoleRepository,
+	authService *AuthService,
+	userService *UserService,
+	throttle *LoginThrottleService,
+) *AdminService {
+	return &AdminService{
+		adminRepo:   adminRepo,
+		userRepo:    userRepo,
+		subjectRepo: subjectRepo,
+		roleRepo:    roleRepo,
+		authService: authService,
+		userService: userService,
+		throttle:    throttle,
+	}
+}
+
+func (s *AdminService) SearchUsers(ctx context.Context, filter models.UserSearchFilter) ([]*models.User, int64, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = 20
+	}
+	if filter.Limit > 100 {
+		filter.Limit = 100
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+	return s.userRepo.Search(ctx, filter)
+}
+
+// GetUser возвращает полный профиль пользователя вместе с его ролями в предметах
+func (s *AdminService) GetUser(ctx context.Context, userID uuid.UUID) (*models.User, error) {
+	user, err := s.userRepo.GetUserByIDIncludingInactive(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+
+	roles, err := s.roleRepo.GetUserRoles(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	user.Roles = roles
+	return user, nil
+}
+
+func (s *AdminService) SetUserActive(ctx context.Context, adminID, userID uuid.UUID, active bool, client models.ClientInfo) error {
+	if adminID == userID && !active {
+		return errors.New("cannot deactivate yourself")
+	}
+
+	if err := s.userService.SetUserActive(ctx, userID, active); err != nil {
+		return err
+	}
+
+	action := models.AdminActionActivateUser
+	if !active {
+		action = models.AdminActionDeactivateUser
+	}
+	return s.audit(ctx, adminID, action, "user", userID, "", client)
+}
+
+// ForceLogout отзывает все сессии пользователя
+func (s *AdminService) ForceLogout(ctx context.Context, adminID, userID uuid.UUID, client models.ClientInfo) error {
+	if _, err := s.GetUser(ctx, userID); err != nil {
+		return err
+	}
+
+	if err := s.authService.RevokeSessions(ctx, userID); err != nil {
+		return err
+	}
+	return s.audit(ctx, adminID, models.AdminActionForceLogout, "user", userID, "", client)
+}
+
+// UnlockLogin снимает временную блокировку входа после неудачных попыток
+func (s *AdminService) UnlockLogin(ctx context.Context, adminID, userID uuid.UUID, client models.ClientInfo) error {
+	user, err := s.GetUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.throttle.UnlockAccount(ctx, user.Email); err != nil {
+		return err
+	}
+	return s.audit(ctx, adminID, models.AdminActionUnlockLogin, "user", userID, "", client)
+}
+
+func (s *AdminService) SetSuperAdmin(ctx context.Context, adminID, userID uuid.UUID, superAdmin bool, client models.ClientInfo) error {
+	if adminID == userID && !superAdmin {
+		return errors.New("cannot revoke your own super admin rights")
+	}
+
+	if _, err := s.GetUser(ctx, userID); err != nil {
+		return err
+	}
+
+	if err := s.userRepo.SetSuperAdmin(ctx, userID, superAdmin); err != nil {
+		return err
+	}
+	return s.audit(ctx, adminID, models.AdminActionSetSuperAdmin, "user", userID, fmt.Sprintf("is_super_admin=%t", superAdmin), client)
+}
+
+// Impersonate выдает супер-администратору временный токен для работы от имени пользователя
+func (s *AdminService) Impersonate(ctx context.Context, adminID, userID uuid.UUID, reason string, client models.ClientInfo) (*models.ImpersonationResponse, error) {
+	if adminID == userID {
+		return nil, errors.New("cannot impersonate yourself")
+	}
+
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+	if user.IsSuperAdmin {
+		return nil, errors.New("cannot impersonate another super admin")
+	}
+
+	token, expiresAt, err := s.authService.IssueImpersonationToken(user, adminID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.audit(ctx, adminID, models.AdminActionImpersonate, "user", userID, reason, client); err != nil {
+		return nil, err
+	}
+
+	return &models.ImpersonationResponse{
+		User:        user,
+		AccessToken: token,
+		ExpiresAt:   expiresAt,
+	}, nil
+}
+
+// ReassignSubjectAdmin назначает администратора предмета, например если прежний покинул платформу
+func (s *AdminService) ReassignSubjectAdmin(ctx context.Context, adminID, subjectID uuid.UUID, req *models.ReassignSubjectAdminRequest, client models.ClientInfo) (*models.Role, error) {
+	subject, err := s.subjectRepo.GetByID(ctx, subjectID)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetUserByID(ctx, req.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+
+	if req.DemoteExisting {
+		roles, err := s.roleRepo.GetSubjectRoles(ctx, subjectID)
+		if err != nil {
+			return nil, err
+		}
+		for _, role := range roles {
+			if role.IsAdmin() && role.UserID != req.UserID {
+				role.RoleType = models.RoleTeacher
+				role.ResetPermissions()
+				role.UpdatedAt = time.Now()
+				if err := s.roleRepo.UpdateAccess(ctx, role); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	role, err := s.roleRepo.GetByUserAndSubject(ctx, req.UserID, subjectID)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		role = &models.Role{
+			ID:        uuid.New(),
+			UserID:    req.UserID,
+			SubjectID: subjectID,
+			RoleType:  models.RoleAdmin,
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+		}
+		if err := s.roleRepo.Create(ctx, role); err != nil {
+			return nil, err
+		}
+	} else if !role.IsAdmin() {
+		role.RoleType = models.RoleAdmin
+		role.ResetPermissions()
+		role.UpdatedAt = time.Now()
+		if err := s.roleRepo.UpdateAccess(ctx, role); err != nil {
+			return nil, err
+		}
+	}
+
+	// владение переходит, если прежних администраторов сняли или владельца больше нет на платформе
+	owner, err := s.userRepo.GetUserByID(ctx, subject.OwnerID)
+	if err != nil {
+		return nil, err
+	}
+	if req.DemoteExisting || owner == nil {
+		if err := s.subjectRepo.SetOwner(ctx, subjectID, req.UserID); err != nil {
+			return nil, err
+		}
+	}
+
+	details := fmt.Sprintf("subject admin -> %s (demote_existing=%t)", req.UserID, req.DemoteExisting)
+	if err := s.audit(ctx, adminID, models.AdminActionReassignSubjectAdmin, "subject", subjectID, details, client); err != nil {
+		return nil, err
+	}
+	return role, nil
+}
+
+func (s *AdminService) GetOrphanedSubjects(ctx context.Context) ([]*models.Subject, error) {
+	return s.subjectRepo.GetOrphaned(ctx)
+}
+
+func (s *AdminService) GetStatistics(ctx context.Context) (*models.PlatformStatistics, error) {
+	return s.adminRepo.GetPlatformStatistics(ctx)
+}
+
+func (s *AdminService) GetAuditLogs(ctx context.Context, targetID *uuid.UUID, limit, offset int) ([]*models.AdminAuditLog, int64, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	if limit > 200 {
+		limit = 200
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	return s.adminRepo.GetAuditLogs(ctx, targetID, limit, offset)
+}
+
+func (s *AdminService) audit(ctx context.Context, actorID uuid.UUID, action models.AdminAction, targetType string, targetID uuid.UUID, details string, client models.ClientInfo) error {
+	return s.adminRepo.CreateAuditLog(ctx, &models.AdminAuditLog{
+		ActorID:    actorID,
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetID,
+		Details:    details,
+		IP:         client.IP,
+	})
+}
@@ -0,0 +1,197 @@
+package services
+
+import (
+	"context"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/wozhdeleniye/redclass-app/internal/models"
+	"github.com/wozhdeleniye/redclass-app/internal/policy"
+	"github.com/wozhdeleniye/redclass-app/internal/repositories/postgres"
+)
+
+// AnalyticsService объективный вклад участников проектов по проблемам, назначениям и результатам
+type AnalyticsService struct {
+	projectRepo *postgres.ProjectRepository
+	problemRepo *postgres.ProblemRepository
+	resultRepo  *postgres.ResultRepository
+	authz       *Authorizer
+}
+
+func NewAnalyticsService(
+	projectRepo *postgres.ProjectRepository,
+	problemRepo *postgres.ProblemRepository,
+	resultRepo *postgres.ResultRepository,
+	authz *Authorizer,
+) *AnalyticsService {
+	return &AnalyticsService{
+		projectRepo: projectRepo,
+		problemRepo: problemRepo,
+		resultRepo:  resultRepo,
+		authz:       authz,
+	}
+}
+
+// GetProjectAnalytics вклад каждого участника проекта; доступен участникам и преподавателям
+func (s *AnalyticsService) GetProjectAnalytics(ctx context.Context, viewerID, projectID uuid.UUID) (*models.ProjectAnalytics, error) {
+	project, err := s.projectRepo.GetByID(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.authz.AuthorizeProject(ctx, viewerID, project, policy.ResourceProject, policy.ActionView); err != nil {
+		return nil, err
+	}
+
+	members, err := s.contributions(ctx, []*models.Project{project})
+	if err != nil {
+		return nil, err
+	}
+	return &models.ProjectAnalytics{ProjectID: project.ID, Title: project.Title, Members: members}, nil
+}
+
+// GetSubjectAnalytics вклад студентов по всем проектам предмета; доля нагрузки считается
+// внутри каждой команды и усредняется по проектам студента
+func (s *AnalyticsService) GetSubjectAnalytics(ctx context.Context, viewerID, subjectID uuid.UUID) (*models.SubjectAnalytics, error) {
+	if _, err := s.authz.AuthorizeSubject(ctx, viewerID, subjectID, policy.ResourceProject, policy.ActionView); err != nil {
+		return nil, err
+	}
+	projects, err := s.projectRepo.GetTeamsBySubject(ctx, subjectID)
+	if err != nil {
+		return nil, err
+	}
+
+	members, err := s.contributions(ctx, projects)
+	if err != nil {
+		return nil, err
+	}
+	return &models.SubjectAnalytics{SubjectID: subjectID, Members: members}, nil
+}
+
+// contributions считает вклад участников переданных проектов, сводя проекты одного участника
+func (s *AnalyticsService) contributions(ctx context.Context, projects []*models.Project) ([]*models.MemberContribution, error) {
+	projectIDs := make([]uuid.UUID, 0, len(projects))
+	for _, p := range projects {
+		projectIDs = append(projectIDs, p.ID)
+	}
+	problems, err := s.problemRepo.GetByProjects(ctx, projectIDs)
+	if err != nil {
+		return nil, err
+	}
+	results, err := s.resultRepo.GetByProjects(ctx, projectIDs)
+	if err != nil {
+		return nil, err
+	}
+	resultByProblem := make(map[uuid.UUID]*models.Result, len(results))
+	for _, r := range results {
+		resultByProblem[r.ProblemID] = r
+	}
+	problemsByProject := make(map[uuid.UUID][]*models.Problem)
+	for _, p := range problems {
+		// главная проблема описывает весь проект и ничего не говорит о вкладе
+		if p.ParentID == nil {
+			continue
+		}
+		problemsByProject[p.ProjectID] = append(problemsByProject[p.ProjectID], p)
+	}
+
+	now := time.Now()
+	byUser := make(map[uuid.UUID]*models.MemberContribution)
+	activity := make(map[uuid.UUID]map[time.Time]*models.ActivityBucket)
+	shares := make(map[uuid.UUID]float64)
+	var order []*models.MemberContribution
+
+	for _, project := range projects {
+		team := make(map[uuid.UUID]*models.MemberContribution, len(project.Members))
+		for _, m := range project.Members {
+			c, ok := byUser[m.UserID]
+			if !ok {
+				c = &models.MemberContribution{UserID: m.UserID, Activity: []*models.ActivityBucket{}}
+				if m.User != nil {
+					c.Nickname = m.User.Nickname
+				}
+				byUser[m.UserID] = c
+				activity[m.UserID] = make(map[time.Time]*models.ActivityBucket)
+				order = append(order, c)
+			}
+			c.Projects++
+			team[m.UserID] = c
+		}
+
+		workload := make(map[uuid.UUID]float64, len(team))
+		var teamWorkload float64
+		for _, p := range problemsByProject[project.ID] {
+			if c := team[p.CreatorID]; c != nil {
+				c.ProblemsCreated++
+				bucket(activity[c.UserID], p.CreatedAt).ProblemsCreated++
+			}
+
+			result := resultByProblem[p.ID]
+			if result != nil {
+				if c := team[result.CreatorID]; c != nil {
+					c.ResultsPosted++
+					bucket(activity[c.UserID], result.CreatedAt).ResultsPosted++
+				}
+			}
+
+			if len(p.Assignees) == 0 {
+				continue
+			}
+			days := math.Max(0, p.EndTime.Sub(p.StartTime).Hours()/24) / float64(len(p.Assignees))
+			for _, a := range p.Assignees {
+				c := team[a.UserID]
+				if c == nil {
+					continue
+				}
+				c.ProblemsAssigned++
+				workload[a.UserID] += days
+				teamWorkload += days
+				switch {
+				case p.Solved && result != nil && result.CreatedAt.After(p.EndTime):
+					c.ProblemsSolved++
+					c.SolvedLate++
+				case p.Solved:
+					c.ProblemsSolved++
+					c.SolvedOnTime++
+				case now.After(p.EndTime):
+					c.Overdue++
+				}
+			}
+		}
+
+		for userID, c := range team {
+			c.Workload += workload[userID]
+			if teamWorkload > 0 {
+				shares[userID] += workload[userID] / teamWorkload * 100
+			}
+		}
+	}
+
+	for _, c := range order {
+		c.Workload = roundPercent(c.Workload)
+		if c.Projects > 0 {
+			c.WorkloadShare = roundPercent(shares[c.UserID] / float64(c.Projects))
+		}
+		for _, b := range activity[c.UserID] {
+			c.Activity = append(c.Activity, b)
+		}
+		sort.Slice(c.Activity, func(i, j int) bool {
+			return c.Activity[i].WeekStart.Before(c.Activity[j].WeekStart)
+		})
+	}
+	return order, nil
+}
+
+// bucket неделя активности, в которую попадает момент at
+func bucket(weeks map[time.Time]*models.ActivityBucket, at time.Time) *models.ActivityBucket {
+	day := at.UTC().Truncate(24 * time.Hour)
+	offset := (int(day.Weekday()) + 6) % 7
+	start := day.AddDate(0, 0, -offset)
+	b, ok := weeks[start]
+	if !ok {
+		b = &models.ActivityBucket{WeekStart: start}
+		weeks[start] = b
+	}
+	return b
+}
@@ -3,6 +3,7 @@ package services
 import (
 	"context"
 	"errors"
+	"strings"
 	"time"
 
 	"github.com/wozhdeleniye/redclass-app/internal/config"
@@ -19,12 +20,15 @@ var (
 	ErrUserNotFound       = errors.New("user not found")
 	ErrTokenExpired       = errors.New("token expired")
 	ErrTokenInvalid       = errors.New("token invalid")
+	ErrAccountDeactivated = errors.New("account is deactivated")
 )
 
 type AuthService struct {
-	userRepo  *postgres.UserRepository
-	tokenRepo *redis.TokenRepository
-	jwtConfig config.JWTConfig
+	userRepo    *postgres.UserRepository
+	tokenRepo   *redis.TokenRepository
+	throttle    *LoginThrottleService
+	jwtConfig   config.JWTConfig
+	adminConfig config.AdminConfig
 }
 
 type JWTConfig struct {
@@ -37,30 +41,46 @@ type JWTConfig struct {
 type Claims struct {
 	UserID uuid.UUID `json:"user_id"`
 	Email  string    `json:"email"`
+	// ImpersonatorID заполнен, если токен выдан супер-администратору для входа под пользователем
+	ImpersonatorID *uuid.UUID `json:"impersonator_id,omitempty"`
 	jwt.RegisteredClaims
 }
 
-func NewAuthService(userRepo *postgres.UserRepository, tokenRepo *redis.TokenRepository, jwtConfig config.JWTConfig) *AuthService {
+func NewAuthService(
+	userRepo *postgres.UserRepository,
+	tokenRepo *redis.TokenRepository,
+	throttle *LoginThrottleService,
+	jwtConfig config.JWTConfig,
+	adminConfig config.AdminConfig,
+) *AuthService {
 	return &AuthService{
-		userRepo:  userRepo,
-		tokenRepo: tokenRepo,
-		jwtConfig: jwtConfig,
+		userRepo:    userRepo,
+		tokenRepo:   tokenRepo,
+		throttle:    throttle,
+		jwtConfig:   jwtConfig,
+		adminConfig: adminConfig,
 	}
 }
 
-func (s *AuthService) Register(ctx context.Context, req *models.CreateUserRequest) (*models.AuthResponse, error) {
+func (s *AuthService) Register(ctx context.Context, req *models.CreateUserRequest, client models.ClientInfo) (*models.AuthResponse, error) {
+	if err := s.throttle.CheckRegister(ctx, req.Email); err != nil {
+		s.throttle.RecordRegister(ctx, req.Email, client, false, err.Error())
+		return nil, err
+	}
 
-	existingUser, err := s.userRepo.GetUserByEmail(ctx, req.Email)
+	existingUser, err := s.userRepo.GetUserByEmailIncludingInactive(ctx, req.Email)
 	if err != nil {
 		return nil, err
 	}
 	if existingUser != nil {
+		s.throttle.RecordRegister(ctx, req.Email, client, false, "user already exists")
 		return nil, errors.New("user already exists")
 	}
 
 	user := &models.User{
 		Email:    req.Email,
 		Nickname: req.Nickname,
+		IsActive: true,
 	}
 
 	if err := user.SetPassword(req.Password); err != nil {
@@ -70,6 +90,7 @@ func (s *AuthService) Register(ctx context.Context, req *models.CreateUserReques
 	if err := s.userRepo.CreateUser(ctx, user); err != nil {
 		return nil, err
 	}
+	s.throttle.RecordRegister(ctx, req.Email, client, true, "")
 
 	tokens, err := s.generateTokens(user)
 	if err != nil {
@@ -83,17 +104,28 @@ func (s *AuthService) Register(ctx context.Context, req *models.CreateUserReques
 	}, nil
 }
 
-func (s *AuthService) Login(ctx context.Context, req *models.LoginRequest) (*models.AuthResponse, error) {
-	user, err := s.userRepo.GetUserByEmail(ctx, req.Email)
+func (s *AuthService) Login(ctx context.Context, req *models.LoginRequest, client models.ClientInfo) (*models.AuthResponse, error) {
+	if err := s.throttle.CheckLogin(ctx, req.Email); err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetUserByEmailIncludingInactive(ctx, req.Email)
 	if err != nil {
 		return nil, err
 	}
-	if user == nil {
+	if user == nil || !user.CheckPassword(req.Password) {
+		if err := s.throttle.RegisterLoginFailure(ctx, req.Email, client, ErrInvalidCredentials.Error()); err != nil {
+			return nil, err
+		}
 		return nil, ErrInvalidCredentials
 	}
 
-	if !user.CheckPassword(req.Password) {
-		return nil, ErrInvalidCredentials
+	if err := s.throttle.RegisterLoginSuccess(ctx, req.Email, client); err != nil {
+		return nil, err
+	}
+
+	if !user.IsActive {
+		return nil, ErrAccountDeactivated
 	}
 
 	tokens, err := s.generateTokens(user)
@@ -119,18 +151,25 @@ func (s *AuthService) RefreshTokens(ctx context.Context, refreshToken string) (*
 		return nil, ErrTokenInvalid
 	}
 
+	if err := s.checkNotRevoked(ctx, claims); err != nil {
+		return nil, err
+	}
+
 	_, err = s.tokenRepo.GetRefreshToken(ctx, claims.UserID)
 	if err != nil {
 		return nil, err
 	}
 
-	user, err := s.userRepo.GetUserByID(ctx, claims.UserID)
+	user, err := s.userRepo.GetUserByIDIncludingInactive(ctx, claims.UserID)
 	if err != nil {
 		return nil, err
 	}
 	if user == nil {
 		return nil, ErrUserNotFound
 	}
+	if !user.IsActive {
+		return nil, ErrAccountDeactivated
+	}
 
 	tokens, err := s.generateTokens(user)
 	if err != nil {
@@ -147,6 +186,11 @@ func (s *AuthService) Logout(ctx context.Context, accessToken, refreshToken stri
 	if err := s.tokenRepo.StoreBlacklistedToken(ctx, accessToken, s.jwtConfig.AccessTokenExpiry); err != nil {
 		return err
 	}
+	// у сессии имперсонации нет своего refresh-токена: выход завершает только ее,
+	// а сессии самого пользователя остаются нетронутыми
+	if claims, err := s.ValidateToken(accessToken); err == nil && claims.ImpersonatorID != nil {
+		return nil
+	}
 	if err := s.tokenRepo.StoreBlacklistedToken(ctx, refreshToken, s.jwtConfig.RefreshTokenExpiry); err != nil {
 		return err
 	}
@@ -158,6 +202,115 @@ func (s *AuthService) ValidateToken(token string) (*Claims, error) {
 	return s.validateToken(token, s.jwtConfig.AccessTokenSecret)
 }
 
+// Authenticate проверяет access-токен, его отзыв при выходе и отзыв всех сессий пользователя
+func (s *AuthService) Authenticate(ctx context.Context, token string) (*Claims, error) {
+	claims, err := s.ValidateToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	blacklisted, err := s.tokenRepo.IsTokenBlacklisted(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	if blacklisted {
+		return nil, ErrTokenInvalid
+	}
+
+	if err := s.checkNotRevoked(ctx, claims); err != nil {
+		return nil, err
+	}
+
+	return claims, nil
+}
+
+// RevokeSessions делает недействительными все ранее выданные токены пользователя
+func (s *AuthService) RevokeSessions(ctx context.Context, userID uuid.UUID) error {
+	if err := s.tokenRepo.StoreSessionsRevokedAt(ctx, userID, time.Now(), s.jwtConfig.RefreshTokenExpiry); err != nil {
+		return err
+	}
+	return s.tokenRepo.DeleteRefreshToken(ctx, userID)
+}
+
+// IssueTokens выдает новую пару токенов, например после смены пароля
+func (s *AuthService) IssueTokens(user *models.User) (*models.TokenPair, error) {
+	return s.generateTokens(user)
+}
+
+// IsSuperAdmin проверяет, является ли активный пользователь супер-администратором платформы
+func (s *AuthService) IsSuperAdmin(ctx context.Context, userID uuid.UUID) (bool, error) {
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	return user != nil && user.IsSuperAdmin, nil
+}
+
+// IssueImpersonationToken выдает короткоживущий access-токен пользователя от имени супер-администратора.
+// Refresh-токен не выдается, чтобы сессия не продлевалась.
+func (s *AuthService) IssueImpersonationToken(user *models.User, impersonatorID uuid.UUID) (string, time.Time, error) {
+	expiresAt := time.Now().Add(s.adminConfig.ImpersonationExpiry)
+	claims := &Claims{
+		UserID:         user.ID,
+		Email:          user.Email,
+		ImpersonatorID: &impersonatorID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			Subject:   user.ID.String(),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	signed, err := token.SignedString([]byte(s.jwtConfig.AccessTokenSecret))
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return signed, expiresAt, nil
+}
+
+// BootstrapSuperAdmins выдает права супер-администратора уже зарегистрированным пользователям
+// из SUPER_ADMIN_EMAILS. Вызывается при старте и только пока на платформе нет ни одного
+// супер-администратора, поэтому снятые вручную права не возвращаются.
+func (s *AuthService) BootstrapSuperAdmins(ctx context.Context) error {
+	if len(s.adminConfig.SuperAdminEmails) == 0 {
+		return nil
+	}
+	count, err := s.userRepo.CountSuperAdmins(ctx)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	emails := make([]string, 0, len(s.adminConfig.SuperAdminEmails))
+	for _, email := range s.adminConfig.SuperAdminEmails {
+		emails = append(emails, strings.ToLower(strings.TrimSpace(email)))
+	}
+	users, err := s.userRepo.GetUsersByEmails(ctx, emails)
+	if err != nil {
+		return err
+	}
+	for _, user := range users {
+		if err := s.userRepo.SetSuperAdmin(ctx, user.ID, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *AuthService) checkNotRevoked(ctx context.Context, claims *Claims) error {
+	revokedAt, err := s.tokenRepo.GetSessionsRevokedAt(ctx, claims.UserID)
+	if err != nil {
+		return err
+	}
+	if !revokedAt.IsZero() && claims.IssuedAt != nil && claims.IssuedAt.Time.Before(revokedAt) {
+		return ErrTokenInvalid
+	}
+	return nil
+}
+
 func (s *AuthService) generateTokens(user *models.User) (*models.TokenPair, error) {
 
 	accessToken, err := s.generateAccessToken(user)
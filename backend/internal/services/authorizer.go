@@ -0,0 +1,297 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/wozhdeleniye/redclass-app/internal/models"
+	"github.com/wozhdeleniye/redclass-app/internal/policy"
+	"github.com/wozhdeleniye/redclass-app/internal/repositories/postgres"
+)
+
+// Authorizer собирает роли пользователя относительно ресурса и сверяет их с таблицей policy.
+// Все сервисы проверяют доступ только через него.
+type Authorizer struct {
+	userRepo    *postgres.UserRepository
+	roleRepo    *postgres.RoleRepository
+	taskRepo    *postgres.TaskRepository
+	projectRepo *postgres.ProjectRepository
+	subjectRepo *postgres.SubjectRepository
+}
+
+var (
+	ErrSubjectArchived = errors.New("subject is archived and read-only")
+	// ErrSubjectNotFound скрывает существование приватного предмета от посторонних
+	ErrSubjectNotFound = errors.New("subject not found")
+)
+
+// SubjectAccess что зритель может видеть в предмете с учетом его видимости
+type SubjectAccess struct {
+	// Visible карточка предмета
+	Visible bool
+	// Contents задания, участники и группы
+	Contents bool
+	Member   bool
+	// ManageMembers зритель вправе видеть код вступления
+	ManageMembers bool
+	// ManageTasks зритель видит черновики и задания с отложенной публикацией
+	ManageTasks bool
+}
+
+// SubjectAccess определяет доступ зрителя к предмету; viewerID == uuid.Nil означает анонима
+func (a *Authorizer) SubjectAccess(ctx context.Context, viewerID uuid.UUID, subject *models.Subject) (*SubjectAccess, error) {
+	access := &SubjectAccess{}
+	if viewerID != uuid.Nil {
+		p, role, err := a.SubjectPrincipal(ctx, viewerID, subject.ID)
+		if err != nil {
+			return nil, err
+		}
+		access.Member = role != nil || p.Has(policy.RoleSuperAdmin)
+		access.ManageMembers = policy.Can(p, policy.ResourceMember, policy.ActionCreate)
+		// права проверяются напрямую: Can запретил бы создание заданий в архиве, а черновики там видны
+		access.ManageTasks = p.Has(policy.RoleSuperAdmin) ||
+			p.HasPermission(models.PermTasksCreate) || p.HasPermission(models.PermTasksManage)
+	}
+
+	access.Contents = access.Member || subject.Visibility == models.VisibilityPublic
+	access.Visible = access.Contents || subject.Visibility == models.VisibilityUnlisted
+	return access, nil
+}
+
+// AuthorizeSubjectContents пропускает к заданиям, участникам и группам предмета
+func (a *Authorizer) AuthorizeSubjectContents(ctx context.Context, viewerID, subjectID uuid.UUID, resource policy.Resource) error {
+	subject, err := a.subjectRepo.GetShallowByID(ctx, subjectID)
+	if err != nil {
+		return err
+	}
+	access, err := a.SubjectAccess(ctx, viewerID, subject)
+	if err != nil {
+		return err
+	}
+	if !access.Visible {
+		return ErrSubjectNotFound
+	}
+	if !access.Contents {
+		return &policy.ForbiddenError{Resource: resource, Action: policy.ActionView, Reason: "available to subject members only"}
+	}
+	return nil
+}
+
+// AuthorizeTaskContents пропускает к заданию и его частям (шаблону, контрольным точкам):
+// нужен доступ к содержимому предмета, а неопубликованное задание видят только преподаватели
+func (a *Authorizer) AuthorizeTaskContents(ctx context.Context, viewerID uuid.UUID, task *models.Task) error {
+	subject := task.Subject
+	if subject == nil {
+		var err error
+		if subject, err = a.subjectRepo.GetShallowByID(ctx, task.SubjectID); err != nil {
+			return err
+		}
+	}
+	access, err := a.SubjectAccess(ctx, viewerID, subject)
+	if err != nil {
+		return err
+	}
+	if !access.Visible {
+		return ErrSubjectNotFound
+	}
+	if !access.Contents {
+		return &policy.ForbiddenError{Resource: policy.ResourceTask, Action: policy.ActionView, Reason: "available to subject members only"}
+	}
+	if !access.ManageTasks && !task.IsPublished(time.Now()) {
+		return errors.New("task not found")
+	}
+	return nil
+}
+
+func NewAuthorizer(
+	userRepo *postgres.UserRepository,
+	roleRepo *postgres.RoleRepository,
+	taskRepo *postgres.TaskRepository,
+	projectRepo *postgres.ProjectRepository,
+	subjectRepo *postgres.SubjectRepository,
+) *Authorizer {
+	return &Authorizer{
+		userRepo:    userRepo,
+		roleRepo:    roleRepo,
+		taskRepo:    taskRepo,
+		projectRepo: projectRepo,
+		subjectRepo: subjectRepo,
+	}
+}
+
+// SubjectPrincipal роли пользователя в предмете; возвращает также его роль (nil, если не участник)
+func (a *Authorizer) SubjectPrincipal(ctx context.Context, userID, subjectID uuid.UUID) (*policy.Principal, *models.Role, error) {
+	p := policy.NewPrincipal(userID)
+
+	user, err := a.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if user != nil && user.IsSuperAdmin {
+		p.Grant(policy.RoleSuperAdmin)
+	}
+
+	role, err := a.roleRepo.GetByUserAndSubject(ctx, userID, subjectID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if role != nil {
+		switch role.RoleType {
+		case models.RoleAdmin:
+			p.Grant(policy.RoleSubjectAdmin)
+		case models.RoleTeacher:
+			p.Grant(policy.RoleTeacher)
+		case models.RoleStudent:
+			p.Grant(policy.RoleStudent)
+		}
+		p.GrantPermissions(role.EffectivePermissions()...)
+	}
+
+	archived, err := a.subjectRepo.IsArchived(ctx, subjectID)
+	if err != nil {
+		return nil, nil, err
+	}
+	p.ReadOnly = archived
+
+	return p, role, nil
+}
+
+// CheckGrantable проверяет, что пользователь сам обладает всеми выдаваемыми правами. Роль
+// по приглашению или из списка несет права по умолчанию, поэтому их сверяют так же.
+func (a *Authorizer) CheckGrantable(ctx context.Context, userID, subjectID uuid.UUID, permissions []models.Permission) error {
+	p, _, err := a.SubjectPrincipal(ctx, userID, subjectID)
+	if err != nil {
+		return err
+	}
+
+	for _, perm := range permissions {
+		if !models.IsKnownPermission(perm) {
+			return fmt.Errorf("unknown permission %q", perm)
+		}
+		if !p.Has(policy.RoleSuperAdmin) && !p.HasPermission(perm) {
+			return fmt.Errorf("%w: cannot grant permission %q you do not have", policy.ErrForbidden, perm)
+		}
+	}
+	return nil
+}
+
+// EnsureSubjectWritable возвращает ErrSubjectArchived для архивного предмета
+func (a *Authorizer) EnsureSubjectWritable(ctx context.Context, subjectID uuid.UUID) error {
+	archived, err := a.subjectRepo.IsArchived(ctx, subjectID)
+	if err != nil {
+		return err
+	}
+	if archived {
+		return ErrSubjectArchived
+	}
+	return nil
+}
+
+// ProjectPrincipal роли пользователя в предмете задания и в самом проекте
+func (a *Authorizer) ProjectPrincipal(ctx context.Context, userID uuid.UUID, project *models.Project) (*policy.Principal, error) {
+	task := project.Task
+	if task == nil {
+		var err error
+		task, err = a.taskRepo.GetByID(ctx, project.TaskID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	p, role, err := a.SubjectPrincipal(ctx, userID, task.SubjectID)
+	if err != nil {
+		return nil, err
+	}
+
+	// роли в проекте действуют, только пока пользователь состоит и в команде, и в предмете
+	member, err := a.projectRepo.GetMember(ctx, project.ID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if member != nil && role != nil {
+		p.Grant(policy.RoleProjectMember)
+		if member.Role == models.ProjectRoleCreator || project.CreatorID == userID {
+			p.Grant(policy.RoleProjectCreator)
+		}
+	}
+	p.Locked = project.Status.IsLocked()
+	p.Archived = project.IsArchived()
+
+	return p, nil
+}
+
+// AuthorizeSubject проверяет действие над ресурсом внутри предмета и возвращает роль пользователя в нем
+func (a *Authorizer) AuthorizeSubject(ctx context.Context, userID, subjectID uuid.UUID, resource policy.Resource, action policy.Action) (*models.Role, error) {
+	p, role, err := a.SubjectPrincipal(ctx, userID, subjectID)
+	if err != nil {
+		return nil, err
+	}
+	if err := policy.Authorize(p, resource, action); err != nil {
+		return nil, err
+	}
+	return role, nil
+}
+
+func (a *Authorizer) AuthorizeTask(ctx context.Context, userID uuid.UUID, task *models.Task, action policy.Action) error {
+	p, role, err := a.SubjectPrincipal(ctx, userID, task.SubjectID)
+	if err != nil {
+		return err
+	}
+	// автор управляет заданием, пока может создавать задания в предмете: ушедший или
+	// пониженный преподаватель теряет эти права
+	if task.CreatedByID == userID && role != nil && role.HasPermission(models.PermTasksCreate) {
+		p.Grant(policy.RoleOwner)
+	}
+	return policy.Authorize(p, policy.ResourceTask, action)
+}
+
+func (a *Authorizer) AuthorizeProject(ctx context.Context, userID uuid.UUID, project *models.Project, resource policy.Resource, action policy.Action) error {
+	p, err := a.ProjectPrincipal(ctx, userID, project)
+	if err != nil {
+		return err
+	}
+	return policy.Authorize(p, resource, action)
+}
+
+// AuthorizeProblem проверяет действие над проблемой с учетом авторства и назначения
+func (a *Authorizer) AuthorizeProblem(ctx context.Context, userID uuid.UUID, problem *models.Problem, action policy.Action) error {
+	return a.authorizeProblemResource(ctx, userID, problem, policy.ResourceProblem, action)
+}
+
+// AuthorizeResult проверяет действие над результатом проблемы
+func (a *Authorizer) AuthorizeResult(ctx context.Context, userID uuid.UUID, problem *models.Problem, action policy.Action) error {
+	return a.authorizeProblemResource(ctx, userID, problem, policy.ResourceResult, action)
+}
+
+func (a *Authorizer) authorizeProblemResource(ctx context.Context, userID uuid.UUID, problem *models.Problem, resource policy.Resource, action policy.Action) error {
+	project := problem.Project
+	if project == nil {
+		var err error
+		project, err = a.projectRepo.GetByID(ctx, problem.ProjectID)
+		if err != nil {
+			return err
+		}
+	}
+
+	p, err := a.ProjectPrincipal(ctx, userID, project)
+	if err != nil {
+		return err
+	}
+	// авторство и назначение дают права только участникам: ушедший из команды их теряет
+	if p.Has(policy.RoleProjectMember) {
+		if problem.CreatorID == userID {
+			p.Grant(policy.RoleOwner)
+		}
+		for _, assignee := range problem.Assignees {
+			if assignee.UserID == userID {
+				p.Grant(policy.RoleAssignee)
+				break
+			}
+		}
+	}
+
+	return policy.Authorize(p, resource, action)
+}
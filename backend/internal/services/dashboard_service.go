@@ -0,0 +1,252 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/wozhdeleniye/redclass-app/internal/models"
+	"github.com/wozhdeleniye/redclass-app/internal/policy"
+	"github.com/wozhdeleniye/redclass-app/internal/repositories/postgres"
+)
+
+const (
+	// dashboardInactiveAfter после какого простоя проект считается заброшенным
+	dashboardInactiveAfter = 7 * 24 * time.Hour
+	// dashboardScheduleLag на сколько процентных пунктов прогресс может отставать от прошедшей доли срока
+	dashboardScheduleLag = 25
+)
+
+// DashboardService обзор проектов задания для преподавателя и просмотр дерева любого проекта
+// без вступления в команду
+type DashboardService struct {
+	taskRepo    *postgres.TaskRepository
+	projectRepo *postgres.ProjectRepository
+	problemRepo *postgres.ProblemRepository
+	resultRepo  *postgres.ResultRepository
+	extensions  *ExtensionService
+	authz       *Authorizer
+}
+
+func NewDashboardService(
+	taskRepo *postgres.TaskRepository,
+	projectRepo *postgres.ProjectRepository,
+	problemRepo *postgres.ProblemRepository,
+	resultRepo *postgres.ResultRepository,
+	extensions *ExtensionService,
+	authz *Authorizer,
+) *DashboardService {
+	return &DashboardService{
+		taskRepo:    taskRepo,
+		projectRepo: projectRepo,
+		problemRepo: problemRepo,
+		resultRepo:  resultRepo,
+		extensions:  extensions,
+		authz:       authz,
+	}
+}
+
+// GetTaskDashboard прогресс, просрочки, последняя активность и признаки риска по каждому
+// проекту задания
+func (s *DashboardService) GetTaskDashboard(ctx context.Context, viewerID, taskID uuid.UUID) (*models.TaskDashboard, error) {
+	task, err := s.taskRepo.GetByID(ctx, taskID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.authz.AuthorizeSubject(ctx, viewerID, task.SubjectID, policy.ResourceProject, policy.ActionView); err != nil {
+		return nil, err
+	}
+
+	projects, err := s.projectRepo.GetTeams(ctx, taskID)
+	if err != nil {
+		return nil, err
+	}
+	projectIDs := make([]uuid.UUID, 0, len(projects))
+	for _, p := range projects {
+		projectIDs = append(projectIDs, p.ID)
+	}
+	problems, err := s.problemRepo.GetByProjects(ctx, projectIDs)
+	if err != nil {
+		return nil, err
+	}
+	results, err := s.resultRepo.GetByProjects(ctx, projectIDs)
+	if err != nil {
+		return nil, err
+	}
+	problemsByProject := make(map[uuid.UUID][]*models.Problem, len(projects))
+	for _, p := range problems {
+		problemsByProject[p.ProjectID] = append(problemsByProject[p.ProjectID], p)
+	}
+	resultByProblem := make(map[uuid.UUID]*models.Result, len(results))
+	for _, r := range results {
+		resultByProblem[r.ProblemID] = r
+	}
+
+	dashboard := &models.TaskDashboard{
+		TaskID:   task.ID,
+		Title:    task.Title,
+		DueDate:  task.DueDate,
+		Projects: []*models.DashboardProject{},
+	}
+	progress := 0
+	for _, project := range projects {
+		row, err := s.projectRow(ctx, task, project, problemsByProject[project.ID], resultByProblem)
+		if err != nil {
+			return nil, err
+		}
+		if project.Status.IsLocked() {
+			dashboard.Submitted++
+		}
+		if len(row.Risks) > 0 {
+			dashboard.AtRisk++
+		}
+		progress += row.Statistics.Percentage
+		dashboard.Projects = append(dashboard.Projects, row)
+	}
+	if len(projects) > 0 {
+		dashboard.AverageProgress = progress / len(projects)
+	}
+	return dashboard, nil
+}
+
+// projectRow собирает строку обзора для одного проекта
+func (s *DashboardService) projectRow(ctx context.Context, task *models.Task, project *models.Project, problems []*models.Problem, results map[uuid.UUID]*models.Result) (*models.DashboardProject, error) {
+	stats, err := s.problemRepo.GetProjectStatistics(ctx, project.ID)
+	if err != nil {
+		return nil, err
+	}
+	due, _, err := s.extensions.DueDateFor(ctx, project.ID, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	row := &models.DashboardProject{
+		ProjectID:    project.ID,
+		Title:        project.Title,
+		Status:       project.Status,
+		SubmittedAt:  project.SubmittedAt,
+		ArchivedAt:   project.ArchivedAt,
+		Members:      []*models.DashboardMember{},
+		Statistics:   stats,
+		DueDate:      due,
+		LastActivity: project.CreatedAt,
+		Risks:        []models.ProjectRisk{},
+	}
+	for _, m := range project.Members {
+		member := &models.DashboardMember{UserID: m.UserID, Role: m.Role}
+		if m.User != nil {
+			member.Nickname = m.User.Nickname
+		}
+		row.Members = append(row.Members, member)
+	}
+
+	now := time.Now()
+	var mainProblem *models.Problem
+	unassigned := false
+	for _, p := range problems {
+		row.LastActivity = latest(row.LastActivity, p.UpdatedAt)
+		if r := results[p.ID]; r != nil {
+			row.LastActivity = latest(row.LastActivity, r.CreatedAt)
+		}
+		if p.ParentID == nil {
+			mainProblem = p
+			continue
+		}
+		if p.Solved {
+			continue
+		}
+		if now.After(p.EndTime) {
+			row.Overdue++
+		}
+		if len(p.Assignees) == 0 {
+			unassigned = true
+		}
+	}
+	if project.SubmittedAt != nil {
+		row.LastActivity = latest(row.LastActivity, *project.SubmittedAt)
+	}
+
+	// сданный проект ждет преподавателя, а архивный закрыт: признаки риска команды к ним не относятся
+	if project.Status.IsLocked() || project.IsArchived() {
+		return row, nil
+	}
+	solved := mainProblem != nil && mainProblem.Solved
+	if !solved && due != nil && now.After(*due) {
+		row.Risks = append(row.Risks, models.RiskOverdue)
+	}
+	if row.Overdue > 0 {
+		row.Risks = append(row.Risks, models.RiskOverdueProblems)
+	}
+	if !solved && mainProblem != nil && due != nil && due.After(mainProblem.StartTime) {
+		elapsed := now.Sub(mainProblem.StartTime).Seconds() / due.Sub(mainProblem.StartTime).Seconds()
+		if elapsed > 0 && float64(stats.Percentage+dashboardScheduleLag) < elapsed*100 {
+			row.Risks = append(row.Risks, models.RiskBehindSchedule)
+		}
+	}
+	if now.Sub(row.LastActivity) > dashboardInactiveAfter {
+		row.Risks = append(row.Risks, models.RiskInactive)
+	}
+	if unassigned {
+		row.Risks = append(row.Risks, models.RiskUnassigned)
+	}
+	if task.TeamMinSize != nil && len(project.Members) < *task.TeamMinSize {
+		row.Risks = append(row.Risks, models.RiskUnderstaffed)
+	}
+	return row, nil
+}
+
+// GetProjectTree дерево проблем проекта с результатами. Преподаватели предмета видят его
+// по праву просмотра всех проектов, не вступая в команду.
+func (s *DashboardService) GetProjectTree(ctx context.Context, viewerID, projectID uuid.UUID) (*models.ProjectTree, error) {
+	project, err := s.projectRepo.GetByID(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.authz.AuthorizeProject(ctx, viewerID, project, policy.ResourceProblem, policy.ActionView); err != nil {
+		return nil, err
+	}
+
+	problems, err := s.problemRepo.GetProjectProblems(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	results, err := s.resultRepo.GetByProject(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := make(map[uuid.UUID]*models.ProblemNode, len(problems))
+	for _, p := range problems {
+		node := &models.ProblemNode{Problem: p, Children: []*models.ProblemNode{}}
+		if r := results[p.ID]; r != nil {
+			node.Result = models.NewResultResponse(r)
+		}
+		nodes[p.ID] = node
+	}
+
+	tree := &models.ProjectTree{
+		ProjectID: project.ID,
+		Title:     project.Title,
+		Status:    project.Status,
+		Problems:  []*models.ProblemNode{},
+	}
+	// проблемы отсортированы по номеру, поэтому порядок детей сохраняется
+	for _, p := range problems {
+		node := nodes[p.ID]
+		if p.ParentID != nil {
+			if parent, ok := nodes[*p.ParentID]; ok {
+				parent.Children = append(parent.Children, node)
+				continue
+			}
+		}
+		tree.Problems = append(tree.Problems, node)
+	}
+	return tree, nil
+}
+
+func latest(a, b time.Time) time.Time {
+	if b.After(a) {
+		return b
+	}
+	return a
+}
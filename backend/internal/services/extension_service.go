@@ -0,0 +1,273 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/wozhdeleniye/redclass-app/internal/models"
+	"github.com/wozhdeleniye/redclass-app/internal/policy"
+	"github.com/wozhdeleniye/redclass-app/internal/repositories/postgres"
+)
+
+// ExtensionService продления сроков сдачи для команд и отдельных студентов и расчет
+// действующих сроков, по которым определяются просрочка и опоздание результатов
+type ExtensionService struct {
+	extensionRepo *postgres.ExtensionRepository
+	projectRepo   *postgres.ProjectRepository
+	taskRepo      *postgres.TaskRepository
+	problemRepo   *postgres.ProblemRepository
+	groups        *GroupService
+	problems      *ProblemService
+	authz         *Authorizer
+}
+
+func NewExtensionService(
+	extensionRepo *postgres.ExtensionRepository,
+	projectRepo *postgres.ProjectRepository,
+	taskRepo *postgres.TaskRepository,
+	problemRepo *postgres.ProblemRepository,
+	groups *GroupService,
+	problems *ProblemService,
+	authz *Authorizer,
+) *ExtensionService {
+	return &ExtensionService{
+		extensionRepo: extensionRepo,
+		projectRepo:   projectRepo,
+		taskRepo:      taskRepo,
+		problemRepo:   problemRepo,
+		groups:        groups,
+		problems:      problems,
+		authz:         authz,
+	}
+}
+
+// GrantExtension продлевает срок команде или одному ее участнику. Повторная выдача тому же
+// получателю заменяет прежнее продление; продление команды сразу расширяет окно проекта.
+func (s *ExtensionService) GrantExtension(ctx context.Context, userID, projectID uuid.UUID, req *models.GrantExtensionRequest) (*models.DeadlineExtension, error) {
+	project, err := s.projectRepo.GetByID(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	task, err := s.taskRepo.GetByID(ctx, project.TaskID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.authz.AuthorizeTask(ctx, userID, task, policy.ActionUpdate); err != nil {
+		return nil, err
+	}
+
+	if req.UserID != nil {
+		isMember, err := s.projectRepo.IsUserMember(ctx, projectID, *req.UserID)
+		if err != nil {
+			return nil, err
+		}
+		if !isMember {
+			return nil, errors.New("user is not a project member")
+		}
+	}
+
+	base, err := s.baseDueDate(ctx, task, project)
+	if err != nil {
+		return nil, err
+	}
+	if base == nil {
+		return nil, errors.New("task has no due date to extend")
+	}
+	if !req.DueDate.After(*base) {
+		return nil, errors.New("extension must be later than the task due date")
+	}
+
+	ext, err := s.extensionRepo.GetFor(ctx, projectID, req.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if ext != nil {
+		ext.DueDate = req.DueDate
+		ext.Reason = req.Reason
+		ext.GrantedByID = userID
+		ext.UpdatedAt = time.Now()
+		err = s.extensionRepo.Update(ctx, ext)
+	} else {
+		ext = &models.DeadlineExtension{
+			ID:          uuid.New(),
+			TaskID:      task.ID,
+			ProjectID:   projectID,
+			UserID:      req.UserID,
+			DueDate:     req.DueDate,
+			Reason:      req.Reason,
+			GrantedByID: userID,
+			CreatedAt:   time.Now(),
+			UpdatedAt:   time.Now(),
+		}
+		err = s.extensionRepo.Create(ctx, ext)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if !ext.IsPersonal() {
+		if err := s.problems.RescheduleProject(ctx, task, project); err != nil {
+			return nil, err
+		}
+	}
+	return ext, nil
+}
+
+// RevokeExtension отзывает продление; окно проекта возвращается к сроку задания
+func (s *ExtensionService) RevokeExtension(ctx context.Context, userID, extensionID uuid.UUID) error {
+	ext, err := s.extensionRepo.GetByID(ctx, extensionID)
+	if err != nil {
+		return err
+	}
+	task, err := s.taskRepo.GetByID(ctx, ext.TaskID)
+	if err != nil {
+		return err
+	}
+	if err := s.authz.AuthorizeTask(ctx, userID, task, policy.ActionUpdate); err != nil {
+		return err
+	}
+
+	if err := s.extensionRepo.Delete(ctx, extensionID); err != nil {
+		return err
+	}
+	if ext.IsPersonal() {
+		return nil
+	}
+	project, err := s.projectRepo.GetByID(ctx, ext.ProjectID)
+	if err != nil {
+		return err
+	}
+	return s.problems.RescheduleProject(ctx, task, project)
+}
+
+// GetTaskExtensions все продления по заданию для преподавателей
+func (s *ExtensionService) GetTaskExtensions(ctx context.Context, userID, taskID uuid.UUID) ([]*models.DeadlineExtension, error) {
+	task, err := s.taskRepo.GetByID(ctx, taskID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.authz.AuthorizeSubject(ctx, userID, task.SubjectID, policy.ResourceProject, policy.ActionView); err != nil {
+		return nil, err
+	}
+	return s.extensionRepo.GetByTask(ctx, taskID)
+}
+
+// GetProjectDeadline сроки проекта и его участников с отметкой о просрочке: работа просрочена,
+// если главная проблема не решена, а действующий срок прошел
+func (s *ExtensionService) GetProjectDeadline(ctx context.Context, viewerID, projectID uuid.UUID) (*models.ProjectDeadline, error) {
+	project, err := s.projectRepo.GetByID(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.authz.AuthorizeProject(ctx, viewerID, project, policy.ResourceProject, policy.ActionView); err != nil {
+		return nil, err
+	}
+	task, err := s.taskRepo.GetByID(ctx, project.TaskID)
+	if err != nil {
+		return nil, err
+	}
+
+	base, err := s.baseDueDate(ctx, task, project)
+	if err != nil {
+		return nil, err
+	}
+	extensions, err := s.extensionRepo.GetByProject(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	mainProblem, err := s.problemRepo.GetMainProblemByProject(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	done := mainProblem != nil && mainProblem.Solved
+
+	var team *models.DeadlineExtension
+	personal := make(map[uuid.UUID]*models.DeadlineExtension)
+	for _, ext := range extensions {
+		if ext.IsPersonal() {
+			personal[*ext.UserID] = ext
+		} else {
+			team = ext
+		}
+	}
+
+	now := time.Now()
+	overdue := func(due *time.Time) bool {
+		return !done && due != nil && now.After(*due)
+	}
+
+	deadline := &models.ProjectDeadline{
+		ProjectID:   projectID,
+		TaskDueDate: base,
+		DueDate:     models.EffectiveDueDate(base, team),
+		Members:     []*models.MemberDeadline{},
+		Extensions:  extensions,
+	}
+	deadline.Overdue = overdue(deadline.DueDate)
+	for _, member := range project.Members {
+		ext := personal[member.UserID]
+		md := &models.MemberDeadline{
+			UserID:   member.UserID,
+			DueDate:  models.EffectiveDueDate(base, team, ext),
+			Extended: ext != nil,
+		}
+		if member.User != nil {
+			md.Nickname = member.User.Nickname
+		}
+		md.Overdue = overdue(md.DueDate)
+		deadline.Members = append(deadline.Members, md)
+	}
+	return deadline, nil
+}
+
+// LateStatus применяет политику опозданий задания к работе, сданной в момент at: при userID
+// учитывается личное продление участника, без него только срок команды
+func (s *ExtensionService) LateStatus(ctx context.Context, projectID uuid.UUID, userID *uuid.UUID, at time.Time) (models.LateStatus, error) {
+	due, task, err := s.DueDateFor(ctx, projectID, userID)
+	if err != nil {
+		return models.LateStatus{}, err
+	}
+	return task.Lateness(due, at), nil
+}
+
+// DueDateFor действующий срок проекта с учетом продления команды и, если задан userID, личного продления
+func (s *ExtensionService) DueDateFor(ctx context.Context, projectID uuid.UUID, userID *uuid.UUID) (*time.Time, *models.Task, error) {
+	project, err := s.projectRepo.GetByID(ctx, projectID)
+	if err != nil {
+		return nil, nil, err
+	}
+	task, err := s.taskRepo.GetByID(ctx, project.TaskID)
+	if err != nil {
+		return nil, nil, err
+	}
+	base, err := s.baseDueDate(ctx, task, project)
+	if err != nil {
+		return nil, nil, err
+	}
+	team, err := s.extensionRepo.GetFor(ctx, projectID, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	if userID == nil {
+		return models.EffectiveDueDate(base, team), task, nil
+	}
+	personal, err := s.extensionRepo.GetFor(ctx, projectID, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return models.EffectiveDueDate(base, team, personal), task, nil
+}
+
+// baseDueDate срок задания для группы создателя проекта, без продлений
+func (s *ExtensionService) baseDueDate(ctx context.Context, task *models.Task, project *models.Project) (*time.Time, error) {
+	if !task.IsTargeted() {
+		return task.DueDate, nil
+	}
+	groupIDs, err := s.groups.UserGroupIDs(ctx, task.SubjectID, project.CreatorID)
+	if err != nil {
+		return nil, err
+	}
+	return task.DueDateFor(task.GroupFor(groupIDs)), nil
+}
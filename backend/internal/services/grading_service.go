@@ -0,0 +1,350 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/wozhdeleniye/redclass-app/internal/models"
+	"github.com/wozhdeleniye/redclass-app/internal/policy"
+	"github.com/wozhdeleniye/redclass-app/internal/repositories/postgres"
+)
+
+// GradingService критерии оценивания заданий, оценки проектов и их участников и журнал предмета.
+// Оценки скрыты от студентов, пока преподаватель их не опубликует.
+type GradingService struct {
+	rubricRepo     *postgres.RubricRepository
+	gradeRepo      *postgres.GradeRepository
+	taskRepo       *postgres.TaskRepository
+	projectRepo    *postgres.ProjectRepository
+	submissionRepo *postgres.SubmissionRepository
+	roleRepo       *postgres.RoleRepository
+	peers          *PeerReviewService
+	authz          *Authorizer
+}
+
+func NewGradingService(
+	rubricRepo *postgres.RubricRepository,
+	gradeRepo *postgres.GradeRepository,
+	taskRepo *postgres.TaskRepository,
+	projectRepo *postgres.ProjectRepository,
+	submissionRepo *postgres.SubmissionRepository,
+	roleRepo *postgres.RoleRepository,
+	peers *PeerReviewService,
+	authz *Authorizer,
+) *GradingService {
+	return &GradingService{
+		rubricRepo:     rubricRepo,
+		gradeRepo:      gradeRepo,
+		taskRepo:       taskRepo,
+		projectRepo:    projectRepo,
+		submissionRepo: submissionRepo,
+		roleRepo:       roleRepo,
+		peers:          peers,
+		authz:          authz,
+	}
+}
+
+// GetRubric критерии задания видны всем, кому видно само задание
+func (s *GradingService) GetRubric(ctx context.Context, viewerID, taskID uuid.UUID) ([]*models.RubricCriterion, error) {
+	task, err := s.taskRepo.GetByID(ctx, taskID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.authz.AuthorizeTaskContents(ctx, viewerID, task); err != nil {
+		return nil, err
+	}
+	return s.rubricRepo.GetByTask(ctx, taskID)
+}
+
+// SetRubric заменяет критерии задания. После первой оценки критерии не меняются,
+// иначе выставленные баллы потеряют смысл.
+func (s *GradingService) SetRubric(ctx context.Context, userID, taskID uuid.UUID, req *models.SetRubricRequest) ([]*models.RubricCriterion, error) {
+	task, err := s.taskRepo.GetByID(ctx, taskID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.authz.AuthorizeTask(ctx, userID, task, policy.ActionUpdate); err != nil {
+		return nil, err
+	}
+
+	graded, err := s.gradeRepo.CountByTask(ctx, taskID)
+	if err != nil {
+		return nil, err
+	}
+	if graded > 0 {
+		return nil, errors.New("rubric cannot be changed after grading has started")
+	}
+
+	criteria := make([]*models.RubricCriterion, 0, len(req.Criteria))
+	for i, input := range req.Criteria {
+		c := &models.RubricCriterion{
+			ID:          uuid.New(),
+			TaskID:      taskID,
+			Position:    i,
+			Title:       input.Title,
+			Description: input.Description,
+			Weight:      input.Weight,
+			Levels:      input.Levels,
+			CreatedAt:   time.Now(),
+			UpdatedAt:   time.Now(),
+		}
+		if c.MaxPoints() == 0 {
+			return nil, fmt.Errorf("criterion %q needs a level worth more than zero points", c.Title)
+		}
+		criteria = append(criteria, c)
+	}
+
+	if err := s.rubricRepo.ReplaceForTask(ctx, taskID, criteria); err != nil {
+		return nil, err
+	}
+	return criteria, nil
+}
+
+// GradeProject оценивает сданный проект по критериям задания. Штраф за опоздание берется
+// из последней сдачи, поправки участников при повторной оценке сохраняются, а поправка
+// взаимной оценки пересчитывается, если раунд уже закрыт.
+func (s *GradingService) GradeProject(ctx context.Context, userID, projectID uuid.UUID, req *models.GradeProjectRequest) (*models.ProjectGrade, error) {
+	project, task, err := s.authorizeGrading(ctx, userID, projectID, policy.ActionCreate)
+	if err != nil {
+		return nil, err
+	}
+	if !project.Status.IsLocked() {
+		return nil, errors.New("only a submitted project can be graded")
+	}
+
+	criteria, err := s.rubricRepo.GetByTask(ctx, task.ID)
+	if err != nil {
+		return nil, err
+	}
+	if len(criteria) == 0 {
+		return nil, errors.New("task has no rubric")
+	}
+	score, ok := models.WeightedScore(criteria, req.Scores)
+	if !ok {
+		return nil, errors.New("every rubric criterion must be scored within its levels")
+	}
+
+	grade, err := s.gradeRepo.GetByProject(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	if grade == nil {
+		grade = &models.ProjectGrade{ProjectID: projectID, TaskID: task.ID, CreatedAt: time.Now()}
+	}
+	grade.GradedByID = userID
+	grade.Comment = req.Comment
+	grade.Scores = req.Scores
+	grade.Score = score
+	grade.Penalty = 0
+	grade.SubmissionID = nil
+	grade.UpdatedAt = time.Now()
+
+	submission, err := s.submissionRepo.GetLatest(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	if submission != nil {
+		grade.SubmissionID = &submission.ID
+		grade.Penalty = submission.Penalty
+	}
+	grade.FinalScore = math.Round(score*(100-grade.Penalty)) / 100
+
+	// участники, вступившие после прошлой оценки, получают оценку проекта без поправки
+	graded := make(map[uuid.UUID]bool, len(grade.Members))
+	for _, m := range grade.Members {
+		graded[m.UserID] = true
+	}
+	for _, member := range project.Members {
+		if !graded[member.UserID] {
+			grade.Members = append(grade.Members, &models.MemberGrade{
+				UserID:    member.UserID,
+				CreatedAt: time.Now(),
+			})
+		}
+	}
+	peerAdjustments, err := s.peers.Adjustments(ctx, task.ID, project)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range grade.Members {
+		m.PeerAdjustment = peerAdjustments[m.UserID]
+		m.Recalculate(grade.FinalScore)
+		m.UpdatedAt = time.Now()
+	}
+
+	if err := s.gradeRepo.Save(ctx, grade); err != nil {
+		return nil, err
+	}
+	if err := s.projectRepo.SetStatus(ctx, projectID, models.ProjectGraded, project.SubmittedAt); err != nil {
+		return nil, err
+	}
+	return grade, nil
+}
+
+// AdjustMemberGrade задает индивидуальную поправку участнику к оценке проекта
+func (s *GradingService) AdjustMemberGrade(ctx context.Context, userID, projectID, memberID uuid.UUID, req *models.AdjustMemberGradeRequest) (*models.MemberGrade, error) {
+	if _, _, err := s.authorizeGrading(ctx, userID, projectID, policy.ActionUpdate); err != nil {
+		return nil, err
+	}
+
+	grade, err := s.gradeRepo.GetByProject(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	if grade == nil {
+		return nil, errors.New("project is not graded yet")
+	}
+
+	for _, m := range grade.Members {
+		if m.UserID != memberID {
+			continue
+		}
+		m.Adjustment = req.Adjustment
+		m.Comment = req.Comment
+		m.Recalculate(grade.FinalScore)
+		m.UpdatedAt = time.Now()
+		if err := s.gradeRepo.SaveMember(ctx, m); err != nil {
+			return nil, err
+		}
+		return m, nil
+	}
+	return nil, errors.New("user has no grade in this project")
+}
+
+// GetProjectGrade оценка проекта для его участников и оценивающих; до публикации
+// участникам она не видна
+func (s *GradingService) GetProjectGrade(ctx context.Context, viewerID, projectID uuid.UUID) (*models.ProjectGrade, error) {
+	project, err := s.projectRepo.GetByID(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.authz.AuthorizeProject(ctx, viewerID, project, policy.ResourceProject, policy.ActionView); err != nil {
+		return nil, err
+	}
+
+	grade, err := s.gradeRepo.GetByProject(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	if grade == nil {
+		return nil, errors.New("grade not found")
+	}
+	if !grade.Released {
+		canSeeHidden, err := s.canSeeHidden(ctx, viewerID, project.TaskID)
+		if err != nil {
+			return nil, err
+		}
+		if !canSeeHidden {
+			return nil, errors.New("grade not found")
+		}
+	}
+	return grade, nil
+}
+
+// ReleaseGrades публикует оценки проектов задания и возвращает число опубликованных
+func (s *GradingService) ReleaseGrades(ctx context.Context, userID, taskID uuid.UUID, req *models.ReleaseGradesRequest) (int64, error) {
+	task, err := s.taskRepo.GetByID(ctx, taskID)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := s.authz.AuthorizeSubject(ctx, userID, task.SubjectID, policy.ResourceGrade, policy.ActionUpdate); err != nil {
+		return 0, err
+	}
+	return s.gradeRepo.Release(ctx, taskID, req.ProjectIDs)
+}
+
+// GetGradebook журнал предмета: оценивающие видят всех студентов и все оценки,
+// студент только свою строку с опубликованными оценками
+func (s *GradingService) GetGradebook(ctx context.Context, viewerID, subjectID uuid.UUID) (*models.Gradebook, error) {
+	p, role, err := s.authz.SubjectPrincipal(ctx, viewerID, subjectID)
+	if err != nil {
+		return nil, err
+	}
+	full := policy.Can(p, policy.ResourceGrade, policy.ActionView)
+	if !full && (role == nil || !role.IsStudent()) {
+		return nil, &policy.ForbiddenError{Resource: policy.ResourceGrade, Action: policy.ActionView}
+	}
+
+	tasks, err := s.taskRepo.GetAllBySubject(ctx, subjectID)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := s.gradeRepo.GetSubjectEntries(ctx, subjectID, !full)
+	if err != nil {
+		return nil, err
+	}
+	roles, err := s.roleRepo.GetSubjectRoles(ctx, subjectID)
+	if err != nil {
+		return nil, err
+	}
+
+	book := &models.Gradebook{SubjectID: subjectID, Tasks: []*models.GradebookTask{}, Rows: []*models.GradebookRow{}}
+	now := time.Now()
+	for _, t := range tasks {
+		if full || t.IsPublished(now) {
+			book.Tasks = append(book.Tasks, &models.GradebookTask{ID: t.ID, Title: t.Title, DueDate: t.DueDate})
+		}
+	}
+
+	rows := make(map[uuid.UUID]*models.GradebookRow)
+	for _, r := range roles {
+		if !r.IsStudent() || (!full && r.UserID != viewerID) {
+			continue
+		}
+		row := &models.GradebookRow{UserID: r.UserID, Grades: []*models.GradebookEntry{}}
+		if r.User != nil {
+			row.Nickname = r.User.Nickname
+		}
+		rows[r.UserID] = row
+		book.Rows = append(book.Rows, row)
+	}
+	for _, e := range entries {
+		if row, ok := rows[e.UserID]; ok {
+			row.Grades = append(row.Grades, e)
+		}
+	}
+	for _, row := range book.Rows {
+		if len(row.Grades) == 0 {
+			continue
+		}
+		sum := 0.0
+		for _, g := range row.Grades {
+			sum += g.Score
+		}
+		avg := math.Round(sum/float64(len(row.Grades))*100) / 100
+		row.Average = &avg
+	}
+	return book, nil
+}
+
+// authorizeGrading находит проект с заданием и проверяет право оценивать в предмете
+func (s *GradingService) authorizeGrading(ctx context.Context, userID, projectID uuid.UUID, action policy.Action) (*models.Project, *models.Task, error) {
+	project, err := s.projectRepo.GetByID(ctx, projectID)
+	if err != nil {
+		return nil, nil, err
+	}
+	task, err := s.taskRepo.GetByID(ctx, project.TaskID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if _, err := s.authz.AuthorizeSubject(ctx, userID, task.SubjectID, policy.ResourceGrade, action); err != nil {
+		return nil, nil, err
+	}
+	return project, task, nil
+}
+
+func (s *GradingService) canSeeHidden(ctx context.Context, viewerID, taskID uuid.UUID) (bool, error) {
+	task, err := s.taskRepo.GetByID(ctx, taskID)
+	if err != nil {
+		return false, err
+	}
+	p, _, err := s.authz.SubjectPrincipal(ctx, viewerID, task.SubjectID)
+	if err != nil {
+		return false, err
+	}
+	return policy.Can(p, policy.ResourceGrade, policy.ActionView), nil
+}
@@ -0,0 +1,338 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/wozhdeleniye/redclass-app/internal/models"
+	"github.com/wozhdeleniye/redclass-app/internal/policy"
+	"github.com/wozhdeleniye/redclass-app/internal/repositories/postgres"
+)
+
+type GroupService struct {
+	groupRepo *postgres.GroupRepository
+	roleRepo  *postgres.RoleRepository
+	authz     *Authorizer
+}
+
+func NewGroupService(groupRepo *postgres.GroupRepository, roleRepo *postgres.RoleRepository, authz *Authorizer) *GroupService {
+	return &GroupService{groupRepo: groupRepo, roleRepo: roleRepo, authz: authz}
+}
+
+func (s *GroupService) CreateGroup(ctx context.Context, userID, subjectID uuid.UUID, req *models.CreateGroupRequest) (*models.Group, error) {
+	if _, err := s.authz.AuthorizeSubject(ctx, userID, subjectID, policy.ResourceMember, policy.ActionCreate); err != nil {
+		return nil, err
+	}
+	return s.create(ctx, subjectID, req.Name, req.Description)
+}
+
+func (s *GroupService) create(ctx context.Context, subjectID uuid.UUID, name, description string) (*models.Group, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, errors.New("group name is required")
+	}
+
+	existing, err := s.groupRepo.GetByName(ctx, subjectID, name)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, errors.New("group with this name already exists")
+	}
+
+	group := &models.Group{
+		ID:          uuid.New(),
+		SubjectID:   subjectID,
+		Name:        name,
+		Description: description,
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+	}
+	if err := s.groupRepo.Create(ctx, group); err != nil {
+		return nil, err
+	}
+	return group, nil
+}
+
+func (s *GroupService) GetSubjectGroups(ctx context.Context, viewerID, subjectID uuid.UUID) ([]*models.Group, error) {
+	if err := s.authz.AuthorizeSubjectContents(ctx, viewerID, subjectID, policy.ResourceMember); err != nil {
+		return nil, err
+	}
+	return s.groupRepo.GetBySubject(ctx, subjectID)
+}
+
+func (s *GroupService) GetGroup(ctx context.Context, viewerID, groupID uuid.UUID) (*models.Group, error) {
+	group, err := s.groupRepo.GetByID(ctx, groupID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.authz.AuthorizeSubjectContents(ctx, viewerID, group.SubjectID, policy.ResourceMember); err != nil {
+		return nil, err
+	}
+	return group, nil
+}
+
+// GetMyGroups возвращает группы предмета, в которых состоит пользователь
+func (s *GroupService) GetMyGroups(ctx context.Context, userID, subjectID uuid.UUID) ([]*models.Group, error) {
+	return s.groupRepo.GetUserGroups(ctx, subjectID, userID)
+}
+
+func (s *GroupService) UpdateGroup(ctx context.Context, userID, groupID uuid.UUID, req *models.UpdateGroupRequest) (*models.Group, error) {
+	group, err := s.groupRepo.GetByID(ctx, groupID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.authz.AuthorizeSubject(ctx, userID, group.SubjectID, policy.ResourceMember, policy.ActionUpdate); err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if name == "" {
+			return nil, errors.New("group name is required")
+		}
+		existing, err := s.groupRepo.GetByName(ctx, group.SubjectID, name)
+		if err != nil {
+			return nil, err
+		}
+		if existing != nil && existing.ID != group.ID {
+			return nil, errors.New("group with this name already exists")
+		}
+		group.Name = name
+	}
+	if req.Description != nil {
+		group.Description = *req.Description
+	}
+	group.UpdatedAt = time.Now()
+
+	if err := s.groupRepo.Update(ctx, group); err != nil {
+		return nil, err
+	}
+	return group, nil
+}
+
+// DeleteGroup удаляет группу; задания, назначенные только ей, становятся заданиями всего предмета
+func (s *GroupService) DeleteGroup(ctx context.Context, userID, groupID uuid.UUID) error {
+	group, err := s.groupRepo.GetByID(ctx, groupID)
+	if err != nil {
+		return err
+	}
+	if _, err := s.authz.AuthorizeSubject(ctx, userID, group.SubjectID, policy.ResourceMember, policy.ActionDelete); err != nil {
+		return err
+	}
+	return s.groupRepo.Delete(ctx, groupID)
+}
+
+// AddMembers добавляет участников предмета в группу
+func (s *GroupService) AddMembers(ctx context.Context, userID, groupID uuid.UUID, userIDs []uuid.UUID) (*models.Group, error) {
+	group, err := s.groupRepo.GetByID(ctx, groupID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.authz.AuthorizeSubject(ctx, userID, group.SubjectID, policy.ResourceMember, policy.ActionUpdate); err != nil {
+		return nil, err
+	}
+
+	for _, id := range userIDs {
+		if err := s.checkJoinable(ctx, group, id); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.groupRepo.AddMembers(ctx, groupID, userIDs); err != nil {
+		return nil, err
+	}
+	return s.groupRepo.GetByID(ctx, groupID)
+}
+
+func (s *GroupService) RemoveMember(ctx context.Context, userID, groupID, memberID uuid.UUID) error {
+	group, err := s.groupRepo.GetByID(ctx, groupID)
+	if err != nil {
+		return err
+	}
+	if _, err := s.authz.AuthorizeSubject(ctx, userID, group.SubjectID, policy.ResourceMember, policy.ActionUpdate); err != nil {
+		return err
+	}
+	return s.groupRepo.RemoveMember(ctx, groupID, memberID)
+}
+
+// AssignByName добавляет участника в группу по названию, создавая группу при необходимости.
+// Используется при импорте списка и вступлении по приглашению; права проверяет вызывающий.
+func (s *GroupService) AssignByName(ctx context.Context, subjectID, userID uuid.UUID, name string) (*models.Group, error) {
+	group, err := s.EnsureGroup(ctx, subjectID, name)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.assign(ctx, group, userID); err != nil {
+		return nil, err
+	}
+	return group, nil
+}
+
+// EnsureGroup возвращает группу предмета с таким названием, создавая ее при необходимости
+func (s *GroupService) EnsureGroup(ctx context.Context, subjectID uuid.UUID, name string) (*models.Group, error) {
+	group, err := s.groupRepo.GetByName(ctx, subjectID, strings.TrimSpace(name))
+	if err != nil {
+		return nil, err
+	}
+	if group != nil {
+		return group, nil
+	}
+	return s.create(ctx, subjectID, name, "")
+}
+
+// Assign добавляет участника в группу без проверки прав, их проверяет вызывающий
+func (s *GroupService) Assign(ctx context.Context, groupID, userID uuid.UUID) error {
+	group, err := s.groupRepo.GetByID(ctx, groupID)
+	if err != nil {
+		return err
+	}
+	return s.assign(ctx, group, userID)
+}
+
+func (s *GroupService) assign(ctx context.Context, group *models.Group, userID uuid.UUID) error {
+	if err := s.checkJoinable(ctx, group, userID); err != nil {
+		return err
+	}
+	return s.groupRepo.AddMembers(ctx, group.ID, []uuid.UUID{userID})
+}
+
+// checkJoinable пользователь должен состоять в предмете, а студент может быть только в одной группе
+func (s *GroupService) checkJoinable(ctx context.Context, group *models.Group, userID uuid.UUID) error {
+	role, err := s.roleRepo.GetByUserAndSubject(ctx, userID, group.SubjectID)
+	if err != nil {
+		return err
+	}
+	if role == nil {
+		return fmt.Errorf("user %s is not a member of this subject", userID)
+	}
+	if !role.IsStudent() {
+		return nil
+	}
+
+	groups, err := s.groupRepo.GetUserGroups(ctx, group.SubjectID, userID)
+	if err != nil {
+		return err
+	}
+	for _, g := range groups {
+		if g.ID != group.ID {
+			return fmt.Errorf("student %s is already in group %q", userID, g.Name)
+		}
+	}
+	return nil
+}
+
+// GetStatistics сводка по группам предмета; при groupID только по одной группе
+func (s *GroupService) GetStatistics(ctx context.Context, userID, subjectID uuid.UUID, groupID *uuid.UUID) ([]*models.GroupStatistics, error) {
+	if _, err := s.authz.AuthorizeSubject(ctx, userID, subjectID, policy.ResourceMember, policy.ActionView); err != nil {
+		return nil, err
+	}
+
+	var groups []*models.Group
+	if groupID != nil {
+		group, err := s.groupRepo.GetByID(ctx, *groupID)
+		if err != nil {
+			return nil, err
+		}
+		if group.SubjectID != subjectID {
+			return nil, errors.New("group not found")
+		}
+		groups = append(groups, group)
+	} else {
+		var err error
+		if groups, err = s.groupRepo.GetBySubject(ctx, subjectID); err != nil {
+			return nil, err
+		}
+	}
+
+	stats := make([]*models.GroupStatistics, 0, len(groups))
+	for _, g := range groups {
+		st, err := s.groupRepo.GetStatistics(ctx, g)
+		if err != nil {
+			return nil, err
+		}
+		stats = append(stats, st)
+	}
+	return stats, nil
+}
+
+// TaskTargets проверяет, что группы принадлежат предмету, и готовит назначения задания
+func (s *GroupService) TaskTargets(ctx context.Context, subjectID, taskID uuid.UUID, targets []models.TaskGroupTarget) ([]*models.TaskGroup, error) {
+	seen := make(map[uuid.UUID]bool, len(targets))
+	result := make([]*models.TaskGroup, 0, len(targets))
+	for _, t := range targets {
+		if seen[t.GroupID] {
+			return nil, errors.New("duplicate group in task targets")
+		}
+		seen[t.GroupID] = true
+
+		group, err := s.groupRepo.GetByID(ctx, t.GroupID)
+		if err != nil {
+			return nil, err
+		}
+		if group.SubjectID != subjectID {
+			return nil, errors.New("group does not belong to the task subject")
+		}
+		result = append(result, &models.TaskGroup{
+			TaskID:    taskID,
+			GroupID:   t.GroupID,
+			DueDate:   t.DueDate,
+			CreatedAt: time.Now(),
+		})
+	}
+	return result, nil
+}
+
+// SaveTaskTargets заменяет назначения задания, подготовленные TaskTargets
+func (s *GroupService) SaveTaskTargets(ctx context.Context, taskID uuid.UUID, targets []*models.TaskGroup) error {
+	return s.groupRepo.SetTaskGroups(ctx, taskID, targets)
+}
+
+// UserGroupIDs ID групп предмета, в которых состоит пользователь
+func (s *GroupService) UserGroupIDs(ctx context.Context, subjectID, userID uuid.UUID) ([]uuid.UUID, error) {
+	groups, err := s.groupRepo.GetUserGroups(ctx, subjectID, userID)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]uuid.UUID, 0, len(groups))
+	for _, g := range groups {
+		ids = append(ids, g.ID)
+	}
+	return ids, nil
+}
+
+// GroupNamesByUser названия групп предмета для каждого участника
+func (s *GroupService) GroupNamesByUser(ctx context.Context, subjectID uuid.UUID) (map[uuid.UUID][]string, error) {
+	memberships, err := s.groupRepo.GetSubjectMemberships(ctx, subjectID)
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[uuid.UUID][]string)
+	for _, m := range memberships {
+		if m.Group != nil {
+			names[m.UserID] = append(names[m.UserID], m.Group.Name)
+		}
+	}
+	return names, nil
+}
+
+// GroupIDsByUser ID групп предмета для каждого участника
+func (s *GroupService) GroupIDsByUser(ctx context.Context, subjectID uuid.UUID) (map[uuid.UUID][]uuid.UUID, error) {
+	memberships, err := s.groupRepo.GetSubjectMemberships(ctx, subjectID)
+	if err != nil {
+		return nil, err
+	}
+	ids := make(map[uuid.UUID][]uuid.UUID)
+	for _, m := range memberships {
+		ids[m.UserID] = append(ids[m.UserID], m.GroupID)
+	}
+	return ids, nil
+}
+
+// RemoveFromSubject исключает пользователя из всех групп предмета при выходе из него
+func (s *GroupService) RemoveFromSubject(ctx context.Context, subjectID, userID uuid.UUID) error {
+	return s.groupRepo.RemoveUserFromSubject(ctx, subjectID, userID)
+}
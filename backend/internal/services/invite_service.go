@@ -0,0 +1,313 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/wozhdeleniye/redclass-app/internal/models"
+	"github.com/wozhdeleniye/redclass-app/internal/policy"
+	"github.com/wozhdeleniye/redclass-app/internal/repositories/postgres"
+)
+
+var ErrInviteInvalid = errors.New("invite is expired, revoked or already used")
+
+type InviteService struct {
+	inviteRepo  *postgres.InviteRepository
+	projectRepo *postgres.ProjectRepository
+	userRepo    *postgres.UserRepository
+	groupRepo   *postgres.GroupRepository
+	authz       *Authorizer
+}
+
+func NewInviteService(
+	inviteRepo *postgres.InviteRepository,
+	projectRepo *postgres.ProjectRepository,
+	userRepo *postgres.UserRepository,
+	groupRepo *postgres.GroupRepository,
+	authz *Authorizer,
+) *InviteService {
+	return &InviteService{
+		inviteRepo:  inviteRepo,
+		projectRepo: projectRepo,
+		userRepo:    userRepo,
+		groupRepo:   groupRepo,
+		authz:       authz,
+	}
+}
+
+// CreateSubjectInvite создает приглашение в предмет с указанной ролью
+func (s *InviteService) CreateSubjectInvite(ctx context.Context, userID, subjectID uuid.UUID, req *models.CreateInviteRequest) (*models.Invite, error) {
+	if _, err := s.authz.AuthorizeSubject(ctx, userID, subjectID, policy.ResourceMember, policy.ActionCreate); err != nil {
+		return nil, err
+	}
+
+	if req.RoleType == "" {
+		req.RoleType = models.RoleStudent
+	}
+	if err := validateRoleType(req.RoleType); err != nil {
+		return nil, err
+	}
+	if err := s.authz.CheckGrantable(ctx, userID, subjectID, models.DefaultPermissions[req.RoleType]); err != nil {
+		return nil, err
+	}
+	if req.GroupID != nil {
+		group, err := s.groupRepo.GetByID(ctx, *req.GroupID)
+		if err != nil {
+			return nil, err
+		}
+		if group.SubjectID != subjectID {
+			return nil, errors.New("group does not belong to this subject")
+		}
+	}
+
+	invite := &models.Invite{
+		TargetType: models.InviteTargetSubject,
+		SubjectID:  &subjectID,
+		RoleType:   req.RoleType,
+		GroupID:    req.GroupID,
+	}
+	return s.create(ctx, userID, invite, req)
+}
+
+// CreateProjectInvite создает приглашение в проект
+func (s *InviteService) CreateProjectInvite(ctx context.Context, userID, projectID uuid.UUID, req *models.CreateInviteRequest) (*models.Invite, error) {
+	project, err := s.projectRepo.GetByID(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.authz.AuthorizeProject(ctx, userID, project, policy.ResourceProject, policy.ActionUpdate); err != nil {
+		return nil, err
+	}
+
+	invite := &models.Invite{
+		TargetType: models.InviteTargetProject,
+		ProjectID:  &projectID,
+	}
+	return s.create(ctx, userID, invite, req)
+}
+
+func (s *InviteService) create(ctx context.Context, userID uuid.UUID, invite *models.Invite, req *models.CreateInviteRequest) (*models.Invite, error) {
+	code, err := s.generateCode(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	invite.ID = uuid.New()
+	invite.Code = code
+	invite.Email = strings.ToLower(strings.TrimSpace(req.Email))
+	invite.MaxUses = req.MaxUses
+	invite.CreatedByID = userID
+	invite.CreatedAt = now
+	invite.UpdatedAt = now
+
+	// именное приглашение одноразовое
+	if invite.Email != "" {
+		one := 1
+		invite.MaxUses = &one
+	}
+	if req.ExpiresInHours != nil {
+		expiresAt := now.Add(time.Duration(*req.ExpiresInHours) * time.Hour)
+		invite.ExpiresAt = &expiresAt
+	}
+
+	if err := s.inviteRepo.Create(ctx, invite); err != nil {
+		return nil, err
+	}
+	return invite, nil
+}
+
+func (s *InviteService) GetSubjectInvites(ctx context.Context, userID, subjectID uuid.UUID) ([]*models.Invite, error) {
+	if _, err := s.authz.AuthorizeSubject(ctx, userID, subjectID, policy.ResourceMember, policy.ActionCreate); err != nil {
+		return nil, err
+	}
+	return s.inviteRepo.GetBySubject(ctx, subjectID)
+}
+
+func (s *InviteService) GetProjectInvites(ctx context.Context, userID, projectID uuid.UUID) ([]*models.Invite, error) {
+	project, err := s.projectRepo.GetByID(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.authz.AuthorizeProject(ctx, userID, project, policy.ResourceProject, policy.ActionUpdate); err != nil {
+		return nil, err
+	}
+	return s.inviteRepo.GetByProject(ctx, projectID)
+}
+
+// RevokeInvite отзывает приглашение; уже вступившие участники остаются
+func (s *InviteService) RevokeInvite(ctx context.Context, userID, inviteID uuid.UUID) error {
+	invite, err := s.inviteRepo.GetByID(ctx, inviteID)
+	if err != nil {
+		return err
+	}
+	if err := s.authorizeManage(ctx, userID, invite); err != nil {
+		return err
+	}
+	return s.inviteRepo.Revoke(ctx, invite.ID, time.Now())
+}
+
+// RegenerateInvite выдает приглашению новый код; старый код перестает работать. Число
+// использований сохраняется, отозванное или отклоненное приглашение не восстанавливается,
+// а истекшее или исчерпанное открывается только с новым сроком или лимитом из req.
+func (s *InviteService) RegenerateInvite(ctx context.Context, userID, inviteID uuid.UUID, req *models.RegenerateInviteRequest) (*models.Invite, error) {
+	invite, err := s.inviteRepo.GetByID(ctx, inviteID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.authorizeManage(ctx, userID, invite); err != nil {
+		return nil, err
+	}
+	if invite.IsRevoked() {
+		return nil, errors.New("invite is revoked")
+	}
+
+	now := time.Now()
+	if req.MaxUses != nil {
+		// именное приглашение остается одноразовым
+		if invite.Email != "" {
+			return nil, errors.New("cannot change the use limit of a personal invite")
+		}
+		if *req.MaxUses <= invite.Uses {
+			return nil, errors.New("max_uses must be greater than the number of uses")
+		}
+		invite.MaxUses = req.MaxUses
+	}
+	if req.ExpiresInHours != nil {
+		expiresAt := now.Add(time.Duration(*req.ExpiresInHours) * time.Hour)
+		invite.ExpiresAt = &expiresAt
+	}
+	if invite.IsExpired(now) {
+		return nil, errors.New("invite is expired, set expires_in_hours to reopen it")
+	}
+	if invite.IsExhausted() {
+		return nil, errors.New("invite is used up, set max_uses to reopen it")
+	}
+
+	code, err := s.generateCode(ctx)
+	if err != nil {
+		return nil, err
+	}
+	invite.Code = code
+	invite.UpdatedAt = now
+
+	ok, err := s.inviteRepo.Rotate(ctx, invite)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, errors.New("invite is revoked")
+	}
+	return invite, nil
+}
+
+// GetMyInvites возвращает действующие именные приглашения текущего пользователя
+func (s *InviteService) GetMyInvites(ctx context.Context, userID uuid.UUID) ([]*models.Invite, error) {
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+	return s.inviteRepo.GetPendingByEmail(ctx, user.Email, time.Now())
+}
+
+// DeclineInvite отклоняет именное приглашение его адресатом
+func (s *InviteService) DeclineInvite(ctx context.Context, userID, inviteID uuid.UUID) error {
+	invite, err := s.inviteRepo.GetByID(ctx, inviteID)
+	if err != nil {
+		return err
+	}
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user == nil || invite.Email == "" || !strings.EqualFold(invite.Email, user.Email) {
+		return errors.New("invite not found")
+	}
+	return s.inviteRepo.Revoke(ctx, invite.ID, time.Now())
+}
+
+// Resolve ищет приглашение по коду для вступления в target. Возвращает nil без ошибки,
+// если такого кода приглашения нет, чтобы вызывающий мог проверить постоянный код.
+func (s *InviteService) Resolve(ctx context.Context, userID uuid.UUID, code string, target models.InviteTarget) (*models.Invite, error) {
+	invite, err := s.inviteRepo.GetByCode(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+	if invite == nil || invite.TargetType != target {
+		return nil, nil
+	}
+
+	if !invite.IsActive(time.Now()) {
+		return nil, ErrInviteInvalid
+	}
+
+	if invite.Email != "" {
+		user, err := s.userRepo.GetUserByID(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		if user == nil || !strings.EqualFold(invite.Email, user.Email) {
+			return nil, errors.New("invite is addressed to another user")
+		}
+	}
+
+	return invite, nil
+}
+
+func (s *InviteService) authorizeManage(ctx context.Context, userID uuid.UUID, invite *models.Invite) error {
+	switch invite.TargetType {
+	case models.InviteTargetSubject:
+		_, err := s.authz.AuthorizeSubject(ctx, userID, *invite.SubjectID, policy.ResourceMember, policy.ActionCreate)
+		return err
+	case models.InviteTargetProject:
+		project, err := s.projectRepo.GetByID(ctx, *invite.ProjectID)
+		if err != nil {
+			return err
+		}
+		return s.authz.AuthorizeProject(ctx, userID, project, policy.ResourceProject, policy.ActionUpdate)
+	}
+	return errors.New("unknown invite target")
+}
+
+func (s *InviteService) generateCode(ctx context.Context) (string, error) {
+	for i := 0; i < 5; i++ {
+		c, err := randomCode(12)
+		if err != nil {
+			return "", err
+		}
+		existing, err := s.inviteRepo.GetByCode(ctx, c)
+		if err != nil {
+			return "", err
+		}
+		if existing == nil {
+			return c, nil
+		}
+	}
+	return "", errors.New("failed to generate unique invite code")
+}
+
+// EnsureSubjectInviteForEmail возвращает действующее именное приглашение в предмет или создает новое
+// с зачислением в группу groupID. Права приглашающего проверяет вызывающий.
+func (s *InviteService) EnsureSubjectInviteForEmail(ctx context.Context, inviterID, subjectID uuid.UUID, email string, roleType models.RoleType, groupID *uuid.UUID) (*models.Invite, error) {
+	existing, err := s.inviteRepo.GetActiveForEmail(ctx, subjectID, email, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return existing, nil
+	}
+
+	invite := &models.Invite{
+		TargetType: models.InviteTargetSubject,
+		SubjectID:  &subjectID,
+		RoleType:   roleType,
+		GroupID:    groupID,
+	}
+	return s.create(ctx, inviterID, invite, &models.CreateInviteRequest{RoleType: roleType, Email: email})
+}
@@ -0,0 +1,289 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/wozhdeleniye/redclass-app/internal/models"
+	"github.com/wozhdeleniye/redclass-app/internal/policy"
+	"github.com/wozhdeleniye/redclass-app/internal/repositories/postgres"
+)
+
+type JoinRequestService struct {
+	joinRepo      *postgres.JoinRequestRepository
+	subjectRepo   *postgres.SubjectRepository
+	projectRepo   *postgres.ProjectRepository
+	roleRepo      *postgres.RoleRepository
+	teams         *TeamService
+	notifications *NotificationService
+	authz         *Authorizer
+}
+
+func NewJoinRequestService(
+	joinRepo *postgres.JoinRequestRepository,
+	subjectRepo *postgres.SubjectRepository,
+	projectRepo *postgres.ProjectRepository,
+	roleRepo *postgres.RoleRepository,
+	teams *TeamService,
+	notifications *NotificationService,
+	authz *Authorizer,
+) *JoinRequestService {
+	return &JoinRequestService{
+		joinRepo:      joinRepo,
+		subjectRepo:   subjectRepo,
+		projectRepo:   projectRepo,
+		roleRepo:      roleRepo,
+		teams:         teams,
+		notifications: notifications,
+		authz:         authz,
+	}
+}
+
+// RequestSubject создает заявку на вступление в предмет или возвращает уже ожидающую
+func (s *JoinRequestService) RequestSubject(ctx context.Context, userID uuid.UUID, subject *models.Subject) (*models.JoinRequest, error) {
+	return s.request(ctx, userID, models.InviteTargetSubject, subject.ID)
+}
+
+// RequestProject создает заявку на вступление в проект или возвращает уже ожидающую
+func (s *JoinRequestService) RequestProject(ctx context.Context, userID uuid.UUID, project *models.Project) (*models.JoinRequest, error) {
+	return s.request(ctx, userID, models.InviteTargetProject, project.ID)
+}
+
+func (s *JoinRequestService) request(ctx context.Context, userID uuid.UUID, target models.InviteTarget, targetID uuid.UUID) (*models.JoinRequest, error) {
+	existing, err := s.joinRepo.GetPending(ctx, userID, target, targetID)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return existing, nil
+	}
+
+	request := &models.JoinRequest{
+		ID:         uuid.New(),
+		TargetType: target,
+		UserID:     userID,
+		Status:     models.JoinRequestPending,
+		CreatedAt:  time.Now(),
+		UpdatedAt:  time.Now(),
+	}
+	if target == models.InviteTargetProject {
+		request.ProjectID = &targetID
+	} else {
+		request.SubjectID = &targetID
+	}
+
+	if err := s.joinRepo.Create(ctx, request); err != nil {
+		return nil, err
+	}
+	return request, nil
+}
+
+func (s *JoinRequestService) GetSubjectRequests(ctx context.Context, userID, subjectID uuid.UUID, status models.JoinRequestStatus) ([]*models.JoinRequest, error) {
+	if _, err := s.authz.AuthorizeSubject(ctx, userID, subjectID, policy.ResourceJoinRequest, policy.ActionView); err != nil {
+		return nil, err
+	}
+	return s.joinRepo.GetByTarget(ctx, models.InviteTargetSubject, subjectID, status)
+}
+
+func (s *JoinRequestService) GetProjectRequests(ctx context.Context, userID, projectID uuid.UUID, status models.JoinRequestStatus) ([]*models.JoinRequest, error) {
+	project, err := s.projectRepo.GetByID(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.authz.AuthorizeProject(ctx, userID, project, policy.ResourceProject, policy.ActionUpdate); err != nil {
+		return nil, err
+	}
+	return s.joinRepo.GetByTarget(ctx, models.InviteTargetProject, projectID, status)
+}
+
+func (s *JoinRequestService) GetMyRequests(ctx context.Context, userID uuid.UUID) ([]*models.JoinRequest, error) {
+	return s.joinRepo.GetByUser(ctx, userID)
+}
+
+// CancelRequest отменяет собственную ожидающую заявку
+func (s *JoinRequestService) CancelRequest(ctx context.Context, userID, requestID uuid.UUID) error {
+	request, err := s.joinRepo.GetByID(ctx, requestID)
+	if err != nil {
+		return err
+	}
+	if request.UserID != userID {
+		return errors.New("join request not found")
+	}
+
+	now := time.Now()
+	request.Status = models.JoinRequestCancelled
+	request.UpdatedAt = now
+	ok, err := s.joinRepo.UpdateStatus(ctx, request)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errors.New("join request is already processed")
+	}
+	return nil
+}
+
+// Decide одобряет или отклоняет заявку; при одобрении пользователь становится участником
+func (s *JoinRequestService) Decide(ctx context.Context, deciderID, requestID uuid.UUID, approve bool) (*models.JoinRequest, error) {
+	request, err := s.joinRepo.GetByID(ctx, requestID)
+	if err != nil {
+		return nil, err
+	}
+
+	targetName, err := s.authorizeDecision(ctx, deciderID, request)
+	if err != nil {
+		return nil, err
+	}
+	if !request.IsPending() {
+		return nil, errors.New("join request is already processed")
+	}
+
+	now := time.Now()
+	request.DecidedByID = &deciderID
+	request.DecidedAt = &now
+	request.UpdatedAt = now
+	if approve {
+		request.Status = models.JoinRequestApproved
+	} else {
+		request.Status = models.JoinRequestRejected
+	}
+
+	// при одобрении участие проверяется до смены статуса, а статус и участник пишутся вместе:
+	// неудачное одобрение оставляет заявку ожидающей
+	var ok bool
+	if approve {
+		role, member, err := s.newMember(ctx, request)
+		if err != nil {
+			return nil, err
+		}
+		ok, err = s.joinRepo.Approve(ctx, request, role, member)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		ok, err = s.joinRepo.UpdateStatus(ctx, request)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if !ok {
+		return nil, errors.New("join request is already processed")
+	}
+
+	s.notifyRequester(ctx, request, targetName)
+	return request, nil
+}
+
+// DecideBulk обрабатывает несколько заявок; ошибка по одной заявке не прерывает остальные
+// и оставляет ее ожидающей
+func (s *JoinRequestService) DecideBulk(ctx context.Context, deciderID uuid.UUID, req *models.DecideJoinRequestsRequest) []models.JoinRequestDecision {
+	decisions := make([]models.JoinRequestDecision, 0, len(req.IDs))
+	for _, id := range req.IDs {
+		decision := models.JoinRequestDecision{ID: id}
+		request, err := s.Decide(ctx, deciderID, id, req.Approve)
+		if err != nil {
+			decision.Error = err.Error()
+		} else {
+			decision.Status = request.Status
+		}
+		decisions = append(decisions, decision)
+	}
+	return decisions
+}
+
+// authorizeDecision проверяет право решать по заявке и возвращает название предмета или проекта
+func (s *JoinRequestService) authorizeDecision(ctx context.Context, deciderID uuid.UUID, request *models.JoinRequest) (string, error) {
+	switch request.TargetType {
+	case models.InviteTargetSubject:
+		subject, err := s.subjectRepo.GetByID(ctx, *request.SubjectID)
+		if err != nil {
+			return "", err
+		}
+		if _, err := s.authz.AuthorizeSubject(ctx, deciderID, subject.ID, policy.ResourceJoinRequest, policy.ActionUpdate); err != nil {
+			return "", err
+		}
+		return subject.Name, nil
+	case models.InviteTargetProject:
+		project, err := s.projectRepo.GetByID(ctx, *request.ProjectID)
+		if err != nil {
+			return "", err
+		}
+		if err := s.authz.AuthorizeProject(ctx, deciderID, project, policy.ResourceProject, policy.ActionUpdate); err != nil {
+			return "", err
+		}
+		return project.Title, nil
+	}
+	return "", errors.New("unknown join request target")
+}
+
+// newMember проверяет, что автора заявки можно принять, и готовит его роль в предмете или
+// участие в проекте; оба nil, если он уже состоит
+func (s *JoinRequestService) newMember(ctx context.Context, request *models.JoinRequest) (*models.Role, *models.ProjectMember, error) {
+	now := time.Now()
+	switch request.TargetType {
+	case models.InviteTargetSubject:
+		existing, err := s.roleRepo.GetByUserAndSubject(ctx, request.UserID, *request.SubjectID)
+		if err != nil {
+			return nil, nil, err
+		}
+		if existing != nil {
+			return nil, nil, nil
+		}
+		return &models.Role{
+			ID:        uuid.New(),
+			UserID:    request.UserID,
+			SubjectID: *request.SubjectID,
+			RoleType:  models.RoleStudent,
+			CreatedAt: now,
+			UpdatedAt: now,
+		}, nil, nil
+	case models.InviteTargetProject:
+		isMember, err := s.projectRepo.IsUserMember(ctx, *request.ProjectID, request.UserID)
+		if err != nil {
+			return nil, nil, err
+		}
+		if isMember {
+			return nil, nil, nil
+		}
+		// пока заявка ждала решения, команда могла заполниться или закрыться регистрация
+		project, err := s.projectRepo.GetByID(ctx, *request.ProjectID)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := s.teams.CheckJoin(ctx, request.UserID, project); err != nil {
+			return nil, nil, err
+		}
+		return nil, &models.ProjectMember{
+			ID:        uuid.New(),
+			ProjectID: *request.ProjectID,
+			UserID:    request.UserID,
+			Role:      models.ProjectRoleMember,
+			CreatedAt: now,
+			UpdatedAt: now,
+		}, nil
+	}
+	return nil, nil, errors.New("unknown join request target")
+}
+
+// notifyRequester сообщает автору заявки о решении; сбой уведомления не отменяет решение
+func (s *JoinRequestService) notifyRequester(ctx context.Context, request *models.JoinRequest, targetName string) {
+	targetID := request.SubjectID
+	if request.TargetType == models.InviteTargetProject {
+		targetID = request.ProjectID
+	}
+
+	notificationType := models.NotificationJoinRequestRejected
+	title := fmt.Sprintf("Join request to %q was rejected", targetName)
+	if request.Status == models.JoinRequestApproved {
+		notificationType = models.NotificationJoinRequestApproved
+		title = fmt.Sprintf("Join request to %q was approved", targetName)
+	}
+
+	if err := s.notifications.Notify(ctx, request.UserID, notificationType, title, "", targetID); err != nil {
+		log.Printf("failed to notify user %s about join request %s: %v", request.UserID, request.ID, err)
+	}
+}
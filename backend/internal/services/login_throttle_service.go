@@ -0,0 +1,214 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/wozhdeleniye/redclass-app/internal/config"
+	"github.com/wozhdeleniye/redclass-app/internal/models"
+	"github.com/wozhdeleniye/redclass-app/internal/repositories/postgres"
+	"github.com/wozhdeleniye/redclass-app/internal/repositories/redis"
+)
+
+var (
+	ErrTooManyAttempts = errors.New("too many attempts, try again later")
+	ErrAccountLocked   = errors.New("account is temporarily locked due to repeated failed login attempts")
+)
+
+// RetryAfterError сообщает, через сколько можно повторить запрос
+type RetryAfterError struct {
+	Err        error
+	RetryAfter time.Duration
+}
+
+func (e *RetryAfterError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *RetryAfterError) Unwrap() error {
+	return e.Err
+}
+
+// LoginThrottleService ограничивает попытки входа и регистрации по email и ведет их аудит.
+// Ограничение по IP выполняется middleware.RateLimitMiddleware на уровне маршрутов.
+type LoginThrottleService struct {
+	limitRepo   *redis.RateLimitRepository
+	attemptRepo *postgres.LoginAttemptRepository
+	cfg         config.RateLimitConfig
+}
+
+func NewLoginThrottleService(
+	limitRepo *redis.RateLimitRepository,
+	attemptRepo *postgres.LoginAttemptRepository,
+	cfg config.RateLimitConfig,
+) *LoginThrottleService {
+	return &LoginThrottleService{
+		limitRepo:   limitRepo,
+		attemptRepo: attemptRepo,
+		cfg:         cfg,
+	}
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// CheckLogin проверяет, можно ли сейчас выполнить попытку входа для email.
+// Как и ограничение по IP, при недоступности Redis вход не блокируется.
+func (s *LoginThrottleService) CheckLogin(ctx context.Context, email string) error {
+	return failOpen("check login", s.checkLogin(ctx, normalizeEmail(email)))
+}
+
+func (s *LoginThrottleService) checkLogin(ctx context.Context, email string) error {
+	lockTTL, err := s.limitRepo.GetBlock(ctx, "login_lock:"+email)
+	if err != nil {
+		return err
+	}
+	if lockTTL > 0 {
+		return &RetryAfterError{Err: ErrAccountLocked, RetryAfter: lockTTL}
+	}
+
+	delayTTL, err := s.limitRepo.GetBlock(ctx, "login_delay:"+email)
+	if err != nil {
+		return err
+	}
+	if delayTTL > 0 {
+		return &RetryAfterError{Err: ErrTooManyAttempts, RetryAfter: delayTTL}
+	}
+
+	count, ttl, err := s.limitRepo.Increment(ctx, "login_email:"+email, s.cfg.Window)
+	if err != nil {
+		return err
+	}
+	if s.cfg.LoginPerEmail > 0 && count > int64(s.cfg.LoginPerEmail) {
+		return &RetryAfterError{Err: ErrTooManyAttempts, RetryAfter: ttl}
+	}
+
+	return nil
+}
+
+// RegisterLoginFailure фиксирует неудачную попытку входа, назначает задержку или временную блокировку
+func (s *LoginThrottleService) RegisterLoginFailure(ctx context.Context, email string, client models.ClientInfo, reason string) error {
+	email = normalizeEmail(email)
+	s.audit(ctx, models.LoginAttemptLogin, email, client, false, reason)
+	return failOpen("register login failure", s.countFailure(ctx, email, client))
+}
+
+func (s *LoginThrottleService) countFailure(ctx context.Context, email string, client models.ClientInfo) error {
+	failures, _, err := s.limitRepo.Increment(ctx, "login_failures:"+email, s.cfg.LockoutWindow)
+	if err != nil {
+		return err
+	}
+
+	if s.cfg.LockoutThreshold > 0 && failures >= int64(s.cfg.LockoutThreshold) {
+		if err := s.limitRepo.SetBlock(ctx, "login_lock:"+email, s.cfg.LockoutDuration); err != nil {
+			return err
+		}
+		s.audit(ctx, models.LoginAttemptLogin, email, client, false, "account locked")
+		return s.limitRepo.Reset(ctx, "login_failures:"+email)
+	}
+
+	if delay := s.delayFor(failures); delay > 0 {
+		return s.limitRepo.SetBlock(ctx, "login_delay:"+email, delay)
+	}
+	return nil
+}
+
+// RegisterLoginSuccess фиксирует успешный вход и сбрасывает счетчики неудач
+func (s *LoginThrottleService) RegisterLoginSuccess(ctx context.Context, email string, client models.ClientInfo) error {
+	email = normalizeEmail(email)
+	s.audit(ctx, models.LoginAttemptLogin, email, client, true, "")
+
+	if err := s.limitRepo.Reset(ctx, "login_failures:"+email); err != nil {
+		return failOpen("register login success", err)
+	}
+	return failOpen("register login success", s.limitRepo.ClearBlock(ctx, "login_delay:"+email))
+}
+
+// CheckRegister ограничивает число регистраций на один email
+func (s *LoginThrottleService) CheckRegister(ctx context.Context, email string) error {
+	email = normalizeEmail(email)
+
+	count, ttl, err := s.limitRepo.Increment(ctx, "register_email:"+email, s.cfg.Window)
+	if err != nil {
+		return failOpen("check register", err)
+	}
+	if s.cfg.RegisterPerEmail > 0 && count > int64(s.cfg.RegisterPerEmail) {
+		return &RetryAfterError{Err: ErrTooManyAttempts, RetryAfter: ttl}
+	}
+	return nil
+}
+
+// RecordRegister записывает попытку регистрации в журнал аудита
+func (s *LoginThrottleService) RecordRegister(ctx context.Context, email string, client models.ClientInfo, success bool, reason string) {
+	s.audit(ctx, models.LoginAttemptRegister, normalizeEmail(email), client, success, reason)
+}
+
+// UnlockAccount снимает блокировку и задержку входа для email
+func (s *LoginThrottleService) UnlockAccount(ctx context.Context, email string) error {
+	email = normalizeEmail(email)
+
+	if err := s.limitRepo.ClearBlock(ctx, "login_lock:"+email); err != nil {
+		return err
+	}
+	if err := s.limitRepo.ClearBlock(ctx, "login_delay:"+email); err != nil {
+		return err
+	}
+	return s.limitRepo.Reset(ctx, "login_failures:"+email)
+}
+
+// failOpen пропускает ошибку хранилища счетчиков: сбой инфраструктуры не должен выглядеть
+// как неверный пароль или блокировка. Отказы по лимитам возвращаются как есть.
+func failOpen(op string, err error) error {
+	if err == nil {
+		return nil
+	}
+	var retry *RetryAfterError
+	if errors.As(err, &retry) {
+		return err
+	}
+	log.Printf("login throttle: %s skipped: %v", op, err)
+	return nil
+}
+
+func (s *LoginThrottleService) delayFor(failures int64) time.Duration {
+	if s.cfg.BaseDelay <= 0 || failures <= int64(s.cfg.DelayAfter) {
+		return 0
+	}
+
+	delay := s.cfg.BaseDelay
+	for i := int64(s.cfg.DelayAfter) + 1; i < failures; i++ {
+		delay *= 2
+		if s.cfg.MaxDelay > 0 && delay >= s.cfg.MaxDelay {
+			return s.cfg.MaxDelay
+		}
+	}
+	return delay
+}
+
+func (s *LoginThrottleService) audit(ctx context.Context, attemptType models.LoginAttemptType, email string, client models.ClientInfo, success bool, reason string) {
+	attempt := &models.LoginAttempt{
+		Type:      attemptType,
+		Email:     email,
+		IP:        client.IP,
+		UserAgent: client.UserAgent,
+		Success:   success,
+		Reason:    reason,
+	}
+	if err := s.attemptRepo.Create(ctx, attempt); err != nil {
+		log.Printf("failed to store %s attempt for %s: %v", attemptType, email, err)
+	}
+}
+
+// RetryAfterSeconds округляет время ожидания вверх до целых секунд для заголовка Retry-After
+func RetryAfterSeconds(d time.Duration) string {
+	seconds := int64((d + time.Second - 1) / time.Second)
+	if seconds < 1 {
+		seconds = 1
+	}
+	return fmt.Sprintf("%d", seconds)
+}
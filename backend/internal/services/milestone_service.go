@@ -0,0 +1,207 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/wozhdeleniye/redclass-app/internal/models"
+	"github.com/wozhdeleniye/redclass-app/internal/policy"
+	"github.com/wozhdeleniye/redclass-app/internal/repositories/postgres"
+)
+
+type MilestoneService struct {
+	milestoneRepo *postgres.MilestoneRepository
+	taskRepo      *postgres.TaskRepository
+	projectRepo   *postgres.ProjectRepository
+	authz         *Authorizer
+}
+
+func NewMilestoneService(
+	milestoneRepo *postgres.MilestoneRepository,
+	taskRepo *postgres.TaskRepository,
+	projectRepo *postgres.ProjectRepository,
+	authz *Authorizer,
+) *MilestoneService {
+	return &MilestoneService{
+		milestoneRepo: milestoneRepo,
+		taskRepo:      taskRepo,
+		projectRepo:   projectRepo,
+		authz:         authz,
+	}
+}
+
+// validateMilestoneDate контрольная точка не может быть позже срока сдачи задания
+func validateMilestoneDate(task *models.Task, dueDate time.Time) error {
+	if task.DueDate != nil && dueDate.After(*task.DueDate) {
+		return errors.New("milestone due_date cannot be later than the task due_date")
+	}
+	return nil
+}
+
+func (s *MilestoneService) CreateMilestone(ctx context.Context, userID, taskID uuid.UUID, req *models.CreateMilestoneRequest) (*models.Milestone, error) {
+	task, err := s.taskRepo.GetByID(ctx, taskID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.authz.AuthorizeTask(ctx, userID, task, policy.ActionUpdate); err != nil {
+		return nil, err
+	}
+	if err := validateMilestoneDate(task, req.DueDate); err != nil {
+		return nil, err
+	}
+
+	milestone := &models.Milestone{
+		ID:          uuid.New(),
+		TaskID:      taskID,
+		Title:       req.Title,
+		Description: req.Description,
+		DueDate:     req.DueDate,
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+	}
+	if err := s.milestoneRepo.Create(ctx, milestone); err != nil {
+		return nil, err
+	}
+	return milestone, nil
+}
+
+// GetTaskMilestones контрольные точки задания для тех, кому видно само задание
+func (s *MilestoneService) GetTaskMilestones(ctx context.Context, viewerID, taskID uuid.UUID) ([]*models.Milestone, error) {
+	task, err := s.taskRepo.GetByID(ctx, taskID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.authz.AuthorizeTaskContents(ctx, viewerID, task); err != nil {
+		return nil, err
+	}
+	return s.milestoneRepo.GetByTask(ctx, taskID)
+}
+
+// authorizeMilestone находит точку и проверяет право изменять ее задание
+func (s *MilestoneService) authorizeMilestone(ctx context.Context, userID, milestoneID uuid.UUID) (*models.Milestone, *models.Task, error) {
+	milestone, err := s.milestoneRepo.GetByID(ctx, milestoneID)
+	if err != nil {
+		return nil, nil, err
+	}
+	task, err := s.taskRepo.GetByID(ctx, milestone.TaskID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := s.authz.AuthorizeTask(ctx, userID, task, policy.ActionUpdate); err != nil {
+		return nil, nil, err
+	}
+	return milestone, task, nil
+}
+
+func (s *MilestoneService) UpdateMilestone(ctx context.Context, userID, milestoneID uuid.UUID, req *models.UpdateMilestoneRequest) (*models.Milestone, error) {
+	milestone, task, err := s.authorizeMilestone(ctx, userID, milestoneID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Title != nil {
+		milestone.Title = *req.Title
+	}
+	if req.Description != nil {
+		milestone.Description = *req.Description
+	}
+	if req.DueDate != nil {
+		if err := validateMilestoneDate(task, *req.DueDate); err != nil {
+			return nil, err
+		}
+		milestone.DueDate = *req.DueDate
+	}
+	milestone.UpdatedAt = time.Now()
+
+	if err := s.milestoneRepo.Update(ctx, milestone); err != nil {
+		return nil, err
+	}
+	return milestone, nil
+}
+
+// DeleteMilestone удаляет точку; привязанные проблемы остаются в проектах без привязки
+func (s *MilestoneService) DeleteMilestone(ctx context.Context, userID, milestoneID uuid.UUID) error {
+	if _, _, err := s.authorizeMilestone(ctx, userID, milestoneID); err != nil {
+		return err
+	}
+	return s.milestoneRepo.Delete(ctx, milestoneID)
+}
+
+// GetProjectMilestones контрольные точки в расписании проекта с отметкой, пройдены ли они
+func (s *MilestoneService) GetProjectMilestones(ctx context.Context, userID, projectID uuid.UUID) ([]*models.MilestoneProgress, error) {
+	project, err := s.projectRepo.GetByID(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.authz.AuthorizeProject(ctx, userID, project, policy.ResourceProject, policy.ActionView); err != nil {
+		return nil, err
+	}
+
+	milestones, err := s.milestoneRepo.GetByTask(ctx, project.TaskID)
+	if err != nil {
+		return nil, err
+	}
+	states, err := s.milestoneRepo.GetProblemStates(ctx, []uuid.UUID{project.ID})
+	if err != nil {
+		return nil, err
+	}
+	return milestoneProgress(milestones, states[project.ID], time.Now()), nil
+}
+
+// GetTaskReport для каждого проекта задания показывает, какие контрольные точки пройдены
+func (s *MilestoneService) GetTaskReport(ctx context.Context, userID, taskID uuid.UUID) ([]*models.ProjectMilestoneReport, error) {
+	task, err := s.taskRepo.GetByID(ctx, taskID)
+	if err != nil {
+		return nil, err
+	}
+	// без роли в проектах просмотр проходит только по праву projects.view_all
+	if _, err := s.authz.AuthorizeSubject(ctx, userID, task.SubjectID, policy.ResourceProject, policy.ActionView); err != nil {
+		return nil, err
+	}
+
+	milestones, err := s.milestoneRepo.GetByTask(ctx, taskID)
+	if err != nil {
+		return nil, err
+	}
+	projects, err := s.projectRepo.GetByTask(ctx, taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	reports := make([]*models.ProjectMilestoneReport, 0, len(projects))
+	if len(projects) == 0 {
+		return reports, nil
+	}
+	projectIDs := make([]uuid.UUID, 0, len(projects))
+	for _, p := range projects {
+		projectIDs = append(projectIDs, p.ID)
+	}
+	states, err := s.milestoneRepo.GetProblemStates(ctx, projectIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	for _, p := range projects {
+		reports = append(reports, &models.ProjectMilestoneReport{
+			ProjectID:  p.ID,
+			Title:      p.Title,
+			Milestones: milestoneProgress(milestones, states[p.ID], now),
+		})
+	}
+	return reports, nil
+}
+
+func milestoneProgress(milestones []*models.Milestone, states []*models.MilestoneProblemState, now time.Time) []*models.MilestoneProgress {
+	byMilestone := make(map[uuid.UUID][]*models.MilestoneProblemState)
+	for _, st := range states {
+		byMilestone[st.MilestoneID] = append(byMilestone[st.MilestoneID], st)
+	}
+	progress := make([]*models.MilestoneProgress, 0, len(milestones))
+	for _, m := range milestones {
+		progress = append(progress, models.NewMilestoneProgress(m, byMilestone[m.ID], now))
+	}
+	return progress
+}
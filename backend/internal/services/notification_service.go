@@ -0,0 +1,53 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/wozhdeleniye/redclass-app/internal/models"
+	"github.com/wozhdeleniye/redclass-app/internal/repositories/postgres"
+)
+
+type NotificationService struct {
+	notificationRepo *postgres.NotificationRepository
+}
+
+func NewNotificationService(notificationRepo *postgres.NotificationRepository) *NotificationService {
+	return &NotificationService{notificationRepo: notificationRepo}
+}
+
+// Notify создает уведомление пользователю
+func (s *NotificationService) Notify(ctx context.Context, userID uuid.UUID, notificationType models.NotificationType, title, body string, targetID *uuid.UUID) error {
+	notification := &models.Notification{
+		ID:        uuid.New(),
+		UserID:    userID,
+		Type:      notificationType,
+		Title:     title,
+		Body:      body,
+		TargetID:  targetID,
+		CreatedAt: time.Now(),
+	}
+	return s.notificationRepo.Create(ctx, notification)
+}
+
+func (s *NotificationService) GetUserNotifications(ctx context.Context, userID uuid.UUID, unreadOnly bool, limit, offset int) ([]*models.Notification, int64, error) {
+	if limit <= 0 {
+		limit = 20
+	}
+	if limit > 100 {
+		limit = 100
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	return s.notificationRepo.GetByUser(ctx, userID, unreadOnly, limit, offset)
+}
+
+func (s *NotificationService) MarkRead(ctx context.Context, userID, notificationID uuid.UUID) error {
+	return s.notificationRepo.MarkRead(ctx, userID, notificationID)
+}
+
+func (s *NotificationService) MarkAllRead(ctx context.Context, userID uuid.UUID) error {
+	return s.notificationRepo.MarkAllRead(ctx, userID)
+}
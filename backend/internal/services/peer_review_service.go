@@ -0,0 +1,417 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/wozhdeleniye/redclass-app/internal/models"
+	"github.com/wozhdeleniye/redclass-app/internal/policy"
+	"github.com/wozhdeleniye/redclass-app/internal/repositories/postgres"
+)
+
+// PeerReviewService взаимная оценка вклада участников команды. Преподаватель видит только
+// обезличенные итоги, авторы отзывов ему не раскрываются.
+type PeerReviewService struct {
+	peerRepo    *postgres.PeerReviewRepository
+	gradeRepo   *postgres.GradeRepository
+	taskRepo    *postgres.TaskRepository
+	projectRepo *postgres.ProjectRepository
+	authz       *Authorizer
+}
+
+func NewPeerReviewService(
+	peerRepo *postgres.PeerReviewRepository,
+	gradeRepo *postgres.GradeRepository,
+	taskRepo *postgres.TaskRepository,
+	projectRepo *postgres.ProjectRepository,
+	authz *Authorizer,
+) *PeerReviewService {
+	return &PeerReviewService{
+		peerRepo:    peerRepo,
+		gradeRepo:   gradeRepo,
+		taskRepo:    taskRepo,
+		projectRepo: projectRepo,
+		authz:       authz,
+	}
+}
+
+// GetRound настройки раунда видны всем, кому видно задание
+func (s *PeerReviewService) GetRound(ctx context.Context, viewerID, taskID uuid.UUID) (*models.PeerReviewRound, error) {
+	task, err := s.taskRepo.GetByID(ctx, taskID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.authz.AuthorizeTaskContents(ctx, viewerID, task); err != nil {
+		return nil, err
+	}
+	return s.getRound(ctx, taskID)
+}
+
+// SetRound создает или меняет раунд задания. Критерии фиксируются после первого отзыва,
+// сроки и правила поправки можно менять и позже.
+func (s *PeerReviewService) SetRound(ctx context.Context, userID, taskID uuid.UUID, req *models.SetPeerReviewRoundRequest) (*models.PeerReviewRound, error) {
+	task, err := s.taskRepo.GetByID(ctx, taskID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.authz.AuthorizeTask(ctx, userID, task, policy.ActionUpdate); err != nil {
+		return nil, err
+	}
+	if req.OpensAt != nil && req.ClosesAt != nil && !req.ClosesAt.After(*req.OpensAt) {
+		return nil, errors.New("peer review must close after it opens")
+	}
+	if req.AutoAdjust && req.MaxAdjustment == 0 {
+		return nil, errors.New("max_adjustment is required for automatic adjustment")
+	}
+
+	round, err := s.peerRepo.GetRound(ctx, taskID)
+	if err != nil {
+		return nil, err
+	}
+	isNew := round == nil
+	if isNew {
+		if len(req.Criteria) == 0 {
+			return nil, errors.New("peer review needs at least one criterion")
+		}
+		round = &models.PeerReviewRound{ID: uuid.New(), TaskID: taskID, CreatedByID: userID, CreatedAt: time.Now()}
+	} else if len(req.Criteria) > 0 {
+		reviews, err := s.peerRepo.CountReviews(ctx, round.ID)
+		if err != nil {
+			return nil, err
+		}
+		if reviews > 0 {
+			return nil, errors.New("criteria cannot be changed after reviews were submitted")
+		}
+	}
+
+	if len(req.Criteria) > 0 {
+		round.Criteria = make([]*models.PeerCriterion, 0, len(req.Criteria))
+		for _, input := range req.Criteria {
+			round.Criteria = append(round.Criteria, &models.PeerCriterion{
+				ID:          uuid.New(),
+				Title:       input.Title,
+				Description: input.Description,
+				MaxScore:    input.MaxScore,
+			})
+		}
+	}
+	round.OpensAt = req.OpensAt
+	round.ClosesAt = req.ClosesAt
+	round.AutoAdjust = req.AutoAdjust
+	round.MaxAdjustment = req.MaxAdjustment
+	round.UpdatedAt = time.Now()
+
+	if isNew {
+		err = s.peerRepo.CreateRound(ctx, round)
+	} else {
+		err = s.peerRepo.UpdateRound(ctx, round)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return round, nil
+}
+
+// SubmitReviews сохраняет отзывы участника о товарищах по проекту, заменяя отправленные ранее
+func (s *PeerReviewService) SubmitReviews(ctx context.Context, userID, projectID uuid.UUID, req *models.SubmitPeerReviewsRequest) ([]*models.PeerReview, error) {
+	project, err := s.projectRepo.GetByID(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	teammates := make(map[uuid.UUID]bool, len(project.Members))
+	for _, m := range project.Members {
+		teammates[m.UserID] = true
+	}
+	if !teammates[userID] {
+		return nil, errors.New("only project members can review teammates")
+	}
+
+	task, err := s.taskRepo.GetByID(ctx, project.TaskID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.authz.EnsureSubjectWritable(ctx, task.SubjectID); err != nil {
+		return nil, err
+	}
+	round, err := s.getRound(ctx, task.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !round.IsOpen(time.Now()) {
+		return nil, errors.New("peer review is not open")
+	}
+
+	reviewed := make(map[uuid.UUID]bool, len(req.Reviews))
+	reviews := make([]*models.PeerReview, 0, len(req.Reviews))
+	for _, input := range req.Reviews {
+		switch {
+		case input.RevieweeID == userID:
+			return nil, errors.New("members cannot review themselves")
+		case !teammates[input.RevieweeID]:
+			return nil, errors.New("reviewee is not a project member")
+		case reviewed[input.RevieweeID]:
+			return nil, errors.New("each teammate can be reviewed only once")
+		}
+		if _, ok := round.Percent(input.Scores); !ok {
+			return nil, errors.New("every criterion must be scored within its scale")
+		}
+		reviewed[input.RevieweeID] = true
+		reviews = append(reviews, &models.PeerReview{
+			ID:         uuid.New(),
+			RoundID:    round.ID,
+			ProjectID:  projectID,
+			ReviewerID: userID,
+			RevieweeID: input.RevieweeID,
+			Scores:     input.Scores,
+			Comment:    input.Comment,
+			CreatedAt:  time.Now(),
+			UpdatedAt:  time.Now(),
+		})
+	}
+
+	if err := s.peerRepo.ReplaceReviews(ctx, round.ID, userID, reviews); err != nil {
+		return nil, err
+	}
+	return reviews, nil
+}
+
+// GetMyReviews отзывы, которые пользователь оставил в проекте
+func (s *PeerReviewService) GetMyReviews(ctx context.Context, userID, projectID uuid.UUID) ([]*models.PeerReview, error) {
+	project, err := s.projectRepo.GetByID(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	isMember, err := s.projectRepo.IsUserMember(ctx, projectID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !isMember {
+		return nil, errors.New("only project members have peer reviews")
+	}
+	round, err := s.getRound(ctx, project.TaskID)
+	if err != nil {
+		return nil, err
+	}
+	return s.peerRepo.GetByReviewer(ctx, round.ID, userID)
+}
+
+// GetProjectSummary обезличенные итоги взаимной оценки в проекте для оценивающих
+func (s *PeerReviewService) GetProjectSummary(ctx context.Context, userID, projectID uuid.UUID) (*models.PeerProjectSummary, error) {
+	project, err := s.projectRepo.GetByID(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	round, err := s.authorizeSummary(ctx, userID, project.TaskID)
+	if err != nil {
+		return nil, err
+	}
+	return s.summarize(ctx, round, project)
+}
+
+// GetTaskSummary итоги взаимной оценки по всем проектам задания
+func (s *PeerReviewService) GetTaskSummary(ctx context.Context, userID, taskID uuid.UUID) ([]*models.PeerProjectSummary, error) {
+	round, err := s.authorizeSummary(ctx, userID, taskID)
+	if err != nil {
+		return nil, err
+	}
+	projects, err := s.projectRepo.GetTeams(ctx, taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	summaries := make([]*models.PeerProjectSummary, 0, len(projects))
+	for _, project := range projects {
+		summary, err := s.summarize(ctx, round, project)
+		if err != nil {
+			return nil, err
+		}
+		summaries = append(summaries, summary)
+	}
+	return summaries, nil
+}
+
+// ApplyAdjustments пересчитывает поправки взаимной оценки в уже выставленных оценках задания;
+// проекты, оцененные после закрытия раунда, получают поправку сразу при оценке.
+// Возвращает число обновленных оценок участников.
+func (s *PeerReviewService) ApplyAdjustments(ctx context.Context, userID, taskID uuid.UUID) (int, error) {
+	task, err := s.taskRepo.GetByID(ctx, taskID)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := s.authz.AuthorizeSubject(ctx, userID, task.SubjectID, policy.ResourceGrade, policy.ActionUpdate); err != nil {
+		return 0, err
+	}
+	round, err := s.getRound(ctx, taskID)
+	if err != nil {
+		return 0, err
+	}
+	if !round.AutoAdjust {
+		return 0, errors.New("automatic adjustment is disabled for this peer review")
+	}
+	if !round.IsClosed(time.Now()) {
+		return 0, errors.New("peer review is still open")
+	}
+
+	projects, err := s.projectRepo.GetTeams(ctx, taskID)
+	if err != nil {
+		return 0, err
+	}
+	updated := 0
+	for _, project := range projects {
+		grade, err := s.gradeRepo.GetByProject(ctx, project.ID)
+		if err != nil {
+			return 0, err
+		}
+		if grade == nil {
+			continue
+		}
+		summary, err := s.summarize(ctx, round, project)
+		if err != nil {
+			return 0, err
+		}
+		adjustments := summary.Adjustments()
+		for _, m := range grade.Members {
+			m.PeerAdjustment = adjustments[m.UserID]
+			m.Recalculate(grade.FinalScore)
+			m.UpdatedAt = time.Now()
+			if err := s.gradeRepo.SaveMember(ctx, m); err != nil {
+				return 0, err
+			}
+			updated++
+		}
+	}
+	return updated, nil
+}
+
+// Adjustments поправки взаимной оценки для участников проекта; nil, если автоматическая
+// поправка в задании выключена или раунд еще не закрыт
+func (s *PeerReviewService) Adjustments(ctx context.Context, taskID uuid.UUID, project *models.Project) (map[uuid.UUID]float64, error) {
+	round, err := s.peerRepo.GetRound(ctx, taskID)
+	if err != nil {
+		return nil, err
+	}
+	if round == nil || !round.AutoAdjust || !round.IsClosed(time.Now()) {
+		return nil, nil
+	}
+	summary, err := s.summarize(ctx, round, project)
+	if err != nil {
+		return nil, err
+	}
+	return summary.Adjustments(), nil
+}
+
+// summarize сводит отзывы проекта по участникам: средние баллы, отклонение от команды
+// и поправку. Комментарии сортируются, чтобы их порядок не выдавал авторов.
+func (s *PeerReviewService) summarize(ctx context.Context, round *models.PeerReviewRound, project *models.Project) (*models.PeerProjectSummary, error) {
+	reviews, err := s.peerRepo.GetByProject(ctx, round.ID, project.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &models.PeerProjectSummary{
+		ProjectID: project.ID,
+		Title:     project.Title,
+		Members:   []*models.PeerMemberSummary{},
+	}
+	byUser := make(map[uuid.UUID]*models.PeerMemberSummary, len(project.Members))
+	for _, m := range project.Members {
+		ms := &models.PeerMemberSummary{
+			UserID:           m.UserID,
+			CriterionAverage: map[uuid.UUID]float64{},
+			Comments:         []string{},
+		}
+		if m.User != nil {
+			ms.Nickname = m.User.Nickname
+		}
+		byUser[m.UserID] = ms
+		summary.Members = append(summary.Members, ms)
+	}
+
+	maxScore := make(map[uuid.UUID]int, len(round.Criteria))
+	for _, c := range round.Criteria {
+		maxScore[c.ID] = c.MaxScore
+	}
+	totals := make(map[uuid.UUID]float64)
+	counted := 0
+	for _, review := range reviews {
+		reviewee, reviewer := byUser[review.RevieweeID], byUser[review.ReviewerID]
+		// отзывы ушедших участников и о них в итоги не входят
+		if reviewee == nil || reviewer == nil {
+			continue
+		}
+		percent, ok := round.Percent(review.Scores)
+		if !ok {
+			continue
+		}
+		counted++
+		reviewer.ReviewsGiven++
+		reviewee.ReviewsReceived++
+		totals[reviewee.UserID] += percent
+		for _, score := range review.Scores {
+			if max := maxScore[score.CriterionID]; max > 0 {
+				reviewee.CriterionAverage[score.CriterionID] += float64(score.Score) / float64(max) * 100
+			}
+		}
+		if review.Comment != "" {
+			reviewee.Comments = append(reviewee.Comments, review.Comment)
+		}
+	}
+	n := len(project.Members)
+	summary.Complete = n > 1 && counted == n*(n-1)
+
+	var teamSum float64
+	rated := 0
+	for _, ms := range summary.Members {
+		sort.Strings(ms.Comments)
+		if ms.ReviewsReceived == 0 {
+			continue
+		}
+		avg := roundPercent(totals[ms.UserID] / float64(ms.ReviewsReceived))
+		ms.Average = &avg
+		for id, sum := range ms.CriterionAverage {
+			ms.CriterionAverage[id] = roundPercent(sum / float64(ms.ReviewsReceived))
+		}
+		teamSum += avg
+		rated++
+	}
+	if rated < 2 || teamSum == 0 {
+		return summary, nil
+	}
+
+	teamAvg := teamSum / float64(rated)
+	for _, ms := range summary.Members {
+		ms.Compare(teamAvg, round.MaxAdjustment)
+	}
+	return summary, nil
+}
+
+// authorizeSummary проверяет право оценивать в предмете задания и возвращает его раунд
+func (s *PeerReviewService) authorizeSummary(ctx context.Context, userID, taskID uuid.UUID) (*models.PeerReviewRound, error) {
+	task, err := s.taskRepo.GetByID(ctx, taskID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.authz.AuthorizeSubject(ctx, userID, task.SubjectID, policy.ResourceGrade, policy.ActionView); err != nil {
+		return nil, err
+	}
+	return s.getRound(ctx, taskID)
+}
+
+func (s *PeerReviewService) getRound(ctx context.Context, taskID uuid.UUID) (*models.PeerReviewRound, error) {
+	round, err := s.peerRepo.GetRound(ctx, taskID)
+	if err != nil {
+		return nil, err
+	}
+	if round == nil {
+		return nil, errors.New("peer review is not configured for this task")
+	}
+	return round, nil
+}
+
+func roundPercent(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -8,36 +8,68 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/wozhdeleniye/redclass-app/internal/models"
+	"github.com/wozhdeleniye/redclass-app/internal/policy"
 	"github.com/wozhdeleniye/redclass-app/internal/repositories/postgres"
 )
 
 type ProblemService struct {
-	problemRepo *postgres.ProblemRepository
-	projectRepo *postgres.ProjectRepository
+	problemRepo   *postgres.ProblemRepository
+	projectRepo   *postgres.ProjectRepository
+	milestoneRepo *postgres.MilestoneRepository
+	extensionRepo *postgres.ExtensionRepository
+	authz         *Authorizer
 }
 
 func NewProblemService(
 	problemRepo *postgres.ProblemRepository,
 	projectRepo *postgres.ProjectRepository,
+	milestoneRepo *postgres.MilestoneRepository,
+	extensionRepo *postgres.ExtensionRepository,
+	authz *Authorizer,
 ) *ProblemService {
 	return &ProblemService{
-		problemRepo: problemRepo,
-		projectRepo: projectRepo,
+		problemRepo:   problemRepo,
+		projectRepo:   projectRepo,
+		milestoneRepo: milestoneRepo,
+		extensionRepo: extensionRepo,
+		authz:         authz,
 	}
 }
 
-// CreateMainProblem создает главную проблему при создании проекта
-func (s *ProblemService) CreateMainProblem(ctx context.Context, project *models.Project) (*models.Problem, error) {
-	if project.Task == nil {
-		return nil, errors.New("project task is not loaded")
+// defaultProjectWindow длительность проекта по заданию без срока сдачи, если не задан конец семестра
+const defaultProjectWindow = 180 * 24 * time.Hour
+
+// projectDeadline окончание главной проблемы проекта: срок сдачи для группы проекта, иначе
+// конец семестра, иначе defaultProjectWindow от начала. Окно не бывает короче нуля, иначе
+// в проекте нельзя было бы запланировать ни одной подпроблемы.
+func projectDeadline(task *models.Task, groupID *uuid.UUID, start time.Time) time.Time {
+	end := start.Add(defaultProjectWindow)
+	if due := task.DueDateFor(groupID); due != nil {
+		end = *due
+	} else if task.Subject != nil && task.Subject.TermEnd != nil && task.Subject.TermEnd.After(start) {
+		end = *task.Subject.TermEnd
 	}
+	if end.Before(start) {
+		return start
+	}
+	return end
+}
 
-	now := time.Now()
-	endTime := now
-	if project.Task.DueDate != nil {
-		endTime = *project.Task.DueDate
+// projectStart начало окна проекта: момент создания, но не раньше официального начала задания
+func projectStart(task *models.Task, created time.Time) time.Time {
+	if task.StartDate != nil && task.StartDate.After(created) {
+		return *task.StartDate
 	}
+	return created
+}
 
+// CreateMainProblem создает главную проблему при создании проекта; groupID группа задания, от которой зависит срок
+func (s *ProblemService) CreateMainProblem(ctx context.Context, project *models.Project, groupID *uuid.UUID) (*models.Problem, error) {
+	if project.Task == nil {
+		return nil, errors.New("project task is not loaded")
+	}
+
+	start := projectStart(project.Task, time.Now())
 	mainProblem := &models.Problem{
 		ProjectID:   project.ID,
 		ParentID:    nil, // главная проблема
@@ -45,8 +77,8 @@ func (s *ProblemService) CreateMainProblem(ctx context.Context, project *models.
 		Number:      1,
 		Title:       fmt.Sprintf("Main: %s", project.Title),
 		Description: fmt.Sprintf("Main problem for project: %s", project.Title),
-		StartTime:   now,
-		EndTime:     endTime,
+		StartTime:   start,
+		EndTime:     projectDeadline(project.Task, groupID, start),
 		Solved:      false,
 	}
 
@@ -56,6 +88,112 @@ func (s *ProblemService) CreateMainProblem(ctx context.Context, project *models.
 	return mainProblem, nil
 }
 
+// RescheduleTask переносит окно главных проблем всех проектов задания на его текущие даты начала и сдачи.
+// Продление команды сохраняется, если оно позже нового срока.
+// Подпроблемы не сдвигаются: те, что оказались вне окна, возвращаются в отчете.
+func (s *ProblemService) RescheduleTask(ctx context.Context, task *models.Task) (*models.ScheduleImpact, error) {
+	projects, err := s.projectRepo.GetByTask(ctx, task.ID)
+	if err != nil {
+		return nil, err
+	}
+	groups, err := s.projectGroups(ctx, task)
+	if err != nil {
+		return nil, err
+	}
+	extensions, err := s.extensionRepo.GetTeamExtensions(ctx, task.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	impact := &models.ScheduleImpact{}
+	for _, project := range projects {
+		changed, err := s.reschedule(ctx, task, project, groups, extensions[project.ID])
+		if err != nil {
+			return nil, err
+		}
+		if changed {
+			impact.Rescheduled++
+		}
+	}
+
+	if impact.OutOfWindow, err = s.problemRepo.GetOutOfWindow(ctx, task.ID); err != nil {
+		return nil, err
+	}
+	return impact, nil
+}
+
+// RescheduleProject пересчитывает окно одного проекта после выдачи или отзыва продления команды
+func (s *ProblemService) RescheduleProject(ctx context.Context, task *models.Task, project *models.Project) error {
+	groups, err := s.projectGroups(ctx, task)
+	if err != nil {
+		return err
+	}
+	extension, err := s.extensionRepo.GetFor(ctx, project.ID, nil)
+	if err != nil {
+		return err
+	}
+	_, err = s.reschedule(ctx, task, project, groups, extension)
+	return err
+}
+
+func (s *ProblemService) projectGroups(ctx context.Context, task *models.Task) (map[uuid.UUID]uuid.UUID, error) {
+	if !task.IsTargeted() {
+		return nil, nil
+	}
+	return s.projectRepo.GetProjectGroups(ctx, task.ID)
+}
+
+// reschedule выставляет окно главной проблемы проекта; false, если оно не изменилось
+func (s *ProblemService) reschedule(ctx context.Context, task *models.Task, project *models.Project, groups map[uuid.UUID]uuid.UUID, extension *models.DeadlineExtension) (bool, error) {
+	mainProblem, err := s.problemRepo.GetMainProblemByProject(ctx, project.ID)
+	if err != nil || mainProblem == nil {
+		return false, err
+	}
+
+	var groupID *uuid.UUID
+	if id, ok := groups[project.ID]; ok {
+		groupID = &id
+	}
+	start := projectStart(task, project.CreatedAt)
+	end := projectDeadline(task, groupID, start)
+	if due := models.EffectiveDueDate(task.DueDateFor(groupID), extension); due != nil && due.After(end) {
+		end = *due
+	}
+	if start.Equal(mainProblem.StartTime) && end.Equal(mainProblem.EndTime) {
+		return false, nil
+	}
+	return true, s.problemRepo.SetMainProblemWindow(ctx, project.ID, start, end)
+}
+
+// OutOfWindow подпроблемы проектов задания, выходящие за окно своего проекта
+func (s *ProblemService) OutOfWindow(ctx context.Context, taskID uuid.UUID) ([]*models.OutOfWindowProblem, error) {
+	return s.problemRepo.GetOutOfWindow(ctx, taskID)
+}
+
+// windowEnd крайний срок подпроблемы: конец окна проекта, а если все исполнители получили
+// личное продление, то самый ранний из их продленных сроков
+func (s *ProblemService) windowEnd(ctx context.Context, mainProblem *models.Problem, assigneeIDs []uuid.UUID) (time.Time, error) {
+	end := mainProblem.EndTime
+	if len(assigneeIDs) == 0 {
+		return end, nil
+	}
+
+	var limit *time.Time
+	for _, userID := range assigneeIDs {
+		ext, err := s.extensionRepo.GetFor(ctx, mainProblem.ProjectID, &userID)
+		if err != nil {
+			return end, err
+		}
+		if ext == nil || !ext.DueDate.After(end) {
+			return end, nil
+		}
+		if limit == nil || ext.DueDate.Before(*limit) {
+			limit = &ext.DueDate
+		}
+	}
+	return *limit, nil
+}
+
 // CreateProblem создает новую проблему (подпроблему или главную)
 func (s *ProblemService) CreateProblem(
 	ctx context.Context,
@@ -72,12 +210,18 @@ func (s *ProblemService) CreateProblem(
 		return nil, errors.New("project not found")
 	}
 
-	isMember, err := s.projectRepo.IsUserMember(ctx, projectID, userID)
-	if err != nil {
+	if err := s.authz.AuthorizeProject(ctx, userID, project, policy.ResourceProblem, policy.ActionCreate); err != nil {
 		return nil, err
 	}
-	if !isMember {
-		return nil, errors.New("user is not a project member")
+
+	if parentID != nil {
+		parent, err := s.problemRepo.GetByID(ctx, *parentID)
+		if err != nil {
+			return nil, err
+		}
+		if parent == nil || parent.ProjectID != projectID {
+			return nil, errors.New("parent problem not found in project")
+		}
 	}
 
 	mainProblem, err := s.problemRepo.GetMainProblemByProject(ctx, projectID)
@@ -98,10 +242,28 @@ func (s *ProblemService) CreateProblem(
 		endTime = *req.EndTime
 	}
 
+	var milestone *models.Milestone
+	if req.MilestoneID != nil {
+		if milestone, err = s.projectMilestone(ctx, project, *req.MilestoneID); err != nil {
+			return nil, err
+		}
+		// без явного срока проблема по умолчанию заканчивается к контрольной точке
+		if req.EndTime == nil && milestone.DueDate.Before(endTime) {
+			endTime = milestone.DueDate
+		}
+		if endTime.After(milestone.DueDate) {
+			return nil, errors.New("end_time cannot be later than the milestone due_date")
+		}
+	}
+
 	if startTime.Before(mainProblem.StartTime) {
 		return nil, errors.New("start_time cannot be earlier than main problem start_time")
 	}
-	if endTime.After(mainProblem.EndTime) {
+	windowEnd, err := s.windowEnd(ctx, mainProblem, req.AssigneeIDs)
+	if err != nil {
+		return nil, err
+	}
+	if endTime.After(windowEnd) {
 		return nil, errors.New("end_time cannot be later than main problem end_time")
 	}
 	if startTime.After(endTime) {
@@ -123,6 +285,7 @@ func (s *ProblemService) CreateProblem(
 		StartTime:   startTime,
 		EndTime:     endTime,
 		Solved:      false,
+		MilestoneID: req.MilestoneID,
 	}
 
 	if err := s.problemRepo.Create(ctx, problem); err != nil {
@@ -163,6 +326,10 @@ func (s *ProblemService) UpdateProblem(
 		return nil, errors.New("problem not found")
 	}
 
+	if err := s.authz.AuthorizeProblem(ctx, userID, problem, policy.ActionUpdate); err != nil {
+		return nil, err
+	}
+
 	mainProblem, err := s.problemRepo.GetMainProblemByProject(ctx, problem.ProjectID)
 	if err != nil {
 		return nil, err
@@ -182,7 +349,23 @@ func (s *ProblemService) UpdateProblem(
 		problem.StartTime = *req.StartTime
 	}
 	if req.EndTime != nil {
-		if req.EndTime.After(mainProblem.EndTime) {
+		var assigneeIDs []uuid.UUID
+		if req.AssigneeIDs != nil {
+			assigneeIDs = *req.AssigneeIDs
+		} else {
+			existing, err := s.problemRepo.GetAssignees(ctx, problemID)
+			if err != nil {
+				return nil, err
+			}
+			for _, a := range existing {
+				assigneeIDs = append(assigneeIDs, a.UserID)
+			}
+		}
+		windowEnd, err := s.windowEnd(ctx, mainProblem, assigneeIDs)
+		if err != nil {
+			return nil, err
+		}
+		if req.EndTime.After(windowEnd) {
 			return nil, errors.New("end_time cannot be later than main problem end_time")
 		}
 		problem.EndTime = *req.EndTime
@@ -192,7 +375,34 @@ func (s *ProblemService) UpdateProblem(
 		return nil, errors.New("start_time cannot be after end_time")
 	}
 
+	if req.MilestoneID != nil {
+		if *req.MilestoneID == uuid.Nil {
+			problem.MilestoneID = nil
+		} else {
+			problem.MilestoneID = req.MilestoneID
+		}
+	}
+	if problem.MilestoneID != nil && (req.MilestoneID != nil || req.EndTime != nil) {
+		milestone, err := s.projectMilestone(ctx, problem.Project, *problem.MilestoneID)
+		if err != nil {
+			return nil, err
+		}
+		if problem.EndTime.After(milestone.DueDate) {
+			return nil, errors.New("end_time cannot be later than the milestone due_date")
+		}
+	}
+
 	if req.AssigneeIDs != nil {
+		for _, assigneeID := range *req.AssigneeIDs {
+			isMember, err := s.projectRepo.IsUserMember(ctx, problem.ProjectID, assigneeID)
+			if err != nil {
+				return nil, err
+			}
+			if !isMember {
+				return nil, fmt.Errorf("user %s is not a project member", assigneeID)
+			}
+		}
+
 		existingAssignees, err := s.problemRepo.GetAssignees(ctx, problemID)
 		if err != nil {
 			return nil, err
@@ -237,18 +447,31 @@ func (s *ProblemService) DeleteProblem(ctx context.Context, userID uuid.UUID, pr
 		return errors.New("problem not found")
 	}
 
-	project, err := s.projectRepo.GetByID(ctx, problem.ProjectID)
-	if err != nil {
+	if err := s.authz.AuthorizeProblem(ctx, userID, problem, policy.ActionDelete); err != nil {
 		return err
 	}
-
-	if problem.CreatorID != userID && project.CreatorID != userID {
-		return errors.New("only creator or project owner can delete problem")
+	if problem.ParentID == nil {
+		return errors.New("main problem cannot be deleted")
+	}
+	if problem.Required {
+		// обязательный этап шаблона может удалить только тот, кто редактирует задание
+		p, err := s.authz.ProjectPrincipal(ctx, userID, problem.Project)
+		if err != nil {
+			return err
+		}
+		if policy.Authorize(p, policy.ResourceTask, policy.ActionUpdate) != nil {
+			return &policy.ForbiddenError{Resource: policy.ResourceProblem, Action: policy.ActionDelete, Reason: "required by the task template"}
+		}
 	}
 
 	return s.problemRepo.Delete(ctx, problemID)
 }
 
+// ReassignMember передает проблемы уходящего участника successor или просто снимает его с них
+func (s *ProblemService) ReassignMember(ctx context.Context, projectID, userID uuid.UUID, successor *uuid.UUID) error {
+	return s.problemRepo.ReassignMember(ctx, projectID, userID, successor)
+}
+
 // GetProblem получает проблему
 func (s *ProblemService) GetProblem(ctx context.Context, userID uuid.UUID, problemID uuid.UUID) (*models.Problem, error) {
 	problem, err := s.problemRepo.GetByID(ctx, problemID)
@@ -259,11 +482,18 @@ func (s *ProblemService) GetProblem(ctx context.Context, userID uuid.UUID, probl
 		return nil, errors.New("problem not found")
 	}
 
+	if err := s.authz.AuthorizeProblem(ctx, userID, problem, policy.ActionView); err != nil {
+		return nil, err
+	}
+
 	return problem, nil
 }
 
 // GetProjectProblems получает все проблемы проекта
 func (s *ProblemService) GetProjectProblems(ctx context.Context, userID uuid.UUID, projectID uuid.UUID, assignedOnly bool) ([]*models.Problem, error) {
+	if err := s.authorizeProject(ctx, userID, projectID, policy.ResourceProblem); err != nil {
+		return nil, err
+	}
 
 	if assignedOnly {
 		return s.problemRepo.GetProjectProblemsAssigned(ctx, projectID, userID)
@@ -274,6 +504,9 @@ func (s *ProblemService) GetProjectProblems(ctx context.Context, userID uuid.UUI
 
 // GetMainProblem получает главную проблему проекта
 func (s *ProblemService) GetMainProblem(ctx context.Context, userID uuid.UUID, projectID uuid.UUID) (*models.Problem, error) {
+	if err := s.authorizeProject(ctx, userID, projectID, policy.ResourceProblem); err != nil {
+		return nil, err
+	}
 	return s.problemRepo.GetMainProblemByProject(ctx, projectID)
 }
 
@@ -292,11 +525,18 @@ func (s *ProblemService) GetSubproblems(ctx context.Context, userID uuid.UUID, p
 		return nil, errors.New("parent problem not found")
 	}
 
+	if err := s.authz.AuthorizeProblem(ctx, userID, parent, policy.ActionView); err != nil {
+		return nil, err
+	}
+
 	return s.problemRepo.GetChildProblems(ctx, parentID)
 }
 
 // GetProjectStatistics возвращает статистику по всем проблемам проекта
 func (s *ProblemService) GetProjectStatistics(ctx context.Context, userID uuid.UUID, projectID uuid.UUID) (*models.ProblemStatistics, error) {
+	if err := s.authorizeProject(ctx, userID, projectID, policy.ResourceProject); err != nil {
+		return nil, err
+	}
 	return s.problemRepo.GetProjectStatistics(ctx, projectID)
 }
 
@@ -304,3 +544,24 @@ func (s *ProblemService) GetProjectStatistics(ctx context.Context, userID uuid.U
 func (s *ProblemService) GetChildrenStatistics(ctx context.Context, parentID uuid.UUID) (*models.ChildrenStatistics, error) {
 	return s.problemRepo.GetChildrenStatistics(ctx, parentID)
 }
+
+// projectMilestone контрольная точка задания, к которому относится проект
+func (s *ProblemService) projectMilestone(ctx context.Context, project *models.Project, milestoneID uuid.UUID) (*models.Milestone, error) {
+	milestone, err := s.milestoneRepo.GetByID(ctx, milestoneID)
+	if err != nil {
+		return nil, err
+	}
+	if milestone.TaskID != project.TaskID {
+		return nil, errors.New("milestone does not belong to the project task")
+	}
+	return milestone, nil
+}
+
+// authorizeProject проверяет право просмотра ресурса проекта
+func (s *ProblemService) authorizeProject(ctx context.Context, userID, projectID uuid.UUID, resource policy.Resource) error {
+	project, err := s.projectRepo.GetByID(ctx, projectID)
+	if err != nil {
+		return err
+	}
+	return s.authz.AuthorizeProject(ctx, userID, project, resource, policy.ActionView)
+}
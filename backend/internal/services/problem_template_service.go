@@ -0,0 +1,308 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/wozhdeleniye/redclass-app/internal/models"
+	"github.com/wozhdeleniye/redclass-app/internal/policy"
+	"github.com/wozhdeleniye/redclass-app/internal/repositories/postgres"
+)
+
+// maxTemplateNodes ограничивает размер шаблона, который разворачивается в каждом проекте
+const maxTemplateNodes = 100
+
+type ProblemTemplateService struct {
+	templateRepo *postgres.ProblemTemplateRepository
+	taskRepo     *postgres.TaskRepository
+	projectRepo  *postgres.ProjectRepository
+	problemRepo  *postgres.ProblemRepository
+	authz        *Authorizer
+}
+
+func NewProblemTemplateService(
+	templateRepo *postgres.ProblemTemplateRepository,
+	taskRepo *postgres.TaskRepository,
+	projectRepo *postgres.ProjectRepository,
+	problemRepo *postgres.ProblemRepository,
+	authz *Authorizer,
+) *ProblemTemplateService {
+	return &ProblemTemplateService{
+		templateRepo: templateRepo,
+		taskRepo:     taskRepo,
+		projectRepo:  projectRepo,
+		problemRepo:  problemRepo,
+		authz:        authz,
+	}
+}
+
+// GetTemplates шаблон задания деревом
+func (s *ProblemTemplateService) GetTemplates(ctx context.Context, viewerID, taskID uuid.UUID) ([]*models.ProblemTemplate, error) {
+	task, err := s.taskRepo.GetByID(ctx, taskID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.authz.AuthorizeTaskContents(ctx, viewerID, task); err != nil {
+		return nil, err
+	}
+
+	templates, err := s.templateRepo.GetByTask(ctx, taskID)
+	if err != nil {
+		return nil, err
+	}
+	return buildTemplateTree(templates), nil
+}
+
+// SetTemplates заменяет шаблон задания. Существующие проекты не меняются, пока
+// преподаватель не вызовет SyncProjects.
+func (s *ProblemTemplateService) SetTemplates(ctx context.Context, userID, taskID uuid.UUID, req *models.SetProblemTemplatesRequest) ([]*models.ProblemTemplate, error) {
+	task, err := s.taskRepo.GetByID(ctx, taskID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.authz.AuthorizeTask(ctx, userID, task, policy.ActionUpdate); err != nil {
+		return nil, err
+	}
+
+	existing, err := s.templateRepo.GetByTask(ctx, taskID)
+	if err != nil {
+		return nil, err
+	}
+	known := make(map[uuid.UUID]bool, len(existing))
+	for _, t := range existing {
+		known[t.ID] = true
+	}
+
+	var flat []*models.ProblemTemplate
+	seen := make(map[uuid.UUID]bool)
+	var walk func(nodes []models.ProblemTemplateNode, parentID *uuid.UUID) error
+	walk = func(nodes []models.ProblemTemplateNode, parentID *uuid.UUID) error {
+		for _, node := range nodes {
+			id := uuid.New()
+			if node.ID != nil {
+				if !known[*node.ID] {
+					return errors.New("template node does not belong to this task")
+				}
+				if seen[*node.ID] {
+					return errors.New("template node is listed twice")
+				}
+				id = *node.ID
+			}
+			seen[id] = true
+
+			flat = append(flat, &models.ProblemTemplate{
+				ID:            id,
+				TaskID:        taskID,
+				ParentID:      parentID,
+				Position:      len(flat),
+				Title:         node.Title,
+				Description:   node.Description,
+				EndOffsetDays: node.EndOffsetDays,
+				DurationDays:  node.DurationDays,
+				Required:      node.Required,
+				CreatedAt:     time.Now(),
+				UpdatedAt:     time.Now(),
+			})
+			if len(flat) > maxTemplateNodes {
+				return errors.New("template is too large")
+			}
+			if err := walk(node.Children, &id); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := walk(req.Problems, nil); err != nil {
+		return nil, err
+	}
+
+	if err := s.templateRepo.ReplaceForTask(ctx, taskID, flat); err != nil {
+		return nil, err
+	}
+	return buildTemplateTree(flat), nil
+}
+
+// SyncProjects переносит текущий шаблон в уже созданные проекты задания: недостающие этапы
+// добавляются, у существующих обновляются название, описание и обязательность. Сроки и
+// прогресс, которые команды могли изменить, не трогаются.
+func (s *ProblemTemplateService) SyncProjects(ctx context.Context, userID, taskID uuid.UUID) (*models.TemplateSyncReport, error) {
+	task, err := s.taskRepo.GetByID(ctx, taskID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.authz.AuthorizeTask(ctx, userID, task, policy.ActionUpdate); err != nil {
+		return nil, err
+	}
+
+	templates, err := s.templateRepo.GetByTask(ctx, taskID)
+	if err != nil {
+		return nil, err
+	}
+	projects, err := s.projectRepo.GetByTask(ctx, taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &models.TemplateSyncReport{}
+	for _, project := range projects {
+		if project.Status.IsLocked() || project.IsArchived() {
+			report.Locked++
+			continue
+		}
+		if err := s.apply(ctx, project, templates, report); err != nil {
+			return nil, err
+		}
+		report.Projects++
+	}
+	return report, nil
+}
+
+// Instantiate разворачивает шаблон задания в только что созданном проекте
+func (s *ProblemTemplateService) Instantiate(ctx context.Context, project *models.Project) error {
+	templates, err := s.templateRepo.GetByTask(ctx, project.TaskID)
+	if err != nil {
+		return err
+	}
+	if len(templates) == 0 {
+		return nil
+	}
+	return s.apply(ctx, project, templates, &models.TemplateSyncReport{})
+}
+
+// CloneTemplates готовит копию шаблона задания для другого задания при клонировании предмета,
+// ничего не сохраняя; узлы получают новые идентификаторы и возвращаются в порядке обхода дерева
+func (s *ProblemTemplateService) CloneTemplates(ctx context.Context, fromTaskID, toTaskID uuid.UUID) ([]*models.ProblemTemplate, error) {
+	templates, err := s.templateRepo.GetByTask(ctx, fromTaskID)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make(map[uuid.UUID]uuid.UUID, len(templates))
+	for _, t := range templates {
+		ids[t.ID] = uuid.New()
+	}
+	copies := make([]*models.ProblemTemplate, 0, len(templates))
+	for _, t := range templates {
+		c := *t
+		c.ID = ids[t.ID]
+		c.TaskID = toTaskID
+		c.Children = nil
+		if t.ParentID != nil {
+			parentID := ids[*t.ParentID]
+			c.ParentID = &parentID
+		}
+		c.CreatedAt = time.Now()
+		c.UpdatedAt = time.Now()
+		copies = append(copies, &c)
+	}
+	return copies, nil
+}
+
+// apply приводит проблемы проекта в соответствие с шаблоном. Проблемы, чей узел удален
+// из шаблона, остаются в проекте, но отвязываются от него и перестают быть обязательными.
+func (s *ProblemTemplateService) apply(ctx context.Context, project *models.Project, templates []*models.ProblemTemplate, report *models.TemplateSyncReport) error {
+	mainProblem, err := s.problemRepo.GetMainProblemByProject(ctx, project.ID)
+	if err != nil {
+		return err
+	}
+	if mainProblem == nil {
+		return errors.New("main problem not found")
+	}
+
+	problems, err := s.problemRepo.GetTemplatedProblems(ctx, project.ID)
+	if err != nil {
+		return err
+	}
+	byTemplate := make(map[uuid.UUID]*models.Problem, len(problems))
+	for _, p := range problems {
+		byTemplate[*p.TemplateID] = p
+	}
+
+	inTemplate := make(map[uuid.UUID]bool, len(templates))
+	for _, t := range templates {
+		inTemplate[t.ID] = true
+	}
+	for _, p := range problems {
+		if inTemplate[*p.TemplateID] {
+			continue
+		}
+		p.TemplateID = nil
+		p.Required = false
+		if err := s.problemRepo.Update(ctx, p); err != nil {
+			return err
+		}
+		report.Detached++
+	}
+
+	// шаблон упорядочен так, что родитель создается раньше своих детей
+	for _, t := range templates {
+		if p, ok := byTemplate[t.ID]; ok {
+			if p.Title == t.Title && p.Description == t.Description && p.Required == t.Required {
+				continue
+			}
+			p.Title = t.Title
+			p.Description = t.Description
+			p.Required = t.Required
+			if err := s.problemRepo.Update(ctx, p); err != nil {
+				return err
+			}
+			report.Updated++
+			continue
+		}
+
+		parentID := mainProblem.ID
+		if t.ParentID != nil {
+			parent, ok := byTemplate[*t.ParentID]
+			if !ok {
+				continue
+			}
+			parentID = parent.ID
+		}
+
+		number, err := s.problemRepo.GetNextNumber(ctx, project.ID)
+		if err != nil {
+			return err
+		}
+		start, end := t.Schedule(mainProblem.StartTime, mainProblem.EndTime)
+		templateID := t.ID
+		problem := &models.Problem{
+			ProjectID:   project.ID,
+			ParentID:    &parentID,
+			CreatorID:   project.CreatorID,
+			Number:      number,
+			Title:       t.Title,
+			Description: t.Description,
+			StartTime:   start,
+			EndTime:     end,
+			TemplateID:  &templateID,
+			Required:    t.Required,
+		}
+		if err := s.problemRepo.Create(ctx, problem); err != nil {
+			return err
+		}
+		byTemplate[t.ID] = problem
+		report.Created++
+	}
+	return nil
+}
+
+func buildTemplateTree(templates []*models.ProblemTemplate) []*models.ProblemTemplate {
+	byID := make(map[uuid.UUID]*models.ProblemTemplate, len(templates))
+	for _, t := range templates {
+		t.Children = []*models.ProblemTemplate{}
+		byID[t.ID] = t
+	}
+	roots := []*models.ProblemTemplate{}
+	for _, t := range templates {
+		if t.ParentID != nil {
+			if parent, ok := byID[*t.ParentID]; ok {
+				parent.Children = append(parent.Children, t)
+				continue
+			}
+		}
+		roots = append(roots, t)
+	}
+	return roots
+}
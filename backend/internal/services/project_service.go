@@ -9,6 +9,7 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/wozhdeleniye/redclass-app/internal/models"
+	"github.com/wozhdeleniye/redclass-app/internal/policy"
 	"github.com/wozhdeleniye/redclass-app/internal/repositories/postgres"
 )
 
@@ -16,15 +17,23 @@ type ProjectService struct {
 	projectRepo *postgres.ProjectRepository
 	taskRepo    *postgres.TaskRepository
 	roleRepo    *postgres.RoleRepository
-	problemRepo *postgres.ProblemRepository
+	groups      *GroupService
+	teams       *TeamService
+	invites     *InviteService
+	requests    *JoinRequestService
+	authz       *Authorizer
 }
 
-func NewProjectService(pr *postgres.ProjectRepository, tr *postgres.TaskRepository, rr *postgres.RoleRepository, prr *postgres.ProblemRepository) *ProjectService {
+func NewProjectService(pr *postgres.ProjectRepository, tr *postgres.TaskRepository, rr *postgres.RoleRepository, groups *GroupService, teams *TeamService, invites *InviteService, requests *JoinRequestService, authz *Authorizer) *ProjectService {
 	return &ProjectService{
 		projectRepo: pr,
 		taskRepo:    tr,
 		roleRepo:    rr,
-		problemRepo: prr,
+		groups:      groups,
+		teams:       teams,
+		invites:     invites,
+		requests:    requests,
+		authz:       authz,
 	}
 }
 
@@ -42,12 +51,13 @@ func (s *ProjectService) CreateProject(ctx context.Context, userID, taskID uuid.
 		return nil, err
 	}
 
-	role, err := s.roleRepo.GetByUserAndSubject(ctx, userID, task.SubjectID)
+	role, err := s.authz.AuthorizeSubject(ctx, userID, task.SubjectID, policy.ResourceProject, policy.ActionCreate)
 	if err != nil {
 		return nil, err
 	}
-	if role == nil {
-		return nil, errors.New("user must be a member of subject to create project")
+
+	if err := s.teams.CheckCreate(ctx, task, role); err != nil {
+		return nil, err
 	}
 
 	existingProject, err := s.projectRepo.GetUserProjectByTask(ctx, userID, taskID)
@@ -58,107 +68,134 @@ func (s *ProjectService) CreateProject(ctx context.Context, userID, taskID uuid.
 		return nil, errors.New("user can only be in one project per task")
 	}
 
-	var code string
-	for i := 0; i < 5; i++ {
-		c, err := randomCode(8)
-		if err != nil {
-			return nil, err
-		}
-		existing, err := s.projectRepo.GetByCode(ctx, c)
-		if err != nil {
-			return nil, err
-		}
-		if existing == nil {
-			code = c
-			break
-		}
+	return s.teams.createProject(ctx, task, userID, req)
+}
+
+// JoinProject вступление в проект по коду приглашения или по постоянному коду проекта.
+// Если проект требует подтверждения создателем, возвращается заявка на вступление.
+func (s *ProjectService) JoinProject(ctx context.Context, userID uuid.UUID, code string) (*models.ProjectMember, *models.JoinRequest, error) {
+	invite, err := s.invites.Resolve(ctx, userID, code, models.InviteTargetProject)
+	if err != nil {
+		return nil, nil, err
 	}
-	if code == "" {
-		return nil, errors.New("failed to generate unique project code")
+
+	var project *models.Project
+	if invite != nil {
+		project, err = s.projectRepo.GetByID(ctx, *invite.ProjectID)
+	} else {
+		project, err = s.projectRepo.GetByCode(ctx, code)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if project == nil {
+		return nil, nil, errors.New("project with this code not found")
 	}
 
-	project := &models.Project{
-		ID:          uuid.New(),
-		TaskID:      taskID,
-		CreatorID:   userID,
-		Title:       req.Title,
-		Description: req.Description,
-		Code:        code,
-		CreatedAt:   time.Now(),
-		UpdatedAt:   time.Now(),
+	task, err := s.taskRepo.GetByID(ctx, project.TaskID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := s.authz.EnsureSubjectWritable(ctx, task.SubjectID); err != nil {
+		return nil, nil, err
 	}
 
-	if err := s.projectRepo.Create(ctx, project); err != nil {
-		return nil, err
+	isMember, err := s.projectRepo.IsUserMember(ctx, project.ID, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if isMember {
+		return nil, nil, errors.New("user is already a member of this project")
+	}
+	if err := s.teams.CheckJoin(ctx, userID, project); err != nil {
+		return nil, nil, err
+	}
+
+	if invite == nil && project.RequiresApproval {
+		request, err := s.requests.RequestProject(ctx, userID, project)
+		if err != nil {
+			return nil, nil, err
+		}
+		return nil, request, nil
 	}
 
 	member := &models.ProjectMember{
 		ID:        uuid.New(),
 		ProjectID: project.ID,
 		UserID:    userID,
-		Role:      models.ProjectRoleCreator,
+		Role:      models.ProjectRoleMember,
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
 	}
-	if err := s.projectRepo.AddMember(ctx, member); err != nil {
-		return nil, err
+	// приглашение тратится только вместе с успешным вступлением
+	if invite != nil {
+		ok, err := s.projectRepo.AddMemberFromInvite(ctx, member, invite.ID)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !ok {
+			return nil, nil, ErrInviteInvalid
+		}
+	} else if err := s.projectRepo.AddMember(ctx, member); err != nil {
+		return nil, nil, err
 	}
+	member.Project = project
+	return member, nil, nil
+}
 
-	task, err = s.taskRepo.GetByID(ctx, project.TaskID)
+// GetTaskProjects проекты задания; при groupID только созданные участниками группы.
+// Постоянный код вступления видят только участники проекта и те, кто видит все проекты предмета.
+func (s *ProjectService) GetTaskProjects(ctx context.Context, viewerID, taskID uuid.UUID, groupID *uuid.UUID) ([]*models.Project, error) {
+	task, err := s.taskRepo.GetByID(ctx, taskID)
 	if err != nil {
 		return nil, err
 	}
-	project.Task = task
-
-	problemService := NewProblemService(s.problemRepo, s.projectRepo)
-	if _, err := problemService.CreateMainProblem(ctx, project); err != nil {
+	if err := s.authz.AuthorizeTaskContents(ctx, viewerID, task); err != nil {
 		return nil, err
 	}
 
-	return project, nil
-}
-
-func (s *ProjectService) JoinProject(ctx context.Context, userID uuid.UUID, code string) (*models.ProjectMember, error) {
-	project, err := s.projectRepo.GetByCode(ctx, code)
+	var projects []*models.Project
+	if groupID != nil {
+		projects, err = s.projectRepo.GetByTaskAndGroup(ctx, taskID, *groupID)
+	} else {
+		projects, err = s.projectRepo.GetByTask(ctx, taskID)
+	}
 	if err != nil {
 		return nil, err
 	}
-	if project == nil {
-		return nil, errors.New("project with this code not found")
-	}
 
-	isMember, err := s.projectRepo.IsUserMember(ctx, project.ID, userID)
+	p, _, err := s.authz.SubjectPrincipal(ctx, viewerID, task.SubjectID)
 	if err != nil {
 		return nil, err
 	}
-	if isMember {
-		return nil, errors.New("user is already a member of this project")
+	if policy.Can(p, policy.ResourceProject, policy.ActionView) {
+		return projects, nil
 	}
-
-	member := &models.ProjectMember{
-		ID:        uuid.New(),
-		ProjectID: project.ID,
-		UserID:    userID,
-		Role:      models.ProjectRoleMember,
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
-	}
-	if err := s.projectRepo.AddMember(ctx, member); err != nil {
+	own, err := s.projectRepo.GetUserProjectByTask(ctx, viewerID, taskID)
+	if err != nil {
 		return nil, err
 	}
-	member.Project = project
-	return member, nil
-}
-
-func (s *ProjectService) GetTaskProjects(ctx context.Context, taskID uuid.UUID) ([]*models.Project, error) {
-	return s.projectRepo.GetByTask(ctx, taskID)
+	for _, project := range projects {
+		if own == nil || project.ID != own.ID {
+			project.Code = ""
+		}
+	}
+	return projects, nil
 }
 
 func (s *ProjectService) GetUserProjects(ctx context.Context, userID uuid.UUID) ([]*models.Project, error) {
 	return s.projectRepo.GetUserProjects(ctx, userID)
 }
 
-func (s *ProjectService) GetProjectUsers(ctx context.Context, projectID uuid.UUID) ([]*models.User, error) {
+func (s *ProjectService) GetProjectUsers(ctx context.Context, userID, projectID uuid.UUID) ([]*models.User, error) {
+	project, err := s.projectRepo.GetByID(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.authz.AuthorizeProject(ctx, userID, project, policy.ResourceProject, policy.ActionView); err != nil {
+		return nil, err
+	}
+
 	members, err := s.projectRepo.GetMembersByProject(ctx, projectID)
 	if err != nil {
 		return nil, err
@@ -171,3 +208,206 @@ func (s *ProjectService) GetProjectUsers(ctx context.Context, projectID uuid.UUI
 	}
 	return users, nil
 }
+
+// SetJoinApproval включает или выключает подтверждение заявок на вступление в проект
+func (s *ProjectService) SetJoinApproval(ctx context.Context, userID, projectID uuid.UUID, requiresApproval bool) (*models.Project, error) {
+	project, err := s.projectRepo.GetByID(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.authz.AuthorizeProject(ctx, userID, project, policy.ResourceProject, policy.ActionUpdate); err != nil {
+		return nil, err
+	}
+
+	if err := s.projectRepo.SetRequiresApproval(ctx, projectID, requiresApproval); err != nil {
+		return nil, err
+	}
+	project.RequiresApproval = requiresApproval
+	return project, nil
+}
+
+// UpdateProject меняет название и описание проекта
+func (s *ProjectService) UpdateProject(ctx context.Context, userID, projectID uuid.UUID, req *models.UpdateProjectRequest) (*models.Project, error) {
+	project, _, err := s.authorizeManage(ctx, userID, projectID, policy.ActionUpdate)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Title != nil {
+		project.Title = *req.Title
+	}
+	if req.Description != nil {
+		project.Description = *req.Description
+	}
+	project.UpdatedAt = time.Now()
+	if err := s.projectRepo.UpdateDetails(ctx, project); err != nil {
+		return nil, err
+	}
+	return project, nil
+}
+
+// LeaveProject выход участника из проекта. Его назначения снимаются, роль создателя переходит
+// к самому раннему участнику, а опустевший проект удаляется.
+func (s *ProjectService) LeaveProject(ctx context.Context, userID, projectID uuid.UUID) error {
+	project, err := s.projectRepo.GetByID(ctx, projectID)
+	if err != nil {
+		return err
+	}
+	isMember, err := s.projectRepo.IsUserMember(ctx, projectID, userID)
+	if err != nil {
+		return err
+	}
+	if !isMember {
+		return errors.New("user is not a member of this project")
+	}
+
+	task, err := s.taskRepo.GetByID(ctx, project.TaskID)
+	if err != nil {
+		return err
+	}
+	if err := s.authz.EnsureSubjectWritable(ctx, task.SubjectID); err != nil {
+		return err
+	}
+	if task.TeamsFormedByTeacher() {
+		return &policy.ForbiddenError{Resource: policy.ResourceProject, Action: policy.ActionUpdate, Reason: "teams are assigned by the teacher"}
+	}
+	if project.Status.IsLocked() {
+		return errors.New("cannot leave a submitted project")
+	}
+	return s.teams.leave(ctx, projectID, userID, nil)
+}
+
+// RemoveMember исключает участника по решению создателя проекта или преподавателя задания.
+// Проблемы участника переходят к reassignTo, если он указан. Создателя может исключить только
+// преподаватель, и тогда роль создателя переходит к самому раннему участнику.
+func (s *ProjectService) RemoveMember(ctx context.Context, userID, projectID, memberID uuid.UUID, reassignTo *uuid.UUID) error {
+	project, byTeacher, err := s.authorizeManage(ctx, userID, projectID, policy.ActionUpdate)
+	if err != nil {
+		return err
+	}
+	if project.Status.IsLocked() {
+		return errors.New("cannot change members of a submitted project")
+	}
+	if memberID == userID {
+		return errors.New("use leave to exit the project")
+	}
+	if memberID == project.CreatorID && !byTeacher {
+		return errors.New("project creator cannot be removed")
+	}
+
+	members := make(map[uuid.UUID]bool, len(project.Members))
+	for _, m := range project.Members {
+		members[m.UserID] = true
+	}
+	if !members[memberID] {
+		return errors.New("user is not a member of this project")
+	}
+	if reassignTo != nil && (*reassignTo == memberID || !members[*reassignTo]) {
+		return errors.New("problems can only be reassigned to another project member")
+	}
+	return s.teams.leave(ctx, projectID, memberID, reassignTo)
+}
+
+// TransferCreator передает роль создателя другому участнику проекта
+func (s *ProjectService) TransferCreator(ctx context.Context, userID, projectID uuid.UUID, req *models.TransferProjectRequest) (*models.Project, error) {
+	project, _, err := s.authorizeManage(ctx, userID, projectID, policy.ActionUpdate)
+	if err != nil {
+		return nil, err
+	}
+	if req.UserID == project.CreatorID {
+		return project, nil
+	}
+	isMember, err := s.projectRepo.IsUserMember(ctx, projectID, req.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if !isMember {
+		return nil, errors.New("user is not a member of this project")
+	}
+
+	if err := s.projectRepo.SetCreator(ctx, projectID, req.UserID); err != nil {
+		return nil, err
+	}
+	return s.projectRepo.GetByID(ctx, projectID)
+}
+
+// RegenerateCode выдает проекту новый постоянный код; старый код перестает работать.
+// Одноразовые приглашения проекта не затрагиваются.
+func (s *ProjectService) RegenerateCode(ctx context.Context, userID, projectID uuid.UUID) (*models.Project, error) {
+	project, _, err := s.authorizeManage(ctx, userID, projectID, policy.ActionUpdate)
+	if err != nil {
+		return nil, err
+	}
+
+	code, err := s.teams.newProjectCode(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.projectRepo.SetCode(ctx, projectID, code); err != nil {
+		return nil, err
+	}
+	project.Code = code
+	return project, nil
+}
+
+// ArchiveProject переводит проект в архив или возвращает из него. Архивный проект остается
+// виден команде и преподавателю, но вступить в него, сдать его или менять проблемы нельзя.
+func (s *ProjectService) ArchiveProject(ctx context.Context, userID, projectID uuid.UUID, archived bool) (*models.Project, error) {
+	project, _, err := s.authorizeManage(ctx, userID, projectID, policy.ActionUpdate)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.authz.EnsureSubjectWritable(ctx, project.Task.SubjectID); err != nil {
+		return nil, err
+	}
+	if project.IsArchived() == archived {
+		return project, nil
+	}
+
+	var archivedAt *time.Time
+	if archived {
+		now := time.Now()
+		archivedAt = &now
+	}
+	if err := s.projectRepo.SetArchivedAt(ctx, projectID, archivedAt); err != nil {
+		return nil, err
+	}
+	project.ArchivedAt = archivedAt
+	return project, nil
+}
+
+// DeleteProject мягко удаляет проект с проблемами и результатами. Команда может удалить только
+// проект, который еще ни разу не сдавался; сданный проект удаляет преподаватель.
+func (s *ProjectService) DeleteProject(ctx context.Context, userID, projectID uuid.UUID) error {
+	project, byTeacher, err := s.authorizeManage(ctx, userID, projectID, policy.ActionDelete)
+	if err != nil {
+		return err
+	}
+	if project.Status != models.ProjectActive && !byTeacher {
+		return &policy.ForbiddenError{Resource: policy.ResourceProject, Action: policy.ActionDelete, Reason: "project has been submitted"}
+	}
+	return s.projectRepo.Delete(ctx, projectID)
+}
+
+// authorizeManage пропускает создателя проекта и преподавателя, ведущего задание;
+// byTeacher сообщает, что право дано заданием, а не ролью в проекте
+func (s *ProjectService) authorizeManage(ctx context.Context, userID, projectID uuid.UUID, action policy.Action) (project *models.Project, byTeacher bool, err error) {
+	project, err = s.projectRepo.GetByID(ctx, projectID)
+	if err != nil {
+		return nil, false, err
+	}
+	task, err := s.taskRepo.GetByID(ctx, project.TaskID)
+	if err != nil {
+		return nil, false, err
+	}
+	project.Task = task
+
+	// преподаватель проверяется первым, чтобы он не терял своих полномочий, состоя в команде
+	if s.authz.AuthorizeTask(ctx, userID, task, policy.ActionUpdate) == nil {
+		return project, true, nil
+	}
+	if err := s.authz.AuthorizeProject(ctx, userID, project, policy.ResourceProject, action); err != nil {
+		return nil, false, err
+	}
+	return project, false, nil
+}
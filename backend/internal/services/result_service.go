@@ -3,9 +3,11 @@ package services
 import (
 	"context"
 	"errors"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/wozhdeleniye/redclass-app/internal/models"
+	"github.com/wozhdeleniye/redclass-app/internal/policy"
 	"github.com/wozhdeleniye/redclass-app/internal/repositories/postgres"
 )
 
@@ -13,13 +15,17 @@ type ResultService struct {
 	resultRepo  *postgres.ResultRepository
 	problemRepo *postgres.ProblemRepository
 	projectRepo *postgres.ProjectRepository
+	extensions  *ExtensionService
+	authz       *Authorizer
 }
 
-func NewResultService(rr *postgres.ResultRepository, pr *postgres.ProblemRepository, pjr *postgres.ProjectRepository) *ResultService {
+func NewResultService(rr *postgres.ResultRepository, pr *postgres.ProblemRepository, pjr *postgres.ProjectRepository, extensions *ExtensionService, authz *Authorizer) *ResultService {
 	return &ResultService{
 		resultRepo:  rr,
 		problemRepo: pr,
 		projectRepo: pjr,
+		extensions:  extensions,
+		authz:       authz,
 	}
 }
 
@@ -33,13 +39,9 @@ func (s *ResultService) CreateResult(ctx context.Context, userID uuid.UUID, prob
 		return nil, errors.New("problem not found")
 	}
 
-	isMember, err := s.projectRepo.IsUserMember(ctx, problem.ProjectID, userID)
-	if err != nil {
+	if err := s.authz.AuthorizeResult(ctx, userID, problem, policy.ActionCreate); err != nil {
 		return nil, err
 	}
-	if !isMember {
-		return nil, errors.New("user is not a project member")
-	}
 
 	existing, err := s.resultRepo.GetByProblemID(ctx, problemID)
 	if err != nil {
@@ -49,11 +51,23 @@ func (s *ResultService) CreateResult(ctx context.Context, userID uuid.UUID, prob
 		return nil, errors.New("result for this problem already exists")
 	}
 
+	// опоздание считается от срока автора результата: у него может быть личное продление
+	late, err := s.extensions.LateStatus(ctx, problem.ProjectID, &userID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if late.Closed {
+		return nil, errors.New("results for this task are no longer accepted")
+	}
+
 	res := &models.Result{
 		ProblemID: problemID,
 		CreatorID: userID,
 		Done:      true,
 		Comment:   req.Comment,
+		Late:      late.Late,
+		DaysLate:  late.DaysLate,
+		Penalty:   late.Penalty,
 	}
 
 	if err := s.resultRepo.Create(ctx, res); err != nil {
@@ -79,13 +93,9 @@ func (s *ResultService) GetResult(ctx context.Context, userID uuid.UUID, problem
 		return nil, errors.New("problem not found")
 	}
 
-	isMember, err := s.projectRepo.IsUserMember(ctx, problem.ProjectID, userID)
-	if err != nil {
+	if err := s.authz.AuthorizeResult(ctx, userID, problem, policy.ActionView); err != nil {
 		return nil, err
 	}
-	if !isMember {
-		return nil, errors.New("user is not a project member")
-	}
 
 	return s.resultRepo.GetByProblemID(ctx, problemID)
 }
@@ -3,44 +3,129 @@ package services
 import (
 	"context"
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/wozhdeleniye/redclass-app/internal/models"
+	"github.com/wozhdeleniye/redclass-app/internal/policy"
 	"github.com/wozhdeleniye/redclass-app/internal/repositories/postgres"
 )
 
 type RoleService struct {
 	roleRepo *postgres.RoleRepository
+	userRepo *postgres.UserRepository
+	groups   *GroupService
+	authz    *Authorizer
 }
 
-func NewRoleService(roleRepo *postgres.RoleRepository) *RoleService {
-	return &RoleService{roleRepo: roleRepo}
+func NewRoleService(roleRepo *postgres.RoleRepository, userRepo *postgres.UserRepository, groups *GroupService, authz *Authorizer) *RoleService {
+	return &RoleService{roleRepo: roleRepo, userRepo: userRepo, groups: groups, authz: authz}
+}
+
+// AddMember добавляет существующего пользователя в предмет с заданной ролью и правами
+func (s *RoleService) AddMember(ctx context.Context, requesterID uuid.UUID, req *models.CreateRoleRequest) (*models.Role, error) {
+	if _, err := s.authz.AuthorizeSubject(ctx, requesterID, req.SubjectID, policy.ResourceMember, policy.ActionCreate); err != nil {
+		return nil, err
+	}
+	if err := s.checkAssignableRole(ctx, requesterID, req.SubjectID, req.RoleType); err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetUserByID(ctx, req.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+
+	existing, err := s.roleRepo.GetByUserAndSubject(ctx, req.UserID, req.SubjectID)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, errors.New("user is already a member of this subject")
+	}
+
+	role := &models.Role{
+		ID:        uuid.New(),
+		UserID:    req.UserID,
+		SubjectID: req.SubjectID,
+		RoleType:  req.RoleType,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	if req.Permissions != nil {
+		if err := s.checkGrantable(ctx, requesterID, req.SubjectID, req.Permissions); err != nil {
+			return nil, err
+		}
+		role.SetPermissions(req.Permissions)
+	} else {
+		if err := s.checkGrantable(ctx, requesterID, req.SubjectID, models.DefaultPermissions[req.RoleType]); err != nil {
+			return nil, err
+		}
+		role.ResetPermissions()
+	}
+
+	if err := s.roleRepo.Create(ctx, role); err != nil {
+		return nil, err
+	}
+	return role, nil
 }
 
 func (s *RoleService) ChangeRole(ctx context.Context, requesterID, roleID uuid.UUID, newRoleType models.RoleType) (*models.Role, error) {
+	return s.UpdateRole(ctx, requesterID, roleID, &models.UpdateRoleRequest{RoleType: &newRoleType})
+}
 
+// UpdateRole меняет тип роли и/или индивидуальные права участника.
+// При смене типа роли без явного списка прав индивидуальные права сбрасываются к умолчаниям
+// новой роли, поэтому выдающий должен сам ими обладать. Свою роль менять нельзя.
+func (s *RoleService) UpdateRole(ctx context.Context, requesterID, roleID uuid.UUID, req *models.UpdateRoleRequest) (*models.Role, error) {
 	role, err := s.roleRepo.GetByID(ctx, roleID)
 	if err != nil {
 		return nil, err
 	}
 
-	requesterRole, err := s.roleRepo.GetByUserAndSubject(ctx, requesterID, role.SubjectID)
-	if err != nil {
+	if _, err := s.authz.AuthorizeSubject(ctx, requesterID, role.SubjectID, policy.ResourceMember, policy.ActionUpdate); err != nil {
 		return nil, err
 	}
-	if requesterRole == nil || !requesterRole.IsAdmin() {
-		return nil, errors.New("only admin can change roles")
+	if role.UserID == requesterID {
+		return nil, fmt.Errorf("%w: cannot change your own role", policy.ErrForbidden)
 	}
 
 	if role.IsAdmin() {
-		return nil, errors.New("cannot change admin role")
+		if err := s.checkAdminChange(ctx, requesterID, role); err != nil {
+			return nil, err
+		}
+		if req.RoleType != nil && *req.RoleType != models.RoleAdmin {
+			if err := s.checkAdminRemains(ctx, role.SubjectID); err != nil {
+				return nil, err
+			}
+		}
 	}
 
-	role.RoleType = newRoleType
+	if req.RoleType != nil {
+		if err := s.checkAssignableRole(ctx, requesterID, role.SubjectID, *req.RoleType); err != nil {
+			return nil, err
+		}
+		if req.Permissions == nil {
+			if err := s.checkGrantable(ctx, requesterID, role.SubjectID, models.DefaultPermissions[*req.RoleType]); err != nil {
+				return nil, err
+			}
+		}
+		role.RoleType = *req.RoleType
+		role.ResetPermissions()
+	}
+	if req.Permissions != nil {
+		if err := s.checkGrantable(ctx, requesterID, role.SubjectID, *req.Permissions); err != nil {
+			return nil, err
+		}
+		role.SetPermissions(*req.Permissions)
+	}
 	role.UpdatedAt = time.Now()
 
-	if err := s.roleRepo.Update(ctx, role); err != nil {
+	if err := s.roleRepo.UpdateAccess(ctx, role); err != nil {
 		return nil, err
 	}
 
@@ -53,21 +138,131 @@ func (s *RoleService) RemoveFromSubject(ctx context.Context, requesterID, roleID
 		return err
 	}
 
-	requesterRole, err := s.roleRepo.GetByUserAndSubject(ctx, requesterID, role.SubjectID)
+	if _, err := s.authz.AuthorizeSubject(ctx, requesterID, role.SubjectID, policy.ResourceMember, policy.ActionDelete); err != nil {
+		return err
+	}
+
+	if role.IsAdmin() {
+		if err := s.checkAdminChange(ctx, requesterID, role); err != nil {
+			return err
+		}
+		if err := s.checkAdminRemains(ctx, role.SubjectID); err != nil {
+			return err
+		}
+	}
+
+	if err := s.roleRepo.Delete(ctx, roleID); err != nil {
+		return err
+	}
+	return s.groups.RemoveFromSubject(ctx, role.SubjectID, role.UserID)
+}
+
+// LeaveSubject выход участника из предмета. Владелец должен сначала передать владение,
+// последний администратор не может уйти.
+func (s *RoleService) LeaveSubject(ctx context.Context, userID, subjectID uuid.UUID) error {
+	role, err := s.roleRepo.GetByUserAndSubject(ctx, userID, subjectID)
 	if err != nil {
 		return err
 	}
-	if requesterRole == nil || !requesterRole.IsAdmin() {
-		return errors.New("only admin can remove members")
+	if role == nil {
+		return errors.New("user is not a member of this subject")
 	}
 
+	if role.Subject != nil && role.Subject.OwnerID == userID {
+		return errors.New("owner cannot leave the subject, transfer ownership first")
+	}
 	if role.IsAdmin() {
-		return errors.New("cannot remove admin from subject")
+		if err := s.checkAdminRemains(ctx, subjectID); err != nil {
+			return err
+		}
 	}
 
-	return s.roleRepo.Delete(ctx, roleID)
+	if err := s.roleRepo.Delete(ctx, role.ID); err != nil {
+		return err
+	}
+	return s.groups.RemoveFromSubject(ctx, subjectID, userID)
 }
 
-func (s *RoleService) GetSubjectRoles(ctx context.Context, subjectID uuid.UUID) ([]*models.Role, error) {
+// GetSubjectRoles участники предмета; при groupID только участники этой группы.
+// Участников непубличного предмета видят только его участники.
+func (s *RoleService) GetSubjectRoles(ctx context.Context, viewerID, subjectID uuid.UUID, groupID *uuid.UUID) ([]*models.Role, error) {
+	if err := s.authz.AuthorizeSubjectContents(ctx, viewerID, subjectID, policy.ResourceMember); err != nil {
+		return nil, err
+	}
+	if groupID != nil {
+		return s.roleRepo.GetGroupRoles(ctx, subjectID, *groupID)
+	}
 	return s.roleRepo.GetSubjectRoles(ctx, subjectID)
 }
+
+// GetMyPermissions возвращает итоговые права пользователя в предмете
+func (s *RoleService) GetMyPermissions(ctx context.Context, userID, subjectID uuid.UUID) ([]models.Permission, error) {
+	p, _, err := s.authz.SubjectPrincipal(ctx, userID, subjectID)
+	if err != nil {
+		return nil, err
+	}
+
+	permissions := make([]models.Permission, 0, len(models.PermissionCatalogue))
+	for _, info := range models.PermissionCatalogue {
+		if p.Has(policy.RoleSuperAdmin) || p.HasPermission(info.Key) {
+			permissions = append(permissions, info.Key)
+		}
+	}
+	return permissions, nil
+}
+
+// checkGrantable не дает выдать права, которых нет у самого выдающего
+func (s *RoleService) checkGrantable(ctx context.Context, requesterID, subjectID uuid.UUID, permissions []models.Permission) error {
+	return s.authz.CheckGrantable(ctx, requesterID, subjectID, permissions)
+}
+
+// checkAssignableRole назначать администраторов могут только администраторы предмета
+func (s *RoleService) checkAssignableRole(ctx context.Context, requesterID, subjectID uuid.UUID, roleType models.RoleType) error {
+	if roleType != models.RoleAdmin {
+		return validateRoleType(roleType)
+	}
+	return s.requireSubjectAdmin(ctx, requesterID, subjectID)
+}
+
+// checkAdminChange роль владельца неизменна, роли администраторов меняют только администраторы
+func (s *RoleService) checkAdminChange(ctx context.Context, requesterID uuid.UUID, role *models.Role) error {
+	if role.Subject != nil && role.Subject.OwnerID == role.UserID {
+		return errors.New("cannot change the owner's role, transfer ownership first")
+	}
+	return s.requireSubjectAdmin(ctx, requesterID, role.SubjectID)
+}
+
+// checkAdminRemains не дает лишить предмет последнего администратора
+func (s *RoleService) checkAdminRemains(ctx context.Context, subjectID uuid.UUID) error {
+	count, err := s.roleRepo.CountAdmins(ctx, subjectID)
+	if err != nil {
+		return err
+	}
+	if count <= 1 {
+		return errors.New("subject must keep at least one admin")
+	}
+	return nil
+}
+
+func (s *RoleService) requireSubjectAdmin(ctx context.Context, userID, subjectID uuid.UUID) error {
+	p, _, err := s.authz.SubjectPrincipal(ctx, userID, subjectID)
+	if err != nil {
+		return err
+	}
+	if !p.Has(policy.RoleSubjectAdmin) && !p.Has(policy.RoleSuperAdmin) {
+		return fmt.Errorf("%w: only subject admins can manage admins", policy.ErrForbidden)
+	}
+	return nil
+}
+
+// validateRoleType проверяет роль, которую можно выдать по приглашению или списку.
+// Администраторов назначают только другие администраторы через checkAssignableRole.
+func validateRoleType(roleType models.RoleType) error {
+	switch roleType {
+	case models.RoleStudent, models.RoleTeacher:
+		return nil
+	case models.RoleAdmin:
+		return errors.New("cannot assign admin role")
+	}
+	return errors.New("unknown role type")
+}
@@ -0,0 +1,329 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/wozhdeleniye/redclass-app/internal/models"
+	"github.com/wozhdeleniye/redclass-app/internal/policy"
+	"github.com/wozhdeleniye/redclass-app/internal/repositories/postgres"
+)
+
+// MaxRosterRows ограничение на число строк в одном импорте
+const MaxRosterRows = 2000
+
+type RosterService struct {
+	roleRepo *postgres.RoleRepository
+	userRepo *postgres.UserRepository
+	groups   *GroupService
+	invites  *InviteService
+	authz    *Authorizer
+	validate *validator.Validate
+}
+
+func NewRosterService(roleRepo *postgres.RoleRepository, userRepo *postgres.UserRepository, groups *GroupService, invites *InviteService, authz *Authorizer) *RosterService {
+	return &RosterService{
+		roleRepo: roleRepo,
+		userRepo: userRepo,
+		groups:   groups,
+		invites:  invites,
+		authz:    authz,
+		validate: validator.New(),
+	}
+}
+
+// rosterColumns номера колонок email, роли и группы; -1 если колонки нет
+type rosterColumns struct {
+	email, role, group int
+}
+
+// ImportRoster зачисляет пользователей в предмет по таблице. Известные email получают роль сразу,
+// для неизвестных создаются именные приглашения. Колонка группы зачисляет в группу, отсутствующие
+// группы создаются. В режиме dryRun ничего не сохраняется.
+func (s *RosterService) ImportRoster(ctx context.Context, userID, subjectID uuid.UUID, table [][]string, defaultRole models.RoleType, dryRun bool) (*models.RosterImportReport, error) {
+	if _, err := s.authz.AuthorizeSubject(ctx, userID, subjectID, policy.ResourceMember, policy.ActionCreate); err != nil {
+		return nil, err
+	}
+
+	if defaultRole == "" {
+		defaultRole = models.RoleStudent
+	}
+	if err := validateRoleType(defaultRole); err != nil {
+		return nil, err
+	}
+	if err := s.authz.CheckGrantable(ctx, userID, subjectID, models.DefaultPermissions[defaultRole]); err != nil {
+		return nil, err
+	}
+	// teacherAllowed можно ли выдавать роль преподавателя из колонки роли
+	teacherAllowed := s.authz.CheckGrantable(ctx, userID, subjectID, models.DefaultPermissions[models.RoleTeacher]) == nil
+
+	cols, dataStart := detectRosterColumns(table)
+	if len(table)-dataStart > MaxRosterRows {
+		return nil, fmt.Errorf("roster is too large, at most %d rows are allowed", MaxRosterRows)
+	}
+
+	report := &models.RosterImportReport{DryRun: dryRun, Rows: []*models.RosterRowResult{}}
+	seen := make(map[string]bool)
+	var emails []string
+
+	for i := dataStart; i < len(table); i++ {
+		row := table[i]
+		if isBlankRow(row) {
+			continue
+		}
+
+		result := &models.RosterRowResult{
+			Line:     i + 1,
+			Email:    strings.ToLower(strings.TrimSpace(cell(row, cols.email))),
+			Group:    strings.TrimSpace(cell(row, cols.group)),
+			RoleType: defaultRole,
+		}
+		report.Rows = append(report.Rows, result)
+
+		if err := s.validate.Var(result.Email, "required,email"); err != nil {
+			result.Fail("invalid email")
+			continue
+		}
+		if seen[result.Email] {
+			result.Fail("duplicate email in file")
+			continue
+		}
+		seen[result.Email] = true
+
+		if raw := cell(row, cols.role); strings.TrimSpace(raw) != "" {
+			roleType, err := parseRosterRole(raw)
+			if err != nil {
+				result.Fail(err.Error())
+				continue
+			}
+			if roleType == models.RoleTeacher && !teacherAllowed {
+				result.Fail("cannot assign teacher role")
+				continue
+			}
+			result.RoleType = roleType
+		}
+
+		emails = append(emails, result.Email)
+	}
+
+	users, err := s.userRepo.GetUsersByEmails(ctx, emails)
+	if err != nil {
+		return nil, err
+	}
+	usersByEmail := make(map[string]*models.User, len(users))
+	for _, u := range users {
+		usersByEmail[strings.ToLower(u.Email)] = u
+	}
+
+	roles, err := s.roleRepo.GetSubjectRoles(ctx, subjectID)
+	if err != nil {
+		return nil, err
+	}
+	members := make(map[uuid.UUID]bool, len(roles))
+	for _, r := range roles {
+		members[r.UserID] = true
+	}
+
+	for _, result := range report.Rows {
+		if result.Status == models.RosterRowError {
+			continue
+		}
+
+		user := usersByEmail[result.Email]
+		switch {
+		case user == nil:
+			result.Status = models.RosterRowInvited
+			if !dryRun {
+				var groupID *uuid.UUID
+				// приглашение зачисляет только в одну группу
+				if names := splitGroups(result.Group); len(names) > 0 {
+					group, err := s.groups.EnsureGroup(ctx, subjectID, names[0])
+					if err != nil {
+						result.Fail(err.Error())
+						continue
+					}
+					groupID = &group.ID
+				}
+				invite, err := s.invites.EnsureSubjectInviteForEmail(ctx, userID, subjectID, result.Email, result.RoleType, groupID)
+				if err != nil {
+					result.Fail(err.Error())
+					continue
+				}
+				result.InviteID = &invite.ID
+			}
+		case !user.IsActive:
+			result.UserID = &user.ID
+			result.Fail("account is deactivated")
+		case members[user.ID]:
+			result.UserID = &user.ID
+			result.Status = models.RosterRowAlreadyMember
+			s.assignRosterGroup(ctx, subjectID, result, dryRun)
+		default:
+			result.UserID = &user.ID
+			result.Status = models.RosterRowAdded
+			if !dryRun {
+				role := &models.Role{
+					ID:        uuid.New(),
+					UserID:    user.ID,
+					SubjectID: subjectID,
+					RoleType:  result.RoleType,
+					CreatedAt: time.Now(),
+					UpdatedAt: time.Now(),
+				}
+				if err := s.roleRepo.Create(ctx, role); err != nil {
+					result.Fail(err.Error())
+					continue
+				}
+			}
+			members[user.ID] = true
+			s.assignRosterGroup(ctx, subjectID, result, dryRun)
+		}
+	}
+
+	for _, result := range report.Rows {
+		report.Total++
+		switch result.Status {
+		case models.RosterRowAdded:
+			report.Added++
+		case models.RosterRowInvited:
+			report.Invited++
+		case models.RosterRowAlreadyMember:
+			report.AlreadyMembers++
+		case models.RosterRowError:
+			report.Errors++
+		}
+	}
+
+	return report, nil
+}
+
+// assignRosterGroup зачисляет участника в группу из строки списка. Ошибка зачисления не отменяет
+// вступление в предмет и попадает в отчет рядом со статусом строки.
+func (s *RosterService) assignRosterGroup(ctx context.Context, subjectID uuid.UUID, result *models.RosterRowResult, dryRun bool) {
+	if dryRun {
+		return
+	}
+	for _, name := range splitGroups(result.Group) {
+		if _, err := s.groups.AssignByName(ctx, subjectID, *result.UserID, name); err != nil {
+			result.Error = "group: " + err.Error()
+			return
+		}
+	}
+}
+
+// splitGroups разбирает ячейку группы; преподаватель может вести несколько групп через запятую,
+// как в выгрузке списка
+func splitGroups(raw string) []string {
+	var names []string
+	for _, name := range strings.Split(raw, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// ExportRoster возвращает участников предмета с email, ролью и датой вступления
+func (s *RosterService) ExportRoster(ctx context.Context, userID, subjectID uuid.UUID) ([]*models.RosterEntry, error) {
+	if _, err := s.authz.AuthorizeSubject(ctx, userID, subjectID, policy.ResourceMember, policy.ActionCreate); err != nil {
+		return nil, err
+	}
+
+	roles, err := s.roleRepo.GetSubjectRoster(ctx, subjectID)
+	if err != nil {
+		return nil, err
+	}
+	groupNames, err := s.groups.GroupNamesByUser(ctx, subjectID)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]*models.RosterEntry, 0, len(roles))
+	for _, r := range roles {
+		if r.User == nil {
+			continue
+		}
+		entries = append(entries, &models.RosterEntry{
+			UserID:   r.UserID,
+			Email:    r.User.Email,
+			Nickname: r.User.Nickname,
+			RoleType: r.RoleType,
+			Groups:   groupNames[r.UserID],
+			IsActive: r.User.IsActive,
+			JoinedAt: r.CreatedAt,
+		})
+	}
+	return entries, nil
+}
+
+// RosterTable переводит выгрузку в строки таблицы с заголовком
+func RosterTable(entries []*models.RosterEntry) [][]string {
+	table := [][]string{{"email", "nickname", "role", "group", "active", "joined_at"}}
+	for _, e := range entries {
+		table = append(table, []string{
+			e.Email,
+			e.Nickname,
+			string(e.RoleType),
+			strings.Join(e.Groups, ", "),
+			fmt.Sprintf("%t", e.IsActive),
+			e.JoinedAt.Format(time.RFC3339),
+		})
+	}
+	return table
+}
+
+// detectRosterColumns ищет заголовок в первой строке; без заголовка колонки идут как email, роль, группа
+func detectRosterColumns(table [][]string) (rosterColumns, int) {
+	cols := rosterColumns{email: -1, role: -1, group: -1}
+	if len(table) == 0 {
+		return cols, 0
+	}
+
+	for i, h := range table[0] {
+		switch strings.ToLower(strings.TrimSpace(h)) {
+		case "email", "e-mail", "mail", "почта", "эл. почта":
+			cols.email = i
+		case "role", "роль":
+			cols.role = i
+		case "group", "группа":
+			cols.group = i
+		}
+	}
+	if cols.email >= 0 {
+		return cols, 1
+	}
+	return rosterColumns{email: 0, role: 1, group: 2}, 0
+}
+
+func parseRosterRole(raw string) (models.RoleType, error) {
+	switch strings.ToLower(strings.TrimSpace(raw)) {
+	case "student", "студент":
+		return models.RoleStudent, nil
+	case "teacher", "преподаватель":
+		return models.RoleTeacher, nil
+	case "admin", "администратор":
+		return "", errors.New("cannot assign admin role")
+	}
+	return "", fmt.Errorf("unknown role %q", raw)
+}
+
+func cell(row []string, i int) string {
+	if i < 0 || i >= len(row) {
+		return ""
+	}
+	return row[i]
+}
+
+func isBlankRow(row []string) bool {
+	for _, c := range row {
+		if strings.TrimSpace(c) != "" {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,29 @@
+package services
+
+import "testing"
+
+func TestDetectRosterColumns(t *testing.T) {
+	noHeader := rosterColumns{email: 0, role: 1, group: 2}
+	cases := []struct {
+		name      string
+		table     [][]string
+		want      rosterColumns
+		dataStart int
+	}{
+		{"empty table", nil, rosterColumns{email: -1, role: -1, group: -1}, 0},
+		{"no header", [][]string{{"a@x.ru", "student", "g1"}}, noHeader, 0},
+		{"english header", [][]string{{"Email", "Role", "Group"}, {"a@x.ru"}}, noHeader, 1},
+		{"reordered russian header", [][]string{{"ФИО", "группа", " Почта ", "роль"}}, rosterColumns{email: 2, role: 3, group: 1}, 1},
+		{"email only", [][]string{{"e-mail"}}, rosterColumns{email: 0, role: -1, group: -1}, 1},
+		// без колонки email заголовок не распознается, и первая строка считается данными
+		{"header without email", [][]string{{"role", "group"}}, noHeader, 0},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, start := detectRosterColumns(tc.table)
+			if got != tc.want || start != tc.dataStart {
+				t.Errorf("got %+v from row %d, want %+v from row %d", got, start, tc.want, tc.dataStart)
+			}
+		})
+	}
+}
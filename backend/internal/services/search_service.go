@@ -0,0 +1,77 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/wozhdeleniye/redclass-app/internal/models"
+	"github.com/wozhdeleniye/redclass-app/internal/repositories/postgres"
+)
+
+type SearchService struct {
+	searchRepo *postgres.SearchRepository
+	userRepo   *postgres.UserRepository
+	roleRepo   *postgres.RoleRepository
+}
+
+func NewSearchService(searchRepo *postgres.SearchRepository, userRepo *postgres.UserRepository, roleRepo *postgres.RoleRepository) *SearchService {
+	return &SearchService{searchRepo: searchRepo, userRepo: userRepo, roleRepo: roleRepo}
+}
+
+// Search полнотекстовый поиск по предметам, заданиям, проектам и задачам. Аноним (uuid.Nil)
+// находит только публичные предметы и их задания, проекты и задачи видны их участникам и тем,
+// кому в предмете разрешено смотреть все проекты.
+func (s *SearchService) Search(ctx context.Context, viewerID uuid.UUID, filter models.SearchFilter) ([]*models.SearchResult, error) {
+	filter.Query = strings.TrimSpace(filter.Query)
+	if utf8.RuneCountInString(filter.Query) < 2 {
+		return nil, errors.New("search query must be at least 2 characters long")
+	}
+	if utf8.RuneCountInString(filter.Query) > 200 {
+		return nil, errors.New("search query is too long")
+	}
+	for _, t := range filter.Types {
+		switch t {
+		case models.SearchSubject, models.SearchTask, models.SearchProject, models.SearchProblem:
+		default:
+			return nil, errors.New("unknown search type " + string(t))
+		}
+	}
+
+	if filter.Limit <= 0 {
+		filter.Limit = 20
+	}
+	if filter.Limit > 50 {
+		filter.Limit = 50
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+
+	if viewerID != uuid.Nil {
+		filter.ViewerID = &viewerID
+
+		user, err := s.userRepo.GetUserByID(ctx, viewerID)
+		if err != nil {
+			return nil, err
+		}
+		filter.All = user != nil && user.IsSuperAdmin
+
+		roles, err := s.roleRepo.GetUserRoles(ctx, viewerID)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range roles {
+			if r.HasPermission(models.PermProjectsViewAll) {
+				filter.ProjectSubjectIDs = append(filter.ProjectSubjectIDs, r.SubjectID)
+			}
+			if r.HasPermission(models.PermTasksCreate) || r.HasPermission(models.PermTasksManage) {
+				filter.TaskSubjectIDs = append(filter.TaskSubjectIDs, r.SubjectID)
+			}
+		}
+	}
+
+	return s.searchRepo.Search(ctx, filter)
+}
@@ -4,10 +4,12 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/wozhdeleniye/redclass-app/internal/models"
+	"github.com/wozhdeleniye/redclass-app/internal/policy"
 	"github.com/wozhdeleniye/redclass-app/internal/repositories/postgres"
 )
 
@@ -15,39 +17,83 @@ type SubjectService struct {
 	subjectRepo *postgres.SubjectRepository
 	roleRepo    *postgres.RoleRepository
 	userRepo    *postgres.UserRepository
+	taskRepo    *postgres.TaskRepository
+	templates   *ProblemTemplateService
+	groups      *GroupService
+	invites     *InviteService
+	requests    *JoinRequestService
+	transfers   *postgres.OwnershipTransferRepository
+	notifier    *NotificationService
+	authz       *Authorizer
 }
 
 func NewSubjectService(
 	subjectRepo *postgres.SubjectRepository,
 	roleRepo *postgres.RoleRepository,
 	userRepo *postgres.UserRepository,
+	taskRepo *postgres.TaskRepository,
+	templates *ProblemTemplateService,
+	groups *GroupService,
+	invites *InviteService,
+	requests *JoinRequestService,
+	transfers *postgres.OwnershipTransferRepository,
+	notifier *NotificationService,
+	authz *Authorizer,
 ) *SubjectService {
 	return &SubjectService{
 		subjectRepo: subjectRepo,
 		roleRepo:    roleRepo,
 		userRepo:    userRepo,
+		taskRepo:    taskRepo,
+		templates:   templates,
+		groups:      groups,
+		invites:     invites,
+		requests:    requests,
+		transfers:   transfers,
+		notifier:    notifier,
+		authz:       authz,
 	}
 }
 
 func (s *SubjectService) CreateSubject(ctx context.Context, creatorID uuid.UUID, req *models.CreateSubjectRequest) (*models.Subject, error) {
+	subject, adminRole, err := newSubject(creatorID, req)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.subjectRepo.CreateWithContents(ctx, subject, adminRole, nil, nil); err != nil {
+		return nil, fmt.Errorf("failed to create subject: %w", err)
+	}
+	return subject, nil
+}
+
+// newSubject проверяет запрос и собирает предмет вместе с ролью администратора для его создателя
+func newSubject(creatorID uuid.UUID, req *models.CreateSubjectRequest) (*models.Subject, *models.Role, error) {
 	if req.Name == "" {
-		return nil, errors.New("subject name is required")
+		return nil, nil, errors.New("subject name is required")
 	}
 	if req.Code == "" {
-		return nil, errors.New("subject code is required")
+		return nil, nil, errors.New("subject code is required")
 	}
-
-	subject := &models.Subject{
-		ID:          uuid.New(),
-		Name:        req.Name,
-		Description: req.Description,
-		Code:        req.Code,
-		CreatedAt:   time.Now(),
-		UpdatedAt:   time.Now(),
+	if err := validateTerm(req.TermStart, req.TermEnd); err != nil {
+		return nil, nil, err
+	}
+	if req.Visibility == "" {
+		req.Visibility = models.VisibilityPrivate
 	}
 
-	if err := s.subjectRepo.Create(ctx, subject); err != nil {
-		return nil, fmt.Errorf("failed to create subject: %w", err)
+	subject := &models.Subject{
+		ID:               uuid.New(),
+		Name:             req.Name,
+		Description:      req.Description,
+		Code:             req.Code,
+		OwnerID:          creatorID,
+		RequiresApproval: req.RequiresApproval,
+		Visibility:       req.Visibility,
+		TermName:         req.TermName,
+		TermStart:        req.TermStart,
+		TermEnd:          req.TermEnd,
+		CreatedAt:        time.Now(),
+		UpdatedAt:        time.Now(),
 	}
 
 	adminRole := &models.Role{
@@ -59,18 +105,49 @@ func (s *SubjectService) CreateSubject(ctx context.Context, creatorID uuid.UUID,
 		UpdatedAt: time.Now(),
 	}
 
-	if err := s.roleRepo.Create(ctx, adminRole); err != nil {
-		return nil, fmt.Errorf("failed to create admin role: %w", err)
+	return subject, adminRole, nil
+}
+
+// GetSubjectByID карточка предмета глазами зрителя (uuid.Nil для анонима). Приватный предмет
+// посторонним не показывается вовсе, у непубличного скрыты задания и участники, а код
+// вступления видят только те, кто может приглашать.
+func (s *SubjectService) GetSubjectByID(ctx context.Context, viewerID, id uuid.UUID) (*models.Subject, error) {
+	subject, err := s.subjectRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
 	}
 
+	access, err := s.authz.SubjectAccess(ctx, viewerID, subject)
+	if err != nil {
+		return nil, err
+	}
+	if !access.Visible {
+		return nil, ErrSubjectNotFound
+	}
+	if !access.Contents {
+		subject.Tasks = nil
+	} else if !access.ManageTasks {
+		now := time.Now()
+		published := subject.Tasks[:0]
+		for _, task := range subject.Tasks {
+			if task.IsPublished(now) {
+				published = append(published, task)
+			}
+		}
+		subject.Tasks = published
+	}
+	if !access.Member {
+		subject.Roles = nil
+	}
+	if !access.ManageMembers {
+		subject.Code = ""
+	}
 	return subject, nil
 }
 
-func (s *SubjectService) GetSubjectByID(ctx context.Context, id uuid.UUID) (*models.Subject, error) {
-	return s.subjectRepo.GetByID(ctx, id)
-}
-
-func (s *SubjectService) GetAllSubjects(ctx context.Context, limit, offset int) ([]*models.Subject, int64, error) {
+// GetAllSubjects каталог предметов: публичные и те, где зритель участник; супер-администратор видит все.
+// Архивные возвращаются только при includeArchived.
+func (s *SubjectService) GetAllSubjects(ctx context.Context, viewerID uuid.UUID, limit, offset int, includeArchived bool) ([]*models.Subject, int64, error) {
 	if limit <= 0 {
 		limit = 20
 	}
@@ -80,66 +157,477 @@ func (s *SubjectService) GetAllSubjects(ctx context.Context, limit, offset int)
 	if offset < 0 {
 		offset = 0
 	}
-	return s.subjectRepo.GetAll(ctx, limit, offset)
+	filter := models.SubjectListFilter{IncludeArchived: includeArchived, Limit: limit, Offset: offset}
+	manageable := make(map[uuid.UUID]bool)
+	if viewerID != uuid.Nil {
+		filter.ViewerID = &viewerID
+
+		user, err := s.userRepo.GetUserByID(ctx, viewerID)
+		if err != nil {
+			return nil, 0, err
+		}
+		filter.All = user != nil && user.IsSuperAdmin
+
+		roles, err := s.roleRepo.GetUserRoles(ctx, viewerID)
+		if err != nil {
+			return nil, 0, err
+		}
+		for _, r := range roles {
+			manageable[r.SubjectID] = filter.All || r.HasPermission(models.PermMembersManage)
+		}
+	}
+
+	subjects, total, err := s.subjectRepo.GetAll(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+	for _, subject := range subjects {
+		if !filter.All && !manageable[subject.ID] {
+			subject.Code = ""
+		}
+	}
+	return subjects, total, nil
 }
 
-func (s *SubjectService) UpdateSubject(ctx context.Context, id uuid.UUID, req *models.UpdateSubjectRequest) (*models.Subject, error) {
+func (s *SubjectService) UpdateSubject(ctx context.Context, userID, id uuid.UUID, req *models.UpdateSubjectRequest) (*models.Subject, error) {
 	subject, err := s.subjectRepo.GetByID(ctx, id)
 	if err != nil {
 		return nil, err
 	}
 
+	if _, err := s.authz.AuthorizeSubject(ctx, userID, id, policy.ResourceSubject, policy.ActionUpdate); err != nil {
+		return nil, err
+	}
+
 	if req.Name != nil {
 		subject.Name = *req.Name
 	}
 	if req.Description != nil {
 		subject.Description = *req.Description
 	}
+	if req.Visibility != nil {
+		subject.Visibility = *req.Visibility
+	}
+	if req.TermName != nil {
+		subject.TermName = *req.TermName
+	}
+	if req.TermStart != nil {
+		subject.TermStart = req.TermStart
+	}
+	if req.TermEnd != nil {
+		subject.TermEnd = req.TermEnd
+	}
+	if err := validateTerm(subject.TermStart, subject.TermEnd); err != nil {
+		return nil, err
+	}
 	subject.UpdatedAt = time.Now()
 
 	if err := s.subjectRepo.Update(ctx, subject); err != nil {
 		return nil, err
 	}
+	if req.RequiresApproval != nil {
+		if err := s.subjectRepo.SetRequiresApproval(ctx, id, *req.RequiresApproval); err != nil {
+			return nil, err
+		}
+		subject.RequiresApproval = *req.RequiresApproval
+	}
 
 	return subject, nil
 }
 
-func (s *SubjectService) DeleteSubject(ctx context.Context, id uuid.UUID) error {
+func (s *SubjectService) DeleteSubject(ctx context.Context, userID, id uuid.UUID) error {
+	if _, err := s.subjectRepo.GetByID(ctx, id); err != nil {
+		return err
+	}
+
+	if _, err := s.authz.AuthorizeSubject(ctx, userID, id, policy.ResourceSubject, policy.ActionDelete); err != nil {
+		return err
+	}
+
 	return s.subjectRepo.Delete(ctx, id)
 }
 
-func (s *SubjectService) JoinSubject(ctx context.Context, userID uuid.UUID, code string) (*models.Role, error) {
-
-	subject, err := s.subjectRepo.GetByCode(ctx, code)
+// JoinSubject вступление в предмет по коду приглашения или по постоянному коду предмета.
+// Если предмет требует подтверждения, вместо роли возвращается заявка на вступление;
+// приглашения подтверждения не требуют.
+func (s *SubjectService) JoinSubject(ctx context.Context, userID uuid.UUID, code string) (*models.Role, *models.JoinRequest, error) {
+	invite, err := s.invites.Resolve(ctx, userID, code, models.InviteTargetSubject)
 	if err != nil {
-		return nil, errors.New("subject with this code not found")
+		return nil, nil, err
+	}
+
+	var subject *models.Subject
+	roleType := models.RoleStudent
+	if invite != nil {
+		subject, err = s.subjectRepo.GetByID(ctx, *invite.SubjectID)
+		if err != nil {
+			return nil, nil, err
+		}
+		roleType = invite.RoleType
+	} else {
+		subject, err = s.subjectRepo.GetByCode(ctx, code)
+		if err != nil {
+			return nil, nil, errors.New("subject with this code not found")
+		}
+	}
+
+	if subject.IsArchived() {
+		return nil, nil, ErrSubjectArchived
 	}
 
 	existingRole, err := s.roleRepo.GetByUserAndSubject(ctx, userID, subject.ID)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	if existingRole != nil {
-		return nil, errors.New("user is already a member of this subject")
+		return nil, nil, errors.New("user is already a member of this subject")
+	}
+
+	if invite == nil && subject.RequiresApproval {
+		request, err := s.requests.RequestSubject(ctx, userID, subject)
+		if err != nil {
+			return nil, nil, err
+		}
+		return nil, request, nil
 	}
 
 	role := &models.Role{
 		ID:        uuid.New(),
 		UserID:    userID,
 		SubjectID: subject.ID,
-		RoleType:  models.RoleStudent,
+		RoleType:  roleType,
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
 	}
 
-	if err := s.roleRepo.Create(ctx, role); err != nil {
-		return nil, err
+	// приглашение используется в одной транзакции с созданием роли: неудачное вступление
+	// не тратит его, а исчерпанное приглашение не дает роли
+	if invite != nil {
+		ok, err := s.roleRepo.CreateFromInvite(ctx, role, invite.ID)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !ok {
+			return nil, nil, ErrInviteInvalid
+		}
+	} else if err := s.roleRepo.Create(ctx, role); err != nil {
+		return nil, nil, err
+	}
+
+	// группа могла быть удалена после выдачи приглашения, вступление от этого не срывается
+	if invite != nil && invite.GroupID != nil {
+		if err := s.groups.Assign(ctx, *invite.GroupID, userID); err != nil {
+			log.Printf("failed to assign user %s to group %s: %v", userID, *invite.GroupID, err)
+		}
 	}
 
 	role.Subject = subject
-	return role, nil
+	return role, nil, nil
+}
+
+// ArchiveSubject переводит предмет в архив или возвращает из него. Архивный предмет
+// остается доступен участникам для чтения, но задания, проекты и задачи в нем не меняются.
+func (s *SubjectService) ArchiveSubject(ctx context.Context, userID, id uuid.UUID, archived bool) (*models.Subject, error) {
+	subject, err := s.subjectRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := s.authz.AuthorizeSubject(ctx, userID, id, policy.ResourceSubject, policy.ActionUpdate); err != nil {
+		return nil, err
+	}
+
+	if subject.IsArchived() == archived {
+		return subject, nil
+	}
+
+	var archivedAt *time.Time
+	if archived {
+		now := time.Now()
+		archivedAt = &now
+	}
+	if err := s.subjectRepo.SetArchivedAt(ctx, id, archivedAt); err != nil {
+		return nil, err
+	}
+	subject.ArchivedAt = archivedAt
+	return subject, nil
+}
+
+// CloneSubject создает предмет нового семестра по образцу существующего: копируются описание
+// и задания вместе с шаблонами этапов, даты начала и сроки сдачи сдвигаются на разницу между началами семестров.
+// Задания копируются черновиками, чтобы преподаватель опубликовал их к новому семестру. Участники, проекты
+// и коды приглашений не копируются, создатель копии становится ее владельцем.
+func (s *SubjectService) CloneSubject(ctx context.Context, userID, sourceID uuid.UUID, req *models.CloneSubjectRequest) (*models.Subject, error) {
+	source, err := s.subjectRepo.GetByID(ctx, sourceID)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := s.authz.AuthorizeSubject(ctx, userID, sourceID, policy.ResourceSubject, policy.ActionUpdate); err != nil {
+		return nil, err
+	}
+
+	tasks, err := s.taskRepo.GetAllBySubject(ctx, sourceID)
+	if err != nil {
+		return nil, err
+	}
+
+	description := source.Description
+	if req.Description != nil {
+		description = *req.Description
+	}
+
+	clone, adminRole, err := newSubject(userID, &models.CreateSubjectRequest{
+		Name:             req.Name,
+		Description:      description,
+		Code:             req.Code,
+		RequiresApproval: source.RequiresApproval,
+		Visibility:       source.Visibility,
+		TermName:         req.TermName,
+		TermStart:        req.TermStart,
+		TermEnd:          req.TermEnd,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// без начала обоих семестров сдвиг не определен, и сроки сдачи сбрасываются
+	var shift time.Duration
+	keepDueDates := source.TermStart != nil && req.TermStart != nil
+	if keepDueDates {
+		shift = req.TermStart.Sub(*source.TermStart)
+	}
+
+	// все копируется одной транзакцией, чтобы сбой не оставил полупустой предмет
+	copies := make([]*models.Task, 0, len(tasks))
+	var templates []*models.ProblemTemplate
+	for _, t := range tasks {
+		task := &models.Task{
+			ID:          uuid.New(),
+			SubjectID:   clone.ID,
+			CreatedByID: userID,
+			Title:       t.Title,
+			Description: t.Description,
+			Status:      models.TaskDraft,
+			CreatedAt:   time.Now(),
+			UpdatedAt:   time.Now(),
+		}
+		if keepDueDates && t.DueDate != nil {
+			dueDate := t.DueDate.Add(shift)
+			task.DueDate = &dueDate
+		}
+		if keepDueDates && t.StartDate != nil {
+			startDate := t.StartDate.Add(shift)
+			task.StartDate = &startDate
+		}
+		copies = append(copies, task)
+
+		nodes, err := s.templates.CloneTemplates(ctx, t.ID, task.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to copy template of task %q: %w", t.Title, err)
+		}
+		templates = append(templates, nodes...)
+	}
+
+	if err := s.subjectRepo.CreateWithContents(ctx, clone, adminRole, copies, templates); err != nil {
+		return nil, fmt.Errorf("failed to clone subject: %w", err)
+	}
+	return clone, nil
+}
+
+func validateTerm(start, end *time.Time) error {
+	if start != nil && end != nil && !end.After(*start) {
+		return errors.New("term end must be after term start")
+	}
+	return nil
 }
 
 func (s *SubjectService) GetUserSubjects(ctx context.Context, userID uuid.UUID) ([]*models.Subject, error) {
 	return s.subjectRepo.GetUserSubjects(ctx, userID)
 }
+
+// ownershipTransferTTL срок, в течение которого получатель может принять владение
+const ownershipTransferTTL = 7 * 24 * time.Hour
+
+// TransferOwnership предлагает участнику предмета стать владельцем. Владение переходит
+// только после подтверждения получателем; прежние ожидающие предложения отменяются.
+func (s *SubjectService) TransferOwnership(ctx context.Context, userID, subjectID uuid.UUID, req *models.TransferOwnershipRequest) (*models.OwnershipTransfer, error) {
+	subject, err := s.subjectRepo.GetByID(ctx, subjectID)
+	if err != nil {
+		return nil, err
+	}
+
+	p, _, err := s.authz.SubjectPrincipal(ctx, userID, subjectID)
+	if err != nil {
+		return nil, err
+	}
+	if subject.OwnerID != userID && !p.Has(policy.RoleSuperAdmin) {
+		return nil, fmt.Errorf("%w: only the owner can transfer ownership", policy.ErrForbidden)
+	}
+
+	if req.UserID == subject.OwnerID {
+		return nil, errors.New("user is already the owner")
+	}
+	target, err := s.roleRepo.GetByUserAndSubject(ctx, req.UserID, subjectID)
+	if err != nil {
+		return nil, err
+	}
+	if target == nil {
+		return nil, errors.New("new owner must be a member of this subject")
+	}
+
+	if err := s.transfers.CancelPending(ctx, subjectID); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	transfer := &models.OwnershipTransfer{
+		ID:         uuid.New(),
+		SubjectID:  subjectID,
+		FromUserID: subject.OwnerID,
+		ToUserID:   req.UserID,
+		Status:     models.OwnershipTransferPending,
+		DemoteFrom: req.DemoteSelf,
+		ExpiresAt:  now.Add(ownershipTransferTTL),
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	if err := s.transfers.Create(ctx, transfer); err != nil {
+		return nil, err
+	}
+
+	s.notify(ctx, req.UserID, models.NotificationOwnershipOffered, fmt.Sprintf("You are offered ownership of %q", subject.Name), subjectID)
+	return transfer, nil
+}
+
+// AcceptOwnershipTransfer получатель принимает владение и становится администратором
+func (s *SubjectService) AcceptOwnershipTransfer(ctx context.Context, userID, transferID uuid.UUID) (*models.Subject, error) {
+	transfer, err := s.pendingTransferFor(ctx, userID, transferID)
+	if err != nil {
+		return nil, err
+	}
+
+	subject, err := s.subjectRepo.GetByID(ctx, transfer.SubjectID)
+	if err != nil {
+		return nil, err
+	}
+	if subject.OwnerID != transfer.FromUserID {
+		return nil, errors.New("ownership transfer is outdated")
+	}
+
+	role, err := s.roleRepo.GetByUserAndSubject(ctx, userID, subject.ID)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return nil, errors.New("new owner must be a member of this subject")
+	}
+
+	ok, err := s.transfers.Decide(ctx, transfer.ID, models.OwnershipTransferAccepted)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, errors.New("ownership transfer is already processed")
+	}
+
+	if !role.IsAdmin() {
+		role.RoleType = models.RoleAdmin
+		role.ResetPermissions()
+		role.UpdatedAt = time.Now()
+		if err := s.roleRepo.UpdateAccess(ctx, role); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.subjectRepo.SetOwner(ctx, subject.ID, userID); err != nil {
+		return nil, err
+	}
+	subject.OwnerID = userID
+
+	if transfer.DemoteFrom {
+		previous, err := s.roleRepo.GetByUserAndSubject(ctx, transfer.FromUserID, subject.ID)
+		if err != nil {
+			return nil, err
+		}
+		if previous != nil && previous.IsAdmin() {
+			previous.RoleType = models.RoleTeacher
+			previous.ResetPermissions()
+			previous.UpdatedAt = time.Now()
+			if err := s.roleRepo.UpdateAccess(ctx, previous); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	s.notify(ctx, transfer.FromUserID, models.NotificationOwnershipAccepted, fmt.Sprintf("Ownership of %q was accepted", subject.Name), subject.ID)
+	return subject, nil
+}
+
+// DeclineOwnershipTransfer получатель отказывается от владения
+func (s *SubjectService) DeclineOwnershipTransfer(ctx context.Context, userID, transferID uuid.UUID) error {
+	transfer, err := s.pendingTransferFor(ctx, userID, transferID)
+	if err != nil {
+		return err
+	}
+
+	ok, err := s.transfers.Decide(ctx, transfer.ID, models.OwnershipTransferDeclined)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errors.New("ownership transfer is already processed")
+	}
+
+	s.notify(ctx, transfer.FromUserID, models.NotificationOwnershipDeclined, "Ownership transfer was declined", transfer.SubjectID)
+	return nil
+}
+
+// CancelOwnershipTransfer владелец отзывает свое предложение
+func (s *SubjectService) CancelOwnershipTransfer(ctx context.Context, userID, transferID uuid.UUID) error {
+	transfer, err := s.transfers.GetByID(ctx, transferID)
+	if err != nil {
+		return err
+	}
+
+	p, _, err := s.authz.SubjectPrincipal(ctx, userID, transfer.SubjectID)
+	if err != nil {
+		return err
+	}
+	if transfer.FromUserID != userID && !p.Has(policy.RoleSuperAdmin) {
+		return errors.New("ownership transfer not found")
+	}
+
+	ok, err := s.transfers.Decide(ctx, transfer.ID, models.OwnershipTransferCancelled)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errors.New("ownership transfer is already processed")
+	}
+	return nil
+}
+
+// GetMyOwnershipTransfers возвращает предложения владения, ожидающие ответа пользователя
+func (s *SubjectService) GetMyOwnershipTransfers(ctx context.Context, userID uuid.UUID) ([]*models.OwnershipTransfer, error) {
+	return s.transfers.GetPendingForUser(ctx, userID, time.Now())
+}
+
+func (s *SubjectService) pendingTransferFor(ctx context.Context, userID, transferID uuid.UUID) (*models.OwnershipTransfer, error) {
+	transfer, err := s.transfers.GetByID(ctx, transferID)
+	if err != nil {
+		return nil, err
+	}
+	if transfer.ToUserID != userID {
+		return nil, errors.New("ownership transfer not found")
+	}
+	if !transfer.IsPending(time.Now()) {
+		return nil, errors.New("ownership transfer is expired or already processed")
+	}
+	return transfer, nil
+}
+
+func (s *SubjectService) notify(ctx context.Context, userID uuid.UUID, notificationType models.NotificationType, title string, subjectID uuid.UUID) {
+	if err := s.notifier.Notify(ctx, userID, notificationType, title, "", &subjectID); err != nil {
+		log.Printf("failed to notify user %s: %v", userID, err)
+	}
+}
@@ -0,0 +1,198 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/wozhdeleniye/redclass-app/internal/models"
+	"github.com/wozhdeleniye/redclass-app/internal/policy"
+	"github.com/wozhdeleniye/redclass-app/internal/repositories/postgres"
+)
+
+// SubmissionService сдача проекта и действия преподавателя над сданным проектом.
+// Пока проект сдан, его проблемы и результаты заморожены политикой доступа.
+type SubmissionService struct {
+	submissionRepo *postgres.SubmissionRepository
+	projectRepo    *postgres.ProjectRepository
+	taskRepo       *postgres.TaskRepository
+	problemRepo    *postgres.ProblemRepository
+	resultRepo     *postgres.ResultRepository
+	extensions     *ExtensionService
+	authz          *Authorizer
+}
+
+func NewSubmissionService(
+	submissionRepo *postgres.SubmissionRepository,
+	projectRepo *postgres.ProjectRepository,
+	taskRepo *postgres.TaskRepository,
+	problemRepo *postgres.ProblemRepository,
+	resultRepo *postgres.ResultRepository,
+	extensions *ExtensionService,
+	authz *Authorizer,
+) *SubmissionService {
+	return &SubmissionService{
+		submissionRepo: submissionRepo,
+		projectRepo:    projectRepo,
+		taskRepo:       taskRepo,
+		problemRepo:    problemRepo,
+		resultRepo:     resultRepo,
+		extensions:     extensions,
+		authz:          authz,
+	}
+}
+
+// SubmitProject сдает проект от имени команды: сохраняет снимок дерева проблем с результатами
+// и замораживает проект. Сдает создатель проекта; опоздание считается от срока команды.
+func (s *SubmissionService) SubmitProject(ctx context.Context, userID, projectID uuid.UUID, req *models.SubmitProjectRequest) (*models.ProjectSubmission, error) {
+	project, err := s.projectRepo.GetByID(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.authz.AuthorizeProject(ctx, userID, project, policy.ResourceProject, policy.ActionUpdate); err != nil {
+		return nil, err
+	}
+	if project.Status.IsLocked() {
+		return nil, errors.New("project is already submitted")
+	}
+	if project.IsArchived() {
+		return nil, errors.New("project is archived")
+	}
+
+	now := time.Now()
+	late, err := s.extensions.LateStatus(ctx, projectID, nil, now)
+	if err != nil {
+		return nil, err
+	}
+	if late.Closed {
+		return nil, errors.New("submissions for this task are no longer accepted")
+	}
+
+	problems, err := s.problemRepo.GetProjectProblems(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	results, err := s.resultRepo.GetByProject(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	snapshot := make([]*models.SnapshotProblem, 0, len(problems))
+	for _, p := range problems {
+		snapshot = append(snapshot, models.NewSnapshotProblem(p, results[p.ID]))
+	}
+
+	// номер попытки назначает репозиторий в транзакции сдачи
+	submission := &models.ProjectSubmission{
+		ID:            uuid.New(),
+		ProjectID:     projectID,
+		SubmittedByID: userID,
+		Comment:       req.Comment,
+		Late:          late.Late,
+		DaysLate:      late.DaysLate,
+		Penalty:       late.Penalty,
+		Problems:      snapshot,
+		CreatedAt:     now,
+	}
+	ok, err := s.submissionRepo.Submit(ctx, submission)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, errors.New("project is already submitted")
+	}
+	return submission, nil
+}
+
+// StartReview отмечает, что преподаватель взял сданный проект на проверку
+func (s *SubmissionService) StartReview(ctx context.Context, userID, projectID uuid.UUID) (*models.Project, error) {
+	project, err := s.authorizeReview(ctx, userID, projectID)
+	if err != nil {
+		return nil, err
+	}
+	if project.Status != models.ProjectSubmitted {
+		return nil, errors.New("only a submitted project can be taken for review")
+	}
+
+	if err := s.projectRepo.SetStatus(ctx, projectID, models.ProjectUnderReview, project.SubmittedAt); err != nil {
+		return nil, err
+	}
+	project.Status = models.ProjectUnderReview
+	return project, nil
+}
+
+// ReturnProject возвращает сданный проект на доработку с комментарием; команда снова может
+// менять проблемы и результаты, а затем сдать проект повторно
+func (s *SubmissionService) ReturnProject(ctx context.Context, userID, projectID uuid.UUID, req *models.ReturnProjectRequest) (*models.ProjectSubmission, error) {
+	project, err := s.authorizeReview(ctx, userID, projectID)
+	if err != nil {
+		return nil, err
+	}
+	if !project.Status.IsLocked() {
+		return nil, errors.New("project is not submitted")
+	}
+
+	submission, err := s.submissionRepo.GetLatest(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	if submission == nil {
+		return nil, errors.New("project has no submissions")
+	}
+
+	now := time.Now()
+	submission.ReturnedAt = &now
+	submission.ReturnedByID = &userID
+	submission.ReturnComment = req.Comment
+	ok, err := s.submissionRepo.Return(ctx, submission)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, errors.New("project is not submitted")
+	}
+	return submission, nil
+}
+
+// GetSubmissions история сдач проекта для его участников и преподавателей
+func (s *SubmissionService) GetSubmissions(ctx context.Context, viewerID, projectID uuid.UUID) ([]*models.ProjectSubmission, error) {
+	project, err := s.projectRepo.GetByID(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.authz.AuthorizeProject(ctx, viewerID, project, policy.ResourceProject, policy.ActionView); err != nil {
+		return nil, err
+	}
+	return s.submissionRepo.GetByProject(ctx, projectID)
+}
+
+func (s *SubmissionService) GetSubmission(ctx context.Context, viewerID, submissionID uuid.UUID) (*models.ProjectSubmission, error) {
+	submission, err := s.submissionRepo.GetByID(ctx, submissionID)
+	if err != nil {
+		return nil, err
+	}
+	project, err := s.projectRepo.GetByID(ctx, submission.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.authz.AuthorizeProject(ctx, viewerID, project, policy.ResourceProject, policy.ActionView); err != nil {
+		return nil, err
+	}
+	return submission, nil
+}
+
+// authorizeReview находит проект и проверяет, что пользователь ведет его задание
+func (s *SubmissionService) authorizeReview(ctx context.Context, userID, projectID uuid.UUID) (*models.Project, error) {
+	project, err := s.projectRepo.GetByID(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	task, err := s.taskRepo.GetByID(ctx, project.TaskID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.authz.AuthorizeTask(ctx, userID, task, policy.ActionUpdate); err != nil {
+		return nil, err
+	}
+	return project, nil
+}
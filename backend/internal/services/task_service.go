@@ -7,6 +7,7 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/wozhdeleniye/redclass-app/internal/models"
+	"github.com/wozhdeleniye/redclass-app/internal/policy"
 	"github.com/wozhdeleniye/redclass-app/internal/repositories/postgres"
 )
 
@@ -14,34 +15,47 @@ type TaskService struct {
 	taskRepo    *postgres.TaskRepository
 	roleRepo    *postgres.RoleRepository
 	subjectRepo *postgres.SubjectRepository
+	groups      *GroupService
+	problems    *ProblemService
+	authz       *Authorizer
 }
 
 func NewTaskService(
 	taskRepo *postgres.TaskRepository,
 	roleRepo *postgres.RoleRepository,
 	subjectRepo *postgres.SubjectRepository,
+	groups *GroupService,
+	problems *ProblemService,
+	authz *Authorizer,
 ) *TaskService {
 	return &TaskService{
 		taskRepo:    taskRepo,
 		roleRepo:    roleRepo,
 		subjectRepo: subjectRepo,
+		groups:      groups,
+		problems:    problems,
+		authz:       authz,
 	}
 }
 
 func (s *TaskService) CreateTask(ctx context.Context, userID uuid.UUID, req *models.CreateTaskRequest) (*models.Task, error) {
 
-	userRole, err := s.roleRepo.GetByUserAndSubject(ctx, userID, req.SubjectID)
-	if err != nil {
+	if _, err := s.authz.AuthorizeSubject(ctx, userID, req.SubjectID, policy.ResourceTask, policy.ActionCreate); err != nil {
 		return nil, err
 	}
-	if userRole == nil || (!userRole.IsTeacher() && !userRole.IsAdmin()) {
-		return nil, errors.New("only teacher or admin can create tasks")
-	}
 
 	if req.Title == "" {
 		return nil, errors.New("task title is required")
 	}
 
+	status := models.TaskPublished
+	if req.Draft {
+		status = models.TaskDraft
+	}
+	if err := validateTaskDates(req.StartDate, req.DueDate); err != nil {
+		return nil, err
+	}
+
 	task := &models.Task{
 		ID:          uuid.New(),
 		SubjectID:   req.SubjectID,
@@ -49,22 +63,81 @@ func (s *TaskService) CreateTask(ctx context.Context, userID uuid.UUID, req *mod
 		Title:       req.Title,
 		Description: req.Description,
 		DueDate:     req.DueDate,
+		Status:      status,
+		PublishAt:   req.PublishAt,
+		StartDate:   req.StartDate,
 		CreatedAt:   time.Now(),
 		UpdatedAt:   time.Now(),
 	}
 
+	task.TeamFormation = models.TeamFormationSelf
+	if err := applyTeamSettings(task, &req.TeamSettings); err != nil {
+		return nil, err
+	}
+	applyLatePolicy(task, &req.LatePolicy)
+
+	targets, err := s.groups.TaskTargets(ctx, req.SubjectID, task.ID, req.Groups)
+	if err != nil {
+		return nil, err
+	}
+
 	if err := s.taskRepo.Create(ctx, task); err != nil {
 		return nil, err
 	}
+	if err := s.groups.SaveTaskTargets(ctx, task.ID, targets); err != nil {
+		return nil, err
+	}
+	task.Groups = targets
 
 	return task, nil
 }
 
-func (s *TaskService) GetTaskByID(ctx context.Context, id uuid.UUID) (*models.Task, error) {
-	return s.taskRepo.GetByID(ctx, id)
+// GetTaskByID задание глазами зрителя (uuid.Nil для анонима)
+func (s *TaskService) GetTaskByID(ctx context.Context, viewerID, id uuid.UUID) (*models.Task, error) {
+	task, err := s.taskRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if task.Subject == nil {
+		return nil, ErrSubjectNotFound
+	}
+
+	access, err := s.authz.SubjectAccess(ctx, viewerID, task.Subject)
+	if err != nil {
+		return nil, err
+	}
+	if !access.Visible {
+		return nil, errors.New("task not found")
+	}
+	if !access.Contents {
+		return nil, &policy.ForbiddenError{Resource: policy.ResourceTask, Action: policy.ActionView, Reason: "available to subject members only"}
+	}
+	if !access.ManageTasks && !task.IsPublished(time.Now()) {
+		return nil, errors.New("task not found")
+	}
+	if !access.ManageMembers {
+		task.Subject.Code = ""
+	}
+	return task, nil
 }
 
-func (s *TaskService) GetTasksBySubject(ctx context.Context, subjectID uuid.UUID, limit, offset int) ([]*models.Task, int64, error) {
+// GetTasksBySubject задания предмета; при groupID только назначенные этой группе или всему предмету
+func (s *TaskService) GetTasksBySubject(ctx context.Context, viewerID, subjectID uuid.UUID, groupID *uuid.UUID, limit, offset int) ([]*models.Task, int64, error) {
+	subject, err := s.subjectRepo.GetShallowByID(ctx, subjectID)
+	if err != nil {
+		return nil, 0, err
+	}
+	access, err := s.authz.SubjectAccess(ctx, viewerID, subject)
+	if err != nil {
+		return nil, 0, err
+	}
+	if !access.Visible {
+		return nil, 0, ErrSubjectNotFound
+	}
+	if !access.Contents {
+		return nil, 0, &policy.ForbiddenError{Resource: policy.ResourceTask, Action: policy.ActionView, Reason: "available to subject members only"}
+	}
+
 	if limit <= 0 {
 		limit = 20
 	}
@@ -74,7 +147,7 @@ func (s *TaskService) GetTasksBySubject(ctx context.Context, subjectID uuid.UUID
 	if offset < 0 {
 		offset = 0
 	}
-	return s.taskRepo.GetBySubject(ctx, subjectID, limit, offset)
+	return s.taskRepo.GetBySubject(ctx, subjectID, groupID, !access.ManageTasks, limit, offset)
 }
 
 func (s *TaskService) UpdateTask(ctx context.Context, userID, taskID uuid.UUID, req *models.UpdateTaskRequest) (*models.Task, error) {
@@ -83,13 +156,9 @@ func (s *TaskService) UpdateTask(ctx context.Context, userID, taskID uuid.UUID,
 		return nil, err
 	}
 
-	userRole, err := s.roleRepo.GetByUserAndSubject(ctx, userID, task.SubjectID)
-	if err != nil {
+	if err := s.authz.AuthorizeTask(ctx, userID, task, policy.ActionUpdate); err != nil {
 		return nil, err
 	}
-	if userRole == nil || (task.CreatedByID != userID && !userRole.IsAdmin()) {
-		return nil, errors.New("only task creator or admin can update task")
-	}
 
 	if req.Title != nil {
 		task.Title = *req.Title
@@ -97,30 +166,155 @@ func (s *TaskService) UpdateTask(ctx context.Context, userID, taskID uuid.UUID,
 	if req.Description != nil {
 		task.Description = *req.Description
 	}
-	if req.DueDate != nil {
+	if req.ClearDueDate {
+		task.DueDate = nil
+	} else if req.DueDate != nil {
 		task.DueDate = req.DueDate
 	}
+	if req.StartDate != nil {
+		task.StartDate = req.StartDate
+	}
+	if err := validateTaskDates(task.StartDate, task.DueDate); err != nil {
+		return nil, err
+	}
+	if err := applyTeamSettings(task, &req.TeamSettings); err != nil {
+		return nil, err
+	}
+	applyLatePolicy(task, &req.LatePolicy)
+	if req.ClearLateCutoff {
+		task.LateCutoffDays = nil
+	}
 	task.UpdatedAt = time.Now()
 
+	var targets []*models.TaskGroup
+	if req.Groups != nil {
+		if targets, err = s.groups.TaskTargets(ctx, task.SubjectID, task.ID, *req.Groups); err != nil {
+			return nil, err
+		}
+	}
+
 	if err := s.taskRepo.Update(ctx, task); err != nil {
 		return nil, err
 	}
+	if req.Groups != nil {
+		if err := s.groups.SaveTaskTargets(ctx, task.ID, targets); err != nil {
+			return nil, err
+		}
+		if task, err = s.taskRepo.GetByID(ctx, task.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	// сроки групп тоже задаются в Groups, поэтому их изменение тоже переносится в проекты
+	if req.DueDate != nil || req.ClearDueDate || req.StartDate != nil || req.Groups != nil {
+		if task.ScheduleImpact, err = s.problems.RescheduleTask(ctx, task); err != nil {
+			return nil, err
+		}
+	}
 
 	return task, nil
 }
 
-func (s *TaskService) DeleteTask(ctx context.Context, userID, taskID uuid.UUID) error {
+// GetScheduleImpact подпроблемы проектов задания, которые не укладываются в окно своего проекта
+func (s *TaskService) GetScheduleImpact(ctx context.Context, userID, taskID uuid.UUID) (*models.ScheduleImpact, error) {
 	task, err := s.taskRepo.GetByID(ctx, taskID)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	if _, err := s.authz.AuthorizeSubject(ctx, userID, task.SubjectID, policy.ResourceProject, policy.ActionView); err != nil {
+		return nil, err
+	}
+
+	outOfWindow, err := s.problems.OutOfWindow(ctx, taskID)
+	if err != nil {
+		return nil, err
+	}
+	return &models.ScheduleImpact{OutOfWindow: outOfWindow}, nil
+}
+
+func validateTaskDates(start, due *time.Time) error {
+	if start != nil && due != nil && start.After(*due) {
+		return errors.New("start_date cannot be after due_date")
+	}
+	return nil
+}
+
+// PublishTask публикует задание сразу или в назначенное время
+func (s *TaskService) PublishTask(ctx context.Context, userID, taskID uuid.UUID, req *models.PublishTaskRequest) (*models.Task, error) {
+	return s.setStatus(ctx, userID, taskID, models.TaskPublished, req.PublishAt)
+}
+
+// UnpublishTask возвращает задание в черновики; созданные по нему проекты сохраняются
+func (s *TaskService) UnpublishTask(ctx context.Context, userID, taskID uuid.UUID) (*models.Task, error) {
+	return s.setStatus(ctx, userID, taskID, models.TaskDraft, nil)
+}
+
+func (s *TaskService) setStatus(ctx context.Context, userID, taskID uuid.UUID, status models.TaskStatus, publishAt *time.Time) (*models.Task, error) {
+	task, err := s.taskRepo.GetByID(ctx, taskID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.authz.AuthorizeTask(ctx, userID, task, policy.ActionUpdate); err != nil {
+		return nil, err
+	}
+
+	task.Status = status
+	task.PublishAt = publishAt
+	task.UpdatedAt = time.Now()
+	if err := s.taskRepo.Update(ctx, task); err != nil {
+		return nil, err
+	}
+	return task, nil
+}
+
+// applyTeamSettings переносит заданные правила формирования команд в задание
+func applyTeamSettings(task *models.Task, settings *models.TeamSettings) error {
+	sizeOrNil := func(size *int) *int {
+		if size == nil || *size == 0 {
+			return nil
+		}
+		return size
+	}
+	if settings.TeamMinSize != nil {
+		task.TeamMinSize = sizeOrNil(settings.TeamMinSize)
+	}
+	if settings.TeamMaxSize != nil {
+		task.TeamMaxSize = sizeOrNil(settings.TeamMaxSize)
+	}
+	if settings.TeamDeadline != nil {
+		task.TeamDeadline = settings.TeamDeadline
+	}
+	if settings.TeamFormation != "" {
+		task.TeamFormation = settings.TeamFormation
 	}
 
-	userRole, err := s.roleRepo.GetByUserAndSubject(ctx, userID, task.SubjectID)
+	if task.TeamMinSize != nil && task.TeamMaxSize != nil && *task.TeamMinSize > *task.TeamMaxSize {
+		return errors.New("team_min_size cannot be greater than team_max_size")
+	}
+	return nil
+}
+
+// applyLatePolicy переносит заданные параметры политики опозданий в задание
+func applyLatePolicy(task *models.Task, late *models.LatePolicy) {
+	if late.LateGraceHours != nil {
+		task.LateGraceHours = *late.LateGraceHours
+	}
+	if late.LatePenaltyPerDay != nil {
+		task.LatePenaltyPerDay = *late.LatePenaltyPerDay
+	}
+	if late.LateCutoffDays != nil {
+		task.LateCutoffDays = late.LateCutoffDays
+	}
+}
+
+func (s *TaskService) DeleteTask(ctx context.Context, userID, taskID uuid.UUID) error {
+	task, err := s.taskRepo.GetByID(ctx, taskID)
 	if err != nil {
 		return err
 	}
-	if userRole == nil || (task.CreatedByID != userID && !userRole.IsAdmin()) {
-		return errors.New("only task creator or admin can delete task")
+
+	if err := s.authz.AuthorizeTask(ctx, userID, task, policy.ActionDelete); err != nil {
+		return err
 	}
 
 	return s.taskRepo.Delete(ctx, taskID)
@@ -0,0 +1,657 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/wozhdeleniye/redclass-app/internal/models"
+	"github.com/wozhdeleniye/redclass-app/internal/policy"
+	"github.com/wozhdeleniye/redclass-app/internal/repositories/postgres"
+)
+
+// TeamService правила формирования команд по заданию и управление командами преподавателем.
+// Команда задания это его проект.
+type TeamService struct {
+	projectRepo *postgres.ProjectRepository
+	taskRepo    *postgres.TaskRepository
+	roleRepo    *postgres.RoleRepository
+	problems    *ProblemService
+	groups      *GroupService
+	templates   *ProblemTemplateService
+	authz       *Authorizer
+}
+
+func NewTeamService(
+	projectRepo *postgres.ProjectRepository,
+	taskRepo *postgres.TaskRepository,
+	roleRepo *postgres.RoleRepository,
+	problems *ProblemService,
+	groups *GroupService,
+	templates *ProblemTemplateService,
+	authz *Authorizer,
+) *TeamService {
+	return &TeamService{
+		projectRepo: projectRepo,
+		taskRepo:    taskRepo,
+		roleRepo:    roleRepo,
+		problems:    problems,
+		groups:      groups,
+		templates:   templates,
+		authz:       authz,
+	}
+}
+
+// checkTeamRules участник предмета может сам менять состав команд, только если их не
+// назначает преподаватель и срок регистрации не истек. От правил освобождены лишь те,
+// кто управляет заданием: его автор и обладатели права tasks.manage.
+func checkTeamRules(task *models.Task, role *models.Role, action policy.Action) error {
+	if role == nil {
+		return &policy.ForbiddenError{Resource: policy.ResourceProject, Action: action, Reason: "not a member of the subject"}
+	}
+	if task.CreatedByID == role.UserID || role.HasPermission(models.PermTasksManage) {
+		return nil
+	}
+	// неопубликованное задание видят только преподаватели, поэтому работать по нему нельзя
+	if !task.IsPublished(time.Now()) {
+		return errors.New("task not found")
+	}
+	if task.TeamsFormedByTeacher() {
+		return &policy.ForbiddenError{Resource: policy.ResourceProject, Action: action, Reason: "teams are assigned by the teacher"}
+	}
+	if task.TeamRegistrationClosed(time.Now()) {
+		return errors.New("team registration deadline has passed")
+	}
+	return nil
+}
+
+// CheckCreate проверяет, может ли пользователь с этой ролью создать команду по заданию
+func (s *TeamService) CheckCreate(ctx context.Context, task *models.Task, role *models.Role) error {
+	if err := checkTeamRules(task, role, policy.ActionCreate); err != nil {
+		return err
+	}
+	return s.checkTarget(ctx, task, role)
+}
+
+// checkTarget студент работает только по заданиям своей группы, как создатель команды, так и вступающий
+func (s *TeamService) checkTarget(ctx context.Context, task *models.Task, role *models.Role) error {
+	if !task.IsTargeted() || !role.IsStudent() {
+		return nil
+	}
+	groupIDs, err := s.groups.UserGroupIDs(ctx, task.SubjectID, role.UserID)
+	if err != nil {
+		return err
+	}
+	if !task.TargetsAny(groupIDs) {
+		return errors.New("task is not assigned to your group")
+	}
+	return nil
+}
+
+// CheckJoin проверяет правила задания перед вступлением пользователя в проект
+func (s *TeamService) CheckJoin(ctx context.Context, userID uuid.UUID, project *models.Project) error {
+	if project.IsArchived() {
+		return errors.New("project is archived")
+	}
+	task, err := s.taskRepo.GetByID(ctx, project.TaskID)
+	if err != nil {
+		return err
+	}
+	role, err := s.roleRepo.GetByUserAndSubject(ctx, userID, task.SubjectID)
+	if err != nil {
+		return err
+	}
+	if err := checkTeamRules(task, role, policy.ActionUpdate); err != nil {
+		return err
+	}
+	if err := s.checkTarget(ctx, task, role); err != nil {
+		return err
+	}
+	return s.checkVacancy(ctx, task, project, userID)
+}
+
+// checkVacancy пользователь еще не в команде по заданию, а в проекте есть место
+func (s *TeamService) checkVacancy(ctx context.Context, task *models.Task, project *models.Project, userID uuid.UUID) error {
+	existing, err := s.projectRepo.GetUserProjectByTask(ctx, userID, task.ID)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return errors.New("user can only be in one project per task")
+	}
+
+	size, err := s.projectRepo.CountMembers(ctx, project.ID)
+	if err != nil {
+		return err
+	}
+	if task.TeamIsFull(size) {
+		return errors.New("team is full")
+	}
+	return nil
+}
+
+// createProject создает проект с постоянным кодом, создателем-участником, главной проблемой
+// и этапами из шаблона задания
+func (s *TeamService) createProject(ctx context.Context, task *models.Task, creatorID uuid.UUID, req *models.CreateProjectRequest) (*models.Project, error) {
+	code, err := s.newProjectCode(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	project := &models.Project{
+		ID:               uuid.New(),
+		TaskID:           task.ID,
+		CreatorID:        creatorID,
+		Title:            req.Title,
+		Description:      req.Description,
+		Code:             code,
+		RequiresApproval: req.RequiresApproval,
+		CreatedAt:        time.Now(),
+		UpdatedAt:        time.Now(),
+	}
+
+	if err := s.projectRepo.Create(ctx, project); err != nil {
+		return nil, err
+	}
+
+	member := &models.ProjectMember{
+		ID:        uuid.New(),
+		ProjectID: project.ID,
+		UserID:    creatorID,
+		Role:      models.ProjectRoleCreator,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	if err := s.projectRepo.AddMember(ctx, member); err != nil {
+		return nil, err
+	}
+	project.Task = task
+
+	var groupID *uuid.UUID
+	if task.IsTargeted() {
+		groupIDs, err := s.groups.UserGroupIDs(ctx, task.SubjectID, creatorID)
+		if err != nil {
+			return nil, err
+		}
+		groupID = task.GroupFor(groupIDs)
+	}
+	if _, err := s.problems.CreateMainProblem(ctx, project, groupID); err != nil {
+		return nil, err
+	}
+	if err := s.templates.Instantiate(ctx, project); err != nil {
+		return nil, err
+	}
+
+	return project, nil
+}
+
+// newProjectCode подбирает постоянный код вступления, еще не занятый другим проектом
+func (s *TeamService) newProjectCode(ctx context.Context) (string, error) {
+	for i := 0; i < 5; i++ {
+		code, err := randomCode(8)
+		if err != nil {
+			return "", err
+		}
+		existing, err := s.projectRepo.GetByCode(ctx, code)
+		if err != nil {
+			return "", err
+		}
+		if existing == nil {
+			return code, nil
+		}
+	}
+	return "", errors.New("failed to generate unique project code")
+}
+
+func (s *TeamService) addMember(ctx context.Context, projectID, userID uuid.UUID) error {
+	return s.projectRepo.AddMember(ctx, &models.ProjectMember{
+		ID:        uuid.New(),
+		ProjectID: projectID,
+		UserID:    userID,
+		Role:      models.ProjectRoleMember,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	})
+}
+
+// authorizeTeams находит задание и проверяет, что пользователь может управлять его командами
+func (s *TeamService) authorizeTeams(ctx context.Context, userID, taskID uuid.UUID) (*models.Task, error) {
+	task, err := s.taskRepo.GetByID(ctx, taskID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.authz.AuthorizeTask(ctx, userID, task, policy.ActionUpdate); err != nil {
+		return nil, err
+	}
+	return task, nil
+}
+
+// requireStudent пользователь должен быть студентом предмета
+func (s *TeamService) requireStudent(ctx context.Context, subjectID, userID uuid.UUID) error {
+	role, err := s.roleRepo.GetByUserAndSubject(ctx, userID, subjectID)
+	if err != nil {
+		return err
+	}
+	if role == nil || !role.IsStudent() {
+		return fmt.Errorf("user %s is not a student of this subject", userID)
+	}
+	return nil
+}
+
+// CreateTeam создает команду из указанных студентов; первый из них становится создателем проекта
+func (s *TeamService) CreateTeam(ctx context.Context, userID, taskID uuid.UUID, req *models.CreateTeamRequest) (*models.Project, error) {
+	task, err := s.authorizeTeams(ctx, userID, taskID)
+	if err != nil {
+		return nil, err
+	}
+	if task.TeamMaxSize != nil && len(req.MemberIDs) > *task.TeamMaxSize {
+		return nil, errors.New("team exceeds team_max_size")
+	}
+
+	seen := make(map[uuid.UUID]bool, len(req.MemberIDs))
+	for _, memberID := range req.MemberIDs {
+		if seen[memberID] {
+			return nil, errors.New("member is listed twice")
+		}
+		seen[memberID] = true
+		if err := s.requireStudent(ctx, task.SubjectID, memberID); err != nil {
+			return nil, err
+		}
+		existing, err := s.projectRepo.GetUserProjectByTask(ctx, memberID, task.ID)
+		if err != nil {
+			return nil, err
+		}
+		if existing != nil {
+			return nil, fmt.Errorf("user %s is already in a team for this task", memberID)
+		}
+	}
+
+	project, err := s.createProject(ctx, task, req.MemberIDs[0], &models.CreateProjectRequest{
+		Title:       req.Title,
+		Description: req.Description,
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, memberID := range req.MemberIDs[1:] {
+		if err := s.addMember(ctx, project.ID, memberID); err != nil {
+			return nil, err
+		}
+	}
+	return s.projectRepo.GetByID(ctx, project.ID)
+}
+
+// MoveMember переводит студента в другую команду задания или исключает из текущей.
+// Если уходит создатель, его роль переходит к самому раннему участнику, а опустевшая команда удаляется.
+func (s *TeamService) MoveMember(ctx context.Context, userID, taskID uuid.UUID, req *models.MoveTeamMemberRequest) (*models.Project, error) {
+	task, err := s.authorizeTeams(ctx, userID, taskID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.requireStudent(ctx, task.SubjectID, req.UserID); err != nil {
+		return nil, err
+	}
+
+	var target *models.Project
+	if req.ProjectID != nil {
+		target, err = s.projectRepo.GetByID(ctx, *req.ProjectID)
+		if err != nil {
+			return nil, err
+		}
+		if target.TaskID != task.ID {
+			return nil, errors.New("project does not belong to this task")
+		}
+		if task.TeamIsFull(len(target.Members)) {
+			return nil, errors.New("team is full")
+		}
+	}
+
+	current, err := s.projectRepo.GetUserProjectByTask(ctx, req.UserID, task.ID)
+	if err != nil {
+		return nil, err
+	}
+	if current != nil && target != nil && current.ID == target.ID {
+		return target, nil
+	}
+	if current != nil {
+		if err := s.leave(ctx, current.ID, req.UserID, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	if target == nil {
+		return nil, nil
+	}
+	if err := s.addMember(ctx, target.ID, req.UserID); err != nil {
+		return nil, err
+	}
+	return s.projectRepo.GetByID(ctx, target.ID)
+}
+
+// leave убирает участника из проекта и при необходимости передает роль создателя или удаляет проект.
+// Проблемы участника переходят к reassignTo, а без него остаются без этого исполнителя.
+func (s *TeamService) leave(ctx context.Context, projectID, userID uuid.UUID, reassignTo *uuid.UUID) error {
+	project, err := s.projectRepo.GetByID(ctx, projectID)
+	if err != nil {
+		return err
+	}
+	if err := s.problems.ReassignMember(ctx, projectID, userID, reassignTo); err != nil {
+		return err
+	}
+	if err := s.projectRepo.RemoveMember(ctx, projectID, userID); err != nil {
+		return err
+	}
+	if project.CreatorID != userID {
+		return nil
+	}
+
+	var successor *models.ProjectMember
+	for _, m := range project.Members {
+		if m.UserID == userID {
+			continue
+		}
+		if successor == nil || m.CreatedAt.Before(successor.CreatedAt) {
+			successor = m
+		}
+	}
+	if successor == nil {
+		return s.projectRepo.Delete(ctx, projectID)
+	}
+	return s.projectRepo.SetCreator(ctx, projectID, successor.UserID)
+}
+
+// eligibleStudents студенты предмета, которым назначено задание, сгруппированные по группе.
+// Для задания всего предмета ключом служит первая группа студента или uuid.Nil.
+func (s *TeamService) eligibleStudents(ctx context.Context, task *models.Task) (map[uuid.UUID][]*models.Role, map[uuid.UUID][]uuid.UUID, error) {
+	roles, err := s.roleRepo.GetSubjectRoles(ctx, task.SubjectID)
+	if err != nil {
+		return nil, nil, err
+	}
+	groupIDs, err := s.groups.GroupIDsByUser(ctx, task.SubjectID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	byGroup := make(map[uuid.UUID][]*models.Role)
+	for _, role := range roles {
+		if !role.IsStudent() {
+			continue
+		}
+		key, ok := teamGroupKey(task, groupIDs[role.UserID])
+		if !ok {
+			continue
+		}
+		byGroup[key] = append(byGroup[key], role)
+	}
+	return byGroup, groupIDs, nil
+}
+
+// teamGroupKey группа, в рамках которой студент распределяется по командам; false, если задание ему не назначено
+func teamGroupKey(task *models.Task, userGroups []uuid.UUID) (uuid.UUID, bool) {
+	if !task.IsTargeted() {
+		if len(userGroups) > 0 {
+			return userGroups[0], true
+		}
+		return uuid.Nil, true
+	}
+	for _, tg := range task.Groups {
+		for _, id := range userGroups {
+			if tg.GroupID == id {
+				return id, true
+			}
+		}
+	}
+	return uuid.Nil, false
+}
+
+// AutoForm распределяет студентов без команды. Команды формируются внутри групп, чтобы
+// в одну команду не попадали студенты разных групп. Новые команды не бывают меньше
+// TeamMinSize: лишние студенты добавляются в команды группы, где есть место, а если места
+// нет, распределение отклоняется целиком.
+func (s *TeamService) AutoForm(ctx context.Context, userID, taskID uuid.UUID, req *models.AutoFormTeamsRequest) (*models.AutoFormTeamsResult, error) {
+	task, err := s.authorizeTeams(ctx, userID, taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	size := req.TeamSize
+	if size == 0 && task.TeamMaxSize != nil {
+		size = *task.TeamMaxSize
+	}
+	if size == 0 {
+		return nil, errors.New("team_size is required when the task has no team_max_size")
+	}
+	if task.TeamMaxSize != nil && size > *task.TeamMaxSize {
+		return nil, errors.New("team_size exceeds team_max_size")
+	}
+	if task.TeamMinSize != nil && size < *task.TeamMinSize {
+		return nil, errors.New("team_size is below team_min_size")
+	}
+
+	byGroup, groupIDs, err := s.eligibleStudents(ctx, task)
+	if err != nil {
+		return nil, err
+	}
+	teams, err := s.projectRepo.GetTeams(ctx, task.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	assigned := make(map[uuid.UUID]bool)
+	teamsByGroup := make(map[uuid.UUID][]*models.Project)
+	for _, team := range teams {
+		for _, m := range team.Members {
+			assigned[m.UserID] = true
+		}
+		key, _ := teamGroupKey(task, groupIDs[team.CreatorID])
+		teamsByGroup[key] = append(teamsByGroup[key], team)
+	}
+
+	minSize := 0
+	if task.TeamMinSize != nil {
+		minSize = *task.TeamMinSize
+	}
+
+	// сначала распределение планируется целиком, чтобы отказ не оставил команды сформированными наполовину
+	type plannedTeam struct {
+		team    *models.Project
+		members []uuid.UUID
+	}
+	var planned []*plannedTeam
+	joins := make(map[uuid.UUID][]uuid.UUID)
+
+	// группы обходятся в постоянном порядке, чтобы номера команд не зависели от порядка map
+	keys := make([]uuid.UUID, 0, len(byGroup))
+	for key := range byGroup {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+
+	for _, key := range keys {
+		var pending []uuid.UUID
+		for _, role := range byGroup[key] {
+			if !assigned[role.UserID] {
+				pending = append(pending, role.UserID)
+			}
+		}
+		rand.Shuffle(len(pending), func(i, j int) { pending[i], pending[j] = pending[j], pending[i] })
+
+		// sizes текущие размеры существующих команд группы с учетом запланированных вступлений
+		existing := teamsByGroup[key]
+		sizes := make(map[uuid.UUID]int, len(existing))
+		for _, team := range existing {
+			sizes[team.ID] = len(team.Members)
+		}
+		smallest := func() *models.Project {
+			sort.SliceStable(existing, func(i, j int) bool { return sizes[existing[i].ID] < sizes[existing[j].ID] })
+			if len(existing) == 0 || sizes[existing[0].ID] >= size {
+				return nil
+			}
+			return existing[0]
+		}
+
+		if req.Mode == models.TeamFormationBalanced {
+			for len(pending) > 0 {
+				team := smallest()
+				if team == nil {
+					break
+				}
+				joins[team.ID] = append(joins[team.ID], pending[0])
+				sizes[team.ID]++
+				pending = pending[1:]
+			}
+		}
+
+		chunks, rest := splitEvenly(pending, size, minSize)
+		var fresh []*plannedTeam
+		for _, chunk := range chunks {
+			fresh = append(fresh, &plannedTeam{members: chunk})
+		}
+		// студенты, которых не хватает на команду минимального размера, добавляются
+		// в самые маленькие команды группы, где еще есть место
+		for _, userID := range rest {
+			var target *plannedTeam
+			for _, t := range fresh {
+				if len(t.members) < size && (target == nil || len(t.members) < len(target.members)) {
+					target = t
+				}
+			}
+			team := smallest()
+			if team != nil && (target == nil || sizes[team.ID] < len(target.members)) {
+				joins[team.ID] = append(joins[team.ID], userID)
+				sizes[team.ID]++
+				continue
+			}
+			if target == nil {
+				return nil, fmt.Errorf("cannot form teams of at least %d students: %d students would be left without a team", minSize, len(rest))
+			}
+			target.members = append(target.members, userID)
+		}
+		planned = append(planned, fresh...)
+	}
+
+	result := &models.AutoFormTeamsResult{Created: []*models.Project{}}
+	for teamID, members := range joins {
+		for _, memberID := range members {
+			if err := s.addMember(ctx, teamID, memberID); err != nil {
+				return nil, err
+			}
+		}
+		result.Assigned += len(members)
+	}
+
+	number := len(teams)
+	for _, t := range planned {
+		number++
+		project, err := s.createProject(ctx, task, t.members[0], &models.CreateProjectRequest{
+			Title: fmt.Sprintf("Team %d", number),
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, memberID := range t.members[1:] {
+			if err := s.addMember(ctx, project.ID, memberID); err != nil {
+				return nil, err
+			}
+		}
+		project.Task = nil
+		result.Created = append(result.Created, project)
+		result.Assigned += len(t.members)
+	}
+	return result, nil
+}
+
+// splitEvenly делит студентов на минимальное число команд не больше size так, чтобы
+// размеры команд различались не больше чем на одного человека. Если при этом команды
+// выходят меньше minSize, команд становится меньше; не поместившиеся студенты
+// возвращаются в rest.
+func splitEvenly(ids []uuid.UUID, size, minSize int) (chunks [][]uuid.UUID, rest []uuid.UUID) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	count := (len(ids) + size - 1) / size
+	if minSize > 0 && len(ids)/count < minSize {
+		count = len(ids) / minSize
+	}
+	if count == 0 {
+		return nil, ids
+	}
+
+	take := len(ids)
+	if take > count*size {
+		take = count * size
+	}
+	chunks = make([][]uuid.UUID, 0, count)
+	start := 0
+	for i := 0; i < count; i++ {
+		n := take / count
+		if i < take%count {
+			n++
+		}
+		chunks = append(chunks, ids[start:start+n])
+		start += n
+	}
+	return chunks, ids[take:]
+}
+
+// GetReport студенты без команды и команды, в которых меньше участников, чем требует задание
+func (s *TeamService) GetReport(ctx context.Context, userID, taskID uuid.UUID) (*models.TeamReport, error) {
+	task, err := s.authorizeTeams(ctx, userID, taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	byGroup, _, err := s.eligibleStudents(ctx, task)
+	if err != nil {
+		return nil, err
+	}
+	teams, err := s.projectRepo.GetTeams(ctx, task.ID)
+	if err != nil {
+		return nil, err
+	}
+	groupNames, err := s.groups.GroupNamesByUser(ctx, task.SubjectID)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &models.TeamReport{
+		Unassigned: []*models.UnassignedStudent{},
+		Undersized: []*models.Project{},
+	}
+	assigned := make(map[uuid.UUID]bool)
+	for _, team := range teams {
+		for _, m := range team.Members {
+			assigned[m.UserID] = true
+		}
+		if task.TeamMinSize != nil && len(team.Members) < *task.TeamMinSize {
+			report.Undersized = append(report.Undersized, team)
+		}
+	}
+
+	for _, roles := range byGroup {
+		for _, role := range roles {
+			if assigned[role.UserID] {
+				continue
+			}
+			report.Unassigned = append(report.Unassigned, &models.UnassignedStudent{
+				User:   role.User,
+				Groups: groupNames[role.UserID],
+			})
+		}
+	}
+	sort.Slice(report.Unassigned, func(i, j int) bool {
+		return unassignedName(report.Unassigned[i]) < unassignedName(report.Unassigned[j])
+	})
+	return report, nil
+}
+
+func unassignedName(s *models.UnassignedStudent) string {
+	if s.User == nil {
+		return ""
+	}
+	return s.User.Nickname
+}
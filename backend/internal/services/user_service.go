@@ -0,0 +1,178 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/wozhdeleniye/redclass-app/internal/models"
+	"github.com/wozhdeleniye/redclass-app/internal/repositories/postgres"
+)
+
+var ErrWrongPassword = errors.New("current password is incorrect")
+
+type UserService struct {
+	userRepo    *postgres.UserRepository
+	roleRepo    *postgres.RoleRepository
+	authService *AuthService
+}
+
+func NewUserService(userRepo *postgres.UserRepository, roleRepo *postgres.RoleRepository, authService *AuthService) *UserService {
+	return &UserService{
+		userRepo:    userRepo,
+		roleRepo:    roleRepo,
+		authService: authService,
+	}
+}
+
+// GetProfile возвращает собственный профиль пользователя
+func (s *UserService) GetProfile(ctx context.Context, userID uuid.UUID) (*models.User, error) {
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+	return user, nil
+}
+
+// UpdateProfile изменяет данные профиля, не требующие повторной аутентификации
+func (s *UserService) UpdateProfile(ctx context.Context, userID uuid.UUID, req *models.UpdateProfileRequest) (*models.User, error) {
+	user, err := s.GetProfile(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Nickname != nil {
+		nickname := strings.TrimSpace(*req.Nickname)
+		if nickname == "" {
+			return nil, errors.New("nickname cannot be empty")
+		}
+		user.Nickname = nickname
+	}
+
+	if err := s.userRepo.UpdateUser(ctx, user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// ChangePassword меняет пароль после проверки текущего, отзывает все сессии и выдает новые токены
+func (s *UserService) ChangePassword(ctx context.Context, userID uuid.UUID, req *models.ChangePasswordRequest) (*models.TokenPair, error) {
+	user, err := s.GetProfile(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !user.CheckPassword(req.CurrentPassword) {
+		return nil, ErrWrongPassword
+	}
+
+	if err := user.SetPassword(req.NewPassword); err != nil {
+		return nil, err
+	}
+	if err := s.userRepo.UpdateUser(ctx, user); err != nil {
+		return nil, err
+	}
+
+	if err := s.authService.RevokeSessions(ctx, user.ID); err != nil {
+		return nil, err
+	}
+	return s.authService.IssueTokens(user)
+}
+
+// ChangeEmail меняет email после проверки пароля, отзывает все сессии и выдает новые токены
+func (s *UserService) ChangeEmail(ctx context.Context, userID uuid.UUID, req *models.ChangeEmailRequest) (*models.AuthResponse, error) {
+	user, err := s.GetProfile(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !user.CheckPassword(req.Password) {
+		return nil, ErrWrongPassword
+	}
+
+	newEmail := strings.TrimSpace(req.NewEmail)
+	if strings.EqualFold(newEmail, user.Email) {
+		return nil, errors.New("new email is the same as current")
+	}
+
+	existing, err := s.userRepo.GetUserByEmailIncludingInactive(ctx, newEmail)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, errors.New("email is already taken")
+	}
+
+	user.Email = newEmail
+	if err := s.userRepo.UpdateUser(ctx, user); err != nil {
+		return nil, err
+	}
+
+	if err := s.authService.RevokeSessions(ctx, user.ID); err != nil {
+		return nil, err
+	}
+	tokens, err := s.authService.IssueTokens(user)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.AuthResponse{
+		User:         user,
+		AccessToken:  tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+	}, nil
+}
+
+// DeactivateOwnAccount деактивирует собственный аккаунт после проверки пароля
+func (s *UserService) DeactivateOwnAccount(ctx context.Context, userID uuid.UUID, req *models.DeactivateAccountRequest) error {
+	user, err := s.GetProfile(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !user.CheckPassword(req.Password) {
+		return ErrWrongPassword
+	}
+	return s.SetUserActive(ctx, userID, false)
+}
+
+// SetUserActive включает или выключает аккаунт; при выключении все сессии пользователя отзываются
+func (s *UserService) SetUserActive(ctx context.Context, userID uuid.UUID, active bool) error {
+	user, err := s.userRepo.GetUserByIDIncludingInactive(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return ErrUserNotFound
+	}
+
+	if err := s.userRepo.SetActive(ctx, userID, active); err != nil {
+		return err
+	}
+	if !active {
+		return s.authService.RevokeSessions(ctx, userID)
+	}
+	return nil
+}
+
+// GetUserCard возвращает публичную карточку пользователя; email виден только себе и участникам общих предметов
+func (s *UserService) GetUserCard(ctx context.Context, viewerID, userID uuid.UUID) (*models.UserCard, error) {
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+
+	withEmail := viewerID == userID
+	if !withEmail {
+		withEmail, err = s.roleRepo.SharesSubject(ctx, viewerID, userID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return user.Card(withEmail), nil
+}